@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+)
+
+// partUpdaters копируют одно поле update_mask из src в dst. Пути, которых здесь нет
+// (uuid, created_at, updated_at), изменить нельзя.
+var partUpdaters = map[string]func(dst, src *inventoryv1.Part){
+	"name":           func(dst, src *inventoryv1.Part) { dst.Name = src.GetName() },
+	"description":    func(dst, src *inventoryv1.Part) { dst.Description = src.GetDescription() },
+	"price":          func(dst, src *inventoryv1.Part) { dst.Price = src.GetPrice() },
+	"stock_quantity": func(dst, src *inventoryv1.Part) { dst.StockQuantity = src.GetStockQuantity() },
+	"category":       func(dst, src *inventoryv1.Part) { dst.Category = src.GetCategory() },
+	"dimensions": func(dst, src *inventoryv1.Part) {
+		dst.Dimensions = proto.Clone(src.GetDimensions()).(*inventoryv1.Dimensions)
+	},
+	"dimensions.length": func(dst, src *inventoryv1.Part) { dimensions(dst).Length = src.GetDimensions().GetLength() },
+	"dimensions.width":  func(dst, src *inventoryv1.Part) { dimensions(dst).Width = src.GetDimensions().GetWidth() },
+	"dimensions.height": func(dst, src *inventoryv1.Part) { dimensions(dst).Height = src.GetDimensions().GetHeight() },
+	"dimensions.weight": func(dst, src *inventoryv1.Part) { dimensions(dst).Weight = src.GetDimensions().GetWeight() },
+	"manufacturer": func(dst, src *inventoryv1.Part) {
+		dst.Manufacturer = proto.Clone(src.GetManufacturer()).(*inventoryv1.Manufacturer)
+	},
+	"manufacturer.name":    func(dst, src *inventoryv1.Part) { manufacturer(dst).Name = src.GetManufacturer().GetName() },
+	"manufacturer.country": func(dst, src *inventoryv1.Part) { manufacturer(dst).Country = src.GetManufacturer().GetCountry() },
+	"manufacturer.website": func(dst, src *inventoryv1.Part) { manufacturer(dst).Website = src.GetManufacturer().GetWebsite() },
+	"tags":                 func(dst, src *inventoryv1.Part) { dst.Tags = append([]string(nil), src.GetTags()...) },
+	"metadata": func(dst, src *inventoryv1.Part) {
+		dst.Metadata = make(map[string]*inventoryv1.Value, len(src.GetMetadata()))
+		for key, value := range src.GetMetadata() {
+			dst.Metadata[key] = proto.Clone(value).(*inventoryv1.Value)
+		}
+	},
+}
+
+// fullUpdateMask — поля, которые заменяет UpdatePart с пустой маской
+var fullUpdateMask = []string{
+	"name", "description", "price", "stock_quantity", "category",
+	"dimensions", "manufacturer", "tags", "metadata",
+}
+
+func dimensions(part *inventoryv1.Part) *inventoryv1.Dimensions {
+	if part.Dimensions == nil {
+		part.Dimensions = &inventoryv1.Dimensions{}
+	}
+	return part.Dimensions
+}
+
+func manufacturer(part *inventoryv1.Part) *inventoryv1.Manufacturer {
+	if part.Manufacturer == nil {
+		part.Manufacturer = &inventoryv1.Manufacturer{}
+	}
+	return part.Manufacturer
+}
+
+// partViolations проверяет деталь целиком и перечисляет все некорректные поля
+func partViolations(part *inventoryv1.Part) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	add := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+	}
+
+	if strings.TrimSpace(part.GetName()) == "" {
+		add("name", "must not be empty")
+	}
+	if !isPositive(part.GetPrice()) {
+		add("price", "must be positive")
+	}
+	if part.GetStockQuantity() < 0 {
+		add("stock_quantity", "must not be negative")
+	}
+	if _, ok := inventoryv1.Category_name[int32(part.GetCategory())]; !ok || part.GetCategory() == inventoryv1.Category_CATEGORY_UNSPECIFIED {
+		add("category", "must be a known category")
+	}
+
+	if part.GetDimensions() == nil {
+		add("dimensions", "is required")
+	} else {
+		for field, value := range map[string]float64{
+			"length": part.GetDimensions().GetLength(),
+			"width":  part.GetDimensions().GetWidth(),
+			"height": part.GetDimensions().GetHeight(),
+			"weight": part.GetDimensions().GetWeight(),
+		} {
+			if !isPositive(value) {
+				add("dimensions."+field, "must be positive")
+			}
+		}
+	}
+
+	for key, value := range part.GetMetadata() {
+		if value.GetValue() == nil {
+			add("metadata."+key, "must have a value")
+		}
+	}
+
+	// Порядок нарушений не зависит от обхода map
+	slices.SortFunc(violations, func(a, b *errdetails.BadRequest_FieldViolation) int {
+		return strings.Compare(a.GetField(), b.GetField())
+	})
+	return violations
+}
+
+// isPositive отсекает также NaN и +Inf
+func isPositive(v float64) bool {
+	return v > 0 && !math.IsInf(v, 0)
+}
+
+// isNonNegative отсекает также NaN и +Inf
+func isNonNegative(v float64) bool {
+	return v >= 0 && !math.IsInf(v, 0)
+}
+
+// invalidArgument возвращает InvalidArgument с деталями google.rpc.BadRequest
+func invalidArgument(violations []*errdetails.BadRequest_FieldViolation) error {
+	st := status.Newf(codes.InvalidArgument, "invalid %s: %s", violations[0].GetField(), violations[0].GetDescription())
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		// Детали не сериализовались: отдаем статус без них
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func invalidField(field, description string) error {
+	return invalidArgument([]*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}})
+}
+
+func (s *inventoryService) CreatePart(ctx context.Context, req *inventoryv1.CreatePartRequest) (*inventoryv1.CreatePartResponse, error) {
+	if req.GetPart() == nil {
+		return nil, invalidField("part", "is required")
+	}
+
+	part := proto.Clone(req.GetPart()).(*inventoryv1.Part)
+	if violations := partViolations(part); len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+
+	createdAt := timestamppb.New(now())
+	part.Uuid = uuid.New().String()
+	part.CreatedAt = createdAt
+	part.UpdatedAt = createdAt
+	if part.Metadata == nil {
+		part.Metadata = make(map[string]*inventoryv1.Value)
+	}
+
+	if err := s.repo.CreatePart(ctx, part); err != nil {
+		return nil, repositoryError(err)
+	}
+
+	return &inventoryv1.CreatePartResponse{Part: part}, nil
+}
+
+func (s *inventoryService) UpdatePart(ctx context.Context, req *inventoryv1.UpdatePartRequest) (*inventoryv1.UpdatePartResponse, error) {
+	partUUID := req.GetPart().GetUuid()
+	if err := uuid.Validate(partUUID); err != nil {
+		return nil, invalidField("part.uuid", "must be a valid UUID")
+	}
+
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = fullUpdateMask
+	}
+	for _, path := range paths {
+		if _, ok := partUpdaters[path]; !ok {
+			return nil, invalidField("update_mask", fmt.Sprintf("path %q cannot be updated", path))
+		}
+	}
+
+	// Блокировка не дает резервированию изменить остаток между чтением и записью детали
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated, err := s.repo.GetPart(ctx, partUUID)
+	if errors.Is(err, ErrPartNotFound) {
+		return nil, status.Errorf(codes.NotFound, "part with UUID %s not found", partUUID)
+	}
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+
+	for _, path := range paths {
+		partUpdaters[path](updated, req.GetPart())
+	}
+	if violations := partViolations(updated); len(violations) > 0 {
+		return nil, invalidArgument(violations)
+	}
+	updated.UpdatedAt = timestamppb.New(now())
+	if err := s.repo.UpdatePart(ctx, updated); errors.Is(err, ErrPartNotFound) {
+		return nil, status.Errorf(codes.NotFound, "part with UUID %s not found", partUUID)
+	} else if err != nil {
+		return nil, repositoryError(err)
+	}
+
+	return &inventoryv1.UpdatePartResponse{Part: updated}, nil
+}
+
+func (s *inventoryService) DeletePart(ctx context.Context, req *inventoryv1.DeletePartRequest) (*inventoryv1.DeletePartResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reserved, err := s.repo.PartReserved(ctx, req.GetUuid())
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	if reserved {
+		return nil, status.Errorf(codes.FailedPrecondition, "part %s is reserved by pending orders", req.GetUuid())
+	}
+
+	err = s.repo.DeletePart(ctx, req.GetUuid())
+	if errors.Is(err, ErrPartNotFound) {
+		return nil, status.Errorf(codes.NotFound, "part with UUID %s not found", req.GetUuid())
+	}
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	return &inventoryv1.DeletePartResponse{}, nil
+}
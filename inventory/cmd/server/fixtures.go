@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+)
+
+const (
+	// defaultSeedFile — каталог для заполнения пустого хранилища, если INVENTORY_SEED_FILE не задан;
+	// путь от каталога inventory
+	defaultSeedFile = "config/parts.yaml"
+
+	fixtureFormatYAML = "yaml"
+	fixtureFormatJSON = "json"
+)
+
+// catalogFixture — формат файла каталога (YAML или JSON, по расширению файла):
+//
+//	parts:
+//	  - uuid: 8d6e1f3a-2c4b-4a5e-9f70-000000000001
+//	    name: Ion Engine Model X1
+//	    price: 45000
+//	    stock_quantity: 5
+//	    category: ENGINE
+//	    dimensions: {length: 120.5, width: 80, height: 120.5, weight: 85}
+//	    manufacturer: {name: SpaceTech Industries, country: USA}
+//	    metadata:
+//	      thrust_mn: {int64: 90}
+//
+// Время создания и изменения в файл не входит: при загрузке оно равно моменту заполнения каталога.
+type catalogFixture struct {
+	Parts []fixturePart `yaml:"parts" json:"parts"`
+}
+
+type fixturePart struct {
+	UUID          string                  `yaml:"uuid" json:"uuid"`
+	Name          string                  `yaml:"name" json:"name"`
+	Description   string                  `yaml:"description,omitempty" json:"description,omitempty"`
+	Price         float64                 `yaml:"price" json:"price"`
+	StockQuantity int64                   `yaml:"stock_quantity" json:"stock_quantity"`
+	Category      string                  `yaml:"category" json:"category"`
+	Dimensions    *fixtureDimensions      `yaml:"dimensions" json:"dimensions"`
+	Manufacturer  *fixtureManufacturer    `yaml:"manufacturer" json:"manufacturer"`
+	Tags          []string                `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Metadata      map[string]fixtureValue `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+type fixtureDimensions struct {
+	Length float64 `yaml:"length" json:"length"`
+	Width  float64 `yaml:"width" json:"width"`
+	Height float64 `yaml:"height" json:"height"`
+	Weight float64 `yaml:"weight" json:"weight"`
+}
+
+type fixtureManufacturer struct {
+	Name    string `yaml:"name" json:"name"`
+	Country string `yaml:"country" json:"country"`
+	Website string `yaml:"website,omitempty" json:"website,omitempty"`
+}
+
+// fixtureValue — значение метаданных; тип задает единственный заполненный ключ,
+// поэтому 1 и 1.0 в файле не путаются
+type fixtureValue struct {
+	String *string  `yaml:"string,omitempty" json:"string,omitempty"`
+	Int64  *int64   `yaml:"int64,omitempty" json:"int64,omitempty"`
+	Double *float64 `yaml:"double,omitempty" json:"double,omitempty"`
+	Bool   *bool    `yaml:"bool,omitempty" json:"bool,omitempty"`
+}
+
+// fixtureFormat определяет формат файла каталога по расширению
+func fixtureFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return fixtureFormatYAML, nil
+	case ".json":
+		return fixtureFormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported catalog file extension %q, expected .yaml, .yml or .json", filepath.Ext(path))
+	}
+}
+
+// loadFixture читает и проверяет файл каталога
+func loadFixture(path string) ([]*inventoryv1.Part, error) {
+	format, err := fixtureFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := parseFixture(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return parts, nil
+}
+
+// parseFixture разбирает и проверяет каталог целиком: при любой ошибке не загружается ни одна деталь.
+// Неизвестные поля считаются ошибкой, чтобы опечатка в файле не превращалась в пустое значение.
+// Пустой файл и файл без ключа parts тоже отклоняются: пустой каталог задается явно, `parts: []`.
+func parseFixture(data []byte, format string) ([]*inventoryv1.Part, error) {
+	var (
+		fixture catalogFixture
+		err     error
+	)
+	switch format {
+	case fixtureFormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&fixture)
+	case fixtureFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&fixture)
+	default:
+		return nil, fmt.Errorf("unknown catalog format %q", format)
+	}
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("catalog file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid catalog file: %w", err)
+	}
+	if fixture.Parts == nil {
+		return nil, errors.New("parts: is required")
+	}
+
+	parts := make([]*inventoryv1.Part, 0, len(fixture.Parts))
+	seen := make(map[string]bool, len(fixture.Parts))
+	for i, cfg := range fixture.Parts {
+		if err := uuid.Validate(cfg.UUID); err != nil {
+			return nil, fmt.Errorf("part #%d: uuid must be a valid UUID, got %q", i+1, cfg.UUID)
+		}
+		if seen[cfg.UUID] {
+			return nil, fmt.Errorf("part %s: duplicate uuid", cfg.UUID)
+		}
+		seen[cfg.UUID] = true
+
+		part, err := cfg.toProto()
+		if err != nil {
+			return nil, fmt.Errorf("part %s: %w", cfg.UUID, err)
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// toProto переводит деталь из файла в proto и проверяет ее так же, как CreatePart;
+// производитель в файле обязателен
+func (cfg fixturePart) toProto() (*inventoryv1.Part, error) {
+	part := &inventoryv1.Part{
+		Uuid:          cfg.UUID,
+		Name:          cfg.Name,
+		Description:   cfg.Description,
+		Price:         cfg.Price,
+		StockQuantity: cfg.StockQuantity,
+		Tags:          cfg.Tags,
+		Metadata:      make(map[string]*inventoryv1.Value, len(cfg.Metadata)),
+	}
+
+	var problems []string
+	category, ok := inventoryv1.Category_value["CATEGORY_"+cfg.Category]
+	if !ok || cfg.Category == "" || cfg.Category == "UNSPECIFIED" {
+		problems = append(problems, fmt.Sprintf("category: unknown category %q", cfg.Category))
+	}
+	part.Category = inventoryv1.Category(category)
+
+	if cfg.Dimensions != nil {
+		part.Dimensions = &inventoryv1.Dimensions{
+			Length: cfg.Dimensions.Length,
+			Width:  cfg.Dimensions.Width,
+			Height: cfg.Dimensions.Height,
+			Weight: cfg.Dimensions.Weight,
+		}
+	}
+
+	if cfg.Manufacturer == nil {
+		problems = append(problems, "manufacturer: is required")
+	} else {
+		part.Manufacturer = &inventoryv1.Manufacturer{
+			Name:    cfg.Manufacturer.Name,
+			Country: cfg.Manufacturer.Country,
+			Website: cfg.Manufacturer.Website,
+		}
+		if strings.TrimSpace(cfg.Manufacturer.Name) == "" {
+			problems = append(problems, "manufacturer.name: must not be empty")
+		}
+		if strings.TrimSpace(cfg.Manufacturer.Country) == "" {
+			problems = append(problems, "manufacturer.country: must not be empty")
+		}
+	}
+
+	for key, value := range cfg.Metadata {
+		converted, err := value.toProto()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("metadata.%s: %v", key, err))
+			continue
+		}
+		part.Metadata[key] = converted
+	}
+
+	for _, violation := range partViolations(part) {
+		// Категорию уже проверили вместе с ее написанием в файле
+		if violation.GetField() != "category" {
+			problems = append(problems, violation.GetField()+": "+violation.GetDescription())
+		}
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return part, nil
+}
+
+func (v fixtureValue) toProto() (*inventoryv1.Value, error) {
+	var values []*inventoryv1.Value
+	if v.String != nil {
+		values = append(values, &inventoryv1.Value{Value: &inventoryv1.Value_StringValue{StringValue: *v.String}})
+	}
+	if v.Int64 != nil {
+		values = append(values, &inventoryv1.Value{Value: &inventoryv1.Value_Int64Value{Int64Value: *v.Int64}})
+	}
+	if v.Double != nil {
+		values = append(values, &inventoryv1.Value{Value: &inventoryv1.Value_DoubleValue{DoubleValue: *v.Double}})
+	}
+	if v.Bool != nil {
+		values = append(values, &inventoryv1.Value{Value: &inventoryv1.Value_BoolValue{BoolValue: *v.Bool}})
+	}
+	if len(values) != 1 {
+		return nil, errors.New("exactly one of string, int64, double or bool is required")
+	}
+	return values[0], nil
+}
+
+// toFixture переводит деталь в формат файла каталога; обратная операция — fixturePart.toProto
+func toFixture(part *inventoryv1.Part) fixturePart {
+	cfg := fixturePart{
+		UUID:          part.GetUuid(),
+		Name:          part.GetName(),
+		Description:   part.GetDescription(),
+		Price:         part.GetPrice(),
+		StockQuantity: part.GetStockQuantity(),
+		Category:      strings.TrimPrefix(part.GetCategory().String(), "CATEGORY_"),
+		Tags:          part.GetTags(),
+	}
+	if dims := part.GetDimensions(); dims != nil {
+		cfg.Dimensions = &fixtureDimensions{
+			Length: dims.GetLength(),
+			Width:  dims.GetWidth(),
+			Height: dims.GetHeight(),
+			Weight: dims.GetWeight(),
+		}
+	}
+	if m := part.GetManufacturer(); m != nil {
+		cfg.Manufacturer = &fixtureManufacturer{
+			Name:    m.GetName(),
+			Country: m.GetCountry(),
+			Website: m.GetWebsite(),
+		}
+	}
+	if len(part.GetMetadata()) > 0 {
+		cfg.Metadata = make(map[string]fixtureValue, len(part.GetMetadata()))
+		for key, value := range part.GetMetadata() {
+			var v fixtureValue
+			switch value := value.GetValue().(type) {
+			case *inventoryv1.Value_StringValue:
+				v.String = &value.StringValue
+			case *inventoryv1.Value_Int64Value:
+				v.Int64 = &value.Int64Value
+			case *inventoryv1.Value_DoubleValue:
+				v.Double = &value.DoubleValue
+			case *inventoryv1.Value_BoolValue:
+				v.Bool = &value.BoolValue
+			}
+			cfg.Metadata[key] = v
+		}
+	}
+	return cfg
+}
+
+// writeFixture записывает детали в формате файла каталога, упорядочив их по UUID,
+// чтобы выгрузки одного каталога совпадали побайтно
+func writeFixture(w io.Writer, parts []*inventoryv1.Part, format string) error {
+	fixture := catalogFixture{Parts: make([]fixturePart, 0, len(parts))}
+	for _, part := range parts {
+		fixture.Parts = append(fixture.Parts, toFixture(part))
+	}
+	slices.SortFunc(fixture.Parts, func(a, b fixturePart) int { return strings.Compare(a.UUID, b.UUID) })
+
+	switch format {
+	case fixtureFormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(fixture); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case fixtureFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(fixture)
+	default:
+		return fmt.Errorf("unknown catalog format %q", format)
+	}
+}
+
+// seedParts заполняет каталог деталями из файла path, если в хранилище еще нет ни одной детали.
+// Файл проверяется и при заполненном каталоге, чтобы ошибка в нем не оставалась незамеченной.
+func seedParts(ctx context.Context, repo InventoryRepository, path string) error {
+	if path == "" {
+		return nil
+	}
+	parts, err := loadFixture(path)
+	if err != nil {
+		return err
+	}
+
+	existing, err := repo.ListParts(ctx, PartsQuery{Limit: 1})
+	if err != nil {
+		return err
+	}
+	if existing.TotalSize > 0 {
+		return nil
+	}
+
+	createdAt := timestamppb.New(now())
+	for _, part := range parts {
+		part.CreatedAt = createdAt
+		part.UpdatedAt = createdAt
+		if err := repo.CreatePart(ctx, part); err != nil {
+			return err
+		}
+	}
+	log.Printf("catalog seeded with %d parts from %s", len(parts), path)
+	return nil
+}
+
+// dumpPageSize — сколько деталей dump запрашивает у сервиса за один вызов ListParts
+const dumpPageSize = maxPageSize
+
+// runDumpCommand выгружает каталог запущенного сервиса в формате файла каталога:
+// результат можно указать в INVENTORY_SEED_FILE, чтобы воспроизвести каталог в другом окружении.
+// Каталог читается через ListParts, поэтому выгрузка работает с любым хранилищем, в том числе memory.
+func runDumpCommand(args []string) int {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	format := flags.String("format", "", "output format: yaml or json; by default taken from -o extension, otherwise yaml")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	addr := flags.String("addr", fmt.Sprintf("localhost:%d", grpcPort), "address of the running Inventory Service")
+	timeout := flags.Duration("timeout", 30*time.Second, "time limit for reading the catalog")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if *format == "" {
+		*format = fixtureFormatYAML
+		if *output != "" {
+			detected, err := fixtureFormat(*output)
+			if err != nil {
+				log.Print(err)
+				return 1
+			}
+			*format = detected
+		}
+	}
+	if *format != fixtureFormatYAML && *format != fixtureFormatJSON {
+		log.Printf("unknown format %q", *format)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	parts, err := fetchCatalog(ctx, *addr)
+	if err != nil {
+		log.Printf("failed to read catalog from %s: %v", *addr, err)
+		return 1
+	}
+
+	if *output == "" {
+		err = writeFixture(os.Stdout, parts, *format)
+	} else {
+		var file *os.File
+		if file, err = os.Create(*output); err == nil {
+			err = writeFixture(file, parts, *format)
+			// Ошибка записи на диск может проявиться только при закрытии файла
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("failed to write catalog: %v", err)
+		return 1
+	}
+	log.Printf("dumped %d parts", len(parts))
+	return 0
+}
+
+// fetchCatalog читает все детали у сервиса по addr постранично, в порядке UUID
+func fetchCatalog(ctx context.Context, addr string) ([]*inventoryv1.Part, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := inventoryv1.NewInventoryServiceClient(conn)
+
+	var (
+		parts     []*inventoryv1.Part
+		pageToken string
+	)
+	for {
+		resp, err := client.ListParts(ctx, &inventoryv1.ListPartsRequest{
+			PageSize:  dumpPageSize,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, resp.GetParts()...)
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			return parts, nil
+		}
+	}
+}
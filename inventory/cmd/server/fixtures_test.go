@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Файл без содержимого или без ключа parts не загружается как пустой каталог,
+// а явно пустой каталог допустим
+func TestLoadFixtureEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		wantErr string
+	}{
+		{name: "empty yaml", file: "parts.yaml", data: "", wantErr: "catalog file is empty"},
+		{name: "whitespace yaml", file: "parts.yml", data: "  \n\n   \n", wantErr: "catalog file is empty"},
+		{name: "comment only yaml", file: "parts.yaml", data: "# nothing here\n", wantErr: "catalog file is empty"},
+		{name: "empty json", file: "parts.json", data: " \n", wantErr: "catalog file is empty"},
+		{name: "yaml without parts", file: "parts.yaml", data: "{}\n", wantErr: "parts: is required"},
+		{name: "yaml with null parts", file: "parts.yaml", data: "parts:\n", wantErr: "parts: is required"},
+		{name: "json without parts", file: "parts.json", data: "{}", wantErr: "parts: is required"},
+		{name: "json null", file: "parts.json", data: "null", wantErr: "parts: is required"},
+		{name: "explicitly empty yaml", file: "parts.yaml", data: "parts: []\n"},
+		{name: "explicitly empty json", file: "parts.json", data: `{"parts": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			parts, err := loadFixture(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), path) {
+					t.Fatalf("loadFixture error = %v, want %q naming %s", err, tt.wantErr, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadFixture: %v", err)
+			}
+			if len(parts) != 0 {
+				t.Errorf("loaded %d parts from an empty catalog", len(parts))
+			}
+		})
+	}
+}
+
+// Каталог по умолчанию загружается без ошибок
+func TestLoadSeedFixture(t *testing.T) {
+	parts, err := loadFixture(filepath.Join("..", "..", defaultSeedFile))
+	if err != nil {
+		t.Fatalf("loadFixture: %v", err)
+	}
+	if len(parts) == 0 {
+		t.Error("seed catalog has no parts")
+	}
+}
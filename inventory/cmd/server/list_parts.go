@@ -0,0 +1,319 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Поля, по которым ListParts упорядочивает детали (order_by)
+const (
+	partsOrderPrice         = "price"
+	partsOrderName          = "name"
+	partsOrderStockQuantity = "stock_quantity"
+	partsOrderCreatedAt     = "created_at"
+)
+
+// PartsOrder — порядок выдачи ListParts: по Field, при равных значениях — по UUID.
+// Пустой Field упорядочивает только по UUID. Desc меняет направление обоих ключей.
+type PartsOrder struct {
+	Field string
+	Desc  bool
+}
+
+// parsePartsOrder разбирает order_by вида "<поле> [asc|desc]"
+func parsePartsOrder(s string) (PartsOrder, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return PartsOrder{}, nil
+	}
+	if len(fields) > 2 {
+		return PartsOrder{}, fmt.Errorf("must be a field name optionally followed by asc or desc, got %q", s)
+	}
+
+	order := PartsOrder{Field: fields[0]}
+	switch order.Field {
+	case partsOrderPrice, partsOrderName, partsOrderStockQuantity, partsOrderCreatedAt:
+	default:
+		return PartsOrder{}, fmt.Errorf("unknown field %q, expected price, name, stock_quantity or created_at", order.Field)
+	}
+	if len(fields) == 2 {
+		switch strings.ToLower(fields[1]) {
+		case "asc":
+		case "desc":
+			order.Desc = true
+		default:
+			return PartsOrder{}, fmt.Errorf("unknown direction %q, expected asc or desc", fields[1])
+		}
+	}
+	return order, nil
+}
+
+// String возвращает order_by в каноническом виде: "price desc" и "price asc" → "price desc" и "price"
+func (o PartsOrder) String() string {
+	if o.Desc {
+		return o.Field + " desc"
+	}
+	return o.Field
+}
+
+// compare задает порядок выдачи деталей
+func (o PartsOrder) compare(a, b *inventoryv1.Part) int {
+	var result int
+	switch o.Field {
+	case partsOrderPrice:
+		result = cmp.Compare(a.GetPrice(), b.GetPrice())
+	case partsOrderName:
+		result = strings.Compare(a.GetName(), b.GetName())
+	case partsOrderStockQuantity:
+		result = cmp.Compare(a.GetStockQuantity(), b.GetStockQuantity())
+	case partsOrderCreatedAt:
+		result = a.GetCreatedAt().AsTime().Compare(b.GetCreatedAt().AsTime())
+	}
+	if result == 0 {
+		result = strings.Compare(a.GetUuid(), b.GetUuid())
+	}
+	if o.Desc {
+		return -result
+	}
+	return result
+}
+
+// PartsCursor — ключ сортировки последней детали страницы: значение поля порядка и UUID.
+// Заполнено только поле, по которому упорядочена выдача.
+type PartsCursor struct {
+	Price         float64    `json:"p,omitempty"`
+	Name          string     `json:"n,omitempty"`
+	StockQuantity int64      `json:"s,omitempty"`
+	CreatedAt     *time.Time `json:"c,omitempty"`
+	UUID          string     `json:"u"`
+}
+
+func (o PartsOrder) cursorFor(part *inventoryv1.Part) *PartsCursor {
+	cursor := &PartsCursor{UUID: part.GetUuid()}
+	switch o.Field {
+	case partsOrderPrice:
+		cursor.Price = part.GetPrice()
+	case partsOrderName:
+		cursor.Name = part.GetName()
+	case partsOrderStockQuantity:
+		cursor.StockQuantity = part.GetStockQuantity()
+	case partsOrderCreatedAt:
+		createdAt := part.GetCreatedAt().AsTime()
+		cursor.CreatedAt = &createdAt
+	}
+	return cursor
+}
+
+// value возвращает значение поля порядка из курсора; для порядка по UUID — nil
+func (o PartsOrder) value(c *PartsCursor) any {
+	switch o.Field {
+	case partsOrderPrice:
+		return c.Price
+	case partsOrderName:
+		return c.Name
+	case partsOrderStockQuantity:
+		return c.StockQuantity
+	case partsOrderCreatedAt:
+		if c.CreatedAt == nil {
+			return time.Time{}
+		}
+		return *c.CreatedAt
+	}
+	return nil
+}
+
+// part возвращает деталь с ключом курсора, чтобы сравнивать с ней детали через PartsOrder.compare
+func (c *PartsCursor) part() *inventoryv1.Part {
+	part := &inventoryv1.Part{
+		Uuid:          c.UUID,
+		Name:          c.Name,
+		Price:         c.Price,
+		StockQuantity: c.StockQuantity,
+	}
+	if c.CreatedAt != nil {
+		part.CreatedAt = timestamppb.New(*c.CreatedAt)
+	}
+	return part
+}
+
+// partsPageToken — содержимое page_token. Токен привязан к фильтру и порядку выдачи:
+// с другим запросом курсор указывал бы на произвольное место выборки.
+type partsPageToken struct {
+	OrderBy string      `json:"o,omitempty"`
+	Filter  string      `json:"f"`
+	Cursor  PartsCursor `json:"c"`
+}
+
+// filterFingerprint — короткий отпечаток фильтра для сверки page_token с запросом
+func filterFingerprint(filter *inventoryv1.PartsFilter) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(filter)
+	if err != nil {
+		return "", fmt.Errorf("marshal filter: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
+
+func encodePartsPageToken(token partsPageToken) (string, error) {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("marshal page token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePartsPageToken(s string) (partsPageToken, bool) {
+	var token partsPageToken
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, false
+	}
+	if err := json.Unmarshal(data, &token); err != nil || token.Cursor.UUID == "" {
+		return token, false
+	}
+	return token, true
+}
+
+// filterViolations проверяет границы диапазонов фильтра и перечисляет все некорректные поля
+func filterViolations(filter *inventoryv1.PartsFilter) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	add := func(field, description string) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: "filter." + field, Description: description})
+	}
+	checkRange := func(field string, r *inventoryv1.DoubleRange) {
+		lower, upper := rangeBounds(r)
+		if lower != nil && !isNonNegative(*lower) {
+			add(field+".min", "must be a non-negative number")
+		}
+		if upper != nil && !isNonNegative(*upper) {
+			add(field+".max", "must be a non-negative number")
+		}
+		if lower != nil && upper != nil && *lower > *upper {
+			add(field, "min must not exceed max")
+		}
+	}
+	checkTimeRange := func(fromField string, from *timestamppb.Timestamp, toField string, to *timestamppb.Timestamp) {
+		if from != nil && from.CheckValid() != nil {
+			add(fromField, "must be a valid timestamp")
+		}
+		if to != nil && to.CheckValid() != nil {
+			add(toField, "must be a valid timestamp")
+		}
+		if from != nil && to != nil && !from.AsTime().Before(to.AsTime()) {
+			add(toField, "must be after "+fromField)
+		}
+	}
+
+	checkRange("price", filter.GetPrice())
+	if filter.GetMinStockQuantity() < 0 {
+		add("min_stock_quantity", "must not be negative")
+	}
+	checkRange("dimensions.length", filter.GetDimensions().GetLength())
+	checkRange("dimensions.width", filter.GetDimensions().GetWidth())
+	checkRange("dimensions.height", filter.GetDimensions().GetHeight())
+	checkRange("dimensions.weight", filter.GetDimensions().GetWeight())
+	checkTimeRange("created_from", filter.GetCreatedFrom(), "created_to", filter.GetCreatedTo())
+	checkTimeRange("updated_from", filter.GetUpdatedFrom(), "updated_to", filter.GetUpdatedTo())
+
+	// Нарушения упорядочены по полю, а не по порядку проверок
+	slices.SortFunc(violations, func(a, b *errdetails.BadRequest_FieldViolation) int {
+		return strings.Compare(a.GetField(), b.GetField())
+	})
+	return violations
+}
+
+// partsQueryFromRequest разбирает и проверяет параметры страницы ListParts.
+// page_size 0 без page_token выбирает все детали: так ListParts работал до появления страниц,
+// и клиенты, которые не передают page_size, по-прежнему получают весь каталог.
+func partsQueryFromRequest(req *inventoryv1.ListPartsRequest) (PartsQuery, error) {
+	query := PartsQuery{Filter: req.GetFilter()}
+	if violations := filterViolations(query.Filter); len(violations) > 0 {
+		return query, invalidArgument(violations)
+	}
+
+	order, err := parsePartsOrder(req.GetOrderBy())
+	if err != nil {
+		return query, invalidField("order_by", err.Error())
+	}
+	query.OrderBy = order
+
+	switch size := req.GetPageSize(); {
+	case size == 0:
+		if req.GetPageToken() != "" {
+			query.Limit = defaultPageSize
+		}
+	case size < 0 || size > maxPageSize:
+		return query, invalidField("page_size", fmt.Sprintf("must be between 1 and %d", maxPageSize))
+	default:
+		query.Limit = int(size)
+	}
+
+	if s := req.GetPageToken(); s != "" {
+		token, ok := decodePartsPageToken(s)
+		if !ok {
+			return query, invalidField("page_token", "is malformed")
+		}
+		fingerprint, err := filterFingerprint(query.Filter)
+		if err != nil {
+			return query, internalError(err)
+		}
+		if token.OrderBy != order.String() || token.Filter != fingerprint {
+			return query, invalidField("page_token", "was issued for a different filter or order_by")
+		}
+		query.After = &token.Cursor
+	}
+	return query, nil
+}
+
+// ListParts возвращает страницу деталей, подходящих под фильтр; фильтрацию, сортировку
+// и разбиение на страницы выполняет хранилище
+func (s *inventoryService) ListParts(ctx context.Context, req *inventoryv1.ListPartsRequest) (*inventoryv1.ListPartsResponse, error) {
+	query, err := partsQueryFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.ListParts(ctx, query)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+
+	resp := &inventoryv1.ListPartsResponse{
+		Parts:     page.Parts,
+		TotalSize: int32(min(page.TotalSize, math.MaxInt32)),
+	}
+	if page.NextCursor != nil {
+		fingerprint, err := filterFingerprint(query.Filter)
+		if err != nil {
+			return nil, internalError(err)
+		}
+		resp.NextPageToken, err = encodePartsPageToken(partsPageToken{
+			OrderBy: query.OrderBy.String(),
+			Filter:  fingerprint,
+			Cursor:  *page.NextCursor,
+		})
+		if err != nil {
+			return nil, internalError(err)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+)
+
+// testPartUUID возвращает UUID тестовой детали с номером n; UUID упорядочены по номеру
+func testPartUUID(n int) string {
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", n)
+}
+
+// newTestCatalog создает сервис с каталогом, в котором у каждого поля сортировки есть повторы:
+//
+//	деталь  name     price  stock  created_at
+//	1       Wing     100    5      t+1
+//	2       Engine   300    0      t
+//	3       Wing     100    5      t+1
+//	4       Hull     50     10     t+2
+//	5       Engine   300    5      t
+//	6       Antenna  100    0      t+2
+func newTestCatalog(t *testing.T) *inventoryService {
+	t.Helper()
+
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	parts := []struct {
+		name     string
+		price    float64
+		stock    int64
+		category inventoryv1.Category
+		created  time.Duration
+	}{
+		{"Wing", 100, 5, inventoryv1.Category_CATEGORY_WING, time.Hour},
+		{"Engine", 300, 0, inventoryv1.Category_CATEGORY_ENGINE, 0},
+		{"Wing", 100, 5, inventoryv1.Category_CATEGORY_WING, time.Hour},
+		{"Hull", 50, 10, inventoryv1.Category_CATEGORY_PORTHOLE, 2 * time.Hour},
+		{"Engine", 300, 5, inventoryv1.Category_CATEGORY_ENGINE, 0},
+		{"Antenna", 100, 0, inventoryv1.Category_CATEGORY_FUEL, 2 * time.Hour},
+	}
+
+	s := &inventoryService{repo: NewInMemoryInventoryRepository()}
+	for i, p := range parts {
+		ts := timestamppb.New(createdAt.Add(p.created))
+		err := s.repo.CreatePart(context.Background(), &inventoryv1.Part{
+			Uuid:          testPartUUID(i + 1),
+			Name:          p.name,
+			Price:         p.price,
+			StockQuantity: p.stock,
+			Category:      p.category,
+			Manufacturer:  &inventoryv1.Manufacturer{Name: "Test", Country: "Test"},
+			CreatedAt:     ts,
+			UpdatedAt:     ts,
+		})
+		if err != nil {
+			t.Fatalf("CreatePart: %v", err)
+		}
+	}
+	return s
+}
+
+// Постраничная выдача ListParts при любом порядке и размере страницы: страницы вместе дают
+// всю выборку в порядке order_by без повторов и пропусков, равные значения упорядочены по UUID
+// (в обратном порядке для desc), а total_size на каждой странице — число деталей под фильтром
+func TestListPartsPages(t *testing.T) {
+	tests := []struct {
+		orderBy string
+		filter  *inventoryv1.PartsFilter
+		want    []int
+	}{
+		{orderBy: "", want: []int{1, 2, 3, 4, 5, 6}},
+		{orderBy: "price", want: []int{4, 1, 3, 6, 2, 5}},
+		{orderBy: "price desc", want: []int{5, 2, 6, 3, 1, 4}},
+		{orderBy: "price ASC", want: []int{4, 1, 3, 6, 2, 5}},
+		{orderBy: "name", want: []int{6, 2, 5, 4, 1, 3}},
+		{orderBy: "name desc", want: []int{3, 1, 4, 5, 2, 6}},
+		{orderBy: "stock_quantity", want: []int{2, 6, 1, 3, 5, 4}},
+		{orderBy: "stock_quantity desc", want: []int{4, 5, 3, 1, 6, 2}},
+		{orderBy: "created_at", want: []int{2, 5, 1, 3, 4, 6}},
+		{orderBy: "created_at desc", want: []int{6, 4, 3, 1, 5, 2}},
+		{
+			orderBy: "price",
+			filter:  &inventoryv1.PartsFilter{Names: []string{"Wing", "Engine"}},
+			want:    []int{1, 3, 2, 5},
+		},
+		{
+			orderBy: "name desc",
+			filter: &inventoryv1.PartsFilter{
+				Categories: []inventoryv1.Category{inventoryv1.Category_CATEGORY_ENGINE, inventoryv1.Category_CATEGORY_FUEL},
+			},
+			want: []int{5, 2, 6},
+		},
+		{orderBy: "stock_quantity", filter: &inventoryv1.PartsFilter{Names: []string{"Rudder"}}, want: nil},
+	}
+
+	s := newTestCatalog(t)
+	for _, tt := range tests {
+		var want []string
+		for _, n := range tt.want {
+			want = append(want, testPartUUID(n))
+		}
+		for pageSize := 1; pageSize <= len(tt.want)+1; pageSize++ {
+			t.Run(fmt.Sprintf("%q/filter %v/page size %d", tt.orderBy, tt.filter, pageSize), func(t *testing.T) {
+				got := listAllPages(t, s, tt.orderBy, tt.filter, int32(pageSize), len(want))
+				if !slices.Equal(got, want) {
+					t.Errorf("pages = %v, want %v", got, want)
+				}
+			})
+		}
+
+		// Без page_size и page_token выборка возвращается целиком, без токена
+		resp, err := s.ListParts(context.Background(), &inventoryv1.ListPartsRequest{Filter: tt.filter, OrderBy: tt.orderBy})
+		if err != nil {
+			t.Fatalf("ListParts %q without page_size: %v", tt.orderBy, err)
+		}
+		if got := partUUIDs(resp.GetParts()); !slices.Equal(got, want) || resp.GetNextPageToken() != "" {
+			t.Errorf("ListParts %q without page_size = %v, token %q; want %v without token",
+				tt.orderBy, got, resp.GetNextPageToken(), want)
+		}
+	}
+}
+
+// listAllPages выбирает страницы, пока есть next_page_token, и проверяет total_size каждой страницы
+func listAllPages(t *testing.T, s *inventoryService, orderBy string, filter *inventoryv1.PartsFilter, pageSize int32, total int) []string {
+	t.Helper()
+
+	var (
+		uuids []string
+		token string
+	)
+	for page := 0; ; page++ {
+		if page > total {
+			t.Fatalf("more than %d pages for %d parts", page, total)
+		}
+		resp, err := s.ListParts(context.Background(), &inventoryv1.ListPartsRequest{
+			Filter:    filter,
+			OrderBy:   orderBy,
+			PageSize:  pageSize,
+			PageToken: token,
+		})
+		if err != nil {
+			t.Fatalf("ListParts page %d: %v", page, err)
+		}
+		if int(resp.GetTotalSize()) != total {
+			t.Errorf("page %d total_size = %d, want %d", page, resp.GetTotalSize(), total)
+		}
+		if len(resp.GetParts()) > int(pageSize) {
+			t.Errorf("page %d has %d parts, page_size %d", page, len(resp.GetParts()), pageSize)
+		}
+		uuids = append(uuids, partUUIDs(resp.GetParts())...)
+
+		token = resp.GetNextPageToken()
+		if token == "" {
+			return uuids
+		}
+		if len(resp.GetParts()) == 0 {
+			t.Fatalf("page %d is empty but has next_page_token", page)
+		}
+	}
+}
+
+func partUUIDs(parts []*inventoryv1.Part) []string {
+	var uuids []string
+	for _, part := range parts {
+		uuids = append(uuids, part.GetUuid())
+	}
+	return uuids
+}
+
+// Изменения каталога между страницами не приводят к повторам и пропускам: следующая страница
+// начинается строго после последней детали предыдущей, даже если эту деталь удалили
+func TestListPartsPagesAfterCatalogChanges(t *testing.T) {
+	s := newTestCatalog(t)
+	ctx := context.Background()
+
+	// price: 4, 1, 3, 6, 2, 5
+	first, err := s.ListParts(ctx, &inventoryv1.ListPartsRequest{OrderBy: "price", PageSize: 3})
+	if err != nil {
+		t.Fatalf("ListParts: %v", err)
+	}
+	if got, want := partUUIDs(first.GetParts()), []string{testPartUUID(4), testPartUUID(1), testPartUUID(3)}; !slices.Equal(got, want) {
+		t.Fatalf("first page = %v, want %v", got, want)
+	}
+
+	// Удаляем последнюю деталь страницы и добавляем дешевую, которая попала бы на уже выданную страницу
+	if err := s.repo.DeletePart(ctx, testPartUUID(3)); err != nil {
+		t.Fatalf("DeletePart: %v", err)
+	}
+	if err := s.repo.CreatePart(ctx, &inventoryv1.Part{Uuid: testPartUUID(7), Name: "Bolt", Price: 1}); err != nil {
+		t.Fatalf("CreatePart: %v", err)
+	}
+
+	second, err := s.ListParts(ctx, &inventoryv1.ListPartsRequest{OrderBy: "price", PageSize: 3, PageToken: first.GetNextPageToken()})
+	if err != nil {
+		t.Fatalf("ListParts second page: %v", err)
+	}
+	if got, want := partUUIDs(second.GetParts()), []string{testPartUUID(6), testPartUUID(2), testPartUUID(5)}; !slices.Equal(got, want) {
+		t.Errorf("second page = %v, want %v", got, want)
+	}
+	if second.GetNextPageToken() != "" || second.GetTotalSize() != 6 {
+		t.Errorf("second page: token %q, total_size %d; want the last page of 6 parts",
+			second.GetNextPageToken(), second.GetTotalSize())
+	}
+}
+
+// page_token действует только с теми же filter и order_by; без page_size страница с токеном
+// содержит defaultPageSize деталей
+func TestListPartsPageToken(t *testing.T) {
+	s := newTestCatalog(t)
+	ctx := context.Background()
+
+	filter := &inventoryv1.PartsFilter{Names: []string{"Wing", "Engine", "Hull"}}
+	first, err := s.ListParts(ctx, &inventoryv1.ListPartsRequest{Filter: filter, OrderBy: "price", PageSize: 2})
+	if err != nil {
+		t.Fatalf("ListParts: %v", err)
+	}
+	token := first.GetNextPageToken()
+	if token == "" {
+		t.Fatal("first page has no next_page_token")
+	}
+
+	tests := []struct {
+		name     string
+		req      *inventoryv1.ListPartsRequest
+		wantCode codes.Code
+		want     []int
+	}{
+		{
+			name: "same request",
+			req:  &inventoryv1.ListPartsRequest{Filter: filter, OrderBy: "price", PageSize: 2, PageToken: token},
+			want: []int{3, 2},
+		},
+		{
+			name: "without page_size",
+			req:  &inventoryv1.ListPartsRequest{Filter: filter, OrderBy: "price", PageToken: token},
+			want: []int{3, 2, 5},
+		},
+		{
+			name: "same order in another spelling",
+			req:  &inventoryv1.ListPartsRequest{Filter: filter, OrderBy: "price asc", PageSize: 2, PageToken: token},
+			want: []int{3, 2},
+		},
+		{
+			name:     "different order_by",
+			req:      &inventoryv1.ListPartsRequest{Filter: filter, OrderBy: "price desc", PageSize: 2, PageToken: token},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "different filter",
+			req:      &inventoryv1.ListPartsRequest{Filter: &inventoryv1.PartsFilter{Names: []string{"Wing"}}, OrderBy: "price", PageSize: 2, PageToken: token},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "without filter",
+			req:      &inventoryv1.ListPartsRequest{OrderBy: "price", PageSize: 2, PageToken: token},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "malformed token",
+			req:      &inventoryv1.ListPartsRequest{Filter: filter, OrderBy: "price", PageSize: 2, PageToken: "not-a-token"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "page_size over the limit",
+			req:      &inventoryv1.ListPartsRequest{Filter: filter, OrderBy: "price", PageSize: maxPageSize + 1},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.ListParts(ctx, tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("ListParts: code %s (%v), want %s", code, err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			var want []string
+			for _, n := range tt.want {
+				want = append(want, testPartUUID(n))
+			}
+			if got := partUUIDs(resp.GetParts()); !slices.Equal(got, want) {
+				t.Errorf("page = %v, want %v", got, want)
+			}
+		})
+	}
+}
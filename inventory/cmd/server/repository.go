@@ -0,0 +1,495 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+)
+
+var (
+	// ErrPartNotFound возвращается хранилищем, если деталь с указанным UUID отсутствует
+	ErrPartNotFound = errors.New("part not found")
+	// ErrReservationNotFound возвращается хранилищем, если у заказа нет резерва
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrInsufficientStock возвращается SaveReservation, если остатка детали не хватает на списание
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// now возвращает текущее время с точностью до миллисекунд, с которой его хранит MongoDB,
+// чтобы ответы на изменения совпадали с тем, что затем читается из хранилища
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// InventoryRepository описывает хранилище каталога деталей и резервов.
+// Реализации потокобезопасны и возвращают копии, которые вызывающий код может изменять.
+// Остатки меняются только вместе с резервом в SaveReservation и не уходят в минус;
+// переходы статусов резервов inventoryService выполняет под своей блокировкой.
+type InventoryRepository interface {
+	GetPart(ctx context.Context, uuid string) (*inventoryv1.Part, error)
+	// ListParts возвращает страницу деталей, подходящих под фильтр запроса, в порядке query.OrderBy
+	ListParts(ctx context.Context, query PartsQuery) (*PartsPage, error)
+	CreatePart(ctx context.Context, part *inventoryv1.Part) error
+	// UpdatePart заменяет сохраненную деталь part целиком
+	UpdatePart(ctx context.Context, part *inventoryv1.Part) error
+	DeletePart(ctx context.Context, uuid string) error
+
+	GetReservation(ctx context.Context, orderUUID string) (*inventoryv1.Reservation, error)
+	// SaveReservation сохраняет резерв и атомарно с ним изменяет остатки деталей на stockDeltas
+	// (ключ — UUID детали, время изменения — reservation.updated_at); удаленные детали пропускаются.
+	// Если остатка детали не хватает на списание, возвращается ErrInsufficientStock и ничего не меняется.
+	SaveReservation(ctx context.Context, reservation *inventoryv1.Reservation, stockDeltas map[string]int64) error
+	// PartReserved сообщает, входит ли деталь в резерв в статусе RESERVED
+	PartReserved(ctx context.Context, partUUID string) (bool, error)
+
+	Close(ctx context.Context) error
+}
+
+// PartsQuery описывает выборку ListParts. Пустой фильтр подходит всем деталям.
+type PartsQuery struct {
+	Filter  *inventoryv1.PartsFilter
+	OrderBy PartsOrder
+	// Limit — размер страницы; 0 означает все подходящие детали
+	Limit int
+	// After — ключ последней детали предыдущей страницы
+	After *PartsCursor
+}
+
+// PartsPage — страница результата ListParts
+type PartsPage struct {
+	Parts []*inventoryv1.Part
+	// NextCursor равен nil на последней странице
+	NextCursor *PartsCursor
+	// TotalSize — число деталей под фильтром без учета страниц
+	TotalSize int
+}
+
+// minStockQuantity возвращает заданный в фильтре минимальный остаток или nil
+func minStockQuantity(filter *inventoryv1.PartsFilter) *int64 {
+	if filter == nil {
+		return nil
+	}
+	return filter.MinStockQuantity
+}
+
+// rangeBounds возвращает заданные границы диапазона; незаданная граница равна nil
+func rangeBounds(r *inventoryv1.DoubleRange) (lower, upper *float64) {
+	if r == nil {
+		return nil, nil
+	}
+	return r.Min, r.Max
+}
+
+// inRange проверяет значение диапазоном: границы включаются, незаданная граница не ограничивает
+func inRange(value float64, r *inventoryv1.DoubleRange) bool {
+	lower, upper := rangeBounds(r)
+	return (lower == nil || value >= *lower) && (upper == nil || value <= *upper)
+}
+
+// partMatches проверяет деталь фильтром: внутри поля значения объединяются по ИЛИ, поля — по И
+func partMatches(part *inventoryv1.Part, filter *inventoryv1.PartsFilter) bool {
+	return matchesUUID(part, filter.GetUuids()) &&
+		matchesName(part, filter.GetNames()) &&
+		matchesCategory(part, filter.GetCategories()) &&
+		matchesManufacturerCountry(part, filter.GetManufacturerCountries()) &&
+		matchesManufacturerName(part, filter.GetManufacturerNames()) &&
+		matchesTags(part, filter.GetTags()) &&
+		matchesPrice(part, filter.GetPrice()) &&
+		matchesStockQuantity(part, minStockQuantity(filter)) &&
+		matchesDimensions(part, filter.GetDimensions()) &&
+		matchesTimeRange(part.GetCreatedAt(), filter.GetCreatedFrom(), filter.GetCreatedTo()) &&
+		matchesTimeRange(part.GetUpdatedAt(), filter.GetUpdatedFrom(), filter.GetUpdatedTo())
+}
+
+// matchesUUID проверяет, соответствует ли деталь фильтру по UUID
+func matchesUUID(part *inventoryv1.Part, uuids []string) bool {
+	if len(uuids) == 0 {
+		return true
+	}
+	for _, uuid := range uuids {
+		if part.GetUuid() == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesName проверяет, соответствует ли деталь фильтру по имени
+func matchesName(part *inventoryv1.Part, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if part.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesCategory проверяет, соответствует ли деталь фильтру по категории
+func matchesCategory(part *inventoryv1.Part, categories []inventoryv1.Category) bool {
+	if len(categories) == 0 {
+		return true
+	}
+	for _, cat := range categories {
+		if part.GetCategory() == cat {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesManufacturerCountry проверяет, соответствует ли деталь фильтру по стране производителя
+func matchesManufacturerCountry(part *inventoryv1.Part, countries []string) bool {
+	if len(countries) == 0 {
+		return true
+	}
+	if part.GetManufacturer() == nil {
+		return false
+	}
+	for _, country := range countries {
+		if part.GetManufacturer().GetCountry() == country {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesManufacturerName проверяет, соответствует ли деталь фильтру по имени производителя
+func matchesManufacturerName(part *inventoryv1.Part, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	if part.GetManufacturer() == nil {
+		return false
+	}
+	for _, name := range names {
+		if part.GetManufacturer().GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTags проверяет, соответствует ли деталь фильтру по тегам
+func matchesTags(part *inventoryv1.Part, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	partTags := part.GetTags()
+	for _, filterTag := range tags {
+		for _, partTag := range partTags {
+			if partTag == filterTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesPrice проверяет, соответствует ли деталь фильтру по диапазону цены
+func matchesPrice(part *inventoryv1.Part, r *inventoryv1.DoubleRange) bool {
+	return inRange(part.GetPrice(), r)
+}
+
+// matchesStockQuantity проверяет, что остаток детали не меньше заданного минимума
+func matchesStockQuantity(part *inventoryv1.Part, minQuantity *int64) bool {
+	return minQuantity == nil || part.GetStockQuantity() >= *minQuantity
+}
+
+// matchesDimensions проверяет, соответствует ли деталь фильтру по диапазонам габаритов
+func matchesDimensions(part *inventoryv1.Part, r *inventoryv1.DimensionsRange) bool {
+	dims := part.GetDimensions()
+	return inRange(dims.GetLength(), r.GetLength()) && inRange(dims.GetWidth(), r.GetWidth()) &&
+		inRange(dims.GetHeight(), r.GetHeight()) && inRange(dims.GetWeight(), r.GetWeight())
+}
+
+// matchesTimeRange проверяет момент интервалом [from, to); незаданная граница не ограничивает
+func matchesTimeRange(t, from, to *timestamppb.Timestamp) bool {
+	return (from == nil || !t.AsTime().Before(from.AsTime())) && (to == nil || t.AsTime().Before(to.AsTime()))
+}
+
+// InMemoryInventoryRepository хранит детали и резервы в памяти процесса; данные теряются при перезапуске.
+// Вторичные индексы по категории, тегу и стране производителя сужают перебор в ListParts.
+type InMemoryInventoryRepository struct {
+	mu           sync.RWMutex
+	parts        map[string]*inventoryv1.Part
+	byCategory   map[inventoryv1.Category]map[string]struct{}
+	byTag        map[string]map[string]struct{}
+	byCountry    map[string]map[string]struct{}
+	reservations map[string]*inventoryv1.Reservation // ключ — order_uuid
+}
+
+func NewInMemoryInventoryRepository() *InMemoryInventoryRepository {
+	return &InMemoryInventoryRepository{
+		parts:        make(map[string]*inventoryv1.Part),
+		byCategory:   make(map[inventoryv1.Category]map[string]struct{}),
+		byTag:        make(map[string]map[string]struct{}),
+		byCountry:    make(map[string]map[string]struct{}),
+		reservations: make(map[string]*inventoryv1.Reservation),
+	}
+}
+
+func addToIndex[K comparable](index map[K]map[string]struct{}, key K, partUUID string) {
+	set, ok := index[key]
+	if !ok {
+		set = make(map[string]struct{})
+		index[key] = set
+	}
+	set[partUUID] = struct{}{}
+}
+
+func removeFromIndex[K comparable](index map[K]map[string]struct{}, key K, partUUID string) {
+	set, ok := index[key]
+	if !ok {
+		return
+	}
+	delete(set, partUUID)
+	if len(set) == 0 {
+		delete(index, key)
+	}
+}
+
+func (r *InMemoryInventoryRepository) index(part *inventoryv1.Part) {
+	addToIndex(r.byCategory, part.GetCategory(), part.GetUuid())
+	addToIndex(r.byCountry, part.GetManufacturer().GetCountry(), part.GetUuid())
+	for _, tag := range part.GetTags() {
+		addToIndex(r.byTag, tag, part.GetUuid())
+	}
+}
+
+func (r *InMemoryInventoryRepository) unindex(part *inventoryv1.Part) {
+	removeFromIndex(r.byCategory, part.GetCategory(), part.GetUuid())
+	removeFromIndex(r.byCountry, part.GetManufacturer().GetCountry(), part.GetUuid())
+	for _, tag := range part.GetTags() {
+		removeFromIndex(r.byTag, tag, part.GetUuid())
+	}
+}
+
+// store сохраняет деталь, заменяя прежнюю версию в индексах
+func (r *InMemoryInventoryRepository) store(part *inventoryv1.Part) {
+	if current, ok := r.parts[part.GetUuid()]; ok {
+		r.unindex(current)
+	}
+	r.parts[part.GetUuid()] = part
+	r.index(part)
+}
+
+func (r *InMemoryInventoryRepository) GetPart(_ context.Context, uuid string) (*inventoryv1.Part, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	part, ok := r.parts[uuid]
+	if !ok {
+		return nil, ErrPartNotFound
+	}
+	return proto.Clone(part).(*inventoryv1.Part), nil
+}
+
+// candidates возвращает UUID деталей, подходящих хотя бы под одно значение самого узкого поля фильтра.
+// Если фильтр не затрагивает индексы, возвращается nil и перебираются все детали.
+func (r *InMemoryInventoryRepository) candidates(filter *inventoryv1.PartsFilter) map[string]struct{} {
+	var (
+		best    map[string]struct{}
+		indexed bool
+	)
+	consider := func(set map[string]struct{}) {
+		if !indexed || len(set) < len(best) {
+			best = set
+		}
+		indexed = true
+	}
+	union := func(sets ...map[string]struct{}) map[string]struct{} {
+		if len(sets) == 1 {
+			return sets[0]
+		}
+		result := make(map[string]struct{})
+		for _, set := range sets {
+			for partUUID := range set {
+				result[partUUID] = struct{}{}
+			}
+		}
+		return result
+	}
+
+	if uuids := filter.GetUuids(); len(uuids) > 0 {
+		set := make(map[string]struct{}, len(uuids))
+		for _, uuid := range uuids {
+			if _, ok := r.parts[uuid]; ok {
+				set[uuid] = struct{}{}
+			}
+		}
+		consider(set)
+	}
+	if categories := filter.GetCategories(); len(categories) > 0 {
+		sets := make([]map[string]struct{}, 0, len(categories))
+		for _, category := range categories {
+			sets = append(sets, r.byCategory[category])
+		}
+		consider(union(sets...))
+	}
+	if tags := filter.GetTags(); len(tags) > 0 {
+		sets := make([]map[string]struct{}, 0, len(tags))
+		for _, tag := range tags {
+			sets = append(sets, r.byTag[tag])
+		}
+		consider(union(sets...))
+	}
+	if countries := filter.GetManufacturerCountries(); len(countries) > 0 {
+		sets := make([]map[string]struct{}, 0, len(countries))
+		for _, country := range countries {
+			sets = append(sets, r.byCountry[country])
+		}
+		consider(union(sets...))
+	}
+
+	if !indexed {
+		return nil
+	}
+	if best == nil {
+		return map[string]struct{}{}
+	}
+	return best
+}
+
+func (r *InMemoryInventoryRepository) ListParts(_ context.Context, query PartsQuery) (*PartsPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*inventoryv1.Part
+	collect := func(part *inventoryv1.Part) {
+		if partMatches(part, query.Filter) {
+			matched = append(matched, part)
+		}
+	}
+
+	if candidates := r.candidates(query.Filter); candidates != nil {
+		for partUUID := range candidates {
+			collect(r.parts[partUUID])
+		}
+	} else {
+		for _, part := range r.parts {
+			collect(part)
+		}
+	}
+
+	page := &PartsPage{TotalSize: len(matched)}
+	compare := query.OrderBy.compare
+	slices.SortFunc(matched, compare)
+	if query.After != nil {
+		after := query.After.part()
+		// Первая деталь строго после курсора
+		start, _ := slices.BinarySearchFunc(matched, after, func(part, after *inventoryv1.Part) int {
+			if compare(part, after) <= 0 {
+				return -1
+			}
+			return 1
+		})
+		matched = matched[start:]
+	}
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+		page.NextCursor = query.OrderBy.cursorFor(matched[len(matched)-1])
+	}
+
+	page.Parts = make([]*inventoryv1.Part, 0, len(matched))
+	for _, part := range matched {
+		page.Parts = append(page.Parts, proto.Clone(part).(*inventoryv1.Part))
+	}
+	return page, nil
+}
+
+func (r *InMemoryInventoryRepository) CreatePart(_ context.Context, part *inventoryv1.Part) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.store(proto.Clone(part).(*inventoryv1.Part))
+	return nil
+}
+
+func (r *InMemoryInventoryRepository) UpdatePart(_ context.Context, part *inventoryv1.Part) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.parts[part.GetUuid()]; !ok {
+		return ErrPartNotFound
+	}
+	r.store(proto.Clone(part).(*inventoryv1.Part))
+	return nil
+}
+
+func (r *InMemoryInventoryRepository) DeletePart(_ context.Context, uuid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	part, ok := r.parts[uuid]
+	if !ok {
+		return ErrPartNotFound
+	}
+	r.unindex(part)
+	delete(r.parts, uuid)
+	return nil
+}
+
+func (r *InMemoryInventoryRepository) GetReservation(_ context.Context, orderUUID string) (*inventoryv1.Reservation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	reservation, ok := r.reservations[orderUUID]
+	if !ok {
+		return nil, ErrReservationNotFound
+	}
+	return proto.Clone(reservation).(*inventoryv1.Reservation), nil
+}
+
+func (r *InMemoryInventoryRepository) SaveReservation(_ context.Context, reservation *inventoryv1.Reservation, stockDeltas map[string]int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Остатки проверяются до изменений, чтобы при нехватке ничего не менять
+	for partUUID, delta := range stockDeltas {
+		if part, ok := r.parts[partUUID]; ok && part.GetStockQuantity()+delta < 0 {
+			return ErrInsufficientStock
+		}
+	}
+	for partUUID, delta := range stockDeltas {
+		part, ok := r.parts[partUUID]
+		if !ok {
+			continue
+		}
+		// Индексируемые поля не меняются, поэтому деталь заменяется без переиндексации
+		updated := proto.Clone(part).(*inventoryv1.Part)
+		updated.StockQuantity += delta
+		updated.UpdatedAt = reservation.GetUpdatedAt()
+		r.parts[partUUID] = updated
+	}
+	r.reservations[reservation.GetOrderUuid()] = proto.Clone(reservation).(*inventoryv1.Reservation)
+	return nil
+}
+
+func (r *InMemoryInventoryRepository) PartReserved(_ context.Context, partUUID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, reservation := range r.reservations {
+		if reservation.GetStatus() != inventoryv1.ReservationStatus_RESERVATION_STATUS_RESERVED {
+			continue
+		}
+		for _, item := range reservation.GetItems() {
+			if item.GetPartUuid() == partUUID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (r *InMemoryInventoryRepository) Close(context.Context) error { return nil }
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+)
+
+// MongoInventoryRepository хранит детали и резервы в MongoDB или совместимом документном хранилище:
+// коллекции parts и reservations. Остатки деталей и резерв SaveReservation меняет в одной транзакции,
+// поэтому MongoDB должна быть запущена набором реплик. Время хранится с точностью до миллисекунд.
+type MongoInventoryRepository struct {
+	client       *mongo.Client
+	parts        *mongo.Collection
+	reservations *mongo.Collection
+}
+
+// NewMongoInventoryRepository подключается к MongoDB по uri и создает индексы коллекций в database
+func NewMongoInventoryRepository(ctx context.Context, uri, database string) (*MongoInventoryRepository, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mongodb: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to ping mongodb: %w", err)
+	}
+
+	db := client.Database(database)
+	r := &MongoInventoryRepository{
+		client:       client,
+		parts:        db.Collection("parts"),
+		reservations: db.Collection("reservations"),
+	}
+	if err := r.ensureIndexes(ctx); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
+	return r, nil
+}
+
+// ensureIndexes создает индексы под фильтры и сортировки ListParts и поиск действующих резервов детали.
+// Создание существующего индекса ничего не меняет, поэтому выполняется при каждом старте.
+func (r *MongoInventoryRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.parts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "category", Value: 1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "manufacturer.country", Value: 1}}},
+		{Keys: bson.D{{Key: "manufacturer.name", Value: 1}}},
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "price", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "stock_quantity", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.reservations.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "items.part_uuid", Value: 1}, {Key: "status", Value: 1}},
+	})
+	return err
+}
+
+type partDocument struct {
+	UUID          string                `bson:"_id"`
+	Name          string                `bson:"name"`
+	Description   string                `bson:"description"`
+	Price         float64               `bson:"price"`
+	StockQuantity int64                 `bson:"stock_quantity"`
+	Category      string                `bson:"category"`
+	Dimensions    *dimensionsDocument   `bson:"dimensions,omitempty"`
+	Manufacturer  *manufacturerDocument `bson:"manufacturer,omitempty"`
+	Tags          []string              `bson:"tags"`
+	// Значения metadata хранятся нативными типами BSON: string, int64, double, bool
+	Metadata  map[string]any `bson:"metadata"`
+	CreatedAt time.Time      `bson:"created_at"`
+	UpdatedAt time.Time      `bson:"updated_at"`
+}
+
+type dimensionsDocument struct {
+	Length float64 `bson:"length"`
+	Width  float64 `bson:"width"`
+	Height float64 `bson:"height"`
+	Weight float64 `bson:"weight"`
+}
+
+type manufacturerDocument struct {
+	Name    string `bson:"name"`
+	Country string `bson:"country"`
+	Website string `bson:"website"`
+}
+
+type reservationDocument struct {
+	OrderUUID string                    `bson:"_id"`
+	Items     []reservationItemDocument `bson:"items"`
+	Status    string                    `bson:"status"`
+	CreatedAt time.Time                 `bson:"created_at"`
+	UpdatedAt time.Time                 `bson:"updated_at"`
+}
+
+type reservationItemDocument struct {
+	PartUUID string `bson:"part_uuid"`
+	Quantity int64  `bson:"quantity"`
+}
+
+func toPartDocument(part *inventoryv1.Part) partDocument {
+	doc := partDocument{
+		UUID:          part.GetUuid(),
+		Name:          part.GetName(),
+		Description:   part.GetDescription(),
+		Price:         part.GetPrice(),
+		StockQuantity: part.GetStockQuantity(),
+		Category:      part.GetCategory().String(),
+		Tags:          part.GetTags(),
+		Metadata:      make(map[string]any, len(part.GetMetadata())),
+		CreatedAt:     part.GetCreatedAt().AsTime(),
+		UpdatedAt:     part.GetUpdatedAt().AsTime(),
+	}
+	if d := part.GetDimensions(); d != nil {
+		doc.Dimensions = &dimensionsDocument{Length: d.GetLength(), Width: d.GetWidth(), Height: d.GetHeight(), Weight: d.GetWeight()}
+	}
+	if m := part.GetManufacturer(); m != nil {
+		doc.Manufacturer = &manufacturerDocument{Name: m.GetName(), Country: m.GetCountry(), Website: m.GetWebsite()}
+	}
+	if doc.Tags == nil {
+		doc.Tags = []string{}
+	}
+	for key, value := range part.GetMetadata() {
+		switch v := value.GetValue().(type) {
+		case *inventoryv1.Value_StringValue:
+			doc.Metadata[key] = v.StringValue
+		case *inventoryv1.Value_Int64Value:
+			doc.Metadata[key] = v.Int64Value
+		case *inventoryv1.Value_DoubleValue:
+			doc.Metadata[key] = v.DoubleValue
+		case *inventoryv1.Value_BoolValue:
+			doc.Metadata[key] = v.BoolValue
+		}
+	}
+	return doc
+}
+
+func (doc partDocument) toProto() *inventoryv1.Part {
+	part := &inventoryv1.Part{
+		Uuid:          doc.UUID,
+		Name:          doc.Name,
+		Description:   doc.Description,
+		Price:         doc.Price,
+		StockQuantity: doc.StockQuantity,
+		Category:      inventoryv1.Category(inventoryv1.Category_value[doc.Category]),
+		Tags:          doc.Tags,
+		Metadata:      make(map[string]*inventoryv1.Value, len(doc.Metadata)),
+		CreatedAt:     timestamppb.New(doc.CreatedAt),
+		UpdatedAt:     timestamppb.New(doc.UpdatedAt),
+	}
+	if d := doc.Dimensions; d != nil {
+		part.Dimensions = &inventoryv1.Dimensions{Length: d.Length, Width: d.Width, Height: d.Height, Weight: d.Weight}
+	}
+	if m := doc.Manufacturer; m != nil {
+		part.Manufacturer = &inventoryv1.Manufacturer{Name: m.Name, Country: m.Country, Website: m.Website}
+	}
+	for key, value := range doc.Metadata {
+		switch v := value.(type) {
+		case string:
+			part.Metadata[key] = &inventoryv1.Value{Value: &inventoryv1.Value_StringValue{StringValue: v}}
+		case int64:
+			part.Metadata[key] = &inventoryv1.Value{Value: &inventoryv1.Value_Int64Value{Int64Value: v}}
+		case int32:
+			part.Metadata[key] = &inventoryv1.Value{Value: &inventoryv1.Value_Int64Value{Int64Value: int64(v)}}
+		case float64:
+			part.Metadata[key] = &inventoryv1.Value{Value: &inventoryv1.Value_DoubleValue{DoubleValue: v}}
+		case bool:
+			part.Metadata[key] = &inventoryv1.Value{Value: &inventoryv1.Value_BoolValue{BoolValue: v}}
+		}
+	}
+	return part
+}
+
+func toReservationDocument(reservation *inventoryv1.Reservation) reservationDocument {
+	doc := reservationDocument{
+		OrderUUID: reservation.GetOrderUuid(),
+		Items:     make([]reservationItemDocument, 0, len(reservation.GetItems())),
+		Status:    reservation.GetStatus().String(),
+		CreatedAt: reservation.GetCreatedAt().AsTime(),
+		UpdatedAt: reservation.GetUpdatedAt().AsTime(),
+	}
+	for _, item := range reservation.GetItems() {
+		doc.Items = append(doc.Items, reservationItemDocument{PartUUID: item.GetPartUuid(), Quantity: item.GetQuantity()})
+	}
+	return doc
+}
+
+func (doc reservationDocument) toProto() *inventoryv1.Reservation {
+	reservation := &inventoryv1.Reservation{
+		OrderUuid: doc.OrderUUID,
+		Items:     make([]*inventoryv1.ReservationItem, 0, len(doc.Items)),
+		Status:    inventoryv1.ReservationStatus(inventoryv1.ReservationStatus_value[doc.Status]),
+		CreatedAt: timestamppb.New(doc.CreatedAt),
+		UpdatedAt: timestamppb.New(doc.UpdatedAt),
+	}
+	for _, item := range doc.Items {
+		reservation.Items = append(reservation.Items, &inventoryv1.ReservationItem{PartUuid: item.PartUUID, Quantity: item.Quantity})
+	}
+	return reservation
+}
+
+// partsQuery переводит PartsFilter в запрос MongoDB: каждое непустое списочное поле фильтра — условие $in,
+// диапазоны — условия $gte, $lte и $lt
+func partsQuery(filter *inventoryv1.PartsFilter) bson.D {
+	query := bson.D{}
+	in := func(field string, values any) {
+		query = append(query, bson.E{Key: field, Value: bson.D{{Key: "$in", Value: values}}})
+	}
+
+	if uuids := filter.GetUuids(); len(uuids) > 0 {
+		in("_id", uuids)
+	}
+	if names := filter.GetNames(); len(names) > 0 {
+		in("name", names)
+	}
+	if categories := filter.GetCategories(); len(categories) > 0 {
+		values := make([]string, 0, len(categories))
+		for _, category := range categories {
+			values = append(values, category.String())
+		}
+		in("category", values)
+	}
+	if countries := filter.GetManufacturerCountries(); len(countries) > 0 {
+		in("manufacturer.country", countries)
+	}
+	if tags := filter.GetTags(); len(tags) > 0 {
+		in("tags", tags)
+	}
+	if names := filter.GetManufacturerNames(); len(names) > 0 {
+		in("manufacturer.name", names)
+	}
+
+	// Условия диапазона поля собираются в один элемент: повторный ключ в запросе MongoDB не допускается
+	between := func(field string, bounds bson.D) {
+		if len(bounds) > 0 {
+			query = append(query, bson.E{Key: field, Value: bounds})
+		}
+	}
+	doubleRange := func(field string, r *inventoryv1.DoubleRange) {
+		var bounds bson.D
+		lower, upper := rangeBounds(r)
+		if lower != nil {
+			bounds = append(bounds, bson.E{Key: "$gte", Value: *lower})
+		}
+		if upper != nil {
+			bounds = append(bounds, bson.E{Key: "$lte", Value: *upper})
+		}
+		between(field, bounds)
+	}
+	timeRange := func(field string, from, to *timestamppb.Timestamp) {
+		var bounds bson.D
+		if from != nil {
+			bounds = append(bounds, bson.E{Key: "$gte", Value: from.AsTime()})
+		}
+		if to != nil {
+			bounds = append(bounds, bson.E{Key: "$lt", Value: to.AsTime()})
+		}
+		between(field, bounds)
+	}
+
+	doubleRange("price", filter.GetPrice())
+	if minStock := minStockQuantity(filter); minStock != nil {
+		between("stock_quantity", bson.D{{Key: "$gte", Value: *minStock}})
+	}
+	doubleRange("dimensions.length", filter.GetDimensions().GetLength())
+	doubleRange("dimensions.width", filter.GetDimensions().GetWidth())
+	doubleRange("dimensions.height", filter.GetDimensions().GetHeight())
+	doubleRange("dimensions.weight", filter.GetDimensions().GetWeight())
+	timeRange("created_at", filter.GetCreatedFrom(), filter.GetCreatedTo())
+	timeRange("updated_at", filter.GetUpdatedFrom(), filter.GetUpdatedTo())
+	return query
+}
+
+func (r *MongoInventoryRepository) GetPart(ctx context.Context, uuid string) (*inventoryv1.Part, error) {
+	var doc partDocument
+	err := r.parts.FindOne(ctx, bson.D{{Key: "_id", Value: uuid}}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrPartNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toProto(), nil
+}
+
+// afterCursorQuery — условие "строго после курсора" для ключевой пагинации по (поле порядка, _id).
+// Поля порядка PartsOrder совпадают с полями документа детали.
+func afterCursorQuery(order PartsOrder, cursor *PartsCursor) bson.E {
+	op := "$gt"
+	if order.Desc {
+		op = "$lt"
+	}
+	if order.Field == "" {
+		return bson.E{Key: "_id", Value: bson.D{{Key: op, Value: cursor.UUID}}}
+	}
+	value := order.value(cursor)
+	return bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: order.Field, Value: bson.D{{Key: op, Value: value}}}},
+		bson.D{{Key: order.Field, Value: value}, {Key: "_id", Value: bson.D{{Key: op, Value: cursor.UUID}}}},
+	}}
+}
+
+func (r *MongoInventoryRepository) ListParts(ctx context.Context, query PartsQuery) (*PartsPage, error) {
+	filter := partsQuery(query.Filter)
+	total, err := r.parts.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	direction := 1
+	if query.OrderBy.Desc {
+		direction = -1
+	}
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if query.OrderBy.Field != "" {
+		sort = append(bson.D{{Key: query.OrderBy.Field, Value: direction}}, sort...)
+	}
+	opts := options.Find().SetSort(sort)
+	if query.Limit > 0 {
+		// Лишний документ показывает, что за страницей есть продолжение
+		opts.SetLimit(int64(query.Limit) + 1)
+	}
+	if query.After != nil {
+		filter = append(filter, afterCursorQuery(query.OrderBy, query.After))
+	}
+
+	cursor, err := r.parts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []partDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	page := &PartsPage{TotalSize: int(total)}
+	if query.Limit > 0 && len(docs) > query.Limit {
+		docs = docs[:query.Limit]
+		page.NextCursor = query.OrderBy.cursorFor(docs[len(docs)-1].toProto())
+	}
+	page.Parts = make([]*inventoryv1.Part, 0, len(docs))
+	for _, doc := range docs {
+		page.Parts = append(page.Parts, doc.toProto())
+	}
+	return page, nil
+}
+
+func (r *MongoInventoryRepository) CreatePart(ctx context.Context, part *inventoryv1.Part) error {
+	_, err := r.parts.InsertOne(ctx, toPartDocument(part))
+	return err
+}
+
+func (r *MongoInventoryRepository) UpdatePart(ctx context.Context, part *inventoryv1.Part) error {
+	result, err := r.parts.ReplaceOne(ctx, bson.D{{Key: "_id", Value: part.GetUuid()}}, toPartDocument(part))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPartNotFound
+	}
+	return nil
+}
+
+func (r *MongoInventoryRepository) DeletePart(ctx context.Context, uuid string) error {
+	result, err := r.parts.DeleteOne(ctx, bson.D{{Key: "_id", Value: uuid}})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrPartNotFound
+	}
+	return nil
+}
+
+func (r *MongoInventoryRepository) GetReservation(ctx context.Context, orderUUID string) (*inventoryv1.Reservation, error) {
+	var doc reservationDocument
+	err := r.reservations.FindOne(ctx, bson.D{{Key: "_id", Value: orderUUID}}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toProto(), nil
+}
+
+func (r *MongoInventoryRepository) SaveReservation(ctx context.Context, reservation *inventoryv1.Reservation, stockDeltas map[string]int64) error {
+	if len(stockDeltas) == 0 {
+		return r.replaceReservation(ctx, reservation)
+	}
+
+	session, err := r.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		for partUUID, delta := range stockDeltas {
+			if err := r.adjustStock(sc, partUUID, delta, reservation.GetUpdatedAt().AsTime()); err != nil {
+				return nil, err
+			}
+		}
+		return nil, r.replaceReservation(sc, reservation)
+	})
+	return err
+}
+
+// adjustStock изменяет остаток детали на delta. Списание выполняется условным $inc:
+// документ обновляется, только если остатка хватает.
+func (r *MongoInventoryRepository) adjustStock(ctx context.Context, partUUID string, delta int64, now time.Time) error {
+	filter := bson.D{{Key: "_id", Value: partUUID}}
+	if delta < 0 {
+		filter = append(filter, bson.E{Key: "stock_quantity", Value: bson.D{{Key: "$gte", Value: -delta}}})
+	}
+	result, err := r.parts.UpdateOne(ctx, filter, bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "stock_quantity", Value: delta}}},
+		{Key: "$set", Value: bson.D{{Key: "updated_at", Value: now}}},
+	})
+	if err != nil {
+		return fmt.Errorf("adjust stock of part %s: %w", partUUID, err)
+	}
+	if result.MatchedCount > 0 || delta >= 0 {
+		return nil
+	}
+
+	// Условие не выполнилось: деталь удалена (ее пропускаем) или остатка не хватает
+	count, err := r.parts.CountDocuments(ctx, bson.D{{Key: "_id", Value: partUUID}}, options.Count().SetLimit(1))
+	if err != nil {
+		return fmt.Errorf("check part %s: %w", partUUID, err)
+	}
+	if count > 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+func (r *MongoInventoryRepository) replaceReservation(ctx context.Context, reservation *inventoryv1.Reservation) error {
+	_, err := r.reservations.ReplaceOne(ctx,
+		bson.D{{Key: "_id", Value: reservation.GetOrderUuid()}},
+		toReservationDocument(reservation),
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *MongoInventoryRepository) PartReserved(ctx context.Context, partUUID string) (bool, error) {
+	count, err := r.reservations.CountDocuments(ctx,
+		bson.D{
+			{Key: "items.part_uuid", Value: partUUID},
+			{Key: "status", Value: inventoryv1.ReservationStatus_RESERVATION_STATUS_RESERVED.String()},
+		},
+		options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *MongoInventoryRepository) Close(ctx context.Context) error {
+	return r.client.Disconnect(ctx)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+)
+
+// aggregateItems проверяет позиции резерва и суммирует количество по каждой детали
+func aggregateItems(items []*inventoryv1.ReservationItem) ([]*inventoryv1.ReservationItem, error) {
+	if len(items) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "items are required")
+	}
+
+	quantities := make(map[string]int64, len(items))
+	order := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.GetPartUuid() == "" {
+			return nil, status.Error(codes.InvalidArgument, "part_uuid is required")
+		}
+		if item.GetQuantity() <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "quantity for part %s must be positive", item.GetPartUuid())
+		}
+		if _, ok := quantities[item.GetPartUuid()]; !ok {
+			order = append(order, item.GetPartUuid())
+		}
+		quantities[item.GetPartUuid()] += item.GetQuantity()
+	}
+
+	result := make([]*inventoryv1.ReservationItem, 0, len(order))
+	for _, partUUID := range order {
+		result = append(result, &inventoryv1.ReservationItem{
+			PartUuid: partUUID,
+			Quantity: quantities[partUUID],
+		})
+	}
+	return result, nil
+}
+
+// stockDeltas возвращает изменения остатков деталей резерва: sign = -1 списывает, +1 возвращает на склад
+func stockDeltas(items []*inventoryv1.ReservationItem, sign int64) map[string]int64 {
+	deltas := make(map[string]int64, len(items))
+	for _, item := range items {
+		deltas[item.GetPartUuid()] += sign * item.GetQuantity()
+	}
+	return deltas
+}
+
+// checkStock проверяет, что все детали резерва существуют и их остатка хватает.
+// Нехватка возвращается как FailedPrecondition с InsufficientStock в деталях.
+func (s *inventoryService) checkStock(ctx context.Context, items []*inventoryv1.ReservationItem) error {
+	partUUIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		partUUIDs = append(partUUIDs, item.GetPartUuid())
+	}
+	page, err := s.repo.ListParts(ctx, PartsQuery{Filter: &inventoryv1.PartsFilter{Uuids: partUUIDs}})
+	if err != nil {
+		return repositoryError(err)
+	}
+	stock := make(map[string]int64, len(page.Parts))
+	for _, part := range page.Parts {
+		stock[part.GetUuid()] = part.GetStockQuantity()
+	}
+
+	var shortages []*inventoryv1.StockShortage
+	for _, item := range items {
+		available, ok := stock[item.GetPartUuid()]
+		if !ok {
+			return status.Errorf(codes.NotFound, "part with UUID %s not found", item.GetPartUuid())
+		}
+		if available < item.GetQuantity() {
+			shortages = append(shortages, &inventoryv1.StockShortage{
+				PartUuid:  item.GetPartUuid(),
+				Requested: item.GetQuantity(),
+				Available: available,
+			})
+		}
+	}
+
+	if len(shortages) > 0 {
+		st, detailsErr := status.New(codes.FailedPrecondition, "insufficient stock").
+			WithDetails(&inventoryv1.InsufficientStock{Shortages: shortages})
+		if detailsErr != nil {
+			return status.Errorf(codes.Internal, "failed to attach error details: %v", detailsErr)
+		}
+		return st.Err()
+	}
+	return nil
+}
+
+func (s *inventoryService) ReserveParts(ctx context.Context, req *inventoryv1.ReservePartsRequest) (*inventoryv1.ReservePartsResponse, error) {
+	if req.GetOrderUuid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uuid is required")
+	}
+
+	items, err := aggregateItems(req.GetItems())
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Повторный запрос по уже зарезервированному заказу возвращает существующий резерв
+	existing, err := s.repo.GetReservation(ctx, req.GetOrderUuid())
+	switch {
+	case err == nil:
+		if existing.GetStatus() != inventoryv1.ReservationStatus_RESERVATION_STATUS_RESERVED {
+			return nil, status.Errorf(codes.FailedPrecondition, "reservation for order %s is %s", req.GetOrderUuid(), existing.GetStatus())
+		}
+		return &inventoryv1.ReservePartsResponse{Reservation: existing}, nil
+	case !errors.Is(err, ErrReservationNotFound):
+		return nil, repositoryError(err)
+	}
+
+	if err := s.checkStock(ctx, items); err != nil {
+		return nil, err
+	}
+
+	createdAt := timestamppb.New(now())
+	reservation := &inventoryv1.Reservation{
+		OrderUuid: req.GetOrderUuid(),
+		Items:     items,
+		Status:    inventoryv1.ReservationStatus_RESERVATION_STATUS_RESERVED,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	err = s.repo.SaveReservation(ctx, reservation, stockDeltas(items, -1))
+	if errors.Is(err, ErrInsufficientStock) {
+		// Остаток изменился в обход блокировки сервиса: сообщаем актуальную нехватку
+		if err := s.checkStock(ctx, items); err != nil {
+			return nil, err
+		}
+		return nil, status.Error(codes.Aborted, "stock changed during reservation, retry the request")
+	}
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+
+	return &inventoryv1.ReservePartsResponse{Reservation: reservation}, nil
+}
+
+func (s *inventoryService) ReleaseReservation(ctx context.Context, req *inventoryv1.ReleaseReservationRequest) (*inventoryv1.ReleaseReservationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation, err := s.repo.GetReservation(ctx, req.GetOrderUuid())
+	if errors.Is(err, ErrReservationNotFound) {
+		return nil, status.Errorf(codes.NotFound, "reservation for order %s not found", req.GetOrderUuid())
+	}
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+
+	switch reservation.GetStatus() {
+	case inventoryv1.ReservationStatus_RESERVATION_STATUS_RELEASED:
+		return &inventoryv1.ReleaseReservationResponse{Reservation: reservation}, nil
+	case inventoryv1.ReservationStatus_RESERVATION_STATUS_RESERVED,
+		inventoryv1.ReservationStatus_RESERVATION_STATUS_COMMITTED:
+		// Подтвержденный резерв снимается при возврате оплаченного заказа
+	default:
+		return nil, status.Errorf(codes.FailedPrecondition, "reservation for order %s is %s", req.GetOrderUuid(), reservation.GetStatus())
+	}
+
+	reservation.Status = inventoryv1.ReservationStatus_RESERVATION_STATUS_RELEASED
+	reservation.UpdatedAt = timestamppb.New(now())
+	if err := s.repo.SaveReservation(ctx, reservation, stockDeltas(reservation.GetItems(), 1)); err != nil {
+		return nil, repositoryError(err)
+	}
+
+	return &inventoryv1.ReleaseReservationResponse{Reservation: reservation}, nil
+}
+
+func (s *inventoryService) CommitReservation(ctx context.Context, req *inventoryv1.CommitReservationRequest) (*inventoryv1.CommitReservationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation, err := s.repo.GetReservation(ctx, req.GetOrderUuid())
+	if errors.Is(err, ErrReservationNotFound) {
+		return nil, status.Errorf(codes.NotFound, "reservation for order %s not found", req.GetOrderUuid())
+	}
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+
+	switch reservation.GetStatus() {
+	case inventoryv1.ReservationStatus_RESERVATION_STATUS_COMMITTED:
+		return &inventoryv1.CommitReservationResponse{Reservation: reservation}, nil
+	case inventoryv1.ReservationStatus_RESERVATION_STATUS_RESERVED:
+	default:
+		return nil, status.Errorf(codes.FailedPrecondition, "reservation for order %s is %s", req.GetOrderUuid(), reservation.GetStatus())
+	}
+
+	reservation.Status = inventoryv1.ReservationStatus_RESERVATION_STATUS_COMMITTED
+	reservation.UpdatedAt = timestamppb.New(now())
+	if err := s.repo.SaveReservation(ctx, reservation, nil); err != nil {
+		return nil, repositoryError(err)
+	}
+
+	return &inventoryv1.CommitReservationResponse{Reservation: reservation}, nil
+}
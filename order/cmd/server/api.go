@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ogen-go/ogen/ogenerrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+)
+
+// writeError пишет ошибку в формате схем *Error из order.openapi.yaml: {"code": ..., "message": ...}.
+// Используется там, где ответ формируется в обход сгенерированного сервера.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    status,
+		"message": message,
+	}); err != nil {
+		log.Printf("error encoding error response: %v", err)
+	}
+}
+
+// handleAPIError — обработчик ошибок сгенерированного сервера.
+// Ошибки разбора запроса (в том числе нарушение контракта) возвращаются как 400,
+// ошибки обработчиков логируются и возвращаются как 500 без подробностей.
+func handleAPIError(_ context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	code := ogenerrors.ErrorCode(err)
+	if code >= http.StatusInternalServerError {
+		log.Printf("error handling %s %s: %v", r.Method, r.URL.Path, err)
+		writeError(w, code, http.StatusText(code))
+		return
+	}
+	writeError(w, code, err.Error())
+}
+
+func badRequestError(message string) *orderv1.BadRequestError {
+	return &orderv1.BadRequestError{
+		Code:    orderv1.NewOptInt(http.StatusBadRequest),
+		Message: orderv1.NewOptString(message),
+	}
+}
+
+// paymentBadRequest переводит отказ PaymentService с InvalidArgument в 400,
+// перенося нарушения из деталей google.rpc.BadRequest в поле violations
+func paymentBadRequest(err error) (*orderv1.BadRequestError, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		return nil, false
+	}
+
+	resp := badRequestError(st.Message())
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, violation := range badRequest.GetFieldViolations() {
+			resp.Violations = append(resp.Violations, orderv1.BadRequestErrorViolationsItem{
+				Field:       violation.GetField(),
+				Description: violation.GetDescription(),
+			})
+		}
+	}
+	return resp, true
+}
+
+// paymentDeclineDomain — домен google.rpc.ErrorInfo, которым PaymentService помечает отказы провайдера
+const paymentDeclineDomain = "payment.bigproj"
+
+// paymentDeclined переводит отказ платежного провайдера в 402 с кодом отказа
+func paymentDeclined(err error) (*orderv1.PaymentRequiredError, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.FailedPrecondition {
+		return nil, false
+	}
+
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != paymentDeclineDomain {
+			continue
+		}
+		declined := &orderv1.PaymentRequiredError{
+			Code:        orderv1.NewOptInt(http.StatusPaymentRequired),
+			Message:     orderv1.NewOptString("payment declined"),
+			DeclineCode: orderv1.NewOptString(info.GetReason()),
+		}
+		// Кошелек инвестора сообщает остаток и требуемую сумму
+		if available, err := strconv.ParseFloat(info.GetMetadata()["available"], 64); err == nil {
+			declined.AvailableBalance = orderv1.NewOptFloat64(available)
+		}
+		if required, err := strconv.ParseFloat(info.GetMetadata()["required"], 64); err == nil {
+			declined.RequiredAmount = orderv1.NewOptFloat64(required)
+		}
+		// Правила антифрода сообщают, какие из них сработали
+		if ruleIDs := info.GetMetadata()["rule_ids"]; ruleIDs != "" {
+			declined.RuleIds = strings.Split(ruleIDs, ",")
+		}
+		return declined, true
+	}
+	return nil, false
+}
+
+func notFoundError() *orderv1.NotFoundError {
+	return &orderv1.NotFoundError{
+		Code:    orderv1.NewOptInt(http.StatusNotFound),
+		Message: orderv1.NewOptString(ErrOrderNotFound.Error()),
+	}
+}
+
+// conflictError описывает недопустимый переход статуса заказа
+func conflictError(err *TransitionError) *orderv1.ConflictError {
+	return &orderv1.ConflictError{
+		Code:            orderv1.NewOptInt(http.StatusConflict),
+		Message:         orderv1.NewOptString(err.Error()),
+		CurrentStatus:   orderv1.NewOptOrderStatus(orderv1.OrderStatus(err.From)),
+		RequestedStatus: orderv1.NewOptOrderStatus(orderv1.OrderStatus(err.To)),
+	}
+}
+
+func badGatewayError() *orderv1.BadGatewayError {
+	return &orderv1.BadGatewayError{
+		Code:    orderv1.NewOptInt(http.StatusBadGateway),
+		Message: orderv1.NewOptString(http.StatusText(http.StatusBadGateway)),
+	}
+}
+
+// parseUUID разбирает UUID, сохраненный строкой. Новые идентификаторы проверяются контрактом,
+// поэтому ошибка возможна только для данных, записанных до его введения.
+func parseUUID(s string) uuid.UUID {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		log.Printf("invalid uuid %q: %v", s, err)
+	}
+	return id
+}
+
+// toOrderDTO преобразует заказ в представление API
+func toOrderDTO(order *Order) orderv1.OrderDTO {
+	dto := orderv1.OrderDTO{
+		OrderUUID:      orderv1.NewOptUUID(parseUUID(order.OrderUUID)),
+		UserUUID:       orderv1.NewOptUUID(parseUUID(order.UserUUID)),
+		PartUuids:      make([]uuid.UUID, 0, len(order.PartUUIDs)),
+		Items:          make([]orderv1.OrderItem, 0, len(order.Items)),
+		TotalPrice:     orderv1.NewOptFloat64(order.TotalPrice),
+		RefundedAmount: orderv1.NewOptFloat64(order.RefundedAmount),
+		Status:         orderv1.NewOptOrderStatus(orderv1.OrderStatus(order.Status)),
+		CreatedAt:      orderv1.NewOptDateTime(order.CreatedAt),
+		UpdatedAt:      orderv1.NewOptDateTime(order.UpdatedAt),
+	}
+	for _, partUUID := range order.PartUUIDs {
+		dto.PartUuids = append(dto.PartUuids, parseUUID(partUUID))
+	}
+	for _, item := range order.Items {
+		dto.Items = append(dto.Items, orderv1.OrderItem{
+			PartUUID:  parseUUID(item.PartUUID),
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		})
+	}
+	if order.TransactionUUID != nil {
+		dto.TransactionUUID = orderv1.NewOptNilUUID(parseUUID(*order.TransactionUUID))
+	}
+	if order.PaymentMethod != nil {
+		dto.PaymentMethod = orderv1.NewOptNilString(*order.PaymentMethod)
+	}
+	return dto
+}
+
+// idempotentOperations — операции, для которых учитывается заголовок Idempotency-Key
+var idempotentOperations = map[string]bool{
+	"postOrders":       true,
+	"postOrdersPay":    true,
+	"postOrdersRefund": true,
+}
+
+// withIdempotency пропускает через IdempotencyMiddleware только запросы к idempotentOperations
+func withIdempotency(server *orderv1.Server, middleware func(http.Handler) http.Handler) http.Handler {
+	idempotent := middleware(server)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := server.FindPath(r.Method, r.URL); ok && idempotentOperations[route.OperationID()] {
+			idempotent.ServeHTTP(w, r)
+			return
+		}
+		server.ServeHTTP(w, r)
+	})
+}
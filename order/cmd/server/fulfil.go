@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// voidAuthorization снимает блокировку суммы авторизованного заказа
+func (h *OrderHandler) voidAuthorization(ctx context.Context, order *Order, reason string) error {
+	if order.TransactionUUID == nil {
+		return fmt.Errorf("order %s is %s but has no transaction", order.OrderUUID, order.Status)
+	}
+	_, err := h.paymentClient.VoidAuthorization(ctx, &paymentv1.VoidAuthorizationRequest{
+		TransactionUuid: *order.TransactionUUID,
+		Reason:          reason,
+	})
+	return err
+}
+
+// PostOrdersFulfil captures the authorized payment and completes the order
+func (h *OrderHandler) PostOrdersFulfil(ctx context.Context, params orderv1.PostOrdersFulfilParams) (orderv1.PostOrdersFulfilRes, error) {
+	orderUUID := params.OrderUUID.String()
+	order, err := h.storage.GetOrder(ctx, orderUUID)
+	if errors.Is(err, ErrOrderNotFound) {
+		return notFoundError(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var transitionErr *TransitionError
+	if err := checkTransition(order, OrderStatusPaid); errors.As(err, &transitionErr) {
+		return conflictError(transitionErr), nil
+	}
+	if order.TransactionUUID == nil {
+		return nil, fmt.Errorf("order %s is %s but has no transaction", orderUUID, order.Status)
+	}
+
+	_, err = h.paymentClient.CapturePayment(ctx, &paymentv1.CapturePaymentRequest{
+		TransactionUuid: *order.TransactionUUID,
+	})
+	if status.Code(err) == codes.FailedPrecondition {
+		// Авторизация истекла или аннулирована: заказ снова ждет оплаты
+		log.Printf("authorization %s for order %s cannot be captured: %v", *order.TransactionUUID, orderUUID, err)
+		err := h.transition(ctx, orderUUID, order.Status, OrderStatusPendingPayment, func(o *Order) {
+			o.TransactionUUID = nil
+			o.PaymentMethod = nil
+		})
+		if errors.As(err, &transitionErr) {
+			return conflictError(transitionErr), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return conflictError(&TransitionError{From: OrderStatusPendingPayment, To: OrderStatusPaid}), nil
+	}
+	if err != nil {
+		log.Printf("error calling PaymentService: %v", err)
+		return badGatewayError(), nil
+	}
+
+	err = h.transition(ctx, orderUUID, order.Status, OrderStatusPaid, nil)
+	if errors.As(err, &transitionErr) {
+		log.Printf("order %s changed to %s during capture, transaction %s requires refund",
+			orderUUID, transitionErr.From, *order.TransactionUUID)
+		return conflictError(transitionErr), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Выданный заказ списывает зарезервированные детали окончательно
+	if _, err := h.inventoryClient.CommitReservation(ctx, &inventoryv1.CommitReservationRequest{
+		OrderUuid: orderUUID,
+	}); err != nil {
+		log.Printf("error committing reservation for order %s: %v", orderUUID, err)
+	}
+
+	return &orderv1.PostOrdersFulfilNoContent{}, nil
+}
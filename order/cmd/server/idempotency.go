@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	userUUIDHeader            = "X-User-UUID"
+
+	maxIdempotencyKeyLength  = 255
+	maxIdempotentRequestBody = 1 << 20
+
+	defaultIdempotencyTTL = 24 * time.Hour
+)
+
+// IdempotencyRecord — сохраненный результат запроса с ключом идемпотентности.
+// Пока Completed == false, запрос с этим ключом еще выполняется.
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	Completed   bool
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// IdempotencyStore хранит ключи идемпотентности.
+// Begin атомарно занимает ключ: если для (scope, key) уже есть неистекшая запись,
+// она возвращается вызывающему, иначе создается новая незавершенная запись и возвращается nil.
+type IdempotencyStore interface {
+	Begin(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error)
+	Complete(ctx context.Context, record *IdempotencyRecord) error
+	Release(ctx context.Context, scope, key string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// InMemoryIdempotencyStore хранит ключи идемпотентности в памяти процесса
+type InMemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[idempotencyID]*IdempotencyRecord
+}
+
+type idempotencyID struct {
+	scope string
+	key   string
+}
+
+func NewInMemoryIdempotencyStore() *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{
+		records: make(map[idempotencyID]*IdempotencyRecord),
+	}
+}
+
+func (s *InMemoryIdempotencyStore) Begin(_ context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyID{scope: record.Scope, key: record.Key}
+	if existing, ok := s.records[id]; ok && time.Now().Before(existing.ExpiresAt) {
+		c := *existing
+		return &c, nil
+	}
+
+	c := *record
+	c.Completed = false
+	s.records[id] = &c
+	return nil, nil
+}
+
+func (s *InMemoryIdempotencyStore) Complete(_ context.Context, record *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := *record
+	c.Completed = true
+	s.records[idempotencyID{scope: record.Scope, key: record.Key}] = &c
+	return nil
+}
+
+func (s *InMemoryIdempotencyStore) Release(_ context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyID{scope: scope, key: key}
+	if existing, ok := s.records[id]; ok && !existing.Completed {
+		delete(s.records, id)
+	}
+	return nil
+}
+
+func (s *InMemoryIdempotencyStore) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, record := range s.records {
+		if !now.Before(record.ExpiresAt) {
+			delete(s.records, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// idempotencyRecorder пропускает ответ обработчика клиенту и одновременно запоминает его
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *idempotencyRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// requestFingerprint хеширует метод, путь и тело запроса.
+// JSON-тело предварительно компактизируется, чтобы различия в пробелах не считались другим запросом.
+func requestFingerprint(r *http.Request, body []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err == nil {
+		body = compact.Bytes()
+	}
+
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// IdempotencyScopeFunc определяет пользователя, в пределах которого уникален ключ идемпотентности.
+// Пустой scope означает, что пользователя определить не удалось.
+type IdempotencyScopeFunc func(r *http.Request, body []byte) (string, error)
+
+// requestUserScope — пользователь из заголовка X-User-UUID, а при его отсутствии — из поля user_uuid тела
+func requestUserScope(r *http.Request, body []byte) string {
+	if user := r.Header.Get(userUUIDHeader); user != "" {
+		return user
+	}
+	var payload struct {
+		UserUUID string `json:"user_uuid"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		return payload.UserUUID
+	}
+	return ""
+}
+
+// idempotencyScope определяет область ключа идемпотентности для запросов к server.
+// Для операций над существующим заказом (оплата, возврат) это владелец заказа: пользователя
+// в их теле нет, а без области ключи разных пользователей совпадали бы.
+// При создании заказа область задает requestUserScope.
+func (h *OrderHandler) idempotencyScope(server *orderv1.Server) IdempotencyScopeFunc {
+	return func(r *http.Request, body []byte) (string, error) {
+		route, ok := server.FindPath(r.Method, r.URL)
+		if !ok || len(route.Args()) == 0 {
+			return requestUserScope(r, body), nil
+		}
+
+		// Единственный параметр пути идемпотентных операций — order_uuid
+		order, err := h.storage.GetOrder(r.Context(), route.Args()[0])
+		if err != nil {
+			return "", err
+		}
+		return order.UserUUID, nil
+	}
+}
+
+// IdempotencyMiddleware обрабатывает заголовок Idempotency-Key.
+// Первый ответ (кроме 5xx) сохраняется на ttl и воспроизводится при повторе запроса с тем же ключом;
+// повтор с другим телом отклоняется 422, а повтор во время выполнения первого запроса — 409.
+// Ключ уникален в пределах пользователя, которого определяет scopeFunc; запрос, для которого
+// пользователь не определен, отклоняется 400.
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration, scopeFunc IdempotencyScopeFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				writeError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentRequestBody+1))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "failed to read request body")
+				return
+			}
+			if len(body) > maxIdempotentRequestBody {
+				writeError(w, http.StatusRequestEntityTooLarge, "request body is too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope, err := scopeFunc(r, body)
+			switch {
+			case errors.Is(err, ErrOrderNotFound):
+				// Обработчик ответит 404, сохранять нечего
+				next.ServeHTTP(w, r)
+				return
+			case err != nil:
+				log.Printf("error resolving idempotency scope: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal Server Error")
+				return
+			case scope == "":
+				writeError(w, http.StatusBadRequest,
+					"Idempotency-Key requires the X-User-UUID header or user_uuid in the request body")
+				return
+			}
+
+			record := &IdempotencyRecord{
+				Scope:       scope,
+				Key:         key,
+				Fingerprint: requestFingerprint(r, body),
+				ExpiresAt:   time.Now().Add(ttl),
+			}
+
+			existing, err := store.Begin(r.Context(), record)
+			if err != nil {
+				log.Printf("error reserving idempotency key: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+			if existing != nil {
+				replayIdempotentResponse(w, existing, record.Fingerprint)
+				return
+			}
+
+			// Сохранение результата не должно прерываться отменой контекста запроса
+			storeCtx := context.WithoutCancel(r.Context())
+			recorder := &idempotencyRecorder{ResponseWriter: w}
+			defer func() {
+				if p := recover(); p != nil {
+					if err := store.Release(storeCtx, record.Scope, record.Key); err != nil {
+						log.Printf("error releasing idempotency key: %v", err)
+					}
+					panic(p)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+				if err := store.Release(storeCtx, record.Scope, record.Key); err != nil {
+					log.Printf("error releasing idempotency key: %v", err)
+				}
+				return
+			}
+
+			record.StatusCode = recorder.status
+			record.Header = recorder.header
+			record.Body = recorder.body.Bytes()
+			if err := store.Complete(storeCtx, record); err != nil {
+				log.Printf("error saving idempotent response: %v", err)
+			}
+		})
+	}
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, existing *IdempotencyRecord, fingerprint string) {
+	switch {
+	case existing.Fingerprint != fingerprint:
+		writeError(w, http.StatusUnprocessableEntity,
+			"Idempotency-Key was already used with a different request")
+	case !existing.Completed:
+		writeError(w, http.StatusConflict,
+			"a request with this Idempotency-Key is still being processed")
+	default:
+		for name, values := range existing.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(idempotencyReplayedHeader, "true")
+		w.WriteHeader(existing.StatusCode)
+		if _, err := w.Write(existing.Body); err != nil {
+			log.Printf("error writing replayed response: %v", err)
+		}
+	}
+}
+
+// runIdempotencyCleanup периодически удаляет истекшие ключи, пока не отменен ctx
+func runIdempotencyCleanup(ctx context.Context, store IdempotencyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := store.DeleteExpired(ctx, now)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("error deleting expired idempotency keys: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("deleted %d expired idempotency keys", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresIdempotencyStore хранит ключи идемпотентности в той же базе, что и заказы
+type PostgresIdempotencyStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresIdempotencyStore(pool *pgxpool.Pool) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{pool: pool}
+}
+
+// Begin вставляет незавершенную запись; истекшая запись с тем же ключом перезаписывается.
+// Если ключ занят, возвращается существующая запись. Между попыткой вставки и чтением
+// запись может успеть истечь и удалиться, поэтому операция повторяется несколько раз.
+func (s *PostgresIdempotencyStore) Begin(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		tag, err := s.pool.Exec(ctx, `
+			INSERT INTO idempotency_keys (scope, key, fingerprint, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (scope, key) DO UPDATE
+			SET fingerprint = EXCLUDED.fingerprint, completed = false, status_code = NULL,
+			    headers = NULL, body = NULL, created_at = now(), expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= now()`,
+			record.Scope, record.Key, record.Fingerprint, record.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 1 {
+			return nil, nil
+		}
+
+		existing := IdempotencyRecord{Scope: record.Scope, Key: record.Key}
+		var statusCode *int
+		err = s.pool.QueryRow(ctx, `
+			SELECT fingerprint, completed, status_code, headers, body, expires_at
+			FROM idempotency_keys
+			WHERE scope = $1 AND key = $2`,
+			record.Scope, record.Key,
+		).Scan(&existing.Fingerprint, &existing.Completed, &statusCode, &existing.Header, &existing.Body, &existing.ExpiresAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if statusCode != nil {
+			existing.StatusCode = *statusCode
+		}
+		return &existing, nil
+	}
+	return nil, fmt.Errorf("failed to reserve idempotency key %q", record.Key)
+}
+
+func (s *PostgresIdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET completed = true, status_code = $3, headers = $4, body = $5
+		WHERE scope = $1 AND key = $2`,
+		record.Scope, record.Key, record.StatusCode, record.Header, record.Body)
+	return err
+}
+
+func (s *PostgresIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	_, err := s.pool.Exec(ctx,
+		"DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND NOT completed",
+		scope, key)
+	return err
+}
+
+func (s *PostgresIdempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
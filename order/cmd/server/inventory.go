@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+)
+
+// reservationItems превращает позиции заказа в позиции резерва на складе
+func reservationItems(items []OrderItem) []*inventoryv1.ReservationItem {
+	result := make([]*inventoryv1.ReservationItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, &inventoryv1.ReservationItem{
+			PartUuid: item.PartUUID,
+			Quantity: item.Quantity,
+		})
+	}
+	return result
+}
+
+// inventoryPageSize — размер страницы ListParts при загрузке деталей заказа, максимальный для Inventory Service
+const inventoryPageSize = 100
+
+// partPrices возвращает текущие цены деталей по UUID; отсутствующих в каталоге деталей в результате нет.
+// ListParts отдает детали страницами, поэтому для заказа с большим числом позиций выполняется несколько запросов.
+func (h *OrderHandler) partPrices(ctx context.Context, partUUIDs []string) (map[string]float64, error) {
+	prices := make(map[string]float64, len(partUUIDs))
+	req := &inventoryv1.ListPartsRequest{
+		Filter:   &inventoryv1.PartsFilter{Uuids: partUUIDs},
+		PageSize: inventoryPageSize,
+	}
+	for {
+		resp, err := h.inventoryClient.ListParts(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range resp.GetParts() {
+			prices[part.GetUuid()] = part.GetPrice()
+		}
+		if resp.GetNextPageToken() == "" {
+			return prices, nil
+		}
+		req.PageToken = resp.GetNextPageToken()
+	}
+}
+
+// insufficientStockError строит ответ 409 по ошибке ReserveParts.
+// Возвращает nil, если ошибка не связана с недостатком остатка.
+func insufficientStockError(err error) *orderv1.InsufficientStockError {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.FailedPrecondition {
+		return nil
+	}
+
+	for _, detail := range st.Details() {
+		insufficient, ok := detail.(*inventoryv1.InsufficientStock)
+		if !ok {
+			continue
+		}
+		shortages := make([]orderv1.InsufficientStockErrorShortagesItem, 0, len(insufficient.GetShortages()))
+		for _, shortage := range insufficient.GetShortages() {
+			shortages = append(shortages, orderv1.InsufficientStockErrorShortagesItem{
+				PartUUID:  parseUUID(shortage.GetPartUuid()),
+				Requested: shortage.GetRequested(),
+				Available: shortage.GetAvailable(),
+			})
+		}
+		return &orderv1.InsufficientStockError{
+			Code:      orderv1.NewOptInt(http.StatusConflict),
+			Message:   orderv1.NewOptString("insufficient stock"),
+			Shortages: shortages,
+		}
+	}
+	return nil
+}
+
+// releaseReservation возвращает зарезервированные под заказ детали на склад.
+// Ошибка только логируется: заказ уже переведен в итоговый статус.
+func (h *OrderHandler) releaseReservation(ctx context.Context, orderUUID string) {
+	if _, err := h.inventoryClient.ReleaseReservation(ctx, &inventoryv1.ReleaseReservationRequest{
+		OrderUuid: orderUUID,
+	}); err != nil {
+		log.Printf("error releasing reservation for order %s: %v", orderUUID, err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+)
+
+// OrderItem represents an order line item.
+// UnitPrice фиксируется при создании заказа и не меняется вместе с ценой детали на складе.
+type OrderItem struct {
+	PartUUID  string
+	Quantity  int64
+	UnitPrice float64
+}
+
+// Total возвращает стоимость позиции
+func (i OrderItem) Total() float64 {
+	return float64(i.Quantity) * i.UnitPrice
+}
+
+// mergeOrderItems объединяет позиции из items и устаревшего поля part_uuids
+// (каждый UUID — одна штука) в список с уникальными part_uuid.
+// Формат UUID и положительное количество проверяются контрактом OpenAPI.
+func mergeOrderItems(requested []orderv1.OrderItemRequest, partUUIDs []uuid.UUID) ([]OrderItem, error) {
+	if len(requested) == 0 && len(partUUIDs) == 0 {
+		return nil, errors.New("items or part_uuids is required and cannot be empty")
+	}
+
+	index := make(map[string]int, len(requested)+len(partUUIDs))
+	items := make([]OrderItem, 0, len(requested)+len(partUUIDs))
+	add := func(partUUID string, quantity int64) {
+		if i, ok := index[partUUID]; ok {
+			items[i].Quantity += quantity
+			return
+		}
+		index[partUUID] = len(items)
+		items = append(items, OrderItem{PartUUID: partUUID, Quantity: quantity})
+	}
+
+	for _, item := range requested {
+		add(item.PartUUID.String(), item.Quantity)
+	}
+	for _, partUUID := range partUUIDs {
+		add(partUUID.String(), 1)
+	}
+	return items, nil
+}
+
+// itemPartUUIDs возвращает UUID деталей заказа в порядке позиций
+func itemPartUUIDs(items []OrderItem) []string {
+	uuids := make([]string, 0, len(items))
+	for _, item := range items {
+		uuids = append(uuids, item.PartUUID)
+	}
+	return uuids
+}
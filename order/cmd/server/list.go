@@ -0,0 +1,205 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+)
+
+// defaultListLimit совпадает со значением по умолчанию параметра limit в order.openapi.yaml
+const defaultListLimit = 20
+
+// OrderSortField задает поле сортировки списка заказов
+type OrderSortField string
+
+const (
+	OrderSortByCreatedAt  OrderSortField = "created_at"
+	OrderSortByTotalPrice OrderSortField = "total_price"
+)
+
+// OrderFilter описывает выборку заказов для ListOrders.
+// Пустые поля не ограничивают выборку; CreatedFrom включается в интервал, CreatedTo — нет.
+type OrderFilter struct {
+	UserUUID    string
+	Statuses    []OrderStatus
+	PartUUID    string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+
+	SortBy   OrderSortField
+	SortDesc bool
+	Limit    int
+	After    *OrderCursor
+}
+
+// OrderPage — страница результата ListOrders
+type OrderPage struct {
+	Orders     []*Order
+	NextCursor *OrderCursor
+}
+
+// OrderCursor указывает на последний заказ предыдущей страницы.
+// Пагинация ключевая: по (значение поля сортировки, order_uuid), поэтому страницы
+// остаются стабильными при вставке новых заказов.
+type OrderCursor struct {
+	SortBy     OrderSortField `json:"s"`
+	SortDesc   bool           `json:"d"`
+	CreatedAt  time.Time      `json:"c"`
+	TotalPrice float64        `json:"p"`
+	OrderUUID  string         `json:"u"`
+}
+
+func cursorFor(order *Order, filter OrderFilter) *OrderCursor {
+	return &OrderCursor{
+		SortBy:     filter.SortBy,
+		SortDesc:   filter.SortDesc,
+		CreatedAt:  order.CreatedAt,
+		TotalPrice: order.TotalPrice,
+		OrderUUID:  order.OrderUUID,
+	}
+}
+
+func (c *OrderCursor) Encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeOrderCursor(s string) (*OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var c OrderCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.OrderUUID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+// less сравнивает заказы в порядке сортировки фильтра
+func (f OrderFilter) less(a, b *Order) bool {
+	var result int
+	switch f.SortBy {
+	case OrderSortByTotalPrice:
+		result = cmp.Compare(a.TotalPrice, b.TotalPrice)
+	default:
+		result = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if result == 0 {
+		result = cmp.Compare(a.OrderUUID, b.OrderUUID)
+	}
+	if f.SortDesc {
+		return result > 0
+	}
+	return result < 0
+}
+
+// afterCursor сообщает, идет ли заказ строго после курсора в порядке сортировки
+func (f OrderFilter) afterCursor(order *Order) bool {
+	if f.After == nil {
+		return true
+	}
+	return f.less(&Order{
+		OrderUUID:  f.After.OrderUUID,
+		CreatedAt:  f.After.CreatedAt,
+		TotalPrice: f.After.TotalPrice,
+	}, order)
+}
+
+func (f OrderFilter) matches(order *Order) bool {
+	if f.UserUUID != "" && order.UserUUID != f.UserUUID {
+		return false
+	}
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, order.Status) {
+		return false
+	}
+	if f.PartUUID != "" && !slices.ContainsFunc(order.Items, func(item OrderItem) bool {
+		return item.PartUUID == f.PartUUID
+	}) {
+		return false
+	}
+	if f.CreatedFrom != nil && order.CreatedAt.Before(*f.CreatedFrom) {
+		return false
+	}
+	if f.CreatedTo != nil && !order.CreatedAt.Before(*f.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+// orderFilterFromParams строит фильтр из параметров GET /api/v1/orders.
+// Форматы, перечисления и границы limit уже проверены контрактом; здесь проверяется только курсор.
+func orderFilterFromParams(params orderv1.ListOrdersParams) (OrderFilter, error) {
+	filter := OrderFilter{
+		SortBy:   OrderSortField(params.SortBy.Or(orderv1.SortByQueryCreatedAt)),
+		SortDesc: params.SortOrder.Or(orderv1.SortOrderQueryDesc) == orderv1.SortOrderQueryDesc,
+		Limit:    params.Limit.Or(defaultListLimit),
+	}
+
+	if userUUID, ok := params.UserUUID.Get(); ok {
+		filter.UserUUID = userUUID.String()
+	}
+	if partUUID, ok := params.PartUUID.Get(); ok {
+		filter.PartUUID = partUUID.String()
+	}
+	for _, status := range params.Status {
+		filter.Statuses = append(filter.Statuses, OrderStatus(status))
+	}
+	if createdFrom, ok := params.CreatedFrom.Get(); ok {
+		filter.CreatedFrom = &createdFrom
+	}
+	if createdTo, ok := params.CreatedTo.Get(); ok {
+		filter.CreatedTo = &createdTo
+	}
+
+	if value, ok := params.Cursor.Get(); ok {
+		cursor, err := decodeOrderCursor(value)
+		if err != nil {
+			return filter, err
+		}
+		if cursor.SortBy != filter.SortBy || cursor.SortDesc != filter.SortDesc {
+			return filter, errors.New("cursor does not match sort_by and sort_order")
+		}
+		filter.After = cursor
+	}
+
+	return filter, nil
+}
+
+// ListOrders returns orders matching query filters
+func (h *OrderHandler) ListOrders(ctx context.Context, params orderv1.ListOrdersParams) (orderv1.ListOrdersRes, error) {
+	filter, err := orderFilterFromParams(params)
+	if err != nil {
+		return badRequestError(err.Error()), nil
+	}
+
+	page, err := h.storage.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &orderv1.ListOrdersResponse{
+		Orders: make([]orderv1.OrderDTO, 0, len(page.Orders)),
+	}
+	for _, order := range page.Orders {
+		response.Orders = append(response.Orders, toOrderDTO(order))
+	}
+	if page.NextCursor != nil {
+		cursor, err := page.NextCursor.Encode()
+		if err != nil {
+			return nil, fmt.Errorf("encode cursor: %w", err)
+		}
+		response.NextCursor = orderv1.NewOptString(cursor)
+	}
+	return response, nil
+}
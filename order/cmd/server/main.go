@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -10,7 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -20,12 +19,16 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
 	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
 	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
 )
 
 const (
 	httpPort = "8080"
+
+	storageDriverMemory   = "memory"
+	storageDriverPostgres = "postgres"
 )
 
 // OrderStatus represents order status
@@ -33,72 +36,44 @@ type OrderStatus string
 
 const (
 	OrderStatusPendingPayment OrderStatus = "PENDING_PAYMENT"
-	OrderStatusPaid           OrderStatus = "PAID"
-	OrderStatusCancelled      OrderStatus = "CANCELLED"
+	// Асинхронный платеж ждет подтверждения провайдера
+	OrderStatusPaymentPending OrderStatus = "PAYMENT_PENDING"
+	// Сумма заказа заблокирована и будет списана при выдаче
+	OrderStatusAuthorized OrderStatus = "AUTHORIZED"
+	OrderStatusPaid       OrderStatus = "PAID"
+	OrderStatusCancelled  OrderStatus = "CANCELLED"
+	// Часть суммы оплаченного заказа возвращена
+	OrderStatusPartiallyRefunded OrderStatus = "PARTIALLY_REFUNDED"
+	// Вся сумма возвращена, детали вернулись на склад
+	OrderStatusRefunded OrderStatus = "REFUNDED"
 )
 
 // Order represents an order
 type Order struct {
-	OrderUUID       string      `json:"order_uuid"`
-	UserUUID        string      `json:"user_uuid"`
-	PartUUIDs       []string    `json:"part_uuids"`
-	TotalPrice      float64     `json:"total_price"`
-	TransactionUUID *string     `json:"transaction_uuid,omitempty"`
-	PaymentMethod   *string     `json:"payment_method,omitempty"`
-	Status          OrderStatus `json:"status"`
-}
-
-// OrderStorage is thread-safe order storage
-type OrderStorage struct {
-	mu     sync.RWMutex
-	orders map[string]*Order
-}
-
-func NewOrderStorage() *OrderStorage {
-	return &OrderStorage{
-		orders: make(map[string]*Order),
-	}
-}
-
-func (s *OrderStorage) GetOrder(uuid string) (*Order, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	order, ok := s.orders[uuid]
-	if !ok {
-		return nil, errors.New("not found")
-	}
-	return order, nil
-}
-
-func (s *OrderStorage) CreateOrder(order *Order) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.orders[order.OrderUUID] = order
-}
-
-func (s *OrderStorage) UpdateOrder(uuid string, updateFunc func(*Order)) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	order, ok := s.orders[uuid]
-	if !ok {
-		return errors.New("not found")
-	}
-	updateFunc(order)
-	return nil
+	OrderUUID       string
+	UserUUID        string
+	PartUUIDs       []string
+	Items           []OrderItem
+	TotalPrice      float64
+	RefundedAmount  float64
+	TransactionUUID *string
+	PaymentMethod   *string
+	Status          OrderStatus
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 // OrderHandler handles order requests
 type OrderHandler struct {
-	storage         *OrderStorage
+	storage         OrderStorage
 	inventoryClient inventoryv1.InventoryServiceClient
 	paymentClient   paymentv1.PaymentServiceClient
+	webhooks        *WebhookDispatcher
 	inventoryConn   *grpc.ClientConn
 	paymentConn     *grpc.ClientConn
 }
 
-func NewOrderHandler() (*OrderHandler, error) {
+func NewOrderHandler(storage OrderStorage, webhooks *WebhookDispatcher) (*OrderHandler, error) {
 	// Connect to Inventory Service
 	inventoryConn, err := grpc.NewClient(
 		"localhost:50051",
@@ -121,9 +96,10 @@ func NewOrderHandler() (*OrderHandler, error) {
 	}
 
 	return &OrderHandler{
-		storage:         NewOrderStorage(),
+		storage:         storage,
 		inventoryClient: inventoryv1.NewInventoryServiceClient(inventoryConn),
 		paymentClient:   paymentv1.NewPaymentServiceClient(paymentConn),
+		webhooks:        webhooks,
 		inventoryConn:   inventoryConn,
 		paymentConn:     paymentConn,
 	}, nil
@@ -143,223 +119,352 @@ func (h *OrderHandler) Close() {
 }
 
 // PostOrders creates a new order
-func (h *OrderHandler) PostOrders(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		UserUUID  string   `json:"user_uuid"`
-		PartUUIDs []string `json:"part_uuids"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}); encodeErr != nil {
-			log.Printf("error encoding error response: %v", encodeErr)
-		}
-		return
-	}
-
-	// Валидация входных данных
-	if req.UserUUID == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": "user_uuid is required"}); encodeErr != nil {
-			log.Printf("error encoding error response: %v", encodeErr)
-		}
-		return
-	}
-
-	if len(req.PartUUIDs) == 0 {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": "part_uuids is required and cannot be empty"}); encodeErr != nil {
-			log.Printf("error encoding error response: %v", encodeErr)
-		}
-		return
+func (h *OrderHandler) PostOrders(ctx context.Context, req *orderv1.CreateOrderRequest, _ orderv1.PostOrdersParams) (orderv1.PostOrdersRes, error) {
+	items, err := mergeOrderItems(req.GetItems(), req.GetPartUuids())
+	if err != nil {
+		return badRequestError(err.Error()), nil
 	}
 
 	// Получаем детали из Inventory Service
-	resp, err := h.inventoryClient.ListParts(r.Context(), &inventoryv1.ListPartsRequest{
-		Filter: &inventoryv1.PartsFilter{
-			Uuids: req.PartUUIDs,
-		},
-	})
+	prices, err := h.partPrices(ctx, itemPartUUIDs(items))
 	if err != nil {
 		log.Printf("error calling InventoryService: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadGateway)
-		if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": "Bad Gateway"}); encodeErr != nil {
-			log.Printf("error encoding error response: %v", encodeErr)
-		}
-		return
-	}
-
-	// Проверяем, что все детали найдены
-	partsFound := make(map[string]bool)
-	for _, part := range resp.GetParts() {
-		partsFound[part.GetUuid()] = true
+		return badGatewayError(), nil
 	}
 
-	for _, uuid := range req.PartUUIDs {
-		if !partsFound[uuid] {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			if encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("part %s not found", uuid)}); encodeErr != nil {
-				log.Printf("error encoding error response: %v", encodeErr)
-			}
-			return
-		}
-	}
+	// Проверяем, что все детали найдены, и фиксируем цену на момент заказа
 
-	// Подсчитываем total_price
 	var totalPrice float64
-	for _, part := range resp.GetParts() {
-		totalPrice += part.GetPrice()
+	for i := range items {
+		price, ok := prices[items[i].PartUUID]
+		if !ok {
+			return badRequestError(fmt.Sprintf("part %s not found", items[i].PartUUID)), nil
+		}
+		items[i].UnitPrice = price
+		totalPrice += items[i].Total()
 	}
 
-	// Создаем заказ
+	// Создаем заказ. Время усекается до микросекунд — точности timestamptz в PostgreSQL,
+	// чтобы курсоры пагинации совпадали для обоих хранилищ.
+	now := time.Now().UTC().Truncate(time.Microsecond)
 	order := &Order{
 		OrderUUID:  uuid.New().String(),
-		UserUUID:   req.UserUUID,
-		PartUUIDs:  req.PartUUIDs,
+		UserUUID:   req.GetUserUUID().String(),
+		PartUUIDs:  itemPartUUIDs(items),
+		Items:      items,
 		TotalPrice: totalPrice,
 		Status:     OrderStatusPendingPayment,
+		CreatedAt:  now,
+		UpdatedAt:  now,
 	}
 
-	h.storage.CreateOrder(order)
+	// Резервируем детали на складе под заказ
+	_, err = h.inventoryClient.ReserveParts(ctx, &inventoryv1.ReservePartsRequest{
+		OrderUuid: order.OrderUUID,
+		Items:     reservationItems(order.Items),
+	})
+	if err != nil {
+		if insufficient := insufficientStockError(err); insufficient != nil {
+			return insufficient, nil
+		}
+		log.Printf("error reserving parts: %v", err)
+		return badGatewayError(), nil
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{
-		"uuid":        order.OrderUUID,
-		"total_price": order.TotalPrice,
-	}); err != nil {
-		log.Printf("error encoding response: %v", err)
+	if err := h.storage.CreateOrder(ctx, order); err != nil {
+		h.releaseReservation(context.WithoutCancel(ctx), order.OrderUUID)
+		return nil, fmt.Errorf("create order: %w", err)
 	}
+	h.webhooks.Publish(ctx, WebhookEventOrderCreated, order)
+
+	return &orderv1.CreateOrderResponse{
+		OrderUUID:  parseUUID(order.OrderUUID),
+		TotalPrice: order.TotalPrice,
+	}, nil
 }
 
 // GetOrders gets order by UUID
-func (h *OrderHandler) GetOrders(w http.ResponseWriter, _ *http.Request, orderUUID string) {
-	order, err := h.storage.GetOrder(orderUUID)
+func (h *OrderHandler) GetOrders(ctx context.Context, params orderv1.GetOrdersParams) (orderv1.GetOrdersRes, error) {
+	order, err := h.storage.GetOrder(ctx, params.OrderUUID.String())
+	if errors.Is(err, ErrOrderNotFound) {
+		return notFoundError(), nil
+	}
 	if err != nil {
-		http.Error(w, "Order not found", http.StatusNotFound)
-		return
+		return nil, err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(order); err != nil {
-		log.Printf("error encoding response: %v", err)
-	}
+	dto := toOrderDTO(order)
+	return &dto, nil
 }
 
-// PostOrdersPay pays the order
-func (h *OrderHandler) PostOrdersPay(w http.ResponseWriter, r *http.Request, orderUUID string) {
-	var req struct {
-		PaymentMethod string `json:"payment_method"`
-	}
+// paymentMethods сопоставляет способы оплаты API и Payment Service
+var paymentMethods = map[orderv1.PaymentMethod]paymentv1.PaymentMethod{
+	orderv1.PaymentMethodUNKNOWN:       paymentv1.PaymentMethod_PAYMENT_METHOD_UNSPECIFIED,
+	orderv1.PaymentMethodCARD:          paymentv1.PaymentMethod_PAYMENT_METHOD_CARD,
+	orderv1.PaymentMethodSBP:           paymentv1.PaymentMethod_PAYMENT_METHOD_SBP,
+	orderv1.PaymentMethodCREDITCARD:    paymentv1.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD,
+	orderv1.PaymentMethodINVESTORMONEY: paymentv1.PaymentMethod_PAYMENT_METHOD_INVESTOR_MONEY,
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+// PostOrdersPay pays the order
+func (h *OrderHandler) PostOrdersPay(ctx context.Context, req *orderv1.PayOrderRequest, params orderv1.PostOrdersPayParams) (orderv1.PostOrdersPayRes, error) {
+	orderUUID := params.OrderUUID.String()
+	order, err := h.storage.GetOrder(ctx, orderUUID)
+	if errors.Is(err, ErrOrderNotFound) {
+		return notFoundError(), nil
 	}
-
-	order, err := h.storage.GetOrder(orderUUID)
 	if err != nil {
-		http.Error(w, "Order not found", http.StatusNotFound)
-		return
+		return nil, err
 	}
 
-	// Вызываем Payment Service
-	paymentMethod := paymentv1.PaymentMethod_PAYMENT_METHOD_UNSPECIFIED
-	switch req.PaymentMethod {
-	case "CARD", "PAYMENT_METHOD_CARD":
-		paymentMethod = paymentv1.PaymentMethod_PAYMENT_METHOD_CARD
-	case "SBP", "PAYMENT_METHOD_SBP":
-		paymentMethod = paymentv1.PaymentMethod_PAYMENT_METHOD_SBP
-	case "CREDIT_CARD", "PAYMENT_METHOD_CREDIT_CARD":
-		paymentMethod = paymentv1.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD
-	case "INVESTOR_MONEY", "PAYMENT_METHOD_INVESTOR_MONEY":
-		paymentMethod = paymentv1.PaymentMethod_PAYMENT_METHOD_INVESTOR_MONEY
+	// Не блокируем деньги за заказ, который уже нельзя оплатить. В PAYMENT_PENDING можно перейти
+	// только из PENDING_PAYMENT, поэтому повторная оплата ожидающего заказа тоже получит 409.
+	var transitionErr *TransitionError
+	if err := checkTransition(order, OrderStatusPaymentPending); errors.As(err, &transitionErr) {
+		return conflictError(transitionErr), nil
 	}
 
-	resp, err := h.paymentClient.PayOrder(r.Context(), &paymentv1.PayOrderRequest{
+	// Авторизуем платеж: деньги списываются только при выдаче заказа
+	resp, err := h.paymentClient.AuthorizePayment(ctx, &paymentv1.AuthorizePaymentRequest{
 		OrderUuid:     orderUUID,
 		UserUuid:      order.UserUUID,
-		PaymentMethod: paymentMethod,
+		PaymentMethod: paymentMethods[req.GetPaymentMethod()],
+		Amount:        toMoney(order.TotalPrice),
+		Currency:      orderCurrency,
 	})
 	if err != nil {
 		log.Printf("error calling PaymentService: %v", err)
-		http.Error(w, "Bad Gateway", http.StatusBadGateway)
-		return
+		if badRequest, ok := paymentBadRequest(err); ok {
+			return badRequest, nil
+		}
+		if declined, ok := paymentDeclined(err); ok {
+			return declined, nil
+		}
+		return badGatewayError(), nil
+	}
+
+	// Асинхронный платеж (СБП, 3-D Secure) ждет callback провайдера: заказ перейдет
+	// в AUTHORIZED или вернется в PENDING_PAYMENT по уведомлению Payment Service
+	next := OrderStatusAuthorized
+	if resp.GetTransaction().GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING {
+		next = OrderStatusPaymentPending
 	}
 
-	// Обновляем заказ
+	// Обновляем заказ. Если за время авторизации заказ успели отменить, переход
+	// PENDING_PAYMENT -> AUTHORIZED не выполнится: снимаем блокировку, клиент получит 409
 	transactionUUID := resp.GetTransactionUuid()
-	if err := h.storage.UpdateOrder(orderUUID, func(o *Order) {
-		o.Status = OrderStatusPaid
+	method := string(req.GetPaymentMethod())
+	err = h.transition(ctx, orderUUID, order.Status, next, func(o *Order) {
 		o.TransactionUUID = &transactionUUID
-		method := req.PaymentMethod
 		o.PaymentMethod = &method
-	}); err != nil {
-		log.Printf("error updating order: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	})
+	if err != nil {
+		h.voidUnlinkedAuthorization(ctx, orderUUID, transactionUUID)
+		if errors.As(err, &transitionErr) {
+			return conflictError(transitionErr), nil
+		}
+		return nil, err
+	}
+
+	return &orderv1.PayOrderResponse{
+		TransactionUUID: parseUUID(transactionUUID),
+		Status:          orderv1.NewOptOrderStatus(orderv1.OrderStatus(next)),
+	}, nil
+}
+
+// voidUnlinkedAuthorization снимает блокировку суммы, которую PostOrdersPay не смог записать в заказ.
+// Конкурентная оплата того же заказа получает от PaymentService ту же действующую транзакцию,
+// поэтому авторизация, на которую заказ уже ссылается, принадлежит выигравшему запросу и не аннулируется.
+// Если заказ не удалось перечитать, блокировка снимается: иначе сумма осталась бы заблокированной до истечения срока.
+func (h *OrderHandler) voidUnlinkedAuthorization(ctx context.Context, orderUUID, transactionUUID string) {
+	// Отмена запроса клиента не должна помешать снять блокировку
+	ctx = context.WithoutCancel(ctx)
+
+	order, err := h.storage.GetOrder(ctx, orderUUID)
+	switch {
+	case err != nil:
+		log.Printf("error reading order %s after failed payment update: %v", orderUUID, err)
+	case order.TransactionUUID != nil && *order.TransactionUUID == transactionUUID:
 		return
+	default:
+		log.Printf("order %s changed to %s during payment, voiding authorization %s",
+			orderUUID, order.Status, transactionUUID)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{
-		"transaction_uuid": transactionUUID,
+	if _, err := h.paymentClient.VoidAuthorization(ctx, &paymentv1.VoidAuthorizationRequest{
+		TransactionUuid: transactionUUID,
+		Reason:          "order changed during payment",
 	}); err != nil {
-		log.Printf("error encoding response: %v", err)
+		log.Printf("error voiding authorization %s: %v", transactionUUID, err)
 	}
 }
 
 // PostOrdersCancel cancels the order
-func (h *OrderHandler) PostOrdersCancel(w http.ResponseWriter, _ *http.Request, orderUUID string) {
-	order, err := h.storage.GetOrder(orderUUID)
+func (h *OrderHandler) PostOrdersCancel(ctx context.Context, params orderv1.PostOrdersCancelParams) (orderv1.PostOrdersCancelRes, error) {
+	orderUUID := params.OrderUUID.String()
+	order, err := h.storage.GetOrder(ctx, orderUUID)
+	if errors.Is(err, ErrOrderNotFound) {
+		return notFoundError(), nil
+	}
 	if err != nil {
-		http.Error(w, "Order not found", http.StatusNotFound)
-		return
+		return nil, err
+	}
+
+	var transitionErr *TransitionError
+
+	// Оплаченный заказ отменяется возвратом оставшейся суммы
+	if order.Status == OrderStatusPaid || order.Status == OrderStatusPartiallyRefunded {
+		amount := order.refundableAmount()
+		_, err := h.refundOrder(ctx, order, amount, "order cancelled", refundKey(order, amount, ""))
+		switch {
+		case errors.As(err, &transitionErr):
+			return conflictError(transitionErr), nil
+		case errors.Is(err, errRefundFailed):
+			return badGatewayError(), nil
+		case err != nil:
+			return nil, err
+		}
+		// Заказ остается в статусе REFUNDED, поэтому событие отмены не публикуется переходом
+		if refunded, err := h.storage.GetOrder(ctx, orderUUID); err != nil {
+			log.Printf("error publishing %s event for order %s: %v", WebhookEventOrderCancelled, orderUUID, err)
+		} else {
+			h.webhooks.Publish(ctx, WebhookEventOrderCancelled, refunded)
+		}
+		return &orderv1.PostOrdersCancelNoContent{}, nil
 	}
 
-	if order.Status == OrderStatusPaid {
-		http.Error(w, "Order already paid and cannot be cancelled", http.StatusConflict)
-		return
+	if err := checkTransition(order, OrderStatusCancelled); errors.As(err, &transitionErr) {
+		return conflictError(transitionErr), nil
 	}
 
-	err = h.storage.UpdateOrder(orderUUID, func(o *Order) {
-		o.Status = OrderStatusCancelled
-	})
+	// У авторизованного заказа и заказа, ожидающего подтверждения платежа, сначала снимаем блокировку суммы
+	if order.Status == OrderStatusAuthorized || order.Status == OrderStatusPaymentPending {
+		if err := h.voidAuthorization(ctx, order, "order cancelled"); err != nil {
+			log.Printf("error calling PaymentService: %v", err)
+			return badGatewayError(), nil
+		}
+	}
+
+	err = h.transition(ctx, orderUUID, order.Status, OrderStatusCancelled, nil)
+	if errors.As(err, &transitionErr) {
+		return conflictError(transitionErr), nil
+	}
 	if err != nil {
-		http.Error(w, "Order not found", http.StatusNotFound)
-		return
+		return nil, err
+	}
+
+	h.releaseReservation(ctx, orderUUID)
+
+	return &orderv1.PostOrdersCancelNoContent{}, nil
+}
+
+// newStorage создает хранилища заказов, ключей идемпотентности и подписок на события
+// по переменным окружения: ORDER_STORAGE_DRIVER (memory | postgres, по умолчанию memory)
+// и ORDER_POSTGRES_DSN. Все хранилища используют один и тот же бэкенд.
+func newStorage(ctx context.Context) (OrderStorage, IdempotencyStore, WebhookStore, error) {
+	driver := os.Getenv("ORDER_STORAGE_DRIVER")
+	switch driver {
+	case "", storageDriverMemory:
+		return NewInMemoryOrderStorage(), NewInMemoryIdempotencyStore(), NewInMemoryWebhookStore(), nil
+	case storageDriverPostgres:
+		dsn := os.Getenv("ORDER_POSTGRES_DSN")
+		if dsn == "" {
+			return nil, nil, nil, errors.New("ORDER_POSTGRES_DSN is required for postgres storage")
+		}
+		storage, err := NewPostgresOrderStorage(ctx, dsn)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return storage, NewPostgresIdempotencyStore(storage.pool), NewPostgresWebhookStore(storage.pool), nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown storage driver %q", driver)
 	}
+}
 
-	w.WriteHeader(http.StatusNoContent)
+// durationFromEnv читает длительность в формате time.ParseDuration, возвращая fallback для пустого значения
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", name, value)
+	}
+	return d, nil
 }
 
 func main() {
-	handler, err := NewOrderHandler()
+	// Сверка заказов с транзакциями по требованию, см. runReconcileCommand
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		os.Exit(runReconcileCommand(os.Args[2:]))
+	}
+
+	idempotencyTTL, err := durationFromEnv("ORDER_IDEMPOTENCY_TTL", defaultIdempotencyTTL)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	reconcileInterval, err := durationFromEnv("ORDER_RECONCILE_INTERVAL", defaultReconcileInterval)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	reconcileWindow, err := durationFromEnv("ORDER_RECONCILE_WINDOW", defaultReconcileWindow)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	// Фоновая сверка исправляет безопасные расхождения, если не задано ORDER_RECONCILE_AUTO_HEAL=false
+	reconcileHeal := true
+	if value := os.Getenv("ORDER_RECONCILE_AUTO_HEAL"); value != "" {
+		if reconcileHeal, err = strconv.ParseBool(value); err != nil {
+			log.Fatalf("invalid configuration: ORDER_RECONCILE_AUTO_HEAL must be a boolean, got %q", value)
+		}
+	}
+	webhookBackoff, err := durationFromEnv("ORDER_WEBHOOK_RETRY_BACKOFF", defaultWebhookRetryBackoff)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	initCtx, initCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	storage, idempotencyStore, webhookStore, err := newStorage(initCtx)
+	initCancel()
 	if err != nil {
-		log.Fatalf("failed to create handler: %v", err)
+		log.Fatalf("failed to create order storage: %v", err)
+	}
+	defer storage.Close()
+
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+	go runIdempotencyCleanup(bgCtx, idempotencyStore, time.Hour)
+
+	// Доставка событий заказов подписчикам
+	webhooks := NewWebhookDispatcher(webhookStore, webhookBackoff,
+		parseWebhookAllowedHosts(os.Getenv("ORDER_WEBHOOK_ALLOWED_HOSTS")))
+	go webhooks.Run(bgCtx)
+
+	handler, err := NewOrderHandler(storage, webhooks)
+	if err != nil {
+		log.Printf("failed to create handler: %v", err)
+		return
 	}
 	defer handler.Close()
 
+	// Фоновая сверка заказов с транзакциями Payment Service
+	go handler.runReconciliation(bgCtx, reconcileInterval, reconcileWindow, reconcileHeal)
+
+	apiServer, err := orderv1.NewServer(handler, orderv1.WithErrorHandler(handleAPIError))
+	if err != nil {
+		log.Printf("failed to create API server: %v", err)
+		return
+	}
+
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(10 * time.Second))
 
-	r.Post("/api/v1/orders", handler.PostOrders)
-	r.Get("/api/v1/orders/{order_uuid}", func(w http.ResponseWriter, r *http.Request) {
-		handler.GetOrders(w, r, chi.URLParam(r, "order_uuid"))
-	})
-	r.Post("/api/v1/orders/{order_uuid}/pay", func(w http.ResponseWriter, r *http.Request) {
-		handler.PostOrdersPay(w, r, chi.URLParam(r, "order_uuid"))
-	})
-	r.Post("/api/v1/orders/{order_uuid}/cancel", func(w http.ResponseWriter, r *http.Request) {
-		handler.PostOrdersCancel(w, r, chi.URLParam(r, "order_uuid"))
-	})
+	// Маршрутизацию, разбор и валидацию запросов выполняет сервер, сгенерированный ogen из order.openapi.yaml
+	idempotency := IdempotencyMiddleware(idempotencyStore, idempotencyTTL, handler.idempotencyScope(apiServer))
+	r.Mount("/", withIdempotency(apiServer, idempotency))
 
 	server := &http.Server{
 		Addr:              net.JoinHostPort("0.0.0.0", httpPort),
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// fakePaymentClient авторизует любой платеж и запоминает аннулированные авторизации.
+// Как и PaymentService, на повторную оплату заказа с действующей авторизацией возвращает ее же.
+type fakePaymentClient struct {
+	paymentv1.PaymentServiceClient
+
+	authorizeDelay time.Duration
+
+	mu         sync.Mutex
+	active     map[string]string // order_uuid -> transaction_uuid действующей авторизации
+	authorized []string
+	voided     []string
+}
+
+func (c *fakePaymentClient) AuthorizePayment(_ context.Context, req *paymentv1.AuthorizePaymentRequest, _ ...grpc.CallOption) (*paymentv1.AuthorizePaymentResponse, error) {
+	time.Sleep(c.authorizeDelay)
+
+	c.mu.Lock()
+	transactionUUID, ok := c.active[req.GetOrderUuid()]
+	if !ok {
+		transactionUUID = uuid.NewString()
+		if c.active == nil {
+			c.active = make(map[string]string)
+		}
+		c.active[req.GetOrderUuid()] = transactionUUID
+		c.authorized = append(c.authorized, transactionUUID)
+	}
+	c.mu.Unlock()
+
+	return &paymentv1.AuthorizePaymentResponse{
+		TransactionUuid: transactionUUID,
+		Transaction: &paymentv1.Transaction{
+			TransactionUuid: transactionUUID,
+			OrderUuid:       req.GetOrderUuid(),
+			Status:          paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED,
+		},
+	}, nil
+}
+
+func (c *fakePaymentClient) VoidAuthorization(_ context.Context, req *paymentv1.VoidAuthorizationRequest, _ ...grpc.CallOption) (*paymentv1.VoidAuthorizationResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.voided = append(c.voided, req.GetTransactionUuid())
+	for orderUUID, transactionUUID := range c.active {
+		if transactionUUID == req.GetTransactionUuid() {
+			delete(c.active, orderUUID)
+		}
+	}
+	return &paymentv1.VoidAuthorizationResponse{}, nil
+}
+
+// fakeInventoryClient подтверждает снятие любого резерва
+type fakeInventoryClient struct {
+	inventoryv1.InventoryServiceClient
+}
+
+func (c *fakeInventoryClient) ReleaseReservation(context.Context, *inventoryv1.ReleaseReservationRequest, ...grpc.CallOption) (*inventoryv1.ReleaseReservationResponse, error) {
+	return &inventoryv1.ReleaseReservationResponse{}, nil
+}
+
+// barrierStorage задерживает первые readers вызовов GetOrder, пока их не сделают все,
+// чтобы каждый участник гонки прочитал заказ до того, как любой из них его изменит
+type barrierStorage struct {
+	OrderStorage
+
+	mu       sync.Mutex
+	readers  int
+	released chan struct{}
+}
+
+func newBarrierStorage(storage OrderStorage, readers int) *barrierStorage {
+	return &barrierStorage{OrderStorage: storage, readers: readers, released: make(chan struct{})}
+}
+
+func (s *barrierStorage) GetOrder(ctx context.Context, uuid string) (*Order, error) {
+	order, err := s.OrderStorage.GetOrder(ctx, uuid)
+
+	s.mu.Lock()
+	s.readers--
+	if s.readers == 0 {
+		close(s.released)
+	}
+	s.mu.Unlock()
+
+	<-s.released
+	return order, err
+}
+
+// testStorages — хранилища, на которых проверяются конкурентные сценарии обработчиков
+var testStorages = map[string]func(t *testing.T) OrderStorage{
+	"memory": func(*testing.T) OrderStorage {
+		return NewInMemoryOrderStorage()
+	},
+	"postgres": func(t *testing.T) OrderStorage {
+		return newTestPostgresStorage(t)
+	},
+}
+
+// newTestHandler создает обработчик с фиктивными клиентами PaymentService и InventoryService
+func newTestHandler(storage OrderStorage, payment *fakePaymentClient) *OrderHandler {
+	return &OrderHandler{
+		storage:         storage,
+		inventoryClient: &fakeInventoryClient{},
+		paymentClient:   payment,
+		webhooks:        NewWebhookDispatcher(NewInMemoryWebhookStore(), time.Second, nil),
+	}
+}
+
+// Оплата и отмена одного неоплаченного заказа, начатые одновременно: успешна ровно одна
+// из операций, вторая получает 409, а авторизация проигравшей оплаты аннулируется
+func TestPayAndCancelRace(t *testing.T) {
+	for name, newStorage := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			storage := newStorage(t)
+			for i := range 20 {
+				// Задержка авторизации дает отмене успеть раньше, без нее чаще выигрывает оплата
+				runPayAndCancelRace(t, storage, time.Duration(i%2)*5*time.Millisecond)
+			}
+		})
+	}
+}
+
+func runPayAndCancelRace(t *testing.T, storage OrderStorage, authorizeDelay time.Duration) {
+	t.Helper()
+	ctx := context.Background()
+
+	order := createTestOrder(t, storage)
+	payment := &fakePaymentClient{authorizeDelay: authorizeDelay}
+	h := newTestHandler(newBarrierStorage(storage, 2), payment)
+	orderUUID := uuid.MustParse(order.OrderUUID)
+
+	var (
+		wg        sync.WaitGroup
+		payRes    orderv1.PostOrdersPayRes
+		payErr    error
+		cancelRes orderv1.PostOrdersCancelRes
+		cancelErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		payRes, payErr = h.PostOrdersPay(ctx,
+			&orderv1.PayOrderRequest{PaymentMethod: orderv1.PaymentMethodCARD},
+			orderv1.PostOrdersPayParams{OrderUUID: orderUUID})
+	}()
+	go func() {
+		defer wg.Done()
+		cancelRes, cancelErr = h.PostOrdersCancel(ctx, orderv1.PostOrdersCancelParams{OrderUUID: orderUUID})
+	}()
+	wg.Wait()
+
+	if payErr != nil || cancelErr != nil {
+		t.Fatalf("pay error %v, cancel error %v", payErr, cancelErr)
+	}
+
+	stored, err := storage.GetOrder(ctx, order.OrderUUID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+
+	_, payConflict := payRes.(*orderv1.ConflictError)
+	_, cancelConflict := cancelRes.(*orderv1.ConflictError)
+	switch {
+	case !payConflict && cancelConflict:
+		paid, ok := payRes.(*orderv1.PayOrderResponse)
+		if !ok {
+			t.Fatalf("pay response %T, want *PayOrderResponse", payRes)
+		}
+		if stored.Status != OrderStatusAuthorized || stored.TransactionUUID == nil ||
+			*stored.TransactionUUID != paid.TransactionUUID.String() {
+			t.Fatalf("pay won but order is %s with transaction %v", stored.Status, stored.TransactionUUID)
+		}
+		if len(payment.voided) != 0 {
+			t.Fatalf("pay won but authorizations %v were voided", payment.voided)
+		}
+	case payConflict && !cancelConflict:
+		if _, ok := cancelRes.(*orderv1.PostOrdersCancelNoContent); !ok {
+			t.Fatalf("cancel response %T, want *PostOrdersCancelNoContent", cancelRes)
+		}
+		if stored.Status != OrderStatusCancelled || stored.TransactionUUID != nil {
+			t.Fatalf("cancel won but order is %s with transaction %v", stored.Status, stored.TransactionUUID)
+		}
+		if len(payment.authorized) != 1 || len(payment.voided) != 1 || payment.voided[0] != payment.authorized[0] {
+			t.Fatalf("cancel won: authorized %v, voided %v, want the authorization voided",
+				payment.authorized, payment.voided)
+		}
+	default:
+		t.Fatalf("pay returned %T and cancel returned %T, want exactly one 409", payRes, cancelRes)
+	}
+}
+
+// Две одновременные оплаты одного заказа получают от PaymentService одну и ту же авторизацию:
+// проигравший запрос получает 409, но не аннулирует авторизацию, на которую ссылается заказ
+func TestConcurrentPayKeepsSharedAuthorization(t *testing.T) {
+	for name, newStorage := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			storage := newStorage(t)
+			ctx := context.Background()
+
+			for range 10 {
+				order := createTestOrder(t, storage)
+				payment := &fakePaymentClient{}
+				h := newTestHandler(newBarrierStorage(storage, 2), payment)
+
+				results := make([]orderv1.PostOrdersPayRes, 2)
+				var wg sync.WaitGroup
+				for i := range results {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						res, err := h.PostOrdersPay(ctx,
+							&orderv1.PayOrderRequest{PaymentMethod: orderv1.PaymentMethodCARD},
+							orderv1.PostOrdersPayParams{OrderUUID: uuid.MustParse(order.OrderUUID)})
+						if err != nil {
+							t.Errorf("PostOrdersPay: %v", err)
+						}
+						results[i] = res
+					}()
+				}
+				wg.Wait()
+
+				var paid, conflicts int
+				for _, res := range results {
+					switch res.(type) {
+					case *orderv1.PayOrderResponse:
+						paid++
+					case *orderv1.ConflictError:
+						conflicts++
+					}
+				}
+				if paid != 1 || conflicts != 1 {
+					t.Fatalf("got %d successful pays and %d conflicts, want 1 and 1", paid, conflicts)
+				}
+
+				stored, err := storage.GetOrder(ctx, order.OrderUUID)
+				if err != nil {
+					t.Fatalf("GetOrder: %v", err)
+				}
+				if stored.Status != OrderStatusAuthorized || stored.TransactionUUID == nil {
+					t.Fatalf("order is %s with transaction %v, want AUTHORIZED", stored.Status, stored.TransactionUUID)
+				}
+				if len(payment.voided) != 0 {
+					t.Fatalf("authorization of the paid order was voided: %v", payment.voided)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS order_items (
+    order_uuid TEXT NOT NULL REFERENCES orders (order_uuid) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    part_uuid  TEXT NOT NULL,
+    quantity   BIGINT NOT NULL CHECK (quantity > 0),
+    unit_price DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (order_uuid, position)
+);
+
+CREATE INDEX IF NOT EXISTS order_items_part_uuid_idx ON order_items (part_uuid);
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS orders_user_created_idx ON orders (user_uuid, created_at, order_uuid);
+CREATE INDEX IF NOT EXISTS orders_created_idx ON orders (created_at, order_uuid);
+CREATE INDEX IF NOT EXISTS orders_total_price_idx ON orders (total_price, order_uuid);
+CREATE INDEX IF NOT EXISTS orders_status_idx ON orders (status);
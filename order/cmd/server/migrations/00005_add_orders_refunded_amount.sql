@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refunded_amount DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    subscription_uuid TEXT PRIMARY KEY,
+    url               TEXT NOT NULL,
+    events            TEXT[] NOT NULL,
+    secret            TEXT NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_uuid     TEXT PRIMARY KEY,
+    subscription_uuid TEXT NOT NULL REFERENCES webhook_subscriptions (subscription_uuid) ON DELETE CASCADE,
+    event_id          TEXT NOT NULL,
+    event_type        TEXT NOT NULL,
+    order_uuid        TEXT NOT NULL,
+    payload           BYTEA NOT NULL,
+    status            TEXT NOT NULL,
+    attempts          INTEGER NOT NULL DEFAULT 0,
+    response_status   INTEGER,
+    last_error        TEXT,
+    created_at        TIMESTAMPTZ NOT NULL,
+    last_attempt_at   TIMESTAMPTZ,
+    next_attempt_at   TIMESTAMPTZ
+);
+
+-- Журнал доставок подписки от новых к старым
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx
+    ON webhook_deliveries (subscription_uuid, created_at DESC, delivery_uuid DESC);
+
+-- Очередь доставок, ожидающих попытки
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'PENDING';
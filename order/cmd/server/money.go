@@ -0,0 +1,30 @@
+package main
+
+import (
+	"math"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// orderCurrency — валюта цен деталей и сумм заказов
+const orderCurrency = "RUB"
+
+// roundMoney округляет сумму до копеек, чтобы накопленные частичные возвраты
+// сравнивались с ценой заказа без погрешности float64
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// toMoney переводит сумму, округленную до копеек, в формат Payment Service
+func toMoney(amount float64) *paymentv1.Money {
+	kopecks := int64(math.Round(amount * 100))
+	return &paymentv1.Money{
+		Units: kopecks / 100,
+		Nanos: int32(kopecks%100) * 10_000_000,
+	}
+}
+
+// fromMoney переводит сумму Payment Service в рубли, округленные до копеек
+func fromMoney(m *paymentv1.Money) float64 {
+	return roundMoney(float64(m.GetUnits()) + float64(m.GetNanos())/1e9)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// PostPaymentNotifications applies the outcome of an asynchronous payment to its order.
+// Уведомление не подписано, поэтому статус транзакции перечитывается из Payment Service.
+func (h *OrderHandler) PostPaymentNotifications(ctx context.Context, req *orderv1.PaymentNotification) (orderv1.PostPaymentNotificationsRes, error) {
+	transactionUUID := req.GetTransactionUUID().String()
+	resp, err := h.paymentClient.GetTransaction(ctx, &paymentv1.GetTransactionRequest{
+		TransactionUuid: transactionUUID,
+	})
+	if status.Code(err) == codes.NotFound {
+		return &orderv1.NotFoundError{
+			Code:    orderv1.NewOptInt(http.StatusNotFound),
+			Message: orderv1.NewOptString("transaction not found"),
+		}, nil
+	}
+	if err != nil {
+		log.Printf("error calling PaymentService: %v", err)
+		return badGatewayError(), nil
+	}
+	transaction := resp.GetTransaction()
+
+	orderUUID := transaction.GetOrderUuid()
+	order, err := h.storage.GetOrder(ctx, orderUUID)
+	if errors.Is(err, ErrOrderNotFound) {
+		return notFoundError(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Заказ уже обработан или оплачивается другой транзакцией: уведомление устарело
+	if order.Status != OrderStatusPaymentPending || order.TransactionUUID == nil || *order.TransactionUUID != transactionUUID {
+		return &orderv1.PostPaymentNotificationsNoContent{}, nil
+	}
+
+	var (
+		next        OrderStatus
+		clearResult bool
+	)
+	switch transaction.GetStatus() {
+	case paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED:
+		next = OrderStatusAuthorized
+	case paymentv1.TransactionStatus_TRANSACTION_STATUS_FAILED,
+		paymentv1.TransactionStatus_TRANSACTION_STATUS_VOIDED:
+		// Платеж не прошел: заказ снова ждет оплаты
+		next = OrderStatusPendingPayment
+		clearResult = true
+	default:
+		// Провайдер еще не сообщил итог
+		return &orderv1.PostPaymentNotificationsNoContent{}, nil
+	}
+
+	err = h.transition(ctx, orderUUID, order.Status, next, func(o *Order) {
+		if clearResult {
+			o.TransactionUUID = nil
+			o.PaymentMethod = nil
+		}
+	})
+	var transitionErr *TransitionError
+	if errors.As(err, &transitionErr) {
+		// Заказ успели отменить, пока приходило уведомление; блокировку сняла отмена
+		log.Printf("order %s changed to %s before payment notification for transaction %s",
+			orderUUID, transitionErr.From, transactionUUID)
+		return &orderv1.PostPaymentNotificationsNoContent{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("order %s moved to %s by transaction %s (%s)", orderUUID, next, transactionUUID, transaction.GetStatus())
+	return &orderv1.PostPaymentNotificationsNoContent{}, nil
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	inventoryv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1"
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const (
+	// defaultReconcileInterval — период фоновой сверки, если ORDER_RECONCILE_INTERVAL не задан
+	defaultReconcileInterval = 10 * time.Minute
+	// defaultReconcileWindow — за какой период сверяются заказы, если ORDER_RECONCILE_WINDOW не задан;
+	// совпадает со сроком авторизации в Payment Service
+	defaultReconcileWindow = 7 * 24 * time.Hour
+	// defaultReconcileGrace — транзакции моложе этого возраста не сверяются:
+	// их заказ может еще обновляться запросом, который их создал
+	defaultReconcileGrace = time.Minute
+
+	// reconcilePageSize — размер страницы при выгрузке заказов и транзакций
+	reconcilePageSize = 100
+)
+
+// MismatchKind — вид расхождения между заказом и транзакциями Payment Service
+type MismatchKind string
+
+const (
+	// MismatchPaidButPending — платеж прошел дальше заказа: например, Order Service упал
+	// между AuthorizePayment и сохранением заказа, и заказ остался в PENDING_PAYMENT
+	MismatchPaidButPending MismatchKind = "paid_but_pending"
+	// MismatchPaidTwice — у заказа несколько действующих транзакций
+	MismatchPaidTwice MismatchKind = "paid_twice"
+	// MismatchPaidWithoutTransaction — заказ считается оплаченным, но его транзакции нет
+	// или она аннулирована либо отклонена
+	MismatchPaidWithoutTransaction MismatchKind = "order_paid_without_transaction"
+)
+
+// Mismatch — найденное расхождение. Healed означает, что заказ исправлен автоматически,
+// Resolution описывает исправление или действие, которое нужно выполнить вручную.
+type Mismatch struct {
+	Kind             MismatchKind `json:"kind"`
+	OrderUUID        string       `json:"order_uuid"`
+	OrderStatus      OrderStatus  `json:"order_status"`
+	TransactionUUIDs []string     `json:"transaction_uuids"`
+	Detail           string       `json:"detail"`
+	Healed           bool         `json:"healed"`
+	Resolution       string       `json:"resolution"`
+}
+
+// ReconcileReport — результат одной сверки
+type ReconcileReport struct {
+	CheckedAt           time.Time  `json:"checked_at"`
+	Since               time.Time  `json:"since"`
+	OrdersChecked       int        `json:"orders_checked"`
+	TransactionsChecked int        `json:"transactions_checked"`
+	Mismatches          []Mismatch `json:"mismatches"`
+}
+
+// Unhealed возвращает число расхождений, требующих ручного разбора
+func (r *ReconcileReport) Unhealed() int {
+	var count int
+	for _, mismatch := range r.Mismatches {
+		if !mismatch.Healed {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteJSON пишет отчет целиком одним JSON-объектом
+func (r *ReconcileReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV пишет расхождения по одному в строке; UUID транзакций разделены точкой с запятой
+func (r *ReconcileReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"kind", "order_uuid", "order_status", "transaction_uuids", "detail", "healed", "resolution"}); err != nil {
+		return err
+	}
+	for _, mismatch := range r.Mismatches {
+		if err := writer.Write([]string{
+			string(mismatch.Kind),
+			mismatch.OrderUUID,
+			string(mismatch.OrderStatus),
+			strings.Join(mismatch.TransactionUUIDs, ";"),
+			mismatch.Detail,
+			strconv.FormatBool(mismatch.Healed),
+			mismatch.Resolution,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReconcileOptions — параметры сверки
+type ReconcileOptions struct {
+	// Since — сверяются заказы и транзакции, созданные начиная с этого момента
+	Since time.Time
+	// Grace — транзакции, изменившиеся позже now - Grace, пропускаются до следующей сверки
+	Grace time.Duration
+	// Heal разрешает автоматически исправлять безопасные расхождения
+	Heal bool
+}
+
+// orderPaymentMethods — обратное сопоставление способов оплаты для восстановления заказа по транзакции
+var orderPaymentMethods = func() map[paymentv1.PaymentMethod]string {
+	methods := make(map[paymentv1.PaymentMethod]string, len(paymentMethods))
+	for api, payment := range paymentMethods {
+		methods[payment] = string(api)
+	}
+	return methods
+}()
+
+// transactionActive сообщает, что транзакция удерживает или списала деньги покупателя
+func transactionActive(transaction *paymentv1.Transaction) bool {
+	switch transaction.GetStatus() {
+	case paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED,
+		paymentv1.TransactionStatus_TRANSACTION_STATUS_PARTIALLY_REFUNDED,
+		paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED,
+		paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING:
+		return true
+	default:
+		return false
+	}
+}
+
+// orderPaid — статусы, в которых у заказа должна быть проведенная транзакция
+var orderPaid = map[OrderStatus]bool{
+	OrderStatusAuthorized:        true,
+	OrderStatusPaid:              true,
+	OrderStatusPartiallyRefunded: true,
+	OrderStatusRefunded:          true,
+}
+
+// Reconcile сверяет заказы с транзакциями Payment Service и, если opts.Heal, исправляет безопасные случаи.
+// Заказ исправляется только допустимым переходом статуса из того, который был прочитан при сверке,
+// поэтому одновременное изменение заказа клиентом не перезаписывается.
+//
+//   - paid_but_pending: заказ PENDING_PAYMENT с авторизацией переходит в AUTHORIZED, с ожидающим
+//     подтверждения платежом — в PAYMENT_PENDING; PAYMENT_PENDING с подтвержденной авторизацией — в AUTHORIZED;
+//     AUTHORIZED со списанной транзакцией — в PAID с подтверждением резерва. Списание по заказу
+//     в PENDING_PAYMENT требует ручного разбора: заказ нельзя перевести в PAID, минуя выдачу.
+//   - order_paid_without_transaction: AUTHORIZED заказ с аннулированной или отклоненной авторизацией
+//     возвращается в PENDING_PAYMENT, как при выдаче; остальные случаи — вручную.
+//   - paid_twice: всегда вручную, лишнюю транзакцию нужно вернуть.
+func (h *OrderHandler) Reconcile(ctx context.Context, opts ReconcileOptions) (*ReconcileReport, error) {
+	report := &ReconcileReport{
+		CheckedAt:  time.Now().UTC(),
+		Since:      opts.Since,
+		Mismatches: []Mismatch{},
+	}
+
+	transactions, err := h.listTransactionsSince(ctx, opts.Since)
+	if err != nil {
+		return nil, fmt.Errorf("list transactions: %w", err)
+	}
+	report.TransactionsChecked = len(transactions)
+
+	byUUID := make(map[string]*paymentv1.Transaction, len(transactions))
+	byOrder := make(map[string][]*paymentv1.Transaction)
+	for _, transaction := range transactions {
+		byUUID[transaction.GetTransactionUuid()] = transaction
+		byOrder[transaction.GetOrderUuid()] = append(byOrder[transaction.GetOrderUuid()], transaction)
+	}
+	settledBefore := report.CheckedAt.Add(-opts.Grace)
+
+	filter := OrderFilter{
+		CreatedFrom: &opts.Since,
+		SortBy:      OrderSortByCreatedAt,
+		Limit:       reconcilePageSize,
+	}
+	for {
+		page, err := h.storage.ListOrders(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("list orders: %w", err)
+		}
+		for _, order := range page.Orders {
+			report.OrdersChecked++
+			if mismatch := h.reconcileOrder(ctx, order, byUUID, byOrder[order.OrderUUID], settledBefore, opts.Heal); mismatch != nil {
+				report.Mismatches = append(report.Mismatches, *mismatch)
+			}
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		filter.After = page.NextCursor
+	}
+	return report, nil
+}
+
+// listTransactionsSince выгружает все транзакции, созданные начиная с since
+func (h *OrderHandler) listTransactionsSince(ctx context.Context, since time.Time) ([]*paymentv1.Transaction, error) {
+	req := &paymentv1.ListTransactionsRequest{
+		Filter:   &paymentv1.TransactionsFilter{CreatedFrom: timestamppb.New(since)},
+		PageSize: reconcilePageSize,
+	}
+	var transactions []*paymentv1.Transaction
+	for {
+		resp, err := h.paymentClient.ListTransactions(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, resp.GetTransactions()...)
+		if resp.GetNextPageToken() == "" {
+			return transactions, nil
+		}
+		req.PageToken = resp.GetNextPageToken()
+	}
+}
+
+// reconcileOrder проверяет один заказ; transactions — все его транзакции из окна сверки
+func (h *OrderHandler) reconcileOrder(ctx context.Context, order *Order, byUUID map[string]*paymentv1.Transaction,
+	transactions []*paymentv1.Transaction, settledBefore time.Time, heal bool) *Mismatch {
+	// Заказ мог еще не успеть сохранить результат свежей транзакции
+	for _, transaction := range transactions {
+		if transaction.GetUpdatedAt().AsTime().After(settledBefore) {
+			return nil
+		}
+	}
+
+	var active []*paymentv1.Transaction
+	for _, transaction := range transactions {
+		if transactionActive(transaction) {
+			active = append(active, transaction)
+		}
+	}
+
+	mismatch := &Mismatch{OrderUUID: order.OrderUUID, OrderStatus: order.Status}
+	switch {
+	case len(active) > 1:
+		mismatch.Kind = MismatchPaidTwice
+		mismatch.TransactionUUIDs = transactionUUIDs(active)
+		mismatch.Detail = fmt.Sprintf("order has %d active transactions: %s", len(active), transactionStatuses(active))
+		mismatch.Resolution = "refund the transactions not referenced by the order"
+		return mismatch
+
+	case order.Status == OrderStatusPendingPayment || order.Status == OrderStatusPaymentPending ||
+		order.Status == OrderStatusAuthorized:
+		if len(active) == 1 && paymentAhead(order, active[0]) {
+			mismatch.Kind = MismatchPaidButPending
+			mismatch.TransactionUUIDs = transactionUUIDs(active)
+			mismatch.Detail = "transaction is " + active[0].GetStatus().String()
+			h.healPaidButPending(ctx, order, active[0], mismatch, heal)
+			return mismatch
+		}
+	}
+
+	if !orderPaid[order.Status] {
+		return nil
+	}
+	if order.TransactionUUID == nil {
+		mismatch.Kind = MismatchPaidWithoutTransaction
+		mismatch.Detail = "order has no transaction"
+		mismatch.Resolution = "find the payment and link it to the order manually"
+		return mismatch
+	}
+
+	transaction, ok := byUUID[*order.TransactionUUID]
+	mismatch.TransactionUUIDs = []string{*order.TransactionUUID}
+	switch {
+	case !ok:
+		mismatch.Detail = "transaction not found in payment service"
+	case transaction.GetOrderUuid() != order.OrderUUID:
+		mismatch.Detail = "transaction belongs to order " + transaction.GetOrderUuid()
+	case transaction.GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_VOIDED,
+		transaction.GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_FAILED:
+		mismatch.Detail = "transaction is " + transaction.GetStatus().String()
+		if order.Status == OrderStatusAuthorized {
+			mismatch.Kind = MismatchPaidWithoutTransaction
+			h.healOrder(ctx, order, OrderStatusPendingPayment, func(o *Order) {
+				o.TransactionUUID = nil
+				o.PaymentMethod = nil
+			}, mismatch, heal)
+			return mismatch
+		}
+	default:
+		return nil
+	}
+	mismatch.Kind = MismatchPaidWithoutTransaction
+	mismatch.Resolution = "check the payment manually"
+	return mismatch
+}
+
+// paymentAhead сообщает, что транзакция продвинулась дальше неоплаченного или авторизованного заказа
+func paymentAhead(order *Order, transaction *paymentv1.Transaction) bool {
+	switch order.Status {
+	case OrderStatusPendingPayment:
+		return true
+	case OrderStatusPaymentPending:
+		return transaction.GetStatus() != paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING
+	case OrderStatusAuthorized:
+		return transaction.GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED ||
+			transaction.GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_PARTIALLY_REFUNDED
+	default:
+		return false
+	}
+}
+
+// healPaidButPending догоняет заказ до состояния транзакции, если это возможно допустимым переходом
+func (h *OrderHandler) healPaidButPending(ctx context.Context, order *Order, transaction *paymentv1.Transaction, mismatch *Mismatch, heal bool) {
+	transactionUUID := transaction.GetTransactionUuid()
+	link := func(o *Order) {
+		method := orderPaymentMethods[transaction.GetPaymentMethod()]
+		o.TransactionUUID = &transactionUUID
+		o.PaymentMethod = &method
+	}
+
+	switch status := transaction.GetStatus(); {
+	case status == paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING && order.Status == OrderStatusPendingPayment:
+		h.healOrder(ctx, order, OrderStatusPaymentPending, link, mismatch, heal)
+	case status == paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED:
+		h.healOrder(ctx, order, OrderStatusAuthorized, link, mismatch, heal)
+	case order.Status == OrderStatusAuthorized && order.TransactionUUID != nil && *order.TransactionUUID == transactionUUID:
+		// Выдача списала деньги, но не успела сохранить заказ
+		if h.healOrder(ctx, order, OrderStatusPaid, nil, mismatch, heal) {
+			if _, err := h.inventoryClient.CommitReservation(ctx, &inventoryv1.CommitReservationRequest{
+				OrderUuid: order.OrderUUID,
+			}); err != nil {
+				log.Printf("error committing reservation for order %s: %v", order.OrderUUID, err)
+			}
+		}
+	default:
+		mismatch.Resolution = "order was charged without authorization, fulfil or refund it manually"
+	}
+}
+
+// healOrder переводит заказ в to, если сверка может исправлять расхождения, и описывает результат в mismatch
+func (h *OrderHandler) healOrder(ctx context.Context, order *Order, to OrderStatus, updateFunc func(*Order), mismatch *Mismatch, heal bool) bool {
+	if !heal {
+		mismatch.Resolution = "move order to " + string(to)
+		return false
+	}
+	err := h.transition(ctx, order.OrderUUID, order.Status, to, updateFunc)
+	var transitionErr *TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		mismatch.Resolution = "order changed to " + string(transitionErr.From) + " during reconciliation"
+		return false
+	case err != nil:
+		log.Printf("failed to heal order %s: %v", order.OrderUUID, err)
+		mismatch.Resolution = "failed to move order to " + string(to)
+		return false
+	}
+	mismatch.Healed = true
+	mismatch.Resolution = "order moved to " + string(to)
+	return true
+}
+
+func transactionUUIDs(transactions []*paymentv1.Transaction) []string {
+	uuids := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		uuids = append(uuids, transaction.GetTransactionUuid())
+	}
+	return uuids
+}
+
+func transactionStatuses(transactions []*paymentv1.Transaction) string {
+	statuses := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		statuses = append(statuses, transaction.GetStatus().String())
+	}
+	return strings.Join(statuses, ", ")
+}
+
+// runReconciliation сверяет заказы при старте и затем раз в interval, пока ctx не отменен
+func (h *OrderHandler) runReconciliation(ctx context.Context, interval, window time.Duration, heal bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := h.Reconcile(ctx, ReconcileOptions{
+			Since: time.Now().Add(-window),
+			Grace: defaultReconcileGrace,
+			Heal:  heal,
+		})
+		if err != nil {
+			log.Printf("reconciliation failed: %v", err)
+		} else {
+			for _, mismatch := range report.Mismatches {
+				log.Printf("RECONCILIATION %s: order %s (%s), transactions %v: %s; %s", mismatch.Kind,
+					mismatch.OrderUUID, mismatch.OrderStatus, mismatch.TransactionUUIDs, mismatch.Detail, mismatch.Resolution)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runReconcileCommand выполняет сверку по требованию: order-server reconcile [-format json|csv] [-window 24h] [-heal].
+// Отчет пишется в stdout. Код выхода: 0 — расхождений, требующих разбора, нет; 1 — ошибка;
+// 2 — остались неисправленные расхождения. Хранилище выбирается теми же переменными окружения,
+// что и у сервера, поэтому сверка имеет смысл с ORDER_STORAGE_DRIVER=postgres.
+func runReconcileCommand(args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	format := flags.String("format", "json", "report format: json or csv")
+	window := flags.Duration("window", defaultReconcileWindow, "check orders created within this period")
+	grace := flags.Duration("grace", defaultReconcileGrace, "skip transactions changed within this period")
+	heal := flags.Bool("heal", false, "move orders to the state of their transactions where it is safe")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if *format != "json" && *format != "csv" {
+		log.Printf("unknown format %q", *format)
+		return 1
+	}
+	if os.Getenv("ORDER_STORAGE_DRIVER") != storageDriverPostgres {
+		log.Printf("warning: in-memory storage is empty outside the running server, set ORDER_STORAGE_DRIVER=postgres")
+	}
+
+	ctx := context.Background()
+	storage, _, webhookStore, err := newStorage(ctx)
+	if err != nil {
+		log.Printf("failed to create order storage: %v", err)
+		return 1
+	}
+	defer storage.Close()
+
+	// События исправленных заказов только ставятся в очередь: доставит их запущенный сервис
+	handler, err := NewOrderHandler(storage, NewWebhookDispatcher(webhookStore, defaultWebhookRetryBackoff,
+		parseWebhookAllowedHosts(os.Getenv("ORDER_WEBHOOK_ALLOWED_HOSTS"))))
+	if err != nil {
+		log.Printf("failed to create handler: %v", err)
+		return 1
+	}
+	defer handler.Close()
+
+	report, err := handler.Reconcile(ctx, ReconcileOptions{
+		Since: time.Now().Add(-*window),
+		Grace: *grace,
+		Heal:  *heal,
+	})
+	if err != nil {
+		log.Printf("reconciliation failed: %v", err)
+		return 1
+	}
+
+	if *format == "csv" {
+		err = report.WriteCSV(os.Stdout)
+	} else {
+		err = report.WriteJSON(os.Stdout)
+	}
+	if err != nil {
+		log.Printf("failed to write report: %v", err)
+		return 1
+	}
+	if report.Unhealed() > 0 {
+		return 2
+	}
+	return 0
+}
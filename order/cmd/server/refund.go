@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// refundableAmount — сумма, которую еще можно вернуть по заказу
+func (o *Order) refundableAmount() float64 {
+	return roundMoney(o.TotalPrice - o.RefundedAmount)
+}
+
+// errRefundFailed — PaymentService не выполнил возврат
+var errRefundFailed = errors.New("payment service refund failed")
+
+// refundApplyAttempts — сколько раз выполненный возврат применяется к заказу,
+// если его статус успевают изменить конкурентные запросы
+const refundApplyAttempts = 3
+
+// refundKey — ключ идемпотентности возврата в PaymentService. С ключом запроса клиента повтор
+// после сбоя получает тот же возврат; без него ключ строится по уже возвращенной сумме,
+// поэтому одинаковые возвраты, начатые с одного состояния заказа, выполняются один раз.
+func refundKey(order *Order, amount float64, requestKey string) string {
+	if requestKey != "" {
+		return "request:" + requestKey
+	}
+	return fmt.Sprintf("refunded:%.2f:amount:%.2f", order.RefundedAmount, amount)
+}
+
+// refundOrder возвращает amount по транзакции заказа через PaymentService и переводит заказ
+// в PARTIALLY_REFUNDED либо, если возвращена вся сумма, в REFUNDED с возвратом деталей на склад.
+// key передается в PaymentService как ключ идемпотентности возврата (см. refundKey).
+func (h *OrderHandler) refundOrder(ctx context.Context, order *Order, amount float64, reason, key string) (*orderv1.RefundOrderResponse, error) {
+	target := OrderStatusPartiallyRefunded
+	if amount >= order.refundableAmount() {
+		target = OrderStatusRefunded
+	}
+	if err := checkTransition(order, target); err != nil {
+		return nil, err
+	}
+	if order.TransactionUUID == nil {
+		return nil, fmt.Errorf("order %s is %s but has no transaction", order.OrderUUID, order.Status)
+	}
+
+	resp, err := h.paymentClient.RefundPayment(ctx, &paymentv1.RefundPaymentRequest{
+		TransactionUuid: *order.TransactionUUID,
+		Amount:          toMoney(amount),
+		Reason:          reason,
+		IdempotencyKey:  key,
+	})
+	if err != nil {
+		log.Printf("error calling PaymentService: %v", err)
+		return nil, fmt.Errorf("%w: %w", errRefundFailed, err)
+	}
+
+	// Деньги уже возвращены, поэтому заказ обязан их отразить, даже если его успели изменить
+	result, err := h.applyRefund(ctx, order.OrderUUID, fromMoney(resp.GetTransaction().GetRefundedAmount()))
+	if err != nil {
+		log.Printf("refund %s for order %s issued but order was not updated: %v",
+			resp.GetRefundUuid(), order.OrderUUID, err)
+		return nil, err
+	}
+	result.RefundUUID = parseUUID(resp.GetRefundUuid())
+	return result, nil
+}
+
+// applyRefund переносит в заказ сумму всех возвратов refunded по его транзакции из PaymentService.
+// Сумма накопительная, поэтому заказ хранит наибольшую из полученных и конкурентные возвраты
+// не теряются и не учитываются дважды. Заказ перечитывается перед каждой попыткой:
+// если статус изменился за время обновления, попытка повторяется.
+func (h *OrderHandler) applyRefund(ctx context.Context, orderUUID string, refunded float64) (*orderv1.RefundOrderResponse, error) {
+	for attempt := 1; ; attempt++ {
+		order, err := h.storage.GetOrder(ctx, orderUUID)
+		if err != nil {
+			return nil, err
+		}
+		refunded = max(refunded, order.RefundedAmount)
+		result := &orderv1.RefundOrderResponse{
+			RefundedAmount: refunded,
+			Status:         orderv1.OrderStatus(order.Status),
+		}
+		// Конкурентный запрос уже учел этот возврат
+		if refunded == order.RefundedAmount {
+			return result, nil
+		}
+
+		target := OrderStatusPartiallyRefunded
+		if refunded >= order.TotalPrice {
+			target = OrderStatusRefunded
+		}
+		err = h.transition(ctx, orderUUID, order.Status, target, func(o *Order) {
+			o.RefundedAmount = max(o.RefundedAmount, refunded)
+			result.RefundedAmount = o.RefundedAmount
+		})
+		var transitionErr *TransitionError
+		if errors.As(err, &transitionErr) && transitionErr.From != order.Status && attempt < refundApplyAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result.Status = orderv1.OrderStatus(target)
+		if target == OrderStatusRefunded {
+			h.releaseReservation(ctx, orderUUID)
+		}
+		return result, nil
+	}
+}
+
+// PostOrdersRefund refunds the whole remaining amount of the order or a part of it
+func (h *OrderHandler) PostOrdersRefund(ctx context.Context, req orderv1.OptRefundOrderRequest, params orderv1.PostOrdersRefundParams) (orderv1.PostOrdersRefundRes, error) {
+	order, err := h.storage.GetOrder(ctx, params.OrderUUID.String())
+	if errors.Is(err, ErrOrderNotFound) {
+		return notFoundError(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Тело необязательно: пустой запрос означает полный возврат
+	amount := order.refundableAmount()
+	if requested, ok := req.Value.Amount.Get(); req.Set && ok {
+		amount = roundMoney(requested)
+		if amount <= 0 || amount > order.refundableAmount() {
+			return badRequestError("amount must be positive and not exceed the refundable amount"), nil
+		}
+	}
+
+	key := refundKey(order, amount, params.IdempotencyKey.Value)
+	result, err := h.refundOrder(ctx, order, amount, req.Value.Reason.Value, key)
+	var transitionErr *TransitionError
+	if errors.As(err, &transitionErr) {
+		return conflictError(transitionErr), nil
+	}
+	if errors.Is(err, errRefundFailed) {
+		if badRequest, ok := paymentBadRequest(err); ok {
+			return badRequest, nil
+		}
+		if declined, ok := paymentDeclined(err); ok {
+			return declined, nil
+		}
+		return badGatewayError(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
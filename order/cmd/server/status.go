@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// orderTransitions — допустимые переходы между статусами заказа.
+// Статус, отсутствующий в ключах, является конечным.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPendingPayment: {OrderStatusAuthorized, OrderStatusPaymentPending, OrderStatusCancelled},
+	// Возврат в PENDING_PAYMENT — провайдер отклонил асинхронный платеж
+	OrderStatusPaymentPending: {OrderStatusAuthorized, OrderStatusPendingPayment, OrderStatusCancelled},
+	// Возврат в PENDING_PAYMENT — истекшая авторизация, заказ нужно оплатить заново
+	OrderStatusAuthorized:        {OrderStatusPaid, OrderStatusCancelled, OrderStatusPendingPayment},
+	OrderStatusPaid:              {OrderStatusPartiallyRefunded, OrderStatusRefunded},
+	OrderStatusPartiallyRefunded: {OrderStatusPartiallyRefunded, OrderStatusRefunded},
+}
+
+// CanTransitionTo сообщает, разрешен ли переход заказа из статуса s в next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	return slices.Contains(orderTransitions[s], next)
+}
+
+// TransitionError — попытка перевести заказ в статус, недостижимый из текущего
+type TransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("order status cannot change from %s to %s", e.From, e.To)
+}
+
+// StatusMismatchError возвращается UpdateOrder, если статус заказа изменился
+// после того, как вызывающий код его прочитал
+type StatusMismatchError struct {
+	Expected OrderStatus
+	Actual   OrderStatus
+}
+
+func (e *StatusMismatchError) Error() string {
+	return fmt.Sprintf("order status changed concurrently: expected %s, actual %s", e.Expected, e.Actual)
+}
+
+// applyTransition применяет updateFunc к заказу со статусом expected и проверяет
+// получившийся переход по таблице orderTransitions. Используется реализациями OrderStorage.
+func applyTransition(order *Order, expected OrderStatus, updateFunc func(*Order)) error {
+	if order.Status != expected {
+		return &StatusMismatchError{Expected: expected, Actual: order.Status}
+	}
+
+	updateFunc(order)
+
+	if order.Status != expected && !expected.CanTransitionTo(order.Status) {
+		return &TransitionError{From: expected, To: order.Status}
+	}
+	return nil
+}
+
+// checkTransition проверяет переход до обращения к внешним сервисам
+func checkTransition(order *Order, to OrderStatus) error {
+	if !order.Status.CanTransitionTo(to) {
+		return &TransitionError{From: order.Status, To: to}
+	}
+	return nil
+}
+
+// transitionEvents — события для подписчиков, которые публикуются при переходе заказа в статус.
+// Заказ считается оплаченным, когда сумма заблокирована: списание при выдаче события не порождает.
+// Отмена оплаченного заказа завершается возвратом, и order.cancelled о ней публикует PostOrdersCancel.
+var transitionEvents = map[OrderStatus]WebhookEventType{
+	OrderStatusAuthorized: WebhookEventOrderPaid,
+	OrderStatusCancelled:  WebhookEventOrderCancelled,
+}
+
+// transition атомарно переводит заказ из статуса from в to, применяя updateFunc,
+// и публикует событие перехода из transitionEvents.
+// Если статус успел измениться, возвращается *TransitionError от фактического статуса.
+func (h *OrderHandler) transition(ctx context.Context, orderUUID string, from, to OrderStatus, updateFunc func(*Order)) error {
+	var updated *Order
+	err := h.storage.UpdateOrder(ctx, orderUUID, from, func(o *Order) {
+		o.Status = to
+		if updateFunc != nil {
+			updateFunc(o)
+		}
+		updated = o
+	})
+
+	var mismatchErr *StatusMismatchError
+	if errors.As(err, &mismatchErr) {
+		return &TransitionError{From: mismatchErr.Actual, To: to}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Хранилище сохраняет тот же заказ, который изменил updateFunc, вместе с новым UpdatedAt
+	if eventType, ok := transitionEvents[to]; ok {
+		h.webhooks.Publish(ctx, eventType, updated)
+	}
+	return nil
+}
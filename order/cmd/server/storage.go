@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrOrderNotFound возвращается хранилищем, если заказ с указанным UUID отсутствует
+var ErrOrderNotFound = errors.New("order not found")
+
+// OrderStorage описывает хранилище заказов.
+// Реализации обязаны быть потокобезопасными, а UpdateOrder — атомарной операцией compare-and-set:
+// если текущий статус заказа не равен expected, возвращается *StatusMismatchError;
+// иначе updateFunc применяется к актуальному состоянию, переход статуса проверяется
+// по orderTransitions (*TransitionError) и заказ сохраняется целиком.
+type OrderStorage interface {
+	GetOrder(ctx context.Context, uuid string) (*Order, error)
+	ListOrders(ctx context.Context, filter OrderFilter) (*OrderPage, error)
+	CreateOrder(ctx context.Context, order *Order) error
+	UpdateOrder(ctx context.Context, uuid string, expected OrderStatus, updateFunc func(*Order)) error
+	Close()
+}
+
+// InMemoryOrderStorage хранит заказы в памяти процесса; данные теряются при перезапуске.
+// Вторичные индексы по пользователю, детали и статусу сужают перебор в ListOrders.
+type InMemoryOrderStorage struct {
+	mu       sync.RWMutex
+	orders   map[string]*Order
+	byUser   map[string]map[string]struct{}
+	byPart   map[string]map[string]struct{}
+	byStatus map[OrderStatus]map[string]struct{}
+}
+
+func NewInMemoryOrderStorage() *InMemoryOrderStorage {
+	return &InMemoryOrderStorage{
+		orders:   make(map[string]*Order),
+		byUser:   make(map[string]map[string]struct{}),
+		byPart:   make(map[string]map[string]struct{}),
+		byStatus: make(map[OrderStatus]map[string]struct{}),
+	}
+}
+
+func addToIndex[K comparable](index map[K]map[string]struct{}, key K, orderUUID string) {
+	set, ok := index[key]
+	if !ok {
+		set = make(map[string]struct{})
+		index[key] = set
+	}
+	set[orderUUID] = struct{}{}
+}
+
+func removeFromIndex[K comparable](index map[K]map[string]struct{}, key K, orderUUID string) {
+	set, ok := index[key]
+	if !ok {
+		return
+	}
+	delete(set, orderUUID)
+	if len(set) == 0 {
+		delete(index, key)
+	}
+}
+
+func (s *InMemoryOrderStorage) index(order *Order) {
+	addToIndex(s.byUser, order.UserUUID, order.OrderUUID)
+	addToIndex(s.byStatus, order.Status, order.OrderUUID)
+	for _, item := range order.Items {
+		addToIndex(s.byPart, item.PartUUID, order.OrderUUID)
+	}
+}
+
+func (s *InMemoryOrderStorage) unindex(order *Order) {
+	removeFromIndex(s.byUser, order.UserUUID, order.OrderUUID)
+	removeFromIndex(s.byStatus, order.Status, order.OrderUUID)
+	for _, item := range order.Items {
+		removeFromIndex(s.byPart, item.PartUUID, order.OrderUUID)
+	}
+}
+
+func (s *InMemoryOrderStorage) GetOrder(_ context.Context, uuid string) (*Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	order, ok := s.orders[uuid]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return order.clone(), nil
+}
+
+// candidates возвращает UUID заказов из самого узкого подходящего индекса.
+// Если фильтр не затрагивает индексы, возвращается nil и перебираются все заказы.
+func (s *InMemoryOrderStorage) candidates(filter OrderFilter) map[string]struct{} {
+	var (
+		best    map[string]struct{}
+		indexed bool
+	)
+	consider := func(set map[string]struct{}) {
+		if !indexed || len(set) < len(best) {
+			best = set
+		}
+		indexed = true
+	}
+
+	if filter.UserUUID != "" {
+		consider(s.byUser[filter.UserUUID])
+	}
+	if filter.PartUUID != "" {
+		consider(s.byPart[filter.PartUUID])
+	}
+	if len(filter.Statuses) == 1 {
+		consider(s.byStatus[filter.Statuses[0]])
+	}
+
+	if !indexed {
+		return nil
+	}
+	if best == nil {
+		return map[string]struct{}{}
+	}
+	return best
+}
+
+func (s *InMemoryOrderStorage) ListOrders(_ context.Context, filter OrderFilter) (*OrderPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*Order
+	collect := func(order *Order) {
+		if filter.matches(order) && filter.afterCursor(order) {
+			matched = append(matched, order)
+		}
+	}
+
+	if candidates := s.candidates(filter); candidates != nil {
+		for orderUUID := range candidates {
+			collect(s.orders[orderUUID])
+		}
+	} else {
+		for _, order := range s.orders {
+			collect(order)
+		}
+	}
+
+	slices.SortFunc(matched, func(a, b *Order) int {
+		switch {
+		case filter.less(a, b):
+			return -1
+		case filter.less(b, a):
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	page := &OrderPage{}
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+		page.NextCursor = cursorFor(matched[len(matched)-1], filter)
+	}
+	page.Orders = make([]*Order, 0, len(matched))
+	for _, order := range matched {
+		page.Orders = append(page.Orders, order.clone())
+	}
+	return page, nil
+}
+
+func (s *InMemoryOrderStorage) CreateOrder(_ context.Context, order *Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := order.clone()
+	s.orders[order.OrderUUID] = stored
+	s.index(stored)
+	return nil
+}
+
+func (s *InMemoryOrderStorage) UpdateOrder(_ context.Context, uuid string, expected OrderStatus, updateFunc func(*Order)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.orders[uuid]
+	if !ok {
+		return ErrOrderNotFound
+	}
+
+	// Изменения применяются к копии, чтобы отклоненный переход не затронул хранимый заказ
+	updated := current.clone()
+	if err := applyTransition(updated, expected, updateFunc); err != nil {
+		return err
+	}
+	updated.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	s.unindex(current)
+	s.orders[uuid] = updated
+	s.index(updated)
+	return nil
+}
+
+func (s *InMemoryOrderStorage) Close() {}
+
+// clone возвращает глубокую копию заказа, чтобы вызывающий код не мог
+// изменить состояние хранилища в обход UpdateOrder
+func (o *Order) clone() *Order {
+	c := *o
+	c.PartUUIDs = append([]string(nil), o.PartUUIDs...)
+	c.Items = append([]OrderItem(nil), o.Items...)
+	if o.TransactionUUID != nil {
+		v := *o.TransactionUUID
+		c.TransactionUUID = &v
+	}
+	if o.PaymentMethod != nil {
+		v := *o.PaymentMethod
+		c.PaymentMethod = &v
+	}
+	return &c
+}
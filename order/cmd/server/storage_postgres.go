@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationsLockID — ключ advisory lock, под которым применяются миграции,
+// чтобы несколько экземпляров сервиса не накатывали их одновременно
+const migrationsLockID = 7_300_001
+
+// PostgresOrderStorage хранит заказы в PostgreSQL
+type PostgresOrderStorage struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresOrderStorage подключается к PostgreSQL по DSN и применяет миграции схемы
+func NewPostgresOrderStorage(ctx context.Context, dsn string) (*PostgresOrderStorage, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create postgres pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+
+	if err := applyMigrations(ctx, pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	return &PostgresOrderStorage{pool: pool}, nil
+}
+
+// applyMigrations накатывает ещё не применённые SQL-файлы из migrations/ в лексикографическом порядке.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью в schema_migrations.
+func applyMigrations(ctx context.Context, pool *pgxpool.Pool) error {
+	if _, err := pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    TEXT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	names, err := fs.Glob(migrationsFS, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		script, err := migrationsFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationsLockID); err != nil {
+				return err
+			}
+
+			var applied bool
+			if err := tx.QueryRow(ctx,
+				"SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", name,
+			).Scan(&applied); err != nil {
+				return err
+			}
+			if applied {
+				return nil
+			}
+
+			if _, err := tx.Exec(ctx, string(script)); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", name); err != nil {
+				return err
+			}
+			log.Printf("applied migration %s", name)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+const orderColumns = `order_uuid, user_uuid, part_uuids, total_price, refunded_amount, transaction_uuid, payment_method, status, created_at, updated_at`
+
+func scanOrder(row pgx.Row) (*Order, error) {
+	var order Order
+	if err := row.Scan(
+		&order.OrderUUID,
+		&order.UserUUID,
+		&order.PartUUIDs,
+		&order.TotalPrice,
+		&order.RefundedAmount,
+		&order.TransactionUUID,
+		&order.PaymentMethod,
+		&order.Status,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	order.CreatedAt = order.CreatedAt.UTC()
+	order.UpdatedAt = order.UpdatedAt.UTC()
+	return &order, nil
+}
+
+// loadItems дочитывает позиции заказов из order_items одним запросом
+func loadItems(ctx context.Context, q querier, orders ...*Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byUUID := make(map[string]*Order, len(orders))
+	uuids := make([]string, 0, len(orders))
+	for _, order := range orders {
+		order.Items = []OrderItem{}
+		byUUID[order.OrderUUID] = order
+		uuids = append(uuids, order.OrderUUID)
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT order_uuid, part_uuid, quantity, unit_price
+		FROM order_items
+		WHERE order_uuid = ANY($1)
+		ORDER BY order_uuid, position`,
+		uuids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			orderUUID string
+			item      OrderItem
+		)
+		if err := rows.Scan(&orderUUID, &item.PartUUID, &item.Quantity, &item.UnitPrice); err != nil {
+			return err
+		}
+		order := byUUID[orderUUID]
+		order.Items = append(order.Items, item)
+	}
+	return rows.Err()
+}
+
+// saveItems заменяет позиции заказа в order_items
+func saveItems(ctx context.Context, tx pgx.Tx, order *Order) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM order_items WHERE order_uuid = $1", order.OrderUUID); err != nil {
+		return err
+	}
+
+	rows := make([][]any, 0, len(order.Items))
+	for i, item := range order.Items {
+		rows = append(rows, []any{order.OrderUUID, i, item.PartUUID, item.Quantity, item.UnitPrice})
+	}
+	_, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"order_items"},
+		[]string{"order_uuid", "position", "part_uuid", "quantity", "unit_price"},
+		pgx.CopyFromRows(rows),
+	)
+	return err
+}
+
+// querier — общее подмножество pgxpool.Pool и pgx.Tx для чтения
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (s *PostgresOrderStorage) GetOrder(ctx context.Context, uuid string) (*Order, error) {
+	order, err := scanOrder(s.pool.QueryRow(ctx,
+		"SELECT "+orderColumns+" FROM orders WHERE order_uuid = $1", uuid))
+	if err != nil {
+		return nil, err
+	}
+	if err := loadItems(ctx, s.pool, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// ListOrders строит запрос по фильтру и использует ключевую пагинацию:
+// условие (sort_column, order_uuid) > / < значений курсора опирается на индексы из миграции 00003
+func (s *PostgresOrderStorage) ListOrders(ctx context.Context, filter OrderFilter) (*OrderPage, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.UserUUID != "" {
+		conditions = append(conditions, "user_uuid = "+arg(filter.UserUUID))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		conditions = append(conditions, "status = ANY("+arg(statuses)+")")
+	}
+	if filter.PartUUID != "" {
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM order_items i WHERE i.order_uuid = orders.order_uuid AND i.part_uuid = "+arg(filter.PartUUID)+")")
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.CreatedTo))
+	}
+
+	sortColumn := "created_at"
+	if filter.SortBy == OrderSortByTotalPrice {
+		sortColumn = "total_price"
+	}
+	direction, comparison := "ASC", ">"
+	if filter.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		var sortValue any = filter.After.CreatedAt
+		if filter.SortBy == OrderSortByTotalPrice {
+			sortValue = filter.After.TotalPrice
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, order_uuid) %s (%s, %s)",
+			sortColumn, comparison, arg(sortValue), arg(filter.After.OrderUUID)))
+	}
+
+	query := "SELECT " + orderColumns + " FROM orders"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, order_uuid %s LIMIT %s",
+		sortColumn, direction, direction, arg(filter.Limit+1))
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Order, error) {
+		return scanOrder(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &OrderPage{}
+	if len(orders) > filter.Limit {
+		orders = orders[:filter.Limit]
+		page.NextCursor = cursorFor(orders[len(orders)-1], filter)
+	}
+	if err := loadItems(ctx, s.pool, orders...); err != nil {
+		return nil, err
+	}
+	page.Orders = orders
+	return page, nil
+}
+
+func (s *PostgresOrderStorage) CreateOrder(ctx context.Context, order *Order) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO orders ("+orderColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+			order.OrderUUID,
+			order.UserUUID,
+			order.PartUUIDs,
+			order.TotalPrice,
+			order.RefundedAmount,
+			order.TransactionUUID,
+			order.PaymentMethod,
+			order.Status,
+			order.CreatedAt,
+			order.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		return saveItems(ctx, tx, order)
+	})
+}
+
+// UpdateOrder блокирует строку заказа (SELECT ... FOR UPDATE) на время проверки статуса
+// и применения updateFunc, поэтому конкурентные обновления одного заказа выполняются последовательно,
+// а второе из них видит уже измененный статус
+func (s *PostgresOrderStorage) UpdateOrder(ctx context.Context, uuid string, expected OrderStatus, updateFunc func(*Order)) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		order, err := scanOrder(tx.QueryRow(ctx,
+			"SELECT "+orderColumns+" FROM orders WHERE order_uuid = $1 FOR UPDATE", uuid))
+		if err != nil {
+			return err
+		}
+		if err := loadItems(ctx, tx, order); err != nil {
+			return err
+		}
+
+		if err := applyTransition(order, expected, updateFunc); err != nil {
+			return err
+		}
+		order.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+		_, err = tx.Exec(ctx, `
+			UPDATE orders
+			SET user_uuid = $2, part_uuids = $3, total_price = $4, refunded_amount = $5,
+			    transaction_uuid = $6, payment_method = $7, status = $8, updated_at = $9
+			WHERE order_uuid = $1`,
+			order.OrderUUID,
+			order.UserUUID,
+			order.PartUUIDs,
+			order.TotalPrice,
+			order.RefundedAmount,
+			order.TransactionUUID,
+			order.PaymentMethod,
+			order.Status,
+			order.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		return saveItems(ctx, tx, order)
+	})
+}
+
+func (s *PostgresOrderStorage) Close() {
+	s.pool.Close()
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newTestPostgresStorage подключается к базе из ORDER_POSTGRES_DSN и применяет миграции;
+// без переменной окружения тест пропускается
+func newTestPostgresStorage(t *testing.T) *PostgresOrderStorage {
+	t.Helper()
+
+	dsn := os.Getenv("ORDER_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("ORDER_POSTGRES_DSN is not set")
+	}
+	storage, err := NewPostgresOrderStorage(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	t.Cleanup(storage.Close)
+	return storage
+}
+
+// createTestOrder сохраняет новый заказ в статусе PENDING_PAYMENT;
+// из Postgres заказ удаляется по завершении теста
+func createTestOrder(t *testing.T, storage OrderStorage) *Order {
+	t.Helper()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	partUUID := uuid.NewString()
+	order := &Order{
+		OrderUUID:  uuid.NewString(),
+		UserUUID:   uuid.NewString(),
+		PartUUIDs:  []string{partUUID},
+		Items:      []OrderItem{{PartUUID: partUUID, Quantity: 2, UnitPrice: 150}},
+		TotalPrice: 300,
+		Status:     OrderStatusPendingPayment,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := storage.CreateOrder(context.Background(), order); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	if pg, ok := storage.(*PostgresOrderStorage); ok {
+		t.Cleanup(func() {
+			if _, err := pg.pool.Exec(context.Background(),
+				"DELETE FROM orders WHERE order_uuid = $1", order.OrderUUID); err != nil {
+				t.Errorf("delete test order: %v", err)
+			}
+		})
+	}
+	return order
+}
+
+func TestPostgresOrderStorageCreateGetUpdate(t *testing.T) {
+	storage := newTestPostgresStorage(t)
+	ctx := context.Background()
+	created := createTestOrder(t, storage)
+
+	got, err := storage.GetOrder(ctx, created.OrderUUID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got.UserUUID != created.UserUUID || got.Status != OrderStatusPendingPayment ||
+		got.TotalPrice != created.TotalPrice || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("GetOrder = %+v, want %+v", got, created)
+	}
+	if len(got.Items) != 1 || got.Items[0] != created.Items[0] {
+		t.Fatalf("GetOrder items = %+v, want %+v", got.Items, created.Items)
+	}
+
+	transactionUUID := uuid.NewString()
+	err = storage.UpdateOrder(ctx, created.OrderUUID, OrderStatusPendingPayment, func(o *Order) {
+		o.Status = OrderStatusAuthorized
+		o.TransactionUUID = &transactionUUID
+	})
+	if err != nil {
+		t.Fatalf("UpdateOrder: %v", err)
+	}
+
+	got, err = storage.GetOrder(ctx, created.OrderUUID)
+	if err != nil {
+		t.Fatalf("GetOrder after update: %v", err)
+	}
+	if got.Status != OrderStatusAuthorized || got.TransactionUUID == nil || *got.TransactionUUID != transactionUUID {
+		t.Fatalf("order after update: status %s, transaction %v", got.Status, got.TransactionUUID)
+	}
+	if !got.UpdatedAt.After(created.UpdatedAt) {
+		t.Errorf("UpdatedAt = %s, want after %s", got.UpdatedAt, created.UpdatedAt)
+	}
+
+	if _, err := storage.GetOrder(ctx, uuid.NewString()); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("GetOrder of unknown order: err = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func TestPostgresOrderStorageRejectsInvalidUpdate(t *testing.T) {
+	storage := newTestPostgresStorage(t)
+	ctx := context.Background()
+	created := createTestOrder(t, storage)
+
+	var mismatchErr *StatusMismatchError
+	err := storage.UpdateOrder(ctx, created.OrderUUID, OrderStatusAuthorized, func(o *Order) {
+		o.Status = OrderStatusPaid
+	})
+	if !errors.As(err, &mismatchErr) || mismatchErr.Actual != OrderStatusPendingPayment {
+		t.Fatalf("UpdateOrder with stale status: err = %v, want StatusMismatchError", err)
+	}
+
+	var transitionErr *TransitionError
+	err = storage.UpdateOrder(ctx, created.OrderUUID, OrderStatusPendingPayment, func(o *Order) {
+		o.Status = OrderStatusRefunded
+		o.RefundedAmount = o.TotalPrice
+	})
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("UpdateOrder with invalid transition: err = %v, want TransitionError", err)
+	}
+
+	got, err := storage.GetOrder(ctx, created.OrderUUID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got.Status != OrderStatusPendingPayment || got.RefundedAmount != 0 {
+		t.Errorf("rejected update changed the order: status %s, refunded %v", got.Status, got.RefundedAmount)
+	}
+}
+
+// Конкурентные UpdateOrder с одним expected: применяется ровно одно,
+// остальные видят уже измененный статус
+func TestPostgresOrderStorageUpdateOrderCAS(t *testing.T) {
+	storage := newTestPostgresStorage(t)
+	ctx := context.Background()
+	created := createTestOrder(t, storage)
+
+	const writers = 8
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		winners    []string
+		start      = make(chan struct{})
+		unexpected []error
+	)
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			transactionUUID := uuid.NewString()
+			<-start
+			err := storage.UpdateOrder(ctx, created.OrderUUID, OrderStatusPendingPayment, func(o *Order) {
+				o.Status = OrderStatusAuthorized
+				o.TransactionUUID = &transactionUUID
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			var mismatchErr *StatusMismatchError
+			switch {
+			case err == nil:
+				winners = append(winners, transactionUUID)
+			case errors.As(err, &mismatchErr) && mismatchErr.Actual == OrderStatusAuthorized:
+			default:
+				unexpected = append(unexpected, err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if len(unexpected) > 0 {
+		t.Fatalf("unexpected UpdateOrder errors: %v", unexpected)
+	}
+	if len(winners) != 1 {
+		t.Fatalf("%d concurrent updates succeeded, want exactly 1", len(winners))
+	}
+
+	got, err := storage.GetOrder(ctx, created.OrderUUID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got.TransactionUUID == nil || *got.TransactionUUID != winners[0] {
+		t.Errorf("stored transaction %v, want the winner's %s", got.TransactionUUID, winners[0])
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultWebhookRetryBackoff — задержка перед второй попыткой доставки,
+	// если ORDER_WEBHOOK_RETRY_BACKOFF не задан; каждая следующая задержка вдвое больше
+	defaultWebhookRetryBackoff = 5 * time.Second
+	// webhookMaxRetryBackoff ограничивает рост задержки между попытками
+	webhookMaxRetryBackoff = time.Hour
+	// webhookMaxAttempts — после стольких неудачных попыток доставка получает статус FAILED
+	webhookMaxAttempts = 8
+
+	// webhookRequestTimeout ограничивает ожидание ответа получателя
+	webhookRequestTimeout = 10 * time.Second
+	// webhookClaimLease — на сколько откладывается следующая попытка выбранной доставки.
+	// Должен превышать webhookRequestTimeout, иначе доставку может выбрать другой экземпляр сервиса.
+	webhookClaimLease = time.Minute
+	// webhookPollInterval — как часто проверять очередь доставок без новых событий
+	webhookPollInterval = time.Second
+	// webhookBatchSize — сколько доставок отправляется одновременно
+	webhookBatchSize = 50
+)
+
+// webhookEvent — тело запроса к подписчику, схема WebhookEvent в order.openapi.yaml
+type webhookEvent struct {
+	EventID   string           `json:"event_id"`
+	Type      WebhookEventType `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Order     json.RawMessage  `json:"order"`
+}
+
+// WebhookDispatcher ставит события заказов в очередь доставок подписчикам и доставляет их
+// с повторами. Очередь хранится в WebhookStore, поэтому недоставленные события переживают
+// перезапуск сервиса, если хранилище — PostgreSQL. События доставляются только на публичные
+// адреса и на хосты из allowedHosts (ORDER_WEBHOOK_ALLOWED_HOSTS).
+type WebhookDispatcher struct {
+	store   WebhookStore
+	targets *webhookTargets
+	client  *http.Client
+	backoff time.Duration
+	wake    chan struct{}
+}
+
+func NewWebhookDispatcher(store WebhookStore, backoff time.Duration, allowedHosts []string) *WebhookDispatcher {
+	targets := newWebhookTargets(allowedHosts)
+	return &WebhookDispatcher{
+		store:   store,
+		targets: targets,
+		client:  targets.client(),
+		backoff: backoff,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Publish ставит событие eventType о заказе в очередь доставки каждой подписке на этот тип.
+// Ошибки только логируются: изменение заказа уже сохранено и не должно откатываться из-за подписчиков.
+func (d *WebhookDispatcher) Publish(ctx context.Context, eventType WebhookEventType, order *Order) {
+	if err := d.publish(ctx, eventType, order); err != nil {
+		log.Printf("error publishing %s event for order %s: %v", eventType, order.OrderUUID, err)
+	}
+}
+
+func (d *WebhookDispatcher) publish(ctx context.Context, eventType WebhookEventType, order *Order) error {
+	subscriptions, err := d.store.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	event := webhookEvent{
+		EventID:   uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: now,
+	}
+	var deliveries []*WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribed(eventType) {
+			continue
+		}
+		// Событие сериализуется один раз и только если на него есть подписчики
+		if event.Order == nil {
+			dto := toOrderDTO(order)
+			if event.Order, err = dto.MarshalJSON(); err != nil {
+				return err
+			}
+		}
+		deliveries = append(deliveries, &WebhookDelivery{
+			UUID:             uuid.New().String(),
+			SubscriptionUUID: subscription.UUID,
+			EventID:          event.EventID,
+			EventType:        eventType,
+			OrderUUID:        order.OrderUUID,
+			Status:           WebhookDeliveryPending,
+			CreatedAt:        now,
+			NextAttemptAt:    &now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		delivery.Payload = payload
+	}
+	if err := d.store.EnqueueDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run доставляет события из очереди, пока ctx не отменен: сразу после Publish
+// и раз в webhookPollInterval для повторных попыток
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		// Пока очередь отдает полные пачки, выбираем следующую без ожидания
+		for ctx.Err() == nil {
+			claimed, err := d.store.ClaimDueDeliveries(ctx, time.Now().UTC(), webhookClaimLease, webhookBatchSize)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.Printf("error claiming webhook deliveries: %v", err)
+				}
+				break
+			}
+
+			var wg sync.WaitGroup
+			for _, delivery := range claimed {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					d.deliver(ctx, delivery)
+				}()
+			}
+			wg.Wait()
+
+			if len(claimed) < webhookBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// deliver выполняет одну попытку доставки и сохраняет ее результат
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *WebhookDelivery) {
+	subscription, err := d.store.GetSubscription(ctx, delivery.SubscriptionUUID)
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		// Подписку удалили вместе с ее доставками
+		return
+	}
+	if err != nil {
+		log.Printf("error loading webhook subscription %s: %v", delivery.SubscriptionUUID, err)
+		return
+	}
+
+	attemptedAt := time.Now().UTC().Truncate(time.Microsecond)
+	statusCode, err := d.send(ctx, subscription, delivery, attemptedAt)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &attemptedAt
+	delivery.ResponseStatus = nil
+	if statusCode != 0 {
+		delivery.ResponseStatus = &statusCode
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = WebhookDeliveryDelivered
+		delivery.LastError = nil
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= webhookMaxAttempts:
+		lastError := err.Error()
+		delivery.Status = WebhookDeliveryFailed
+		delivery.LastError = &lastError
+		delivery.NextAttemptAt = nil
+		log.Printf("webhook delivery %s to %s failed after %d attempts: %v",
+			delivery.UUID, subscription.URL, delivery.Attempts, err)
+	default:
+		lastError := err.Error()
+		next := attemptedAt.Add(d.retryDelay(delivery.Attempts))
+		delivery.LastError = &lastError
+		delivery.NextAttemptAt = &next
+		log.Printf("webhook delivery %s to %s failed (attempt %d), retrying at %s: %v",
+			delivery.UUID, subscription.URL, delivery.Attempts, next.Format(time.RFC3339), err)
+	}
+
+	// Результат попытки сохраняется и при остановке сервиса, иначе доставка повторится после lease
+	if err := d.store.UpdateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Printf("error saving webhook delivery %s: %v", delivery.UUID, err)
+	}
+}
+
+// send отправляет подписанное событие и возвращает HTTP-статус ответа;
+// ошибка означает, что получатель не подтвердил доставку ответом 2xx
+func (d *WebhookDispatcher) send(ctx context.Context, subscription *WebhookSubscription, delivery *WebhookDelivery, attemptedAt time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(attemptedAt.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", string(delivery.EventType))
+	req.Header.Set("X-Webhook-Delivery", delivery.UUID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", signWebhook(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Дочитываем ответ, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay — задержка перед попыткой, следующей за attempts неудачными: backoff·2^(attempts−1)
+func (d *WebhookDispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < webhookMaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxRetryBackoff)
+}
+
+// signWebhook подписывает тело события: sha256=hex(HMAC-SHA256(secret, "<timestamp>.<payload>")).
+// Метка времени входит в подпись, чтобы получатель мог отбрасывать перехваченные старые запросы.
+func signWebhook(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+)
+
+const testWebhookSecret = "test-secret"
+
+// receivedWebhook — запрос, который получил тестовый подписчик
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestReceiver запускает подписчика, который отвечает statuses по очереди (после них — 204)
+// и передает полученные запросы в канал
+func newTestReceiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan receivedWebhook) {
+	t.Helper()
+
+	received := make(chan receivedWebhook, 16)
+	statusCh := make(chan int, len(statuses))
+	for _, code := range statuses {
+		statusCh <- code
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{header: r.Header.Clone(), body: body}
+		select {
+		case code := <-statusCh:
+			w.WriteHeader(code)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+// startTestDispatcher запускает доставку событий с короткой задержкой повторов и подписывает
+// receiverURL на order.paid
+func startTestDispatcher(t *testing.T, receiverURL string, allowedHosts []string) (*WebhookDispatcher, *InMemoryWebhookStore, string) {
+	t.Helper()
+
+	store := NewInMemoryWebhookStore()
+	dispatcher := NewWebhookDispatcher(store, 10*time.Millisecond, allowedHosts)
+	subscriptionUUID := uuid.NewString()
+	err := store.CreateSubscription(context.Background(), &WebhookSubscription{
+		UUID:      subscriptionUUID,
+		URL:       receiverURL,
+		Events:    []WebhookEventType{WebhookEventOrderPaid},
+		Secret:    testWebhookSecret,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dispatcher.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return dispatcher, store, subscriptionUUID
+}
+
+// waitDelivery ждет, пока единственная доставка подписки не будет удовлетворять done
+func waitDelivery(t *testing.T, store WebhookStore, subscriptionUUID string, done func(*WebhookDelivery) bool) *WebhookDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := store.ListDeliveries(context.Background(),
+			WebhookDeliveryFilter{SubscriptionUUID: subscriptionUUID, Limit: 10})
+		if err != nil {
+			t.Fatalf("ListDeliveries: %v", err)
+		}
+		if len(deliveries) == 1 && done(deliveries[0]) {
+			return deliveries[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery did not reach the expected state, deliveries: %+v", deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func receiveWebhook(t *testing.T, received <-chan receivedWebhook) receivedWebhook {
+	t.Helper()
+
+	select {
+	case req := <-received:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("receiver got no request")
+		return receivedWebhook{}
+	}
+}
+
+// Подписчик получает подписанное событие; после ответа 500 доставка повторяется
+// с тем же X-Webhook-Delivery и завершается после ответа 2xx
+func TestWebhookDeliverySignedAndRetried(t *testing.T) {
+	receiver, received := newTestReceiver(t, http.StatusInternalServerError)
+	dispatcher, store, subscriptionUUID := startTestDispatcher(t, receiver.URL, []string{"127.0.0.1"})
+
+	order := &Order{
+		OrderUUID:  uuid.NewString(),
+		UserUUID:   uuid.NewString(),
+		TotalPrice: 300,
+		Status:     OrderStatusAuthorized,
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+	}
+	dispatcher.Publish(context.Background(), WebhookEventOrderPaid, order)
+
+	first := receiveWebhook(t, received)
+	second := receiveWebhook(t, received)
+
+	for _, req := range []receivedWebhook{first, second} {
+		mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+		mac.Write([]byte(req.header.Get("X-Webhook-Timestamp") + "."))
+		mac.Write(req.body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if got := req.header.Get("X-Webhook-Signature"); !hmac.Equal([]byte(got), []byte(want)) {
+			t.Errorf("X-Webhook-Signature = %q, want %q", got, want)
+		}
+		if got := req.header.Get("X-Webhook-Event"); got != string(WebhookEventOrderPaid) {
+			t.Errorf("X-Webhook-Event = %q, want %q", got, WebhookEventOrderPaid)
+		}
+	}
+	if first.header.Get("X-Webhook-Delivery") != second.header.Get("X-Webhook-Delivery") {
+		t.Errorf("retry changed X-Webhook-Delivery from %q to %q",
+			first.header.Get("X-Webhook-Delivery"), second.header.Get("X-Webhook-Delivery"))
+	}
+
+	var event struct {
+		Type  WebhookEventType `json:"type"`
+		Order struct {
+			OrderUUID string `json:"order_uuid"`
+			Status    string `json:"status"`
+		} `json:"order"`
+	}
+	if err := json.Unmarshal(second.body, &event); err != nil {
+		t.Fatalf("event body %s: %v", second.body, err)
+	}
+	if event.Type != WebhookEventOrderPaid || event.Order.OrderUUID != order.OrderUUID ||
+		event.Order.Status != string(OrderStatusAuthorized) {
+		t.Errorf("event = %+v, want order.paid for order %s", event, order.OrderUUID)
+	}
+
+	delivery := waitDelivery(t, store, subscriptionUUID, func(d *WebhookDelivery) bool {
+		return d.Status == WebhookDeliveryDelivered
+	})
+	if delivery.Attempts != 2 || delivery.ResponseStatus == nil || *delivery.ResponseStatus != http.StatusNoContent {
+		t.Errorf("delivery after retry: attempts %d, response status %v", delivery.Attempts, delivery.ResponseStatus)
+	}
+}
+
+// Без разрешения в ORDER_WEBHOOK_ALLOWED_HOSTS события не доставляются на loopback,
+// даже если подписка уже сохранена
+func TestWebhookDeliveryRejectsLoopback(t *testing.T) {
+	receiver, received := newTestReceiver(t)
+	dispatcher, store, subscriptionUUID := startTestDispatcher(t, receiver.URL, nil)
+
+	dispatcher.Publish(context.Background(), WebhookEventOrderPaid, &Order{
+		OrderUUID: uuid.NewString(),
+		UserUUID:  uuid.NewString(),
+		Status:    OrderStatusAuthorized,
+	})
+
+	delivery := waitDelivery(t, store, subscriptionUUID, func(d *WebhookDelivery) bool {
+		return d.Attempts > 0
+	})
+	if delivery.Status != WebhookDeliveryPending || delivery.LastError == nil ||
+		!strings.Contains(*delivery.LastError, errWebhookTargetForbidden.Error()) {
+		t.Errorf("delivery to loopback: status %s, error %v", delivery.Status, delivery.LastError)
+	}
+	select {
+	case <-received:
+		t.Error("receiver on loopback got a request")
+	default:
+	}
+}
+
+func TestCreateWebhookSubscriptionChecksTarget(t *testing.T) {
+	tests := []struct {
+		url          string
+		allowedHosts []string
+		wantCreated  bool
+	}{
+		{url: "https://203.0.113.10/hooks", wantCreated: true},
+		{url: "http://127.0.0.1:9000/hooks"},
+		{url: "http://[::1]/hooks"},
+		{url: "http://10.1.2.3/hooks"},
+		{url: "http://192.168.0.10/hooks"},
+		{url: "http://169.254.169.254/latest/meta-data"},
+		{url: "http://0.0.0.0/hooks"},
+		{url: "http://[::ffff:127.0.0.1]/hooks"},
+		{url: "http://localhost/hooks"},
+		{url: "http://localhost:9000/hooks", allowedHosts: []string{"localhost"}, wantCreated: true},
+		{url: "http://127.0.0.1:9000/hooks", allowedHosts: []string{"127.0.0.1"}, wantCreated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			h := &OrderHandler{webhooks: NewWebhookDispatcher(NewInMemoryWebhookStore(), time.Second, tt.allowedHosts)}
+			target, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := h.CreateWebhookSubscription(context.Background(), &orderv1.CreateWebhookSubscriptionRequest{
+				URL:    *target,
+				Events: []orderv1.WebhookEventType{orderv1.WebhookEventTypeOrderPaid},
+			})
+			if err != nil {
+				t.Fatalf("CreateWebhookSubscription: %v", err)
+			}
+			_, created := res.(*orderv1.WebhookSubscription)
+			if created != tt.wantCreated {
+				t.Errorf("CreateWebhookSubscription returned %T, want created = %v", res, tt.wantCreated)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSubscriptionNotFound возвращается хранилищем, если подписка с указанным UUID отсутствует
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// WebhookEventType — тип события заказа, на который можно подписаться
+type WebhookEventType string
+
+const (
+	WebhookEventOrderCreated   WebhookEventType = "order.created"
+	WebhookEventOrderPaid      WebhookEventType = "order.paid"
+	WebhookEventOrderCancelled WebhookEventType = "order.cancelled"
+)
+
+// WebhookDeliveryStatus — состояние доставки события одной подписке
+type WebhookDeliveryStatus string
+
+const (
+	// Доставка ждет первой или повторной попытки
+	WebhookDeliveryPending WebhookDeliveryStatus = "PENDING"
+	// Получатель ответил 2xx
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED"
+	// Попытки исчерпаны
+	WebhookDeliveryFailed WebhookDeliveryStatus = "FAILED"
+)
+
+// WebhookSubscription — адрес, на который отправляются события выбранных типов
+type WebhookSubscription struct {
+	UUID      string
+	URL       string
+	Events    []WebhookEventType
+	Secret    string
+	CreatedAt time.Time
+}
+
+// Subscribed сообщает, оформлена ли подписка на события типа eventType
+func (s *WebhookSubscription) Subscribed(eventType WebhookEventType) bool {
+	return slices.Contains(s.Events, eventType)
+}
+
+// WebhookDelivery — доставка одного события одной подписке. Payload подписывается
+// и отправляется без изменений при каждой попытке.
+type WebhookDelivery struct {
+	UUID             string
+	SubscriptionUUID string
+	EventID          string
+	EventType        WebhookEventType
+	OrderUUID        string
+	Payload          []byte
+	Status           WebhookDeliveryStatus
+	Attempts         int
+	ResponseStatus   *int
+	LastError        *string
+	CreatedAt        time.Time
+	LastAttemptAt    *time.Time
+	NextAttemptAt    *time.Time
+}
+
+// WebhookDeliveryFilter — выборка журнала доставок подписки, от новых к старым
+type WebhookDeliveryFilter struct {
+	SubscriptionUUID string
+	// Пустой статус — доставки в любом статусе
+	Status WebhookDeliveryStatus
+	Limit  int
+}
+
+// WebhookStore описывает хранилище подписок и журнала доставок.
+// Удаление подписки удаляет и ее доставки.
+// ClaimDueDeliveries выбирает доставки PENDING, срок попытки которых наступил к now,
+// и откладывает их следующую попытку на lease, чтобы несколько экземпляров сервиса
+// не отправили одну доставку одновременно; результат попытки сохраняет UpdateDelivery.
+type WebhookStore interface {
+	CreateSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	GetSubscription(ctx context.Context, uuid string) (*WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, uuid string) error
+	EnqueueDeliveries(ctx context.Context, deliveries []*WebhookDelivery) error
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]*WebhookDelivery, error)
+}
+
+// InMemoryWebhookStore хранит подписки и доставки в памяти процесса; данные теряются при перезапуске
+type InMemoryWebhookStore struct {
+	mu            sync.RWMutex
+	subscriptions map[string]*WebhookSubscription
+	deliveries    map[string]*WebhookDelivery
+}
+
+func NewInMemoryWebhookStore() *InMemoryWebhookStore {
+	return &InMemoryWebhookStore{
+		subscriptions: make(map[string]*WebhookSubscription),
+		deliveries:    make(map[string]*WebhookDelivery),
+	}
+}
+
+func (s *InMemoryWebhookStore) CreateSubscription(_ context.Context, subscription *WebhookSubscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscriptions[subscription.UUID] = subscription.clone()
+	return nil
+}
+
+func (s *InMemoryWebhookStore) GetSubscription(_ context.Context, uuid string) (*WebhookSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscription, ok := s.subscriptions[uuid]
+	if !ok {
+		return nil, ErrSubscriptionNotFound
+	}
+	return subscription.clone(), nil
+}
+
+func (s *InMemoryWebhookStore) ListSubscriptions(_ context.Context) ([]*WebhookSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscriptions := make([]*WebhookSubscription, 0, len(s.subscriptions))
+	for _, subscription := range s.subscriptions {
+		subscriptions = append(subscriptions, subscription.clone())
+	}
+	slices.SortFunc(subscriptions, func(a, b *WebhookSubscription) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return subscriptions, nil
+}
+
+func (s *InMemoryWebhookStore) DeleteSubscription(_ context.Context, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[uuid]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	delete(s.subscriptions, uuid)
+	for deliveryUUID, delivery := range s.deliveries {
+		if delivery.SubscriptionUUID == uuid {
+			delete(s.deliveries, deliveryUUID)
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryWebhookStore) EnqueueDeliveries(_ context.Context, deliveries []*WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		// Подписку могли удалить, пока готовилось событие
+		if _, ok := s.subscriptions[delivery.SubscriptionUUID]; !ok {
+			continue
+		}
+		s.deliveries[delivery.UUID] = delivery.clone()
+	}
+	return nil
+}
+
+func (s *InMemoryWebhookStore) ClaimDueDeliveries(_ context.Context, now time.Time, lease time.Duration, limit int) ([]*WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == WebhookDeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	slices.SortFunc(due, func(a, b *WebhookDelivery) int {
+		return a.NextAttemptAt.Compare(*b.NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	leasedUntil := now.Add(lease)
+	claimed := make([]*WebhookDelivery, 0, len(due))
+	for _, delivery := range due {
+		delivery.NextAttemptAt = &leasedUntil
+		claimed = append(claimed, delivery.clone())
+	}
+	return claimed, nil
+}
+
+func (s *InMemoryWebhookStore) UpdateDelivery(_ context.Context, delivery *WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Доставка удаленной подписки больше не нужна
+	if _, ok := s.deliveries[delivery.UUID]; !ok {
+		return nil
+	}
+	s.deliveries[delivery.UUID] = delivery.clone()
+	return nil
+}
+
+func (s *InMemoryWebhookStore) ListDeliveries(_ context.Context, filter WebhookDeliveryFilter) ([]*WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.SubscriptionUUID != filter.SubscriptionUUID {
+			continue
+		}
+		if filter.Status != "" && delivery.Status != filter.Status {
+			continue
+		}
+		matched = append(matched, delivery)
+	}
+	slices.SortFunc(matched, func(a, b *WebhookDelivery) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.UUID, a.UUID)
+	})
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+
+	deliveries := make([]*WebhookDelivery, 0, len(matched))
+	for _, delivery := range matched {
+		deliveries = append(deliveries, delivery.clone())
+	}
+	return deliveries, nil
+}
+
+func (s *WebhookSubscription) clone() *WebhookSubscription {
+	c := *s
+	c.Events = append([]WebhookEventType(nil), s.Events...)
+	return &c
+}
+
+func (d *WebhookDelivery) clone() *WebhookDelivery {
+	c := *d
+	c.Payload = append([]byte(nil), d.Payload...)
+	if d.ResponseStatus != nil {
+		v := *d.ResponseStatus
+		c.ResponseStatus = &v
+	}
+	if d.LastError != nil {
+		v := *d.LastError
+		c.LastError = &v
+	}
+	if d.LastAttemptAt != nil {
+		v := *d.LastAttemptAt
+		c.LastAttemptAt = &v
+	}
+	if d.NextAttemptAt != nil {
+		v := *d.NextAttemptAt
+		c.NextAttemptAt = &v
+	}
+	return &c
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresWebhookStore хранит подписки и журнал доставок в той же базе, что и заказы
+type PostgresWebhookStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresWebhookStore(pool *pgxpool.Pool) *PostgresWebhookStore {
+	return &PostgresWebhookStore{pool: pool}
+}
+
+const subscriptionColumns = `subscription_uuid, url, events, secret, created_at`
+
+func scanSubscription(row pgx.Row) (*WebhookSubscription, error) {
+	var (
+		subscription WebhookSubscription
+		events       []string
+	)
+	if err := row.Scan(
+		&subscription.UUID,
+		&subscription.URL,
+		&events,
+		&subscription.Secret,
+		&subscription.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	for _, event := range events {
+		subscription.Events = append(subscription.Events, WebhookEventType(event))
+	}
+	subscription.CreatedAt = subscription.CreatedAt.UTC()
+	return &subscription, nil
+}
+
+func (s *PostgresWebhookStore) CreateSubscription(ctx context.Context, subscription *WebhookSubscription) error {
+	events := make([]string, 0, len(subscription.Events))
+	for _, event := range subscription.Events {
+		events = append(events, string(event))
+	}
+	_, err := s.pool.Exec(ctx,
+		"INSERT INTO webhook_subscriptions ("+subscriptionColumns+") VALUES ($1, $2, $3, $4, $5)",
+		subscription.UUID,
+		subscription.URL,
+		events,
+		subscription.Secret,
+		subscription.CreatedAt,
+	)
+	return err
+}
+
+func (s *PostgresWebhookStore) GetSubscription(ctx context.Context, uuid string) (*WebhookSubscription, error) {
+	return scanSubscription(s.pool.QueryRow(ctx,
+		"SELECT "+subscriptionColumns+" FROM webhook_subscriptions WHERE subscription_uuid = $1", uuid))
+}
+
+func (s *PostgresWebhookStore) ListSubscriptions(ctx context.Context) ([]*WebhookSubscription, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+subscriptionColumns+" FROM webhook_subscriptions ORDER BY created_at, subscription_uuid")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*WebhookSubscription, error) {
+		return scanSubscription(row)
+	})
+}
+
+// DeleteSubscription удаляет подписку; доставки удаляются каскадно
+func (s *PostgresWebhookStore) DeleteSubscription(ctx context.Context, uuid string) error {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM webhook_subscriptions WHERE subscription_uuid = $1", uuid)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+const deliveryColumns = `delivery_uuid, subscription_uuid, event_id, event_type, order_uuid, payload, status,
+	attempts, response_status, last_error, created_at, last_attempt_at, next_attempt_at`
+
+func scanDelivery(row pgx.Row) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	if err := row.Scan(
+		&delivery.UUID,
+		&delivery.SubscriptionUUID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.OrderUUID,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&delivery.LastAttemptAt,
+		&delivery.NextAttemptAt,
+	); err != nil {
+		return nil, err
+	}
+	delivery.CreatedAt = delivery.CreatedAt.UTC()
+	return &delivery, nil
+}
+
+func collectDeliveries(rows pgx.Rows) ([]*WebhookDelivery, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*WebhookDelivery, error) {
+		return scanDelivery(row)
+	})
+}
+
+// EnqueueDeliveries сохраняет доставки одним пакетом запросов; доставки удаленных
+// к этому моменту подписок пропускаются
+func (s *PostgresWebhookStore) EnqueueDeliveries(ctx context.Context, deliveries []*WebhookDelivery) error {
+	batch := &pgx.Batch{}
+	for _, d := range deliveries {
+		batch.Queue(`
+			INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+			SELECT $1::text, $2::text, $3::text, $4::text, $5::text, $6::bytea, $7::text, $8::integer,
+			       $9::integer, $10::text, $11::timestamptz, $12::timestamptz, $13::timestamptz
+			WHERE EXISTS (SELECT 1 FROM webhook_subscriptions WHERE subscription_uuid = $2::text)`,
+			d.UUID, d.SubscriptionUUID, d.EventID, d.EventType, d.OrderUUID, d.Payload, d.Status,
+			d.Attempts, d.ResponseStatus, d.LastError, d.CreatedAt, d.LastAttemptAt, d.NextAttemptAt)
+	}
+	return s.pool.SendBatch(ctx, batch).Close()
+}
+
+// ClaimDueDeliveries откладывает выбранные доставки одним UPDATE; SKIP LOCKED не дает
+// двум экземплярам сервиса выбрать одни и те же строки
+func (s *PostgresWebhookStore) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*WebhookDelivery, error) {
+	rows, err := s.pool.Query(ctx, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE delivery_uuid IN (
+			SELECT delivery_uuid FROM webhook_deliveries
+			WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns,
+		now, now.Add(lease), WebhookDeliveryPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectDeliveries(rows)
+}
+
+func (s *PostgresWebhookStore) UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, response_status = $4, last_error = $5,
+		    last_attempt_at = $6, next_attempt_at = $7
+		WHERE delivery_uuid = $1`,
+		delivery.UUID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseStatus,
+		delivery.LastError,
+		delivery.LastAttemptAt,
+		delivery.NextAttemptAt,
+	)
+	return err
+}
+
+func (s *PostgresWebhookStore) ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]*WebhookDelivery, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE subscription_uuid = $1 AND ($2::text = '' OR status = $2)
+		ORDER BY created_at DESC, delivery_uuid DESC
+		LIMIT $3`,
+		filter.SubscriptionUUID, filter.Status, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectDeliveries(rows)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errWebhookTargetForbidden — адрес подписки указывает во внутреннюю сеть сервиса
+var errWebhookTargetForbidden = errors.New("webhook target resolves to a loopback, private or link-local address")
+
+// sharedAddressSpace — 100.64.0.0/10 (RFC 6598), адреса за NAT провайдера
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// forbiddenWebhookAddr сообщает, что по адресу нельзя доставлять события без разрешения
+// в ORDER_WEBHOOK_ALLOWED_HOSTS: иначе подписка позволила бы обращаться к внутренним сервисам
+// и метаданным облака от имени Order Service
+func forbiddenWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr)
+}
+
+// parseWebhookAllowedHosts разбирает ORDER_WEBHOOK_ALLOWED_HOSTS: имена хостов или IP-адреса
+// через запятую, которым разрешено получать события по внутренним адресам (например, localhost
+// для локального получателя)
+func parseWebhookAllowedHosts(value string) []string {
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// webhookTargets проверяет адреса получателей событий
+type webhookTargets struct {
+	allowed  map[string]bool
+	resolver *net.Resolver
+}
+
+func newWebhookTargets(allowedHosts []string) *webhookTargets {
+	targets := &webhookTargets{allowed: make(map[string]bool, len(allowedHosts)), resolver: net.DefaultResolver}
+	for _, host := range allowedHosts {
+		targets.allowed[strings.ToLower(host)] = true
+	}
+	return targets
+}
+
+func (t *webhookTargets) isAllowed(host string) bool {
+	return t.allowed[strings.ToLower(strings.Trim(host, "[]"))]
+}
+
+// check проверяет хост подписки при ее создании: все его адреса должны быть публичными.
+// DNS может вернуть другой адрес при доставке, поэтому ее соединения проверяются повторно в dialContext.
+func (t *webhookTargets) check(ctx context.Context, host string) error {
+	if t.isAllowed(host) {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		if forbiddenWebhookAddr(addr) {
+			return errWebhookTargetForbidden
+		}
+		return nil
+	}
+
+	addrs, err := t.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve webhook host %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if forbiddenWebhookAddr(addr) {
+			return errWebhookTargetForbidden
+		}
+	}
+	return nil
+}
+
+// dialContext устанавливает соединение с получателем и отклоняет его, если адрес, в который
+// разрешился хост, внутренний. Проверка при соединении защищает и от смены DNS-записи после
+// создания подписки, и от редиректов на внутренние адреса.
+func (t *webhookTargets) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: webhookRequestTimeout, KeepAlive: 30 * time.Second}
+	if host, _, err := net.SplitHostPort(address); err != nil || !t.isAllowed(host) {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if forbiddenWebhookAddr(addrPort.Addr()) {
+				return fmt.Errorf("dial %s: %w", address, errWebhookTargetForbidden)
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// client возвращает HTTP-клиент доставки событий. Прокси из окружения не используется:
+// соединение через него обошло бы проверку адреса получателя.
+func (t *webhookTargets) client() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = t.dialContext
+	return &http.Client{Timeout: webhookRequestTimeout, Transport: transport}
+}
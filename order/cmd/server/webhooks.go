@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	orderv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/openapi/order/v1"
+)
+
+// webhookSecretBytes — длина секрета подписи, который генерируется для подписки без своего секрета
+const webhookSecretBytes = 32
+
+// CreateWebhookSubscription registers a URL for order events
+func (h *OrderHandler) CreateWebhookSubscription(ctx context.Context, req *orderv1.CreateWebhookSubscriptionRequest) (orderv1.CreateWebhookSubscriptionRes, error) {
+	target := req.GetURL()
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return badRequestError("url must be an absolute http or https URL"), nil
+	}
+	if err := h.webhooks.targets.check(ctx, target.Hostname()); err != nil {
+		if errors.Is(err, errWebhookTargetForbidden) {
+			return badRequestError("url must not point to a loopback, private or link-local address"), nil
+		}
+		return badRequestError("url host cannot be resolved"), nil
+	}
+
+	secret, ok := req.GetSecret().Get()
+	if !ok {
+		raw := make([]byte, webhookSecretBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(raw)
+	}
+
+	subscription := &WebhookSubscription{
+		UUID:      uuid.New().String(),
+		URL:       target.String(),
+		Secret:    secret,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	for _, event := range req.GetEvents() {
+		subscription.Events = append(subscription.Events, WebhookEventType(event))
+	}
+	if err := h.webhooks.store.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	// Секрет возвращается только здесь: в списке подписок его нет
+	dto := toWebhookSubscriptionDTO(subscription)
+	dto.Secret = orderv1.NewOptString(subscription.Secret)
+	return &dto, nil
+}
+
+// ListWebhookSubscriptions lists webhook subscriptions
+func (h *OrderHandler) ListWebhookSubscriptions(ctx context.Context) (orderv1.ListWebhookSubscriptionsRes, error) {
+	subscriptions, err := h.webhooks.store.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &orderv1.ListWebhookSubscriptionsResponse{
+		Subscriptions: make([]orderv1.WebhookSubscription, 0, len(subscriptions)),
+	}
+	for _, subscription := range subscriptions {
+		resp.Subscriptions = append(resp.Subscriptions, toWebhookSubscriptionDTO(subscription))
+	}
+	return resp, nil
+}
+
+// DeleteWebhookSubscription deletes a subscription together with its delivery log
+func (h *OrderHandler) DeleteWebhookSubscription(ctx context.Context, params orderv1.DeleteWebhookSubscriptionParams) (orderv1.DeleteWebhookSubscriptionRes, error) {
+	err := h.webhooks.store.DeleteSubscription(ctx, params.SubscriptionUUID.String())
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		return subscriptionNotFoundError(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &orderv1.DeleteWebhookSubscriptionNoContent{}, nil
+}
+
+// ListWebhookDeliveries returns the delivery log of a subscription, newest first
+func (h *OrderHandler) ListWebhookDeliveries(ctx context.Context, params orderv1.ListWebhookDeliveriesParams) (orderv1.ListWebhookDeliveriesRes, error) {
+	subscriptionUUID := params.SubscriptionUUID.String()
+	_, err := h.webhooks.store.GetSubscription(ctx, subscriptionUUID)
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		return subscriptionNotFoundError(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := h.webhooks.store.ListDeliveries(ctx, WebhookDeliveryFilter{
+		SubscriptionUUID: subscriptionUUID,
+		Status:           WebhookDeliveryStatus(params.Status.Or("")),
+		Limit:            params.Limit.Or(defaultListLimit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &orderv1.ListWebhookDeliveriesResponse{
+		Deliveries: make([]orderv1.WebhookDelivery, 0, len(deliveries)),
+	}
+	for _, delivery := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, toWebhookDeliveryDTO(delivery))
+	}
+	return resp, nil
+}
+
+func subscriptionNotFoundError() *orderv1.NotFoundError {
+	return &orderv1.NotFoundError{
+		Code:    orderv1.NewOptInt(http.StatusNotFound),
+		Message: orderv1.NewOptString(ErrSubscriptionNotFound.Error()),
+	}
+}
+
+func toWebhookSubscriptionDTO(subscription *WebhookSubscription) orderv1.WebhookSubscription {
+	dto := orderv1.WebhookSubscription{
+		SubscriptionUUID: parseUUID(subscription.UUID),
+		Events:           make([]orderv1.WebhookEventType, 0, len(subscription.Events)),
+		CreatedAt:        subscription.CreatedAt,
+	}
+	// Адрес проверен при создании подписки
+	if target, err := url.Parse(subscription.URL); err == nil {
+		dto.URL = *target
+	}
+	for _, event := range subscription.Events {
+		dto.Events = append(dto.Events, orderv1.WebhookEventType(event))
+	}
+	return dto
+}
+
+func toWebhookDeliveryDTO(delivery *WebhookDelivery) orderv1.WebhookDelivery {
+	dto := orderv1.WebhookDelivery{
+		DeliveryUUID: parseUUID(delivery.UUID),
+		EventID:      parseUUID(delivery.EventID),
+		EventType:    orderv1.WebhookEventType(delivery.EventType),
+		OrderUUID:    parseUUID(delivery.OrderUUID),
+		Status:       orderv1.WebhookDeliveryStatus(delivery.Status),
+		Attempts:     delivery.Attempts,
+		CreatedAt:    delivery.CreatedAt,
+	}
+	if delivery.ResponseStatus != nil {
+		dto.ResponseStatus = orderv1.NewOptInt(*delivery.ResponseStatus)
+	}
+	if delivery.LastError != nil {
+		dto.LastError = orderv1.NewOptString(*delivery.LastError)
+	}
+	if delivery.LastAttemptAt != nil {
+		dto.LastAttemptAt = orderv1.NewOptDateTime(*delivery.LastAttemptAt)
+	}
+	if delivery.NextAttemptAt != nil && delivery.Status == WebhookDeliveryPending {
+		dto.NextAttemptAt = orderv1.NewOptDateTime(*delivery.NextAttemptAt)
+	}
+	return dto
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const (
+	// defaultAuthorizationTTL — срок действия авторизации, если PAYMENT_AUTHORIZATION_TTL не задан.
+	// Сборка корабля может занимать дни, поэтому сумма блокируется на неделю.
+	defaultAuthorizationTTL = 7 * 24 * time.Hour
+	// defaultVoidInterval — период проверки истекших авторизаций, если PAYMENT_VOID_INTERVAL не задан
+	defaultVoidInterval = time.Minute
+	// voidBatchSize — сколько истекших авторизаций аннулируется за один запрос к хранилищу
+	voidBatchSize = 100
+)
+
+// isAuthorization сообщает, создана ли транзакция AuthorizePayment: только у авторизаций задан срок действия
+func isAuthorization(transaction *paymentv1.Transaction) bool {
+	return transaction.GetExpiresAt() != nil
+}
+
+// AuthorizePayment блокирует сумму заказа до now + authorizationTTL
+func (s *paymentService) AuthorizePayment(ctx context.Context, req *paymentv1.AuthorizePaymentRequest) (*paymentv1.AuthorizePaymentResponse, error) {
+	expiresAt := timestamppb.New(time.Now().Add(s.authorizationTTL).UTC().Truncate(time.Microsecond))
+	transaction, err := s.createTransaction(ctx, req, paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if transaction.GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING {
+		log.Printf("Авторизация по заказу %s ожидает подтверждения провайдера, transaction_uuid: %s",
+			req.GetOrderUuid(), transaction.GetTransactionUuid())
+	} else {
+		log.Printf("Сумма %d.%09d %s заблокирована по заказу %s, transaction_uuid: %s, до %s",
+			req.GetAmount().GetUnits(), req.GetAmount().GetNanos(), req.GetCurrency(), req.GetOrderUuid(),
+			transaction.GetTransactionUuid(), transaction.GetExpiresAt().AsTime().Format(time.RFC3339))
+	}
+
+	return &paymentv1.AuthorizePaymentResponse{
+		TransactionUuid: transaction.GetTransactionUuid(),
+		Transaction:     transaction,
+	}, nil
+}
+
+// CapturePayment списывает авторизованную сумму, если срок авторизации не истек
+func (s *paymentService) CapturePayment(ctx context.Context, req *paymentv1.CapturePaymentRequest) (*paymentv1.CapturePaymentResponse, error) {
+	if err := validate(transactionRequest(req), transactionRules); err != nil {
+		return nil, err
+	}
+
+	captured, err := s.runOperation(ctx, req.GetTransactionUuid(), providerOperation{
+		pending: &paymentv1.PendingOperation{Type: paymentv1.OperationType_OPERATION_TYPE_CAPTURE},
+		begin: func(transaction *paymentv1.Transaction) (bool, error) {
+			switch transaction.GetStatus() {
+			case paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED:
+				if !transaction.GetExpiresAt().AsTime().After(time.Now()) {
+					return false, status.Errorf(codes.FailedPrecondition, "authorization %s has expired", req.GetTransactionUuid())
+				}
+				return true, nil
+			case paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED,
+				paymentv1.TransactionStatus_TRANSACTION_STATUS_PARTIALLY_REFUNDED,
+				paymentv1.TransactionStatus_TRANSACTION_STATUS_REFUNDED:
+				// Уже списано: повтор возвращает транзакцию без изменений
+				return false, nil
+			default:
+				return false, status.Errorf(codes.FailedPrecondition,
+					"transaction %s is %s and cannot be captured", req.GetTransactionUuid(), transaction.GetStatus())
+			}
+		},
+	})
+	if err != nil {
+		return nil, transactionUpdateError(req.GetTransactionUuid(), err)
+	}
+
+	log.Printf("Списана авторизация %s по заказу %s", captured.GetTransactionUuid(), captured.GetOrderUuid())
+	return &paymentv1.CapturePaymentResponse{Transaction: captured}, nil
+}
+
+// VoidAuthorization снимает блокировку суммы без списания
+func (s *paymentService) VoidAuthorization(ctx context.Context, req *paymentv1.VoidAuthorizationRequest) (*paymentv1.VoidAuthorizationResponse, error) {
+	if err := validate(transactionRequest(req), transactionRules); err != nil {
+		return nil, err
+	}
+
+	voided, err := s.voidAuthorization(ctx, req.GetTransactionUuid(), req.GetReason())
+	if err != nil {
+		return nil, transactionUpdateError(req.GetTransactionUuid(), err)
+	}
+	return &paymentv1.VoidAuthorizationResponse{Transaction: voided}, nil
+}
+
+func (s *paymentService) voidAuthorization(ctx context.Context, transactionUUID, reason string) (*paymentv1.Transaction, error) {
+	voided, err := s.runOperation(ctx, transactionUUID, providerOperation{
+		pending: &paymentv1.PendingOperation{Type: paymentv1.OperationType_OPERATION_TYPE_VOID},
+		begin: func(transaction *paymentv1.Transaction) (bool, error) {
+			switch transaction.GetStatus() {
+			case paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED,
+				paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING:
+				return true, nil
+			case paymentv1.TransactionStatus_TRANSACTION_STATUS_VOIDED:
+				// Уже аннулирована: повтор возвращает транзакцию без изменений
+				return false, nil
+			default:
+				return false, status.Errorf(codes.FailedPrecondition,
+					"transaction %s is %s and cannot be voided", transactionUUID, transaction.GetStatus())
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Авторизация %s по заказу %s аннулирована, причина: %q", transactionUUID, voided.GetOrderUuid(), reason)
+	return voided, nil
+}
+
+// transactionUpdateError переводит ошибку UpdateTransaction или runOperation в gRPC-статус.
+// Статусы, которые вернула updateFunc, и ошибки провайдера передаются как есть.
+func transactionUpdateError(transactionUUID string, err error) error {
+	if errors.Is(err, ErrTransactionNotFound) {
+		return status.Errorf(codes.NotFound, "transaction %s not found", transactionUUID)
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return storeError(err)
+}
+
+// voidExpiredAuthorizations раз в interval аннулирует авторизации с истекшим сроком, пока ctx не отменен
+func (s *paymentService) voidExpiredAuthorizations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.voidExpiredBatches(ctx)
+		}
+	}
+}
+
+// voidExpiredBatches аннулирует истекшие авторизации пачками по voidBatchSize.
+// Авторизация, которую успели списать после выборки, пропускается.
+func (s *paymentService) voidExpiredBatches(ctx context.Context) {
+	for {
+		uuids, err := s.store.ListExpiredAuthorizations(ctx, time.Now(), voidBatchSize)
+		if err != nil {
+			log.Printf("failed to list expired authorizations: %v", err)
+			return
+		}
+
+		processed := 0
+		for _, transactionUUID := range uuids {
+			voided, err := s.voidAuthorization(ctx, transactionUUID, "authorization expired")
+			if err != nil && status.Code(err) != codes.FailedPrecondition {
+				log.Printf("failed to void expired authorization %s: %v", transactionUUID, err)
+				continue
+			}
+			if err == nil {
+				// Заказ, ожидающий подтверждения платежа, должен вернуться к оплате
+				s.notifier.Notify(voided)
+			}
+			processed++
+		}
+
+		// Если пачка неполная или ни одну авторизацию не удалось обработать, ждем следующего тика
+		if len(uuids) < voidBatchSize || processed == 0 {
+			return
+		}
+	}
+}
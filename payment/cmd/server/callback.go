@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const (
+	// httpPort — порт HTTP-сервера для callback'ов провайдеров
+	httpPort = 8082
+
+	// signatureHeader содержит hex(HMAC-SHA256(тело запроса)) с общим секретом провайдера
+	signatureHeader = "X-Signature"
+	// maxCallbackBodySize ограничивает тело callback'а
+	maxCallbackBodySize = 1 << 20
+)
+
+// Итоги асинхронной операции в callback'е провайдера
+const (
+	CallbackStatusSucceeded = "SUCCEEDED"
+	CallbackStatusFailed    = "FAILED"
+)
+
+// ProviderCallback — уведомление провайдера о завершении асинхронной операции
+type ProviderCallback struct {
+	Reference   string `json:"reference"`
+	Status      string `json:"status"`
+	DeclineCode string `json:"decline_code,omitempty"`
+}
+
+// callbackSecret возвращает секрет подписи callback'ов из PAYMENT_CALLBACK_SECRET.
+// Если переменная не задана, генерируется случайный секрет: его знает только встроенный симулятор.
+func callbackSecret() ([]byte, error) {
+	if secret := os.Getenv("PAYMENT_CALLBACK_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate callback secret: %w", err)
+	}
+	log.Println("PAYMENT_CALLBACK_SECRET is not set, callbacks are accepted only from the built-in simulator")
+	return secret, nil
+}
+
+// signCallback подписывает тело callback'а секретом провайдера
+func signCallback(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignature сравнивает подпись за постоянное время
+func verifySignature(secret, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// callbackError — тело ответа на отклоненный callback
+type callbackError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeCallbackError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(callbackError{Code: code, Message: message})
+}
+
+// newCallbackRouter регистрирует HTTP-маршруты payment service
+func (s *paymentService) newCallbackRouter() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/providers/{provider}/callbacks", s.handleProviderCallback)
+	return mux
+}
+
+// handleProviderCallback принимает итог асинхронной операции от провайдера.
+// Повторный callback с тем же итогом подтверждается без изменений, противоречащий ему — 409.
+func (s *paymentService) handleProviderCallback(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackBodySize))
+	if err != nil {
+		writeCallbackError(w, http.StatusRequestEntityTooLarge, "request body is too large")
+		return
+	}
+	if !verifySignature(s.callbackSecret, body, r.Header.Get(signatureHeader)) {
+		writeCallbackError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+
+	var callback ProviderCallback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		writeCallbackError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if callback.Reference == "" {
+		writeCallbackError(w, http.StatusBadRequest, "reference is required")
+		return
+	}
+	if callback.Status != CallbackStatusSucceeded && callback.Status != CallbackStatusFailed {
+		writeCallbackError(w, http.StatusBadRequest, fmt.Sprintf("status must be %s or %s", CallbackStatusSucceeded, CallbackStatusFailed))
+		return
+	}
+
+	transaction, changed, err := s.completePayment(r.Context(), provider, callback)
+	switch {
+	case errors.Is(err, ErrTransactionNotFound):
+		writeCallbackError(w, http.StatusNotFound, fmt.Sprintf("operation %s not found", callback.Reference))
+		return
+	case status.Code(err) == codes.FailedPrecondition:
+		log.Printf("Callback %s провайдера %s отклонен: %v", callback.Reference, provider, err)
+		writeCallbackError(w, http.StatusConflict, status.Convert(err).Message())
+		return
+	case err != nil:
+		log.Printf("failed to apply callback %s: %v", callback.Reference, err)
+		writeCallbackError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	if changed {
+		log.Printf("Транзакция %s по заказу %s завершена провайдером: %s",
+			transaction.GetTransactionUuid(), transaction.GetOrderUuid(), transaction.GetStatus())
+		s.notifier.Notify(transaction)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// completePayment переводит ожидающую транзакцию в итоговый статус по callback'у:
+// успех — в AUTHORIZED для авторизации и SUCCEEDED для списания, отказ — в FAILED.
+// changed сообщает, изменилась ли транзакция.
+func (s *paymentService) completePayment(ctx context.Context, provider string, callback ProviderCallback) (*paymentv1.Transaction, bool, error) {
+	pending, err := s.store.GetTransactionByReference(ctx, provider, callback.Reference)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var (
+		completed *paymentv1.Transaction
+		changed   bool
+	)
+	err = s.store.UpdateTransaction(ctx, pending.GetTransactionUuid(), func(transaction *paymentv1.Transaction) error {
+		completed = transaction
+		succeeded := callback.Status == CallbackStatusSucceeded
+
+		if transaction.GetStatus() != paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING {
+			// Повтор callback'а: итог уже применен
+			if succeeded == (transaction.GetStatus() != paymentv1.TransactionStatus_TRANSACTION_STATUS_FAILED &&
+				transaction.GetStatus() != paymentv1.TransactionStatus_TRANSACTION_STATUS_VOIDED) {
+				return nil
+			}
+			return status.Errorf(codes.FailedPrecondition,
+				"transaction %s is %s and cannot be completed as %s", transaction.GetTransactionUuid(), transaction.GetStatus(), callback.Status)
+		}
+
+		switch {
+		case !succeeded:
+			transaction.Status = paymentv1.TransactionStatus_TRANSACTION_STATUS_FAILED
+			transaction.DeclineCode = callback.DeclineCode
+		case isAuthorization(transaction):
+			transaction.Status = paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED
+		default:
+			transaction.Status = paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED
+		}
+		transaction.UpdatedAt = now()
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return completed, changed, nil
+}
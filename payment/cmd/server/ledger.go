@@ -0,0 +1,309 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// Счета главной книги. У каждого покупателя свой счет customer:<user_uuid>.
+// Сумма балансов merchant и refunds — выручка за вычетом возвратов, fees — комиссии провайдеров.
+const (
+	accountMerchant       = "merchant"
+	accountRefunds        = "refunds"
+	accountFees           = "fees"
+	customerAccountPrefix = "customer:"
+)
+
+const (
+	// feeBasisPoints — комиссия провайдера с оплаты в сотых долях процента (1,5%).
+	// Комиссия округляется вниз до копейки и при возврате не возвращается.
+	feeBasisPoints = 150
+
+	// defaultLedgerCheckInterval — период проверки инварианта главной книги,
+	// если PAYMENT_LEDGER_CHECK_INTERVAL не задан
+	defaultLedgerCheckInterval = time.Hour
+)
+
+func customerAccount(userUUID string) string {
+	return customerAccountPrefix + userUUID
+}
+
+// charged — статусы, в которых сумма транзакции списана с покупателя
+var charged = map[paymentv1.TransactionStatus]bool{
+	paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED:          true,
+	paymentv1.TransactionStatus_TRANSACTION_STATUS_PARTIALLY_REFUNDED: true,
+	paymentv1.TransactionStatus_TRANSACTION_STATUS_REFUNDED:           true,
+}
+
+// ledgerEntries возвращает проводки, которыми записывается изменение транзакции before -> after
+// (before == nil для новой транзакции). Хранилище сохраняет их атомарно вместе с транзакцией,
+// поэтому в книгу попадает каждое движение денег независимо от того, какой вызов его выполнил:
+//   - списание (PayOrder, CapturePayment, callback провайдера): кредит покупателя,
+//     дебет merchant за вычетом комиссии и дебет fees на сумму комиссии;
+//   - возврат: дебет покупателя и кредит refunds на сумму возврата.
+func ledgerEntries(before, after *paymentv1.Transaction) []*paymentv1.LedgerEntry {
+	var entries []*paymentv1.LedgerEntry
+	createdAt := after.GetUpdatedAt()
+	customer := customerAccount(after.GetUserUuid())
+
+	if charged[after.GetStatus()] && (before == nil || !charged[before.GetStatus()]) {
+		amount := toNanos(after.GetAmount())
+		fee := paymentFee(amount)
+		entries = append(entries, &paymentv1.LedgerEntry{
+			EntryUuid:       uuid.New().String(),
+			TransactionUuid: after.GetTransactionUuid(),
+			Type:            paymentv1.LedgerEntryType_LEDGER_ENTRY_TYPE_PAYMENT,
+			Currency:        after.GetCurrency(),
+			Postings: postings(
+				posting{customer, -amount},
+				posting{accountMerchant, amount - fee},
+				posting{accountFees, fee},
+			),
+			CreatedAt: createdAt,
+		})
+	}
+
+	refunded := toNanos(after.GetRefundedAmount())
+	if before != nil {
+		refunded -= toNanos(before.GetRefundedAmount())
+	}
+	if refunded > 0 {
+		entries = append(entries, &paymentv1.LedgerEntry{
+			EntryUuid:       uuid.New().String(),
+			TransactionUuid: after.GetTransactionUuid(),
+			Type:            paymentv1.LedgerEntryType_LEDGER_ENTRY_TYPE_REFUND,
+			Currency:        after.GetCurrency(),
+			Postings: postings(
+				posting{customer, refunded},
+				posting{accountRefunds, -refunded},
+			),
+			CreatedAt: createdAt,
+		})
+	}
+	return entries
+}
+
+// posting — сторона проводки с суммой в миллиардных долях
+type posting struct {
+	account string
+	amount  int64
+}
+
+// postings переводит стороны проводки в proto, пропуская нулевые суммы
+func postings(sides ...posting) []*paymentv1.Posting {
+	result := make([]*paymentv1.Posting, 0, len(sides))
+	for _, side := range sides {
+		if side.amount != 0 {
+			result = append(result, &paymentv1.Posting{Account: side.account, Amount: fromNanos(side.amount)})
+		}
+	}
+	return result
+}
+
+// paymentFee считает комиссию провайдера с суммы в миллиардных долях, округляя вниз до копейки
+func paymentFee(amount int64) int64 {
+	const nanosPerKopeck = nanosPerUnit / 100
+	return amount / nanosPerKopeck * feeBasisPoints / 10_000 * nanosPerKopeck
+}
+
+// LedgerFilter описывает выборку проводок для ListLedgerEntries.
+// Пустые поля не ограничивают выборку; проводки упорядочены от новых к старым.
+type LedgerFilter struct {
+	Account         string
+	TransactionUUID string
+
+	Limit int
+	After *PageCursor
+}
+
+// LedgerPage — страница результата ListLedgerEntries
+type LedgerPage struct {
+	Entries    []*paymentv1.LedgerEntry
+	NextCursor *PageCursor
+}
+
+func entryCursorFor(entry *paymentv1.LedgerEntry) *PageCursor {
+	return &PageCursor{
+		CreatedAt: entry.GetCreatedAt().AsTime(),
+		UUID:      entry.GetEntryUuid(),
+	}
+}
+
+// compareEntries задает порядок выдачи проводок: от новых к старым, при равном времени — по убыванию UUID
+func compareEntries(a, b *paymentv1.LedgerEntry) int {
+	if result := b.GetCreatedAt().AsTime().Compare(a.GetCreatedAt().AsTime()); result != 0 {
+		return result
+	}
+	return cmp.Compare(b.GetEntryUuid(), a.GetEntryUuid())
+}
+
+func (f LedgerFilter) matches(entry *paymentv1.LedgerEntry) bool {
+	if f.TransactionUUID != "" && entry.GetTransactionUuid() != f.TransactionUUID {
+		return false
+	}
+	if f.Account == "" {
+		return true
+	}
+	for _, posting := range entry.GetPostings() {
+		if posting.GetAccount() == f.Account {
+			return true
+		}
+	}
+	return false
+}
+
+// afterCursor сообщает, идет ли проводка строго после курсора
+func (f LedgerFilter) afterCursor(entry *paymentv1.LedgerEntry) bool {
+	if f.After == nil {
+		return true
+	}
+	createdAt := entry.GetCreatedAt().AsTime()
+	if !createdAt.Equal(f.After.CreatedAt) {
+		return createdAt.Before(f.After.CreatedAt)
+	}
+	return entry.GetEntryUuid() < f.After.UUID
+}
+
+// LedgerViolation — нарушение инварианта главной книги: ненулевая сумма сторон проводки
+// или, если EntryUUID пуст, ненулевая сумма всех проводок в валюте
+type LedgerViolation struct {
+	EntryUUID string
+	Currency  string
+	Sum       int64
+}
+
+func (v LedgerViolation) String() string {
+	if v.EntryUUID == "" {
+		return fmt.Sprintf("ledger total in %s is %s", v.Currency, formatNanos(v.Sum))
+	}
+	return fmt.Sprintf("entry %s is unbalanced by %s %s", v.EntryUUID, formatNanos(v.Sum), v.Currency)
+}
+
+// checkLedgerAccount проверяет формат счета
+func checkLedgerAccount(account string) error {
+	switch {
+	case account == accountMerchant, account == accountRefunds, account == accountFees:
+		return nil
+	case strings.HasPrefix(account, customerAccountPrefix):
+		if err := checkUUID(strings.TrimPrefix(account, customerAccountPrefix)); err != nil {
+			return errors.New("customer account must be customer:<user_uuid>")
+		}
+		return nil
+	default:
+		return fmt.Errorf("must be %s, %s, %s or %s<user_uuid>", accountMerchant, accountRefunds, accountFees, customerAccountPrefix)
+	}
+}
+
+// accountBalanceRules описывают допустимый GetAccountBalanceRequest
+var accountBalanceRules = []fieldRule[*paymentv1.GetAccountBalanceRequest]{
+	{"account", func(req *paymentv1.GetAccountBalanceRequest) error { return checkLedgerAccount(req.GetAccount()) }},
+	{"currency", func(req *paymentv1.GetAccountBalanceRequest) error { return checkCurrency(req.GetCurrency()) }},
+}
+
+func (s *paymentService) GetAccountBalance(ctx context.Context, req *paymentv1.GetAccountBalanceRequest) (*paymentv1.GetAccountBalanceResponse, error) {
+	if err := validate(req, accountBalanceRules); err != nil {
+		return nil, err
+	}
+
+	balance, err := s.store.GetAccountBalance(ctx, req.GetAccount(), req.GetCurrency())
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return &paymentv1.GetAccountBalanceResponse{
+		Account:  req.GetAccount(),
+		Currency: req.GetCurrency(),
+		Balance:  fromNanos(balance),
+	}, nil
+}
+
+// ledgerFilterFromRequest разбирает и проверяет параметры ListLedgerEntries
+func ledgerFilterFromRequest(req *paymentv1.ListLedgerEntriesRequest) (LedgerFilter, error) {
+	filter := LedgerFilter{
+		Account:         req.GetFilter().GetAccount(),
+		TransactionUUID: req.GetFilter().GetTransactionUuid(),
+		Limit:           defaultPageSize,
+	}
+
+	if filter.Account != "" {
+		if err := checkLedgerAccount(filter.Account); err != nil {
+			return filter, fmt.Errorf("invalid account: %w", err)
+		}
+	}
+
+	switch size := req.GetPageSize(); {
+	case size == 0:
+	case size < 0 || size > maxPageSize:
+		return filter, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+	default:
+		filter.Limit = int(size)
+	}
+
+	if token := req.GetPageToken(); token != "" {
+		cursor, err := decodePageCursor(token)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = cursor
+	}
+
+	return filter, nil
+}
+
+func (s *paymentService) ListLedgerEntries(ctx context.Context, req *paymentv1.ListLedgerEntriesRequest) (*paymentv1.ListLedgerEntriesResponse, error) {
+	filter, err := ledgerFilterFromRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	page, err := s.store.ListLedgerEntries(ctx, filter)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	resp := &paymentv1.ListLedgerEntriesResponse{Entries: page.Entries}
+	if page.NextCursor != nil {
+		if resp.NextPageToken, err = pageToken(page.NextCursor); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// verifyLedger проверяет инвариант главной книги при старте и затем раз в interval, пока ctx не отменен
+func (s *paymentService) verifyLedger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.checkLedger(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkLedger логирует каждое нарушение инварианта: стороны каждой проводки
+// и все проводки в каждой валюте должны давать в сумме ноль
+func (s *paymentService) checkLedger(ctx context.Context) {
+	violations, err := s.store.LedgerViolations(ctx)
+	if err != nil {
+		log.Printf("failed to check ledger: %v", err)
+		return
+	}
+	for _, violation := range violations {
+		log.Printf("LEDGER INVARIANT VIOLATED: %s", violation)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// wantEntries — ожидаемые проводки транзакции: суммы сторон по счетам в миллиардных долях по типу проводки
+type wantEntries map[paymentv1.LedgerEntryType]map[string]int64
+
+// Авторизация, списание, частичный возврат, аннулирование и оплата без авторизации записываются
+// сбалансированными проводками: стороны каждой проводки в сумме дают ноль, а книга остается без нарушений
+func TestLedgerEntriesBalanced(t *testing.T) {
+	for name, newStores := range testStores {
+		t.Run(name, func(t *testing.T) {
+			store, wallets := newStores(t)
+			s := newTestService(t, store, wallets)
+			ctx := context.Background()
+			userUUID := newTestInvestor(t, s, 0)
+			customer := customerAccount(userUUID)
+
+			// 1000.37: комиссия 1,5% — 15.005 — округляется вниз до 15.00
+			captured := authorizeCard(t, s, userUUID, &paymentv1.Money{Units: 1000, Nanos: 370_000_000})
+			checkLedgerEntries(t, s, captured.GetTransactionUuid(), nil)
+
+			if _, err := s.CapturePayment(ctx, &paymentv1.CapturePaymentRequest{
+				TransactionUuid: captured.GetTransactionUuid(),
+			}); err != nil {
+				t.Fatalf("CapturePayment: %v", err)
+			}
+			if _, err := s.RefundPayment(ctx, &paymentv1.RefundPaymentRequest{
+				TransactionUuid: captured.GetTransactionUuid(),
+				Amount:          &paymentv1.Money{Units: 200, Nanos: 100_000_000},
+				Reason:          "partial refund",
+				IdempotencyKey:  uuid.NewString(),
+			}); err != nil {
+				t.Fatalf("RefundPayment: %v", err)
+			}
+			checkLedgerEntries(t, s, captured.GetTransactionUuid(), wantEntries{
+				paymentv1.LedgerEntryType_LEDGER_ENTRY_TYPE_PAYMENT: {
+					customer:        -1000_370_000_000,
+					accountMerchant: 985_370_000_000,
+					accountFees:     15_000_000_000,
+				},
+				paymentv1.LedgerEntryType_LEDGER_ENTRY_TYPE_REFUND: {
+					customer:       200_100_000_000,
+					accountRefunds: -200_100_000_000,
+				},
+			})
+
+			voided := authorizeCard(t, s, userUUID, rubles(400))
+			if _, err := s.VoidAuthorization(ctx, &paymentv1.VoidAuthorizationRequest{
+				TransactionUuid: voided.GetTransactionUuid(),
+				Reason:          "order cancelled",
+			}); err != nil {
+				t.Fatalf("VoidAuthorization: %v", err)
+			}
+			checkLedgerEntries(t, s, voided.GetTransactionUuid(), nil)
+
+			paid, err := s.PayOrder(ctx, &paymentv1.PayOrderRequest{
+				OrderUuid:     uuid.NewString(),
+				UserUuid:      userUUID,
+				PaymentMethod: paymentv1.PaymentMethod_PAYMENT_METHOD_CARD,
+				Amount:        rubles(50),
+				Currency:      testCurrency,
+			})
+			if err != nil {
+				t.Fatalf("PayOrder: %v", err)
+			}
+			checkLedgerEntries(t, s, paid.GetTransactionUuid(), wantEntries{
+				paymentv1.LedgerEntryType_LEDGER_ENTRY_TYPE_PAYMENT: {
+					customer:        -50_000_000_000,
+					accountMerchant: 49_250_000_000,
+					accountFees:     750_000_000,
+				},
+			})
+
+			balance, err := s.store.GetAccountBalance(ctx, customer, testCurrency)
+			if err != nil {
+				t.Fatalf("GetAccountBalance: %v", err)
+			}
+			if want := int64(-1000_370_000_000 + 200_100_000_000 - 50_000_000_000); balance != want {
+				t.Errorf("customer balance = %s, want %s", formatNanos(balance), formatNanos(want))
+			}
+
+			violations, err := s.store.LedgerViolations(ctx)
+			if err != nil {
+				t.Fatalf("LedgerViolations: %v", err)
+			}
+			if len(violations) > 0 {
+				t.Errorf("LedgerViolations = %v, want none", violations)
+			}
+		})
+	}
+}
+
+// authorizeCard блокирует сумму на карте пользователя через симулятор
+func authorizeCard(t *testing.T, s *paymentService, userUUID string, amount *paymentv1.Money) *paymentv1.Transaction {
+	t.Helper()
+
+	resp, err := s.AuthorizePayment(context.Background(), &paymentv1.AuthorizePaymentRequest{
+		OrderUuid:     uuid.NewString(),
+		UserUuid:      userUUID,
+		PaymentMethod: paymentv1.PaymentMethod_PAYMENT_METHOD_CARD,
+		Amount:        amount,
+		Currency:      testCurrency,
+	})
+	if err != nil {
+		t.Fatalf("AuthorizePayment: %v", err)
+	}
+	return resp.GetTransaction()
+}
+
+// checkLedgerEntries сверяет проводки транзакции с want и проверяет,
+// что стороны каждой из них в сумме дают ноль
+func checkLedgerEntries(t *testing.T, s *paymentService, transactionUUID string, want wantEntries) {
+	t.Helper()
+
+	page, err := s.store.ListLedgerEntries(context.Background(), LedgerFilter{TransactionUUID: transactionUUID, Limit: 100})
+	if err != nil {
+		t.Fatalf("ListLedgerEntries: %v", err)
+	}
+	if len(page.Entries) != len(want) {
+		t.Fatalf("transaction %s has %d ledger entries, want %d: %v", transactionUUID, len(page.Entries), len(want), page.Entries)
+	}
+
+	for _, entry := range page.Entries {
+		expected, ok := want[entry.GetType()]
+		if !ok || entry.GetCurrency() != testCurrency {
+			t.Errorf("unexpected entry %s of type %s in %s", entry.GetEntryUuid(), entry.GetType(), entry.GetCurrency())
+			continue
+		}
+
+		var sum int64
+		got := make(map[string]int64, len(entry.GetPostings()))
+		for _, posting := range entry.GetPostings() {
+			got[posting.GetAccount()] = toNanos(posting.GetAmount())
+			sum += toNanos(posting.GetAmount())
+		}
+		if sum != 0 {
+			t.Errorf("entry %s postings sum to %s, want 0", entry.GetEntryUuid(), formatNanos(sum))
+		}
+		if len(got) != len(expected) {
+			t.Errorf("%s entry postings = %v, want %v", entry.GetType(), got, expected)
+			continue
+		}
+		for account, amount := range expected {
+			if got[account] != amount {
+				t.Errorf("%s entry: %s posting = %s, want %s",
+					entry.GetType(), account, formatNanos(got[account]), formatNanos(amount))
+			}
+		}
+	}
+}
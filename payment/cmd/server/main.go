@@ -2,43 +2,372 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
 )
 
-const grpcPort = 50052
+const (
+	grpcPort = 50052
+
+	storageDriverMemory   = "memory"
+	storageDriverPostgres = "postgres"
+)
 
 type paymentService struct {
 	paymentv1.UnimplementedPaymentServiceServer
+
+	store            TransactionStore
+	wallets          WalletStore
+	providers        *ProviderRouter
+	rules            *RulesEngine
+	providerTimeout  time.Duration
+	authorizationTTL time.Duration
+	callbackSecret   []byte
+	notifier         *OrderNotifier
 }
 
-func (s *paymentService) PayOrder(ctx context.Context, req *paymentv1.PayOrderRequest) (*paymentv1.PayOrderResponse, error) {
-	_ = ctx // context используется для соответствия интерфейсу gRPC
-	_ = req // параметры могут использоваться в будущем (order_uuid, user_uuid, payment_method)
+// now возвращает текущее время с точностью timestamptz в PostgreSQL,
+// чтобы курсоры пагинации совпадали для обоих хранилищ
+func now() *timestamppb.Timestamp {
+	return timestamppb.New(time.Now().UTC().Truncate(time.Microsecond))
+}
 
-	// Генерируем UUID транзакции
-	transactionUUID := uuid.New().String()
+// storeError логирует ошибку хранилища и скрывает подробности от клиента
+func storeError(err error) error {
+	log.Printf("transaction store error: %v", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func (s *paymentService) PayOrder(ctx context.Context, req *paymentv1.PayOrderRequest) (*paymentv1.PayOrderResponse, error) {
+	transaction, err := s.createTransaction(ctx, req, paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	// Логируем в консоль
-	log.Printf("Оплата прошла успешно, transaction_uuid: %s", transactionUUID)
+	if transaction.GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING {
+		log.Printf("Оплата ожидает подтверждения провайдера, transaction_uuid: %s", transaction.GetTransactionUuid())
+	} else {
+		log.Printf("Оплата прошла успешно, transaction_uuid: %s, сумма: %d.%09d %s",
+			transaction.GetTransactionUuid(), req.GetAmount().GetUnits(), req.GetAmount().GetNanos(), req.GetCurrency())
+	}
 
-	// Возвращаем transaction_uuid
+	// Возвращаем transaction_uuid и запись транзакции
 	return &paymentv1.PayOrderResponse{
-		TransactionUuid: transactionUUID,
+		TransactionUuid: transaction.GetTransactionUuid(),
+		Transaction:     transaction,
 	}, nil
 }
 
+// createTransaction проверяет запрос и правила антифрода, проводит операцию у провайдера способа оплаты
+// и сохраняет транзакцию в статусе initial: AUTHORIZED — блокировка суммы, иначе — списание.
+// Если по заказу уже есть действующая транзакция, возвращается она (см. repeatedPayment).
+func (s *paymentService) createTransaction(ctx context.Context, req paymentRequest, initial paymentv1.TransactionStatus, expiresAt *timestamppb.Timestamp) (*paymentv1.Transaction, error) {
+	if err := validate(req, paymentRules); err != nil {
+		return nil, err
+	}
+
+	// Повтор по уже оплаченному заказу не доходит до провайдера
+	existing, err := s.store.GetTransactionByOrder(ctx, req.GetOrderUuid())
+	if err == nil {
+		return repeatedPayment(req, initial, existing)
+	}
+	if !errors.Is(err, ErrTransactionNotFound) {
+		return nil, storeError(err)
+	}
+
+	provider, err := s.providers.ForMethod(req.GetPaymentMethod())
+	if err != nil {
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	}
+
+	risk, err := s.rules.Evaluate(ctx, s.store, req)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	switch risk.GetDecision() {
+	case paymentv1.RiskDecision_RISK_DECISION_DENY:
+		return nil, s.denyPayment(ctx, req, risk, expiresAt)
+	case paymentv1.RiskDecision_RISK_DECISION_REVIEW:
+		log.Printf("Оплата заказа %s требует ручной проверки: %s", req.GetOrderUuid(), riskSummary(risk))
+	}
+
+	providerReq := ProviderRequest{
+		OrderUUID:     req.GetOrderUuid(),
+		UserUUID:      req.GetUserUuid(),
+		PaymentMethod: req.GetPaymentMethod(),
+		Amount:        req.GetAmount(),
+		Currency:      req.GetCurrency(),
+	}
+	authorize := initial == paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED
+
+	providerCtx, cancel := s.providerContext(ctx)
+	defer cancel()
+	var result ProviderResult
+	if authorize {
+		result, err = provider.Authorize(providerCtx, providerReq)
+	} else {
+		result, err = provider.Charge(providerCtx, providerReq)
+	}
+	if err != nil {
+		log.Printf("Провайдер %s не провел оплату заказа %s: %v", provider.Name(), req.GetOrderUuid(), err)
+		return nil, providerError(err)
+	}
+
+	// Асинхронная операция ждет callback провайдера
+	transactionStatus := initial
+	if result.Pending {
+		transactionStatus = paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING
+	}
+
+	// Сохраняем транзакцию: по ней оформляются возвраты и отчеты
+	createdAt := now()
+	transaction := &paymentv1.Transaction{
+		TransactionUuid:   uuid.New().String(),
+		OrderUuid:         req.GetOrderUuid(),
+		UserUuid:          req.GetUserUuid(),
+		PaymentMethod:     req.GetPaymentMethod(),
+		Amount:            req.GetAmount(),
+		Currency:          req.GetCurrency(),
+		RefundedAmount:    &paymentv1.Money{},
+		Status:            transactionStatus,
+		CreatedAt:         createdAt,
+		UpdatedAt:         createdAt,
+		ExpiresAt:         expiresAt,
+		Provider:          provider.Name(),
+		ProviderReference: result.Reference,
+		Risk:              risk,
+	}
+	err = s.store.CreateTransaction(ctx, transaction)
+	if errors.Is(err, ErrOrderAlreadyPaid) {
+		// Параллельный запрос по тому же заказу успел раньше: отменяем свою операцию у провайдера
+		s.cancelProviderOperation(ctx, provider, result.Reference, authorize || result.Pending, req.GetAmount())
+		existing, err := s.store.GetTransactionByOrder(ctx, req.GetOrderUuid())
+		if err != nil {
+			return nil, storeError(err)
+		}
+		return repeatedPayment(req, initial, existing)
+	}
+	if err != nil {
+		// Деньги у провайдера уже заблокированы или списаны (кошелек инвестора фиксирует это
+		// в своей транзакции), а записи о них нет: отменяем операцию, иначе они повиснут
+		log.Printf("Не удалось сохранить оплату заказа %s, операция %s %s отменяется: %v",
+			req.GetOrderUuid(), provider.Name(), result.Reference, err)
+		s.cancelProviderOperation(context.WithoutCancel(ctx), provider, result.Reference, authorize || result.Pending, req.GetAmount())
+		return nil, storeError(err)
+	}
+	return transaction, nil
+}
+
+// denyPayment сохраняет запрещенную правилами оплату отклоненной транзакцией, чтобы решение осталось
+// в истории пользователя, и возвращает отказ risk_denied с идентификаторами сработавших правил.
+// Отклоненная транзакция не занимает заказ: после изменения правил его можно оплатить снова.
+func (s *paymentService) denyPayment(ctx context.Context, req paymentRequest, risk *paymentv1.RiskAssessment, expiresAt *timestamppb.Timestamp) error {
+	createdAt := now()
+	transaction := &paymentv1.Transaction{
+		TransactionUuid: uuid.New().String(),
+		OrderUuid:       req.GetOrderUuid(),
+		UserUuid:        req.GetUserUuid(),
+		PaymentMethod:   req.GetPaymentMethod(),
+		Amount:          req.GetAmount(),
+		Currency:        req.GetCurrency(),
+		RefundedAmount:  &paymentv1.Money{},
+		Status:          paymentv1.TransactionStatus_TRANSACTION_STATUS_FAILED,
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt,
+		ExpiresAt:       expiresAt,
+		DeclineCode:     DeclineRiskDenied,
+		Risk:            risk,
+	}
+	if err := s.store.CreateTransaction(ctx, transaction); err != nil {
+		return storeError(err)
+	}
+
+	log.Printf("Правила запретили оплату заказа %s: %s, transaction_uuid: %s",
+		req.GetOrderUuid(), riskSummary(risk), transaction.GetTransactionUuid())
+	return providerError(&DeclineError{
+		Provider: rulesEngineName,
+		Code:     DeclineRiskDenied,
+		Metadata: map[string]string{
+			"rule_ids":         strings.Join(risk.GetRuleIds(), ","),
+			"transaction_uuid": transaction.GetTransactionUuid(),
+		},
+	})
+}
+
+// cancelProviderOperation снимает блокировку или возвращает списание, которое не удалось сохранить:
+// заказ уже оплачен параллельным запросом или хранилище вернуло ошибку. Ошибка отмены только логируется.
+func (s *paymentService) cancelProviderOperation(ctx context.Context, provider Provider, reference string, authorized bool, amount *paymentv1.Money) {
+	providerCtx, cancel := s.providerContext(ctx)
+	defer cancel()
+
+	var err error
+	if authorized {
+		err = provider.Void(providerCtx, reference)
+	} else {
+		// Списание возвращается целиком, поэтому ключом идемпотентности служит сама операция
+		err = provider.Refund(providerCtx, reference, amount, reference)
+	}
+	if err != nil {
+		log.Printf("failed to cancel unsaved %s operation %s: %v", provider.Name(), reference, err)
+	}
+}
+
+// repeatedPayment обрабатывает повторную оплату или авторизацию уже оплаченного заказа: например, ретрай
+// Order Service после таймаута. Если плательщик и сумма совпадают, возвращается исходная транзакция,
+// иначе запрос отклоняется с FailedPrecondition. Несписанная авторизация не засчитывается как оплата.
+func repeatedPayment(req paymentRequest, requested paymentv1.TransactionStatus, transaction *paymentv1.Transaction) (*paymentv1.Transaction, error) {
+	if transaction.GetUserUuid() != req.GetUserUuid() ||
+		toNanos(transaction.GetAmount()) != toNanos(req.GetAmount()) ||
+		transaction.GetCurrency() != req.GetCurrency() {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"order %s is already paid by transaction %s with a different user or amount",
+			req.GetOrderUuid(), transaction.GetTransactionUuid())
+	}
+	if requested != paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED && isAuthorization(transaction) &&
+		(transaction.GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED ||
+			transaction.GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING) {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"order %s has a pending authorization %s", req.GetOrderUuid(), transaction.GetTransactionUuid())
+	}
+
+	log.Printf("Повторная оплата заказа %s, возвращаем transaction_uuid: %s",
+		req.GetOrderUuid(), transaction.GetTransactionUuid())
+	return transaction, nil
+}
+
+// newStore создает хранилища транзакций и кошельков по переменным окружения:
+// PAYMENT_STORAGE_DRIVER (memory | postgres, по умолчанию memory) и PAYMENT_POSTGRES_DSN
+func newStore(ctx context.Context) (TransactionStore, WalletStore, error) {
+	driver := os.Getenv("PAYMENT_STORAGE_DRIVER")
+	switch driver {
+	case "", storageDriverMemory:
+		return NewInMemoryTransactionStore(), NewInMemoryWalletStore(), nil
+	case storageDriverPostgres:
+		dsn := os.Getenv("PAYMENT_POSTGRES_DSN")
+		if dsn == "" {
+			return nil, nil, errors.New("PAYMENT_POSTGRES_DSN is required for postgres storage")
+		}
+		store, err := NewPostgresTransactionStore(ctx, dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, NewPostgresWalletStore(store.pool), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// durationFromEnv читает длительность в формате time.ParseDuration, возвращая fallback для пустого значения
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", name, value)
+	}
+	return d, nil
+}
+
 func main() {
+	authorizationTTL, err := durationFromEnv("PAYMENT_AUTHORIZATION_TTL", defaultAuthorizationTTL)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	voidInterval, err := durationFromEnv("PAYMENT_VOID_INTERVAL", defaultVoidInterval)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	ledgerCheckInterval, err := durationFromEnv("PAYMENT_LEDGER_CHECK_INTERVAL", defaultLedgerCheckInterval)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	providerTimeout, err := durationFromEnv("PAYMENT_PROVIDER_TIMEOUT", defaultProviderTimeout)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	pendingOperationLease, err := durationFromEnv("PAYMENT_PENDING_OPERATION_LEASE", defaultPendingOperationLease)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	// Раньше истечения аренды операция могла еще не завершиться у провайдера
+	if pendingOperationLease <= providerTimeout {
+		log.Fatalf("invalid configuration: PAYMENT_PENDING_OPERATION_LEASE must exceed PAYMENT_PROVIDER_TIMEOUT")
+	}
+	simulatorLatency, err := durationFromEnv("PAYMENT_SIMULATOR_LATENCY", 0)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	simulatorCallbackDelay, err := durationFromEnv("PAYMENT_SIMULATOR_CALLBACK_DELAY", defaultSimulatorCallbackDelay)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	rulesReloadInterval, err := durationFromEnv("PAYMENT_RULES_RELOAD_INTERVAL", defaultRulesReloadInterval)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	secret, err := callbackSecret()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	callbackBaseURL := os.Getenv("PAYMENT_CALLBACK_BASE_URL")
+	if callbackBaseURL == "" {
+		callbackBaseURL = fmt.Sprintf("http://localhost:%d", httpPort)
+	}
+	// Пустое значение отключает уведомления Order Service
+	notificationURL, ok := os.LookupEnv("PAYMENT_ORDER_NOTIFICATION_URL")
+	if !ok {
+		notificationURL = defaultOrderNotificationURL
+	}
+
+	// Пустое значение отключает правила; без переменной берется файл по умолчанию, если он есть
+	rulesFile, ok := os.LookupEnv("PAYMENT_RULES_FILE")
+	if !ok {
+		rulesFile = defaultRulesFile
+		if _, err := os.Stat(rulesFile); errors.Is(err, fs.ErrNotExist) {
+			log.Printf("rules file %s not found, payments are not checked by rules", rulesFile)
+			rulesFile = ""
+		}
+	}
+	rules, err := NewRulesEngine(rulesFile)
+	if err != nil {
+		log.Fatalf("failed to load rules: %v", err)
+	}
+
+	initCtx, initCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	store, wallets, err := newStore(initCtx)
+	initCancel()
+	if err != nil {
+		log.Fatalf("failed to create transaction store: %v", err)
+	}
+	defer store.Close()
+
+	simulator := NewSimulatedProvider(simulatorLatency, simulatorCallbackDelay, callbackBaseURL, secret)
+	providers, err := NewProviderRouter(defaultProviderRoutes, simulator, NewWalletProvider(wallets))
+	if err != nil {
+		log.Fatalf("invalid provider routes: %v", err)
+	}
+
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
@@ -48,9 +377,29 @@ func main() {
 	s := grpc.NewServer()
 
 	// Создаем сервис
-	service := &paymentService{}
+	service := &paymentService{
+		store:            store,
+		wallets:          wallets,
+		providers:        providers,
+		rules:            rules,
+		providerTimeout:  providerTimeout,
+		authorizationTTL: authorizationTTL,
+		callbackSecret:   secret,
+		notifier:         NewOrderNotifier(notificationURL),
+	}
 	paymentv1.RegisterPaymentServiceServer(s, service)
 
+	// Фоновое аннулирование истекших авторизаций
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go service.voidExpiredAuthorizations(jobsCtx, voidInterval)
+	// Завершение операций у провайдера, прерванных сбоем
+	go service.recoverPendingOperations(jobsCtx, voidInterval, pendingOperationLease)
+	// Периодическая проверка сбалансированности главной книги
+	go service.verifyLedger(jobsCtx, ledgerCheckInterval)
+	// Перечитывание файла правил при его изменении
+	go rules.watch(jobsCtx, rulesReloadInterval)
+
 	// Включаем рефлексию для отладки
 	reflection.Register(s)
 
@@ -61,11 +410,30 @@ func main() {
 		}
 	}()
 
+	// HTTP-сервер принимает callback'и провайдеров
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", httpPort),
+		Handler:           service.newCallbackRouter(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		log.Printf("📨 Payment Service callback server listening on port %d", httpPort)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("failed to serve callbacks: %v", err)
+		}
+	}()
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("🛑 Shutting down Payment Service...")
+	stopJobs()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("callback server shutdown error: %v", err)
+	}
 	s.GracefulStop()
 	log.Println("✅ Server stopped")
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const testCurrency = "RUB"
+
+// testStores — хранилища, на которых проверяются сценарии сервиса; каждое создает хранилище
+// транзакций и кошельков для одного теста
+var testStores = map[string]func(t *testing.T) (TransactionStore, WalletStore){
+	"memory": func(*testing.T) (TransactionStore, WalletStore) {
+		return NewInMemoryTransactionStore(), NewInMemoryWalletStore()
+	},
+	"postgres": func(t *testing.T) (TransactionStore, WalletStore) {
+		store := newTestPostgresStore(t)
+		return store, NewPostgresWalletStore(store.pool)
+	},
+}
+
+// newTestPostgresStore подключается к базе из PAYMENT_POSTGRES_DSN и применяет миграции;
+// без переменной окружения тест пропускается
+func newTestPostgresStore(t *testing.T) *PostgresTransactionStore {
+	t.Helper()
+
+	dsn := os.Getenv("PAYMENT_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("PAYMENT_POSTGRES_DSN is not set")
+	}
+	store, err := NewPostgresTransactionStore(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+// newTestService создает сервис без правил и уведомлений Order Service: оплата INVESTOR_MONEY
+// проходит через кошельки wallets, остальные способы — через симулятор без задержек
+func newTestService(t *testing.T, store TransactionStore, wallets WalletStore) *paymentService {
+	t.Helper()
+
+	rules, err := NewRulesEngine("")
+	if err != nil {
+		t.Fatalf("NewRulesEngine: %v", err)
+	}
+	providers, err := NewProviderRouter(defaultProviderRoutes,
+		NewSimulatedProvider(0, time.Hour, "http://127.0.0.1:0", []byte("test-secret")), NewWalletProvider(wallets))
+	if err != nil {
+		t.Fatalf("NewProviderRouter: %v", err)
+	}
+	return &paymentService{
+		store:            store,
+		wallets:          wallets,
+		providers:        providers,
+		rules:            rules,
+		providerTimeout:  time.Second,
+		authorizationTTL: time.Hour,
+		notifier:         NewOrderNotifier(""),
+	}
+}
+
+// newTestInvestor пополняет кошелек нового пользователя на balance рублей;
+// из Postgres его транзакции, проводки и кошелек удаляются по завершении теста
+func newTestInvestor(t *testing.T, s *paymentService, balance int64) string {
+	t.Helper()
+
+	userUUID := uuid.NewString()
+	if _, err := s.wallets.TopUp(context.Background(), userUUID, testCurrency, balance*nanosPerUnit); err != nil {
+		t.Fatalf("TopUp: %v", err)
+	}
+	if pg, ok := s.store.(*PostgresTransactionStore); ok {
+		t.Cleanup(func() {
+			for _, query := range []string{
+				`DELETE FROM ledger_postings WHERE entry_uuid IN (SELECT entry_uuid FROM ledger_entries
+					WHERE transaction_uuid IN (SELECT transaction_uuid FROM transactions WHERE user_uuid = $1))`,
+				`DELETE FROM ledger_entries WHERE transaction_uuid IN (SELECT transaction_uuid FROM transactions WHERE user_uuid = $1)`,
+				`DELETE FROM refunds WHERE transaction_uuid IN (SELECT transaction_uuid FROM transactions WHERE user_uuid = $1)`,
+				`DELETE FROM transactions WHERE user_uuid = $1`,
+				`DELETE FROM wallet_refunds WHERE reference IN (SELECT reference FROM wallet_operations WHERE user_uuid = $1)`,
+				`DELETE FROM wallet_operations WHERE user_uuid = $1`,
+				`DELETE FROM wallets WHERE user_uuid = $1`,
+			} {
+				if _, err := pg.pool.Exec(context.Background(), query, userUUID); err != nil {
+					t.Errorf("delete test investor: %v", err)
+				}
+			}
+		})
+	}
+	return userUUID
+}
+
+// rubles возвращает сумму в целых рублях
+func rubles(units int64) *paymentv1.Money {
+	return &paymentv1.Money{Units: units}
+}
+
+// getWallet возвращает кошелек пользователя в тестовой валюте
+func getWallet(t *testing.T, s *paymentService, userUUID string) *paymentv1.Wallet {
+	t.Helper()
+
+	wallet, err := s.wallets.GetWallet(context.Background(), userUUID, testCurrency)
+	if err != nil {
+		t.Fatalf("GetWallet: %v", err)
+	}
+	return wallet
+}
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    transaction_uuid TEXT PRIMARY KEY,
+    order_uuid       TEXT NOT NULL,
+    user_uuid        TEXT NOT NULL,
+    payment_method   TEXT NOT NULL,
+    amount           NUMERIC(28, 9) NOT NULL CHECK (amount > 0),
+    currency         CHAR(3) NOT NULL,
+    refunded_amount  NUMERIC(28, 9) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0 AND refunded_amount <= amount),
+    status           TEXT NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL,
+    updated_at       TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transactions_created_idx ON transactions (created_at DESC, transaction_uuid DESC);
+CREATE INDEX IF NOT EXISTS transactions_user_created_idx ON transactions (user_uuid, created_at DESC, transaction_uuid DESC);
+CREATE INDEX IF NOT EXISTS transactions_order_idx ON transactions (order_uuid);
@@ -0,0 +1,3 @@
+-- Заказ оплачивается не более одного раза: повторный PayOrder находит транзакцию по order_uuid
+DROP INDEX IF EXISTS transactions_order_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_order_uuid_key ON transactions (order_uuid);
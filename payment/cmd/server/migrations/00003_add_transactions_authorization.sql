@@ -0,0 +1,11 @@
+-- Двухфазная оплата: авторизация действует до expires_at, после чего аннулируется (VOIDED).
+-- Аннулированная авторизация не мешает оплатить заказ заново, поэтому уникальность order_uuid
+-- распространяется только на действующие транзакции.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS transactions_order_uuid_key;
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_active_order_uuid_key
+    ON transactions (order_uuid) WHERE status <> 'TRANSACTION_STATUS_VOIDED';
+
+CREATE INDEX IF NOT EXISTS transactions_authorization_expiry_idx
+    ON transactions (expires_at) WHERE status = 'TRANSACTION_STATUS_AUTHORIZED';
@@ -0,0 +1,4 @@
+-- Провайдер, через который проведена транзакция, и идентификатор операции у него.
+-- Транзакции, созданные до появления провайдеров, считаются проведенными через симулятор.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS provider TEXT NOT NULL DEFAULT 'simulator';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS provider_reference TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+-- Асинхронные платежи: транзакция ждет callback провайдера в статусе PENDING
+-- и завершается отказом (FAILED) с кодом decline_code. Отклоненная транзакция,
+-- как и аннулированная, не мешает оплатить заказ заново.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS decline_code TEXT NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS transactions_active_order_uuid_key;
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_active_order_uuid_key
+    ON transactions (order_uuid) WHERE status NOT IN ('TRANSACTION_STATUS_VOIDED', 'TRANSACTION_STATUS_FAILED');
+
+-- Callback провайдера находит транзакцию по идентификатору операции
+CREATE INDEX IF NOT EXISTS transactions_provider_reference_idx
+    ON transactions (provider, provider_reference);
+
+-- Зависшие в PENDING авторизации аннулируются по истечении срока так же, как AUTHORIZED
+DROP INDEX IF EXISTS transactions_authorization_expiry_idx;
+CREATE INDEX IF NOT EXISTS transactions_authorization_expiry_idx
+    ON transactions (expires_at) WHERE status IN ('TRANSACTION_STATUS_AUTHORIZED', 'TRANSACTION_STATUS_PENDING');
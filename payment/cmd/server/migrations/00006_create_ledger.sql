@@ -0,0 +1,23 @@
+-- Главная книга: каждое движение денег по транзакции записывается проводкой,
+-- стороны которой (ledger_postings) в сумме дают ноль. Положительная сумма — дебет счета, отрицательная — кредит.
+-- Проводки пишутся с момента применения миграции; более ранние транзакции в книгу не переносятся.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    entry_uuid       TEXT PRIMARY KEY,
+    transaction_uuid TEXT NOT NULL REFERENCES transactions (transaction_uuid),
+    type             TEXT NOT NULL,
+    currency         CHAR(3) NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_created_idx ON ledger_entries (created_at DESC, entry_uuid DESC);
+CREATE INDEX IF NOT EXISTS ledger_entries_transaction_idx ON ledger_entries (transaction_uuid);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    entry_uuid TEXT NOT NULL REFERENCES ledger_entries (entry_uuid),
+    account    TEXT NOT NULL,
+    currency   CHAR(3) NOT NULL,
+    amount     NUMERIC(28, 9) NOT NULL CHECK (amount <> 0),
+    PRIMARY KEY (entry_uuid, account)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_postings_account_idx ON ledger_postings (account, currency);
@@ -0,0 +1,22 @@
+-- Кошельки инвесторов для оплаты PAYMENT_METHOD_INVESTOR_MONEY.
+-- held — сумма несписанных авторизаций; оплатить можно не больше balance - held.
+CREATE TABLE IF NOT EXISTS wallets (
+    user_uuid  TEXT NOT NULL,
+    currency   CHAR(3) NOT NULL,
+    balance    NUMERIC(28, 9) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    held       NUMERIC(28, 9) NOT NULL DEFAULT 0 CHECK (held >= 0 AND held <= balance),
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_uuid, currency)
+);
+
+-- Операции кошелька; reference хранится в transactions.provider_reference
+CREATE TABLE IF NOT EXISTS wallet_operations (
+    reference       TEXT PRIMARY KEY,
+    user_uuid       TEXT NOT NULL,
+    currency        CHAR(3) NOT NULL,
+    amount          NUMERIC(28, 9) NOT NULL CHECK (amount > 0),
+    refunded_amount NUMERIC(28, 9) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0 AND refunded_amount <= amount),
+    status          TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_uuid, currency) REFERENCES wallets (user_uuid, currency)
+);
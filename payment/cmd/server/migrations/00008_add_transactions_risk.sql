@@ -0,0 +1,8 @@
+-- Решение правил антифрода, принятое при создании транзакции. Транзакции, созданные
+-- до появления правил, остаются с RISK_DECISION_UNSPECIFIED.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS risk_decision TEXT NOT NULL DEFAULT 'RISK_DECISION_UNSPECIFIED';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS risk_rule_ids TEXT[] NOT NULL DEFAULT '{}';
+
+-- Очередь ручной проверки: ListTransactions с фильтром risk_decision = REVIEW
+CREATE INDEX IF NOT EXISTS transactions_risk_review_idx
+    ON transactions (created_at DESC, transaction_uuid DESC) WHERE risk_decision = 'RISK_DECISION_REVIEW';
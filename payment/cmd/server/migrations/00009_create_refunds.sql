@@ -0,0 +1,15 @@
+-- Возвраты по транзакциям. Ключ идемпотентности уникален в пределах транзакции:
+-- повтор RefundPayment с тем же ключом возвращает сохраненный возврат.
+-- Возвраты, выполненные до применения миграции, учтены только в transactions.refunded_amount.
+CREATE TABLE IF NOT EXISTS refunds (
+    refund_uuid      TEXT PRIMARY KEY,
+    transaction_uuid TEXT NOT NULL REFERENCES transactions (transaction_uuid),
+    amount           NUMERIC(28, 9) NOT NULL CHECK (amount > 0),
+    reason           TEXT NOT NULL DEFAULT '',
+    idempotency_key  TEXT,
+    created_at       TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS refunds_transaction_idx ON refunds (transaction_uuid, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS refunds_idempotency_key_idx
+    ON refunds (transaction_uuid, idempotency_key) WHERE idempotency_key IS NOT NULL;
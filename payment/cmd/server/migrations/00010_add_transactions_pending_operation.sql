@@ -0,0 +1,11 @@
+-- Операция у провайдера, которая выполняется по транзакции (PendingOperation). Провайдер
+-- вызывается вне блокировки строки; отметку, оставшуюся после сбоя, снимает recoverOperation.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS pending_operation_uuid TEXT;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS pending_operation_type TEXT;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS pending_operation_amount NUMERIC(28, 9);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS pending_operation_key TEXT;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS pending_operation_started_at TIMESTAMPTZ;
+
+-- Незавершенные операции для восстановления (ListStalePendingOperations)
+CREATE INDEX IF NOT EXISTS transactions_pending_operation_idx
+    ON transactions (pending_operation_started_at) WHERE pending_operation_uuid IS NOT NULL;
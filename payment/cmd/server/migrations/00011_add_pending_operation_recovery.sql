@@ -0,0 +1,11 @@
+-- Причина возврата, которая нужна, чтобы завершить прерванный сбоем возврат (recoverOperation)
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS pending_operation_reason TEXT;
+
+-- Выполненные возвраты операций кошелька: повтор возврата с тем же ключом ничего не меняет
+CREATE TABLE IF NOT EXISTS wallet_refunds (
+    reference       TEXT NOT NULL REFERENCES wallet_operations (reference),
+    idempotency_key TEXT NOT NULL,
+    amount          NUMERIC(28, 9) NOT NULL CHECK (amount > 0),
+    created_at      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (reference, idempotency_key)
+);
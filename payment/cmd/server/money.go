@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const nanosPerUnit = 1_000_000_000
+
+// currencyPattern — формат кода валюты ISO 4217
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// amountPattern — неотрицательная десятичная сумма с точностью до миллиардных долей
+var amountPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,9})?$`)
+
+// toNanos переводит сумму в миллиардные доли для точной арифметики
+func toNanos(m *paymentv1.Money) int64 {
+	return m.GetUnits()*nanosPerUnit + int64(m.GetNanos())
+}
+
+func fromNanos(nanos int64) *paymentv1.Money {
+	return &paymentv1.Money{
+		Units: nanos / nanosPerUnit,
+		Nanos: int32(nanos % nanosPerUnit),
+	}
+}
+
+// formatNanos форматирует сумму в миллиардных долях со знаком, например -0.500000000
+func formatNanos(nanos int64) string {
+	sign := ""
+	if nanos < 0 {
+		sign = "-"
+		nanos = -nanos
+	}
+	return fmt.Sprintf("%s%d.%09d", sign, nanos/nanosPerUnit, nanos%nanosPerUnit)
+}
+
+// parseNanos разбирает неотрицательную десятичную сумму вроде "1500.50" в миллиардные доли
+func parseNanos(value string) (int64, error) {
+	if !amountPattern.MatchString(value) {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	units, fraction, _ := strings.Cut(value, ".")
+	whole, err := strconv.ParseInt(units, 10, 64)
+	if err != nil || whole > math.MaxInt64/nanosPerUnit-1 {
+		return 0, fmt.Errorf("amount %q is too large", value)
+	}
+	nanos, _ := strconv.ParseInt(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+	return whole*nanosPerUnit + nanos, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const (
+	// defaultOrderNotificationURL — куда сообщать о завершении асинхронных платежей,
+	// если PAYMENT_ORDER_NOTIFICATION_URL не задан
+	defaultOrderNotificationURL = "http://localhost:8080/api/v1/payment-notifications"
+
+	// notifyAttempts и notifyBackoff — число попыток уведомления и задержка перед первым повтором
+	notifyAttempts = 3
+	notifyBackoff  = time.Second
+)
+
+// OrderNotifier сообщает Order Service, что асинхронный платеж завершен.
+// Уведомление содержит только идентификаторы: Order Service перечитывает транзакцию через GetTransaction,
+// поэтому его не нужно подписывать.
+type OrderNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewOrderNotifier создает уведомитель; с пустым url уведомления не отправляются
+func NewOrderNotifier(url string) *OrderNotifier {
+	return &OrderNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type paymentNotification struct {
+	TransactionUUID string `json:"transaction_uuid"`
+	OrderUUID       string `json:"order_uuid"`
+}
+
+// Notify отправляет уведомление в фоне, повторяя его с экспоненциальной задержкой
+func (n *OrderNotifier) Notify(transaction *paymentv1.Transaction) {
+	if n.url == "" {
+		return
+	}
+	body, err := json.Marshal(paymentNotification{
+		TransactionUUID: transaction.GetTransactionUuid(),
+		OrderUUID:       transaction.GetOrderUuid(),
+	})
+	if err != nil {
+		log.Printf("failed to encode notification for transaction %s: %v", transaction.GetTransactionUuid(), err)
+		return
+	}
+
+	go func() {
+		backoff := notifyBackoff
+		for attempt := 1; ; attempt++ {
+			err := n.send(body)
+			if err == nil {
+				return
+			}
+			if attempt == notifyAttempts {
+				log.Printf("failed to notify order service about transaction %s: %v",
+					transaction.GetTransactionUuid(), err)
+				return
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}()
+}
+
+func (n *OrderNotifier) send(body []byte) error {
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("order service responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const (
+	// defaultProviderTimeout — сколько ждать ответа провайдера, если PAYMENT_PROVIDER_TIMEOUT не задан
+	defaultProviderTimeout = 10 * time.Second
+	// defaultPendingOperationLease — через сколько незавершенная операция у провайдера считается
+	// прерванной сбоем, если PAYMENT_PENDING_OPERATION_LEASE не задан
+	defaultPendingOperationLease = 5 * time.Minute
+)
+
+// declineDomain — домен google.rpc.ErrorInfo для отказов провайдера
+const declineDomain = "payment.bigproj"
+
+// Коды отказа провайдера. Передаются клиенту в ErrorInfo.reason в верхнем регистре.
+const (
+	DeclineCardDeclined         = "card_declined"
+	DeclineInsufficientFunds    = "insufficient_funds"
+	DeclineAuthenticationFailed = "authentication_failed"
+	// DeclineRiskDenied — оплату запретили правила антифрода (см. RulesEngine), провайдер не вызывался
+	DeclineRiskDenied = "risk_denied"
+)
+
+// ErrProviderTimeout возвращается, если провайдер не ответил за отведенное время
+var ErrProviderTimeout = errors.New("payment provider timed out")
+
+// DeclineError — провайдер отклонил операцию.
+// Metadata дополняет google.rpc.ErrorInfo подробностями отказа, например остатком средств.
+type DeclineError struct {
+	Provider string
+	Code     string
+	Metadata map[string]string
+}
+
+func (e *DeclineError) Error() string {
+	return fmt.Sprintf("payment declined by %s: %s", e.Provider, e.Code)
+}
+
+// ProviderRequest описывает операцию над суммой заказа
+type ProviderRequest struct {
+	OrderUUID     string
+	UserUUID      string
+	PaymentMethod paymentv1.PaymentMethod
+	Amount        *paymentv1.Money
+	Currency      string
+}
+
+// ProviderResult — результат Charge или Authorize
+type ProviderResult struct {
+	// Reference — идентификатор операции у провайдера
+	Reference string
+	// Pending — провайдер завершит операцию асинхронно и сообщит итог через callback
+	Pending bool
+}
+
+// Provider — адаптер платежного провайдера (эквайринга, СБП и т.п.).
+// Charge и Authorize возвращают идентификатор операции у провайдера (reference),
+// по которому затем выполняются Capture, Void и Refund, а также приходят callback'и.
+// Отказ провайдера возвращается как *DeclineError, отсутствие ответа — как ErrProviderTimeout.
+// Повтор Capture и Void уже выполненной операции ничего не меняет.
+type Provider interface {
+	Name() string
+	Charge(ctx context.Context, req ProviderRequest) (ProviderResult, error)
+	Authorize(ctx context.Context, req ProviderRequest) (ProviderResult, error)
+	Capture(ctx context.Context, reference string, amount *paymentv1.Money) error
+	Void(ctx context.Context, reference string) error
+	// Refund возвращает часть списанной суммы; повтор с тем же key не возвращает ее второй раз
+	Refund(ctx context.Context, reference string, amount *paymentv1.Money, key string) error
+}
+
+// ProviderRouter выбирает провайдера по способу оплаты
+type ProviderRouter struct {
+	providers map[string]Provider                // ключ — Provider.Name()
+	routes    map[paymentv1.PaymentMethod]string // способ оплаты -> имя провайдера
+}
+
+func NewProviderRouter(routes map[paymentv1.PaymentMethod]string, providers ...Provider) (*ProviderRouter, error) {
+	router := &ProviderRouter{
+		providers: make(map[string]Provider, len(providers)),
+		routes:    routes,
+	}
+	for _, provider := range providers {
+		router.providers[provider.Name()] = provider
+	}
+	for method, name := range routes {
+		if _, ok := router.providers[name]; !ok {
+			return nil, fmt.Errorf("route %s refers to unknown provider %q", method, name)
+		}
+	}
+	return router, nil
+}
+
+// ForMethod возвращает провайдера, обслуживающего способ оплаты
+func (r *ProviderRouter) ForMethod(method paymentv1.PaymentMethod) (Provider, error) {
+	name, ok := r.routes[method]
+	if !ok {
+		return nil, fmt.Errorf("no provider configured for %s", method)
+	}
+	return r.providers[name], nil
+}
+
+// ForTransaction возвращает провайдера, через который проведена транзакция
+func (r *ProviderRouter) ForTransaction(transaction *paymentv1.Transaction) (Provider, error) {
+	provider, ok := r.providers[transaction.GetProvider()]
+	if !ok {
+		return nil, fmt.Errorf("transaction %s refers to unknown provider %q",
+			transaction.GetTransactionUuid(), transaction.GetProvider())
+	}
+	return provider, nil
+}
+
+// defaultProviderRoutes — таблица маршрутизации по умолчанию: деньги инвесторов списываются с их кошельков,
+// остальные способы оплаты идут через симулятор
+var defaultProviderRoutes = map[paymentv1.PaymentMethod]string{
+	paymentv1.PaymentMethod_PAYMENT_METHOD_CARD:           simulatorProviderName,
+	paymentv1.PaymentMethod_PAYMENT_METHOD_SBP:            simulatorProviderName,
+	paymentv1.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD:    simulatorProviderName,
+	paymentv1.PaymentMethod_PAYMENT_METHOD_INVESTOR_MONEY: walletProviderName,
+}
+
+// providerError переводит ошибку провайдера в gRPC-статус:
+// отказ — FailedPrecondition с деталями google.rpc.ErrorInfo, таймаут — Unavailable
+func providerError(err error) error {
+	var decline *DeclineError
+	switch {
+	case errors.As(err, &decline):
+		metadata := map[string]string{"provider": decline.Provider}
+		maps.Copy(metadata, decline.Metadata)
+		st := status.New(codes.FailedPrecondition, decline.Error())
+		detailed, detailsErr := st.WithDetails(&errdetails.ErrorInfo{
+			Reason:   strings.ToUpper(decline.Code),
+			Domain:   declineDomain,
+			Metadata: metadata,
+		})
+		if detailsErr != nil {
+			return st.Err()
+		}
+		return detailed.Err()
+	case errors.Is(err, ErrProviderTimeout), errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.Unavailable, "payment provider did not respond in time")
+	default:
+		log.Printf("payment provider error: %v", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// providerContext ограничивает вызов провайдера providerTimeout
+func (s *paymentService) providerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.providerTimeout)
+}
+
+// callProvider выполняет операцию у провайдера, через который проведена транзакция,
+// и переводит ее ошибку в gRPC-статус. Вызывается вне блокировки транзакции, см. runOperation.
+func (s *paymentService) callProvider(ctx context.Context, transaction *paymentv1.Transaction, call func(context.Context, Provider) error) error {
+	provider, err := s.providers.ForTransaction(transaction)
+	if err != nil {
+		return err
+	}
+
+	providerCtx, cancel := s.providerContext(ctx)
+	defer cancel()
+	if err := call(providerCtx, provider); err != nil {
+		log.Printf("Провайдер %s не выполнил операцию по транзакции %s: %v",
+			provider.Name(), transaction.GetTransactionUuid(), err)
+		return providerError(err)
+	}
+	return nil
+}
+
+// providerOperation — операция у провайдера над существующей транзакцией, которую выполняет runOperation
+type providerOperation struct {
+	// pending — отметка, которой транзакция помечается на время вызова провайдера; ее тип
+	// определяет вызов провайдера (callOperation) и изменение транзакции после него (commitOperation)
+	pending *paymentv1.PendingOperation
+	// begin проверяет транзакцию под блокировкой и сообщает, нужен ли вызов провайдера.
+	// false без ошибки — операция уже выполнена, транзакция возвращается без изменений.
+	begin func(transaction *paymentv1.Transaction) (bool, error)
+}
+
+// callOperation выполняет у провайдера операцию, которой помечена транзакция. Повтор безопасен:
+// списание и аннулирование идемпотентны, а возврат передается с operation_uuid как ключом идемпотентности.
+func callOperation(ctx context.Context, provider Provider, transaction *paymentv1.Transaction, pending *paymentv1.PendingOperation) error {
+	reference := transaction.GetProviderReference()
+	switch pending.GetType() {
+	case paymentv1.OperationType_OPERATION_TYPE_CAPTURE:
+		return provider.Capture(ctx, reference, transaction.GetAmount())
+	case paymentv1.OperationType_OPERATION_TYPE_VOID:
+		return provider.Void(ctx, reference)
+	case paymentv1.OperationType_OPERATION_TYPE_REFUND:
+		return provider.Refund(ctx, reference, pending.GetAmount(), pending.GetOperationUuid())
+	default:
+		return fmt.Errorf("unknown operation type %s", pending.GetType())
+	}
+}
+
+// commitOperation применяет к транзакции результат успешной операции у провайдера
+func commitOperation(transaction *paymentv1.Transaction, pending *paymentv1.PendingOperation) {
+	switch pending.GetType() {
+	case paymentv1.OperationType_OPERATION_TYPE_CAPTURE:
+		transaction.Status = paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED
+	case paymentv1.OperationType_OPERATION_TYPE_VOID:
+		transaction.Status = paymentv1.TransactionStatus_TRANSACTION_STATUS_VOIDED
+	case paymentv1.OperationType_OPERATION_TYPE_REFUND:
+		// Пока операция не завершена, другие возвраты по транзакции отклоняются,
+		// поэтому сумма возвратов с ее начала не изменилась
+		total := toNanos(transaction.GetRefundedAmount()) + toNanos(pending.GetAmount())
+		transaction.RefundedAmount = fromNanos(total)
+		transaction.Status = paymentv1.TransactionStatus_TRANSACTION_STATUS_PARTIALLY_REFUNDED
+		if total == toNanos(transaction.GetAmount()) {
+			transaction.Status = paymentv1.TransactionStatus_TRANSACTION_STATUS_REFUNDED
+		}
+		transaction.Refunds = append(transaction.Refunds, &paymentv1.Refund{
+			RefundUuid:     uuid.New().String(),
+			Amount:         pending.GetAmount(),
+			Reason:         pending.GetReason(),
+			IdempotencyKey: pending.GetIdempotencyKey(),
+			CreatedAt:      now(),
+		})
+	}
+	transaction.UpdatedAt = now()
+}
+
+// runOperation выполняет операцию у провайдера, не удерживая транзакцию заблокированной
+// (мьютекс хранилища в памяти, строку и соединение PostgreSQL) на время сетевого вызова:
+//  1. под блокировкой begin проверяет транзакцию, и она помечается pending_operation —
+//     конкурентные операции по ней отклоняются с Aborted;
+//  2. провайдер вызывается без блокировки;
+//  3. под блокировкой отметка снимается, а после успешного вызова применяется commitOperation.
+//
+// Если отметку не удалось снять (сбой сервиса или хранилища), операцию завершает recoverOperation.
+func (s *paymentService) runOperation(ctx context.Context, transactionUUID string, op providerOperation) (*paymentv1.Transaction, error) {
+	var started *paymentv1.Transaction
+	err := s.store.UpdateTransaction(ctx, transactionUUID, func(transaction *paymentv1.Transaction) error {
+		needed, err := op.begin(transaction)
+		if err != nil {
+			return err
+		}
+		started = proto.Clone(transaction).(*paymentv1.Transaction)
+		if !needed {
+			return nil
+		}
+		if pending := transaction.GetPendingOperation(); pending != nil {
+			return status.Errorf(codes.Aborted, "transaction %s has a %s operation in progress since %s",
+				transactionUUID, pending.GetType(), pending.GetStartedAt().AsTime().Format(time.RFC3339))
+		}
+
+		op.pending.OperationUuid = uuid.New().String()
+		op.pending.StartedAt = now()
+		transaction.PendingOperation = op.pending
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if op.pending.GetOperationUuid() == "" {
+		return started, nil
+	}
+
+	callErr := s.callProvider(ctx, started, func(ctx context.Context, provider Provider) error {
+		return callOperation(ctx, provider, started, op.pending)
+	})
+
+	// Результат сохраняется, даже если клиент уже не ждет ответа
+	finished, err := s.finishOperation(context.WithoutCancel(ctx), transactionUUID, op.pending, callErr == nil)
+	if err != nil {
+		return nil, err
+	}
+	if callErr != nil {
+		return nil, callErr
+	}
+	return finished, nil
+}
+
+// finishOperation снимает отметку операции и, если провайдер ее выполнил, применяет результат
+func (s *paymentService) finishOperation(ctx context.Context, transactionUUID string, pending *paymentv1.PendingOperation, succeeded bool) (*paymentv1.Transaction, error) {
+	var finished *paymentv1.Transaction
+	err := s.store.UpdateTransaction(ctx, transactionUUID, func(transaction *paymentv1.Transaction) error {
+		if transaction.GetPendingOperation().GetOperationUuid() != pending.GetOperationUuid() {
+			return fmt.Errorf("operation %s is no longer pending", pending.GetOperationUuid())
+		}
+		transaction.PendingOperation = nil
+		if succeeded {
+			commitOperation(transaction, pending)
+		}
+		finished = transaction
+		return nil
+	})
+	if err != nil {
+		if succeeded {
+			log.Printf("Провайдер выполнил операцию %s %s по транзакции %s, но результат не сохранен: %v",
+				pending.GetType(), pending.GetOperationUuid(), transactionUUID, err)
+		} else {
+			log.Printf("Не удалось снять отметку операции %s по транзакции %s: %v",
+				pending.GetOperationUuid(), transactionUUID, err)
+		}
+		return nil, err
+	}
+	return finished, nil
+}
+
+// recoverOperation завершает операцию, отметка которой осталась в транзакции с момента before или раньше:
+// сервис упал между вызовом провайдера и сохранением результата или не смог его сохранить.
+// Операция повторяется у провайдера (см. callOperation). После успеха применяется ее результат,
+// после отказа провайдера отметка снимается без изменений; если провайдер недоступен,
+// отметка остается до следующей попытки. Транзакция без устаревшей отметки возвращается как есть.
+func (s *paymentService) recoverOperation(ctx context.Context, transactionUUID string, before time.Time) (*paymentv1.Transaction, error) {
+	transaction, err := s.store.GetTransaction(ctx, transactionUUID)
+	if err != nil {
+		return nil, err
+	}
+	pending := transaction.GetPendingOperation()
+	if pending == nil || pending.GetStartedAt().AsTime().After(before) {
+		return transaction, nil
+	}
+
+	callErr := s.callProvider(ctx, transaction, func(ctx context.Context, provider Provider) error {
+		return callOperation(ctx, provider, transaction, pending)
+	})
+	if callErr != nil && status.Code(callErr) != codes.FailedPrecondition {
+		return nil, callErr
+	}
+
+	recovered, err := s.finishOperation(ctx, transactionUUID, pending, callErr == nil)
+	if err != nil {
+		return nil, err
+	}
+	if callErr != nil {
+		log.Printf("Провайдер отклонил незавершенную операцию %s %s по транзакции %s, отметка снята",
+			pending.GetType(), pending.GetOperationUuid(), transactionUUID)
+	} else {
+		log.Printf("Незавершенная операция %s %s по транзакции %s выполнена повторно",
+			pending.GetType(), pending.GetOperationUuid(), transactionUUID)
+	}
+	return recovered, nil
+}
+
+// recoverPendingOperations раз в interval завершает операции, отметки которых старше lease, пока ctx не отменен
+func (s *paymentService) recoverPendingOperations(ctx context.Context, interval, lease time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.recoverStaleOperations(ctx, time.Now().Add(-lease))
+		}
+	}
+}
+
+// recoverStaleOperations завершает операции, начатые не позже before, пачками по voidBatchSize
+func (s *paymentService) recoverStaleOperations(ctx context.Context, before time.Time) {
+	for {
+		uuids, err := s.store.ListStalePendingOperations(ctx, before, voidBatchSize)
+		if err != nil {
+			log.Printf("failed to list stale pending operations: %v", err)
+			return
+		}
+
+		recovered := 0
+		for _, transactionUUID := range uuids {
+			transaction, err := s.recoverOperation(ctx, transactionUUID, before)
+			if err != nil {
+				log.Printf("failed to recover pending operation of transaction %s: %v", transactionUUID, err)
+				continue
+			}
+			if transaction.GetStatus() == paymentv1.TransactionStatus_TRANSACTION_STATUS_VOIDED {
+				// Заказ, ожидающий подтверждения платежа, должен вернуться к оплате
+				s.notifier.Notify(transaction)
+			}
+			recovered++
+		}
+
+		// Если пачка неполная или ни одну операцию не удалось завершить, ждем следующего тика
+		if len(uuids) < voidBatchSize || recovered == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const simulatorProviderName = "simulator"
+
+// Магические суммы симулятора: сценарий выбирается по копейкам суммы операции
+const (
+	simulatorDeclineKopecks           = 1 // xx.01 — карта отклонена
+	simulatorInsufficientFundsKopecks = 2 // xx.02 — недостаточно средств
+	simulatorTimeoutKopecks           = 3 // xx.03 — провайдер не отвечает
+	simulator3DSKopecks               = 4 // xx.04 — 3-D Secure, подтверждение приходит callback'ом
+	simulator3DSFailedKopecks         = 5 // xx.05 — 3-D Secure, плательщик не прошел проверку
+)
+
+const (
+	// defaultSimulatorCallbackDelay — через сколько симулятор присылает callback асинхронной операции,
+	// если PAYMENT_SIMULATOR_CALLBACK_DELAY не задан
+	defaultSimulatorCallbackDelay = 2 * time.Second
+
+	// simulatorCallbackAttempts и simulatorCallbackBackoff — число попыток доставки callback'а
+	// и задержка перед первым повтором
+	simulatorCallbackAttempts = 5
+	simulatorCallbackBackoff  = time.Second
+)
+
+// SimulatedProvider — локальный симулятор платежного шлюза для разработки и проверки
+// сценариев отказа без реального эквайринга. Все операции выполняются с задержкой latency;
+// Charge, Authorize и Refund завершаются отказом или таймаутом для магических сумм.
+// Оплата через СБП и по карте с 3-D Secure (xx.04, xx.05) асинхронна: операция возвращается
+// в статусе pending, а через callbackDelay симулятор присылает подписанный callback на callbackURL,
+// повторяя его, пока Payment Service не ответит 2xx. Симулятор не хранит состояние и принимает любой reference.
+type SimulatedProvider struct {
+	latency         time.Duration
+	callbackDelay   time.Duration
+	callbackBackoff time.Duration
+	callbackURL     string
+	secret          []byte
+	client          *http.Client
+}
+
+func NewSimulatedProvider(latency, callbackDelay time.Duration, callbackURL string, secret []byte) *SimulatedProvider {
+	return &SimulatedProvider{
+		latency:         latency,
+		callbackDelay:   callbackDelay,
+		callbackBackoff: simulatorCallbackBackoff,
+		callbackURL:     callbackURL,
+		secret:          secret,
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *SimulatedProvider) Name() string {
+	return simulatorProviderName
+}
+
+func (p *SimulatedProvider) Charge(ctx context.Context, req ProviderRequest) (ProviderResult, error) {
+	return p.newPayment(ctx, "charge", req)
+}
+
+func (p *SimulatedProvider) Authorize(ctx context.Context, req ProviderRequest) (ProviderResult, error) {
+	return p.newPayment(ctx, "authorize", req)
+}
+
+func (p *SimulatedProvider) Capture(ctx context.Context, reference string, _ *paymentv1.Money) error {
+	return p.wait(ctx)
+}
+
+func (p *SimulatedProvider) Void(ctx context.Context, reference string) error {
+	return p.wait(ctx)
+}
+
+func (p *SimulatedProvider) Refund(ctx context.Context, reference string, amount *paymentv1.Money, _ string) error {
+	_, err := p.newOperation(ctx, "refund", amount)
+	return err
+}
+
+// newPayment проводит списание или авторизацию. Асинхронный сценарий завершается callback'ом:
+// xx.05 — отказом authentication_failed, остальные суммы — успехом.
+func (p *SimulatedProvider) newPayment(ctx context.Context, operation string, req ProviderRequest) (ProviderResult, error) {
+	reference, err := p.newOperation(ctx, operation, req.Amount)
+	if err != nil {
+		return ProviderResult{}, err
+	}
+
+	cents := kopecks(req.Amount)
+	if req.PaymentMethod != paymentv1.PaymentMethod_PAYMENT_METHOD_SBP &&
+		cents != simulator3DSKopecks && cents != simulator3DSFailedKopecks {
+		return ProviderResult{Reference: reference}, nil
+	}
+
+	callback := ProviderCallback{Reference: reference, Status: CallbackStatusSucceeded}
+	if cents == simulator3DSFailedKopecks {
+		callback = ProviderCallback{Reference: reference, Status: CallbackStatusFailed, DeclineCode: DeclineAuthenticationFailed}
+	}
+	go p.sendCallback(callback)
+	return ProviderResult{Reference: reference, Pending: true}, nil
+}
+
+// sendCallback через callbackDelay отправляет подписанный callback, как это делает настоящий провайдер.
+// Ошибка соединения или ответ не 2xx повторяются с экспоненциальной задержкой до simulatorCallbackAttempts раз.
+func (p *SimulatedProvider) sendCallback(callback ProviderCallback) {
+	time.Sleep(p.callbackDelay)
+
+	body, err := json.Marshal(callback)
+	if err != nil {
+		log.Printf("simulator: failed to encode callback %s: %v", callback.Reference, err)
+		return
+	}
+
+	backoff := p.callbackBackoff
+	for attempt := 1; ; attempt++ {
+		err := p.deliverCallback(body)
+		if err == nil {
+			log.Printf("simulator: callback %s %s delivered", callback.Reference, callback.Status)
+			return
+		}
+		if attempt == simulatorCallbackAttempts {
+			log.Printf("simulator: callback %s failed after %d attempts: %v", callback.Reference, attempt, err)
+			return
+		}
+		log.Printf("simulator: callback %s attempt %d failed, retrying in %s: %v", callback.Reference, attempt, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// deliverCallback отправляет подписанное тело callback'а один раз
+func (p *SimulatedProvider) deliverCallback(body []byte) error {
+	url := p.callbackURL + "/api/v1/providers/" + simulatorProviderName + "/callbacks"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signatureHeader, signCallback(p.secret, body))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("payment service responded %s", resp.Status)
+	}
+	return nil
+}
+
+// newOperation разыгрывает сценарий по сумме и возвращает reference новой операции
+func (p *SimulatedProvider) newOperation(ctx context.Context, operation string, amount *paymentv1.Money) (string, error) {
+	if err := p.wait(ctx); err != nil {
+		return "", err
+	}
+
+	switch kopecks(amount) {
+	case simulatorDeclineKopecks:
+		return "", &DeclineError{Provider: simulatorProviderName, Code: DeclineCardDeclined}
+	case simulatorInsufficientFundsKopecks:
+		return "", &DeclineError{Provider: simulatorProviderName, Code: DeclineInsufficientFunds}
+	case simulatorTimeoutKopecks:
+		// Не отвечаем, пока вызывающий не прекратит ожидание
+		<-ctx.Done()
+		return "", ErrProviderTimeout
+	}
+
+	reference := "sim_" + uuid.New().String()
+	log.Printf("simulator: %s %d.%09d -> %s", operation, amount.GetUnits(), amount.GetNanos(), reference)
+	return reference, nil
+}
+
+// wait имитирует сетевую задержку провайдера
+func (p *SimulatedProvider) wait(ctx context.Context) error {
+	if p.latency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(p.latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ErrProviderTimeout
+	}
+}
+
+// kopecks возвращает копейки суммы (сотые доли)
+func kopecks(amount *paymentv1.Money) int32 {
+	return amount.GetNanos() / (nanosPerUnit / 100) % 100
+}
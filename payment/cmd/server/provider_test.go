@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// Отметка операции, оставшаяся после сбоя между вызовом провайдера и сохранением результата,
+// блокирует операции по транзакции, пока фоновая задача не повторит операцию у провайдера.
+// Повтор выполняется и если провайдер успел провести операцию до сбоя, и если не успел,
+// но деньги при этом не двигаются дважды.
+func TestRecoverStalePendingOperation(t *testing.T) {
+	tests := []struct {
+		name string
+		// authorize — транзакция создается AuthorizePayment, иначе PayOrder
+		authorize bool
+		pending   *paymentv1.PendingOperation
+		// wantStatus, wantRefunded, wantBalance — транзакция и кошелек после восстановления;
+		// блокировка кошелька после любого исхода снята
+		wantStatus   paymentv1.TransactionStatus
+		wantRefunded int64
+		wantBalance  int64
+	}{
+		{
+			name:        "void",
+			authorize:   true,
+			pending:     &paymentv1.PendingOperation{Type: paymentv1.OperationType_OPERATION_TYPE_VOID},
+			wantStatus:  paymentv1.TransactionStatus_TRANSACTION_STATUS_VOIDED,
+			wantBalance: 1000,
+		},
+		{
+			name:        "capture",
+			authorize:   true,
+			pending:     &paymentv1.PendingOperation{Type: paymentv1.OperationType_OPERATION_TYPE_CAPTURE},
+			wantStatus:  paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED,
+			wantBalance: 700,
+		},
+		{
+			name: "refund",
+			pending: &paymentv1.PendingOperation{
+				Type:           paymentv1.OperationType_OPERATION_TYPE_REFUND,
+				Amount:         rubles(100),
+				IdempotencyKey: "refund-1",
+				Reason:         "damaged part",
+			},
+			wantStatus:   paymentv1.TransactionStatus_TRANSACTION_STATUS_PARTIALLY_REFUNDED,
+			wantRefunded: 100,
+			wantBalance:  800,
+		},
+	}
+	for storeName, newStores := range testStores {
+		for _, tt := range tests {
+			for _, providerDone := range []bool{false, true} {
+				name := storeName + "/" + tt.name + "/provider not called"
+				if providerDone {
+					name = storeName + "/" + tt.name + "/provider called"
+				}
+				t.Run(name, func(t *testing.T) {
+					store, wallets := newStores(t)
+					s := newTestService(t, store, wallets)
+					transaction := newTestTransaction(t, s, tt.authorize)
+					pending := leavePendingOperation(t, s, transaction, tt.pending, time.Now().Add(-time.Hour))
+					if providerDone {
+						provider, err := s.providers.ForTransaction(transaction)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if err := callOperation(context.Background(), provider, transaction, pending); err != nil {
+							t.Fatalf("provider call before the crash: %v", err)
+						}
+					}
+
+					checkRecovery(t, s, transaction.GetTransactionUuid(), tt.pending.GetType())
+
+					recovered, err := s.store.GetTransaction(context.Background(), transaction.GetTransactionUuid())
+					if err != nil {
+						t.Fatalf("GetTransaction: %v", err)
+					}
+					if recovered.GetPendingOperation() != nil || recovered.GetStatus() != tt.wantStatus ||
+						toNanos(recovered.GetRefundedAmount()) != tt.wantRefunded*nanosPerUnit {
+						t.Fatalf("recovered transaction: status %s, refunded %s, pending %v; want %s, refunded %d",
+							recovered.GetStatus(), formatNanos(toNanos(recovered.GetRefundedAmount())),
+							recovered.GetPendingOperation(), tt.wantStatus, tt.wantRefunded)
+					}
+					if tt.wantRefunded > 0 {
+						refunds := recovered.GetRefunds()
+						if len(refunds) != 1 || refunds[0].GetIdempotencyKey() != tt.pending.GetIdempotencyKey() ||
+							refunds[0].GetReason() != tt.pending.GetReason() {
+							t.Errorf("recovered refunds = %v, want one refund with the pending key and reason", refunds)
+						}
+					}
+
+					wallet := getWallet(t, s, transaction.GetUserUuid())
+					if toNanos(wallet.GetBalance()) != tt.wantBalance*nanosPerUnit || toNanos(wallet.GetHeld()) != 0 {
+						t.Errorf("wallet after recovery: balance %s, held %s; want %d and 0",
+							formatNanos(toNanos(wallet.GetBalance())), formatNanos(toNanos(wallet.GetHeld())), tt.wantBalance)
+					}
+
+					violations, err := s.store.LedgerViolations(context.Background())
+					if err != nil {
+						t.Fatalf("LedgerViolations: %v", err)
+					}
+					if len(violations) > 0 {
+						t.Errorf("ledger violations after recovery: %+v", violations)
+					}
+				})
+			}
+		}
+	}
+}
+
+// Отметка операции, которая еще может выполняться, фоновая задача не трогает
+func TestRecoverKeepsFreshPendingOperation(t *testing.T) {
+	s := newTestService(t, NewInMemoryTransactionStore(), NewInMemoryWalletStore())
+	transaction := newTestTransaction(t, s, true)
+	leavePendingOperation(t, s, transaction,
+		&paymentv1.PendingOperation{Type: paymentv1.OperationType_OPERATION_TYPE_VOID}, time.Now())
+
+	s.recoverStaleOperations(context.Background(), time.Now().Add(-defaultPendingOperationLease))
+
+	current, err := s.store.GetTransaction(context.Background(), transaction.GetTransactionUuid())
+	if err != nil {
+		t.Fatalf("GetTransaction: %v", err)
+	}
+	if current.GetPendingOperation() == nil || current.GetStatus() != paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED {
+		t.Fatalf("fresh operation was recovered: status %s, pending %v", current.GetStatus(), current.GetPendingOperation())
+	}
+}
+
+// Симулятор повторяет callback, пока Payment Service не примет его, и не повторяет принятый
+func TestSimulatorCallbackRetries(t *testing.T) {
+	secret := []byte("test-secret")
+	responses := []int{http.StatusInternalServerError, http.StatusNotFound, http.StatusNoContent}
+	var (
+		mu     sync.Mutex
+		bodies [][]byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if !verifySignature(secret, body, r.Header.Get(signatureHeader)) {
+			t.Errorf("callback attempt %d has an invalid signature", len(bodies)+1)
+		}
+		bodies = append(bodies, body)
+		w.WriteHeader(responses[min(len(bodies), len(responses))-1])
+	}))
+	defer server.Close()
+
+	provider := NewSimulatedProvider(0, 0, server.URL, secret)
+	provider.callbackBackoff = time.Millisecond
+	provider.sendCallback(ProviderCallback{Reference: "sim_retry", Status: CallbackStatusSucceeded})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != len(responses) {
+		t.Fatalf("callback sent %d times, want %d", len(bodies), len(responses))
+	}
+	for _, body := range bodies[1:] {
+		if !bytes.Equal(body, bodies[0]) {
+			t.Errorf("retried callback body = %s, want %s", body, bodies[0])
+		}
+	}
+}
+
+// Недоступный Payment Service получает не больше simulatorCallbackAttempts попыток
+func TestSimulatorCallbackGivesUp(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	provider := NewSimulatedProvider(0, 0, server.URL, []byte("test-secret"))
+	provider.callbackBackoff = time.Millisecond
+	provider.sendCallback(ProviderCallback{Reference: "sim_unavailable", Status: CallbackStatusFailed})
+
+	if got := attempts.Load(); got != simulatorCallbackAttempts {
+		t.Errorf("callback sent %d times, want %d", got, simulatorCallbackAttempts)
+	}
+}
+
+// newTestTransaction проводит оплату или авторизацию 300 рублей с кошелька инвестора, пополненного на 1000
+func newTestTransaction(t *testing.T, s *paymentService, authorize bool) *paymentv1.Transaction {
+	t.Helper()
+	ctx := context.Background()
+
+	userUUID := newTestInvestor(t, s, 1000)
+	if authorize {
+		resp, err := s.AuthorizePayment(ctx, &paymentv1.AuthorizePaymentRequest{
+			OrderUuid:     uuid.NewString(),
+			UserUuid:      userUUID,
+			PaymentMethod: paymentv1.PaymentMethod_PAYMENT_METHOD_INVESTOR_MONEY,
+			Amount:        rubles(300),
+			Currency:      testCurrency,
+		})
+		if err != nil {
+			t.Fatalf("AuthorizePayment: %v", err)
+		}
+		return resp.GetTransaction()
+	}
+
+	resp, err := s.PayOrder(ctx, &paymentv1.PayOrderRequest{
+		OrderUuid:     uuid.NewString(),
+		UserUuid:      userUUID,
+		PaymentMethod: paymentv1.PaymentMethod_PAYMENT_METHOD_INVESTOR_MONEY,
+		Amount:        rubles(300),
+		Currency:      testCurrency,
+	})
+	if err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+	return resp.GetTransaction()
+}
+
+// leavePendingOperation помечает транзакцию операцией, начатой в startedAt, как это делает первый шаг
+// runOperation, после которого сервис упал
+func leavePendingOperation(t *testing.T, s *paymentService, transaction *paymentv1.Transaction, pending *paymentv1.PendingOperation, startedAt time.Time) *paymentv1.PendingOperation {
+	t.Helper()
+
+	pending = proto.Clone(pending).(*paymentv1.PendingOperation)
+	pending.OperationUuid = uuid.NewString()
+	pending.StartedAt = timestamppb.New(startedAt.UTC().Truncate(time.Microsecond))
+	err := s.store.UpdateTransaction(context.Background(), transaction.GetTransactionUuid(), func(tx *paymentv1.Transaction) error {
+		tx.PendingOperation = pending
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("leave pending operation: %v", err)
+	}
+	return pending
+}
+
+// checkRecovery проверяет, что до восстановления операции по транзакции отклоняются с Aborted,
+// и запускает восстановление устаревших отметок
+func checkRecovery(t *testing.T, s *paymentService, transactionUUID string, operation paymentv1.OperationType) {
+	t.Helper()
+	ctx := context.Background()
+
+	var err error
+	if operation == paymentv1.OperationType_OPERATION_TYPE_REFUND {
+		_, err = s.RefundPayment(ctx, &paymentv1.RefundPaymentRequest{TransactionUuid: transactionUUID, Amount: rubles(1)})
+	} else {
+		_, err = s.VoidAuthorization(ctx, &paymentv1.VoidAuthorizationRequest{TransactionUuid: transactionUUID})
+	}
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("operation on a marked transaction: err = %v, want Aborted", err)
+	}
+
+	before := time.Now().Add(-time.Minute)
+	stale, err := s.store.ListStalePendingOperations(ctx, before, voidBatchSize)
+	if err != nil {
+		t.Fatalf("ListStalePendingOperations: %v", err)
+	}
+	if !slices.Contains(stale, transactionUUID) {
+		t.Fatalf("ListStalePendingOperations = %v, want %s", stale, transactionUUID)
+	}
+
+	s.recoverStaleOperations(ctx, before)
+
+	stale, err = s.store.ListStalePendingOperations(ctx, before, voidBatchSize)
+	if err != nil {
+		t.Fatalf("ListStalePendingOperations after recovery: %v", err)
+	}
+	if slices.Contains(stale, transactionUUID) {
+		t.Fatalf("transaction %s is still pending after recovery", transactionUUID)
+	}
+}
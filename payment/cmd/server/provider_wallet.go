@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const walletProviderName = "wallet"
+
+// WalletProvider проводит оплату деньгами инвестора с его кошелька в WalletStore.
+// Нехватка средств возвращается отказом insufficient_funds с остатком в метаданных:
+// available — доступная сумма, required — сумма операции.
+type WalletProvider struct {
+	store WalletStore
+}
+
+func NewWalletProvider(store WalletStore) *WalletProvider {
+	return &WalletProvider{store: store}
+}
+
+func (p *WalletProvider) Name() string {
+	return walletProviderName
+}
+
+func (p *WalletProvider) Charge(ctx context.Context, req ProviderRequest) (ProviderResult, error) {
+	return p.hold(ctx, req, true)
+}
+
+func (p *WalletProvider) Authorize(ctx context.Context, req ProviderRequest) (ProviderResult, error) {
+	return p.hold(ctx, req, false)
+}
+
+func (p *WalletProvider) Capture(ctx context.Context, reference string, _ *paymentv1.Money) error {
+	return p.store.Capture(ctx, reference)
+}
+
+func (p *WalletProvider) Void(ctx context.Context, reference string) error {
+	return p.store.Void(ctx, reference)
+}
+
+func (p *WalletProvider) Refund(ctx context.Context, reference string, amount *paymentv1.Money, key string) error {
+	return p.store.Refund(ctx, reference, toNanos(amount), key)
+}
+
+func (p *WalletProvider) hold(ctx context.Context, req ProviderRequest, captured bool) (ProviderResult, error) {
+	operation := WalletOperation{
+		Reference: "wal_" + uuid.New().String(),
+		UserUUID:  req.UserUUID,
+		Currency:  req.Currency,
+		Amount:    toNanos(req.Amount),
+		Captured:  captured,
+	}
+
+	var insufficient *InsufficientFundsError
+	err := p.store.Hold(ctx, operation)
+	if errors.As(err, &insufficient) {
+		return ProviderResult{}, &DeclineError{
+			Provider: walletProviderName,
+			Code:     DeclineInsufficientFunds,
+			Metadata: map[string]string{
+				"available": formatNanos(insufficient.Available),
+				"required":  formatNanos(operation.Amount),
+				"currency":  req.Currency,
+			},
+		}
+	}
+	if err != nil {
+		return ProviderResult{}, err
+	}
+	return ProviderResult{Reference: operation.Reference}, nil
+}
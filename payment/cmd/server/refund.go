@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// refundable — статусы транзакций, по которым деньги списаны и их можно вернуть
+var refundable = map[paymentv1.TransactionStatus]bool{
+	paymentv1.TransactionStatus_TRANSACTION_STATUS_SUCCEEDED:          true,
+	paymentv1.TransactionStatus_TRANSACTION_STATUS_PARTIALLY_REFUNDED: true,
+}
+
+// refundByKey возвращает возврат по транзакции, выполненный с ключом идемпотентности key
+func refundByKey(transaction *paymentv1.Transaction, key string) *paymentv1.Refund {
+	if key == "" {
+		return nil
+	}
+	for _, refund := range transaction.GetRefunds() {
+		if refund.GetIdempotencyKey() == key {
+			return refund
+		}
+	}
+	return nil
+}
+
+// RefundPayment возвращает средства по ранее проведенной транзакции.
+// Сумма всех возвратов не может превышать сумму оплаты.
+// Повтор с тем же idempotency_key возвращает уже выполненный возврат.
+func (s *paymentService) RefundPayment(ctx context.Context, req *paymentv1.RefundPaymentRequest) (*paymentv1.RefundPaymentResponse, error) {
+	if err := validate(req, refundPaymentRules); err != nil {
+		return nil, err
+	}
+
+	var (
+		refund   *paymentv1.Refund
+		replayed bool
+	)
+	refunded, err := s.runOperation(ctx, req.GetTransactionUuid(), providerOperation{
+		pending: &paymentv1.PendingOperation{
+			Type:           paymentv1.OperationType_OPERATION_TYPE_REFUND,
+			Amount:         req.GetAmount(),
+			IdempotencyKey: req.GetIdempotencyKey(),
+			Reason:         req.GetReason(),
+		},
+		begin: func(transaction *paymentv1.Transaction) (bool, error) {
+			if existing := refundByKey(transaction, req.GetIdempotencyKey()); existing != nil {
+				if toNanos(existing.GetAmount()) != toNanos(req.GetAmount()) {
+					return false, status.Errorf(codes.InvalidArgument,
+						"idempotency key %q was already used for a refund of a different amount", req.GetIdempotencyKey())
+				}
+				refund, replayed = existing, true
+				return false, nil
+			}
+
+			if !refundable[transaction.GetStatus()] {
+				return false, status.Errorf(codes.FailedPrecondition,
+					"transaction %s is %s and cannot be refunded", req.GetTransactionUuid(), transaction.GetStatus())
+			}
+			if toNanos(transaction.GetRefundedAmount())+toNanos(req.GetAmount()) > toNanos(transaction.GetAmount()) {
+				return false, status.Errorf(codes.FailedPrecondition,
+					"refund exceeds the remaining amount of transaction %s", req.GetTransactionUuid())
+			}
+			return true, nil
+		},
+	})
+	if err != nil {
+		return nil, transactionUpdateError(req.GetTransactionUuid(), err)
+	}
+	if refund == nil {
+		// Возврат добавлен в конец списка commitOperation
+		refunds := refunded.GetRefunds()
+		refund = refunds[len(refunds)-1]
+	}
+
+	if replayed {
+		log.Printf("Повтор возврата %s по транзакции %s с ключом %q",
+			refund.GetRefundUuid(), req.GetTransactionUuid(), req.GetIdempotencyKey())
+	} else {
+		log.Printf("Возврат %d.%09d %s по транзакции %s (заказ %s), refund_uuid: %s, причина: %q",
+			req.GetAmount().GetUnits(), req.GetAmount().GetNanos(), refunded.GetCurrency(),
+			req.GetTransactionUuid(), refunded.GetOrderUuid(), refund.GetRefundUuid(), req.GetReason())
+	}
+
+	return &paymentv1.RefundPaymentResponse{
+		RefundUuid:  refund.GetRefundUuid(),
+		Transaction: refunded,
+	}, nil
+}
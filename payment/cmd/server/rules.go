@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// Типы правил антифрода и лимитов
+const (
+	// ruleAmountLimit ограничивает сумму оплат пользователя в валюте за period (дневной, месячный лимит)
+	ruleAmountLimit = "amount_limit"
+	// ruleVelocity ограничивает число попыток оплаты пользователя за period
+	ruleVelocity = "velocity"
+	// ruleMethodCap ограничивает сумму одной оплаты способом payment_method
+	ruleMethodCap = "method_cap"
+	// ruleBlocklist срабатывает на оплаты пользователей из user_uuids
+	ruleBlocklist = "blocklist"
+)
+
+const (
+	// defaultRulesFile — файл правил, если PAYMENT_RULES_FILE не задан; путь от каталога payment
+	defaultRulesFile = "config/rules.json"
+	// defaultRulesReloadInterval — как часто проверять изменение файла правил,
+	// если PAYMENT_RULES_RELOAD_INTERVAL не задан
+	defaultRulesReloadInterval = 10 * time.Second
+
+	// rulesEngineName — источник отказа в google.rpc.ErrorInfo для запрещенных правилами оплат
+	rulesEngineName = "rules"
+)
+
+// ruleActions — допустимые действия правила
+var ruleActions = map[string]paymentv1.RiskDecision{
+	"review": paymentv1.RiskDecision_RISK_DECISION_REVIEW,
+	"deny":   paymentv1.RiskDecision_RISK_DECISION_DENY,
+}
+
+// rulesConfig — формат файла правил:
+//
+//	{"rules": [{"id": "daily_limit", "type": "amount_limit", "action": "deny",
+//	            "period": "24h", "currency": "RUB", "max_amount": 500000}]}
+type rulesConfig struct {
+	Rules []ruleConfig `json:"rules"`
+}
+
+type ruleConfig struct {
+	ID            string      `json:"id"`
+	Type          string      `json:"type"`
+	Action        string      `json:"action"`
+	Period        string      `json:"period"`
+	Currency      string      `json:"currency"`
+	MaxAmount     json.Number `json:"max_amount"`
+	MaxCount      int         `json:"max_count"`
+	PaymentMethod string      `json:"payment_method"`
+	UserUUIDs     []string    `json:"user_uuids"`
+}
+
+// rule — проверенное правило из файла конфигурации
+type rule struct {
+	id        string
+	kind      string
+	action    paymentv1.RiskDecision
+	period    time.Duration
+	currency  string
+	maxAmount int64
+	maxCount  int
+	method    paymentv1.PaymentMethod
+	users     map[string]bool
+}
+
+// parseRules разбирает и проверяет файл правил целиком: при любой ошибке не применяется ни одно правило
+func parseRules(data []byte) ([]rule, error) {
+	var config rulesConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+
+	rules := make([]rule, 0, len(config.Rules))
+	seen := make(map[string]bool, len(config.Rules))
+	for i, cfg := range config.Rules {
+		if cfg.ID == "" {
+			return nil, fmt.Errorf("rule #%d: id is required", i+1)
+		}
+		if seen[cfg.ID] {
+			return nil, fmt.Errorf("rule %s: duplicate id", cfg.ID)
+		}
+		seen[cfg.ID] = true
+
+		r, err := newRule(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", cfg.ID, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func newRule(cfg ruleConfig) (rule, error) {
+	r := rule{id: cfg.ID, kind: cfg.Type, currency: cfg.Currency}
+
+	action, ok := ruleActions[cfg.Action]
+	if !ok {
+		return r, fmt.Errorf("action must be review or deny, got %q", cfg.Action)
+	}
+	r.action = action
+
+	// Поля, которые нужны правилу этого типа
+	var needPeriod, needAmount bool
+	switch cfg.Type {
+	case ruleAmountLimit:
+		needPeriod, needAmount = true, true
+	case ruleVelocity:
+		needPeriod = true
+		if cfg.MaxCount <= 0 {
+			return r, errors.New("max_count must be positive")
+		}
+		r.maxCount = cfg.MaxCount
+	case ruleMethodCap:
+		needAmount = true
+		r.method = paymentv1.PaymentMethod(paymentv1.PaymentMethod_value["PAYMENT_METHOD_"+cfg.PaymentMethod])
+		if err := checkPaymentMethod(r.method); err != nil {
+			return r, fmt.Errorf("payment_method %q: %w", cfg.PaymentMethod, err)
+		}
+	case ruleBlocklist:
+		r.users = make(map[string]bool, len(cfg.UserUUIDs))
+		for _, userUUID := range cfg.UserUUIDs {
+			if err := checkUUID(userUUID); err != nil {
+				return r, fmt.Errorf("user_uuids: %q %w", userUUID, err)
+			}
+			r.users[userUUID] = true
+		}
+	default:
+		return r, fmt.Errorf("unknown type %q", cfg.Type)
+	}
+
+	if needPeriod {
+		period, err := time.ParseDuration(cfg.Period)
+		if err != nil || period <= 0 {
+			return r, fmt.Errorf("period must be a positive duration, got %q", cfg.Period)
+		}
+		r.period = period
+	}
+	if needAmount {
+		if err := checkCurrency(cfg.Currency); err != nil {
+			return r, fmt.Errorf("currency %q: %w", cfg.Currency, err)
+		}
+		maxAmount, err := parseNanos(cfg.MaxAmount.String())
+		if err != nil {
+			return r, fmt.Errorf("max_amount: %w", err)
+		}
+		r.maxAmount = maxAmount
+	}
+	return r, nil
+}
+
+// RulesEngine проверяет оплаты правилами из файла конфигурации и подхватывает изменения файла без перезапуска.
+// Лимиты считаются по уже сохраненным транзакциям, поэтому одновременные оплаты одного пользователя
+// могут вместе немного превысить лимит.
+type RulesEngine struct {
+	path    string
+	rules   atomic.Pointer[[]rule]
+	modTime time.Time // время изменения загруженного файла; меняется только в watch
+}
+
+// NewRulesEngine загружает правила из path; с пустым path все оплаты разрешены
+func NewRulesEngine(path string) (*RulesEngine, error) {
+	e := &RulesEngine{path: path}
+	e.rules.Store(&[]rule{})
+	if path == "" {
+		return e, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.load(); err != nil {
+		return nil, err
+	}
+	e.modTime = info.ModTime()
+	return e, nil
+}
+
+func (e *RulesEngine) load() error {
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return err
+	}
+	rules, err := parseRules(data)
+	if err != nil {
+		return err
+	}
+	e.rules.Store(&rules)
+	log.Printf("Загружено правил антифрода: %d из %s", len(rules), e.path)
+	return nil
+}
+
+// watch раз в interval проверяет время изменения файла правил и перечитывает его, пока ctx не отменен.
+// Файл с ошибкой не применяется: продолжают действовать загруженные ранее правила.
+func (e *RulesEngine) watch(ctx context.Context, interval time.Duration) {
+	if e.path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(e.path)
+		if err != nil {
+			log.Printf("failed to check rules file: %v", err)
+			continue
+		}
+		if info.ModTime().Equal(e.modTime) {
+			continue
+		}
+		e.modTime = info.ModTime()
+		if err := e.load(); err != nil {
+			log.Printf("failed to reload rules, keeping previous ones: %v", err)
+		}
+	}
+}
+
+// activityKey — выборка транзакций пользователя для правил с одинаковыми валютой и периодом
+type activityKey struct {
+	currency string
+	period   time.Duration
+}
+
+// Evaluate проверяет оплату всеми правилами. Решение — самое строгое действие среди сработавших правил
+// (DENY строже REVIEW), без сработавших правил — ALLOW.
+func (e *RulesEngine) Evaluate(ctx context.Context, store TransactionStore, req paymentRequest) (*paymentv1.RiskAssessment, error) {
+	assessment := &paymentv1.RiskAssessment{Decision: paymentv1.RiskDecision_RISK_DECISION_ALLOW}
+	amount := toNanos(req.GetAmount())
+	evaluatedAt := time.Now()
+
+	activities := make(map[activityKey]UserActivity)
+	activity := func(currency string, period time.Duration) (UserActivity, error) {
+		key := activityKey{currency: currency, period: period}
+		if cached, ok := activities[key]; ok {
+			return cached, nil
+		}
+		result, err := store.UserActivity(ctx, req.GetUserUuid(), currency, evaluatedAt.Add(-period))
+		if err != nil {
+			return result, err
+		}
+		activities[key] = result
+		return result, nil
+	}
+
+	for _, r := range *e.rules.Load() {
+		var triggered bool
+		switch r.kind {
+		case ruleAmountLimit:
+			if r.currency != req.GetCurrency() {
+				continue
+			}
+			spent, err := activity(r.currency, r.period)
+			if err != nil {
+				return nil, err
+			}
+			triggered = spent.Amount+amount > r.maxAmount
+		case ruleVelocity:
+			recent, err := activity("", r.period)
+			if err != nil {
+				return nil, err
+			}
+			triggered = recent.Count >= r.maxCount
+		case ruleMethodCap:
+			triggered = r.method == req.GetPaymentMethod() && r.currency == req.GetCurrency() && amount > r.maxAmount
+		case ruleBlocklist:
+			triggered = r.users[req.GetUserUuid()]
+		}
+
+		if triggered {
+			assessment.RuleIds = append(assessment.RuleIds, r.id)
+			assessment.Decision = max(assessment.Decision, r.action)
+		}
+	}
+	return assessment, nil
+}
+
+// riskSummary описывает решение правил для логов
+func riskSummary(risk *paymentv1.RiskAssessment) string {
+	decision := strings.TrimPrefix(risk.GetDecision().String(), "RISK_DECISION_")
+	if len(risk.GetRuleIds()) == 0 {
+		return decision
+	}
+	return decision + " (" + strings.Join(risk.GetRuleIds(), ", ") + ")"
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// testRulesUser — пользователь, оплаты которого проверяются правилами
+const testRulesUser = "11111111-1111-1111-1111-111111111111"
+
+// Каждое условие срабатывает только на оплаты за своей границей
+func TestRulesEvaluate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		// history — суммы прошлых оплат пользователя картой в рублях
+		history  []int64
+		method   paymentv1.PaymentMethod
+		amount   int64
+		currency string
+		user     string
+		want     paymentv1.RiskDecision
+	}{
+		{
+			name:    "amount_limit reached exactly",
+			rules:   `{"rules": [{"id": "daily", "type": "amount_limit", "action": "deny", "period": "24h", "currency": "RUB", "max_amount": 1000}]}`,
+			history: []int64{600},
+			amount:  400,
+			want:    paymentv1.RiskDecision_RISK_DECISION_ALLOW,
+		},
+		{
+			name:    "amount_limit exceeded",
+			rules:   `{"rules": [{"id": "daily", "type": "amount_limit", "action": "deny", "period": "24h", "currency": "RUB", "max_amount": 1000}]}`,
+			history: []int64{600},
+			amount:  401,
+			want:    paymentv1.RiskDecision_RISK_DECISION_DENY,
+		},
+		{
+			name:     "amount_limit in another currency",
+			rules:    `{"rules": [{"id": "daily", "type": "amount_limit", "action": "deny", "period": "24h", "currency": "RUB", "max_amount": 1000}]}`,
+			history:  []int64{600},
+			amount:   401,
+			currency: "USD",
+			want:     paymentv1.RiskDecision_RISK_DECISION_ALLOW,
+		},
+		{
+			name:    "velocity below max_count",
+			rules:   `{"rules": [{"id": "velocity", "type": "velocity", "action": "review", "period": "1m", "max_count": 2}]}`,
+			history: []int64{10},
+			amount:  10,
+			want:    paymentv1.RiskDecision_RISK_DECISION_ALLOW,
+		},
+		{
+			name:    "velocity at max_count",
+			rules:   `{"rules": [{"id": "velocity", "type": "velocity", "action": "review", "period": "1m", "max_count": 2}]}`,
+			history: []int64{10, 10},
+			amount:  10,
+			want:    paymentv1.RiskDecision_RISK_DECISION_REVIEW,
+		},
+		{
+			name:   "method_cap reached exactly",
+			rules:  `{"rules": [{"id": "credit_cap", "type": "method_cap", "action": "deny", "payment_method": "CREDIT_CARD", "currency": "RUB", "max_amount": 300}]}`,
+			method: paymentv1.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD,
+			amount: 300,
+			want:   paymentv1.RiskDecision_RISK_DECISION_ALLOW,
+		},
+		{
+			name:   "method_cap exceeded",
+			rules:  `{"rules": [{"id": "credit_cap", "type": "method_cap", "action": "deny", "payment_method": "CREDIT_CARD", "currency": "RUB", "max_amount": 300}]}`,
+			method: paymentv1.PaymentMethod_PAYMENT_METHOD_CREDIT_CARD,
+			amount: 301,
+			want:   paymentv1.RiskDecision_RISK_DECISION_DENY,
+		},
+		{
+			name:   "method_cap with another method",
+			rules:  `{"rules": [{"id": "credit_cap", "type": "method_cap", "action": "deny", "payment_method": "CREDIT_CARD", "currency": "RUB", "max_amount": 300}]}`,
+			method: paymentv1.PaymentMethod_PAYMENT_METHOD_CARD,
+			amount: 301,
+			want:   paymentv1.RiskDecision_RISK_DECISION_ALLOW,
+		},
+		{
+			name:   "blocklist",
+			rules:  `{"rules": [{"id": "blocked", "type": "blocklist", "action": "deny", "user_uuids": ["` + testRulesUser + `"]}]}`,
+			amount: 1,
+			want:   paymentv1.RiskDecision_RISK_DECISION_DENY,
+		},
+		{
+			name:   "blocklist with another user",
+			rules:  `{"rules": [{"id": "blocked", "type": "blocklist", "action": "deny", "user_uuids": ["` + testRulesUser + `"]}]}`,
+			amount: 1,
+			user:   uuid.NewString(),
+			want:   paymentv1.RiskDecision_RISK_DECISION_ALLOW,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, NewInMemoryTransactionStore(), NewInMemoryWalletStore())
+			for _, amount := range tt.history {
+				payCard(t, s, amount)
+			}
+
+			req := &paymentv1.PayOrderRequest{
+				OrderUuid:     uuid.NewString(),
+				UserUuid:      testRulesUser,
+				PaymentMethod: paymentv1.PaymentMethod_PAYMENT_METHOD_CARD,
+				Amount:        rubles(tt.amount),
+				Currency:      testCurrency,
+			}
+			if tt.method != paymentv1.PaymentMethod_PAYMENT_METHOD_UNSPECIFIED {
+				req.PaymentMethod = tt.method
+			}
+			if tt.currency != "" {
+				req.Currency = tt.currency
+			}
+			if tt.user != "" {
+				req.UserUuid = tt.user
+			}
+
+			risk, err := newTestRules(t, tt.rules).Evaluate(context.Background(), s.store, req)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if risk.GetDecision() != tt.want {
+				t.Errorf("decision = %s, want %s", riskSummary(risk), tt.want)
+			}
+			if triggered := tt.want != paymentv1.RiskDecision_RISK_DECISION_ALLOW; triggered != (len(risk.GetRuleIds()) == 1) {
+				t.Errorf("rule_ids = %v for decision %s", risk.GetRuleIds(), tt.want)
+			}
+		})
+	}
+}
+
+// Из нескольких сработавших правил решение берется по самому строгому, а в rule_ids
+// перечисляются все сработавшие правила в порядке файла
+func TestRulesEvaluateSeveralMatches(t *testing.T) {
+	const rules = `{"rules": [
+		{"id": "sbp_review", "type": "method_cap", "action": "review", "payment_method": "SBP", "currency": "RUB", "max_amount": 100},
+		{"id": "daily", "type": "amount_limit", "action": "deny", "period": "24h", "currency": "RUB", "max_amount": 1000},
+		{"id": "velocity", "type": "velocity", "action": "review", "period": "1m", "max_count": 1},
+		{"id": "blocked", "type": "blocklist", "action": "deny", "user_uuids": []}
+	]}`
+	tests := []struct {
+		name        string
+		amount      int64
+		wantRuleIDs []string
+		want        paymentv1.RiskDecision
+	}{
+		{
+			name:        "review rules only",
+			amount:      101,
+			wantRuleIDs: []string{"sbp_review", "velocity"},
+			want:        paymentv1.RiskDecision_RISK_DECISION_REVIEW,
+		},
+		{
+			name:        "deny overrides review",
+			amount:      1001,
+			wantRuleIDs: []string{"sbp_review", "daily", "velocity"},
+			want:        paymentv1.RiskDecision_RISK_DECISION_DENY,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, NewInMemoryTransactionStore(), NewInMemoryWalletStore())
+			payCard(t, s, 1)
+
+			risk, err := newTestRules(t, rules).Evaluate(context.Background(), s.store, &paymentv1.PayOrderRequest{
+				OrderUuid:     uuid.NewString(),
+				UserUuid:      testRulesUser,
+				PaymentMethod: paymentv1.PaymentMethod_PAYMENT_METHOD_SBP,
+				Amount:        rubles(tt.amount),
+				Currency:      testCurrency,
+			})
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+			if risk.GetDecision() != tt.want || !slices.Equal(risk.GetRuleIds(), tt.wantRuleIDs) {
+				t.Errorf("risk = %s, want %s %v", riskSummary(risk), tt.want, tt.wantRuleIDs)
+			}
+		})
+	}
+}
+
+// Файл правил с ошибкой не применяется: до исправления действуют загруженные ранее правила
+func TestRulesReloadKeepsPreviousOnError(t *testing.T) {
+	engine := newTestRules(t,
+		`{"rules": [{"id": "blocked", "type": "blocklist", "action": "deny", "user_uuids": ["`+testRulesUser+`"]}]}`)
+	s := newTestService(t, NewInMemoryTransactionStore(), NewInMemoryWalletStore())
+
+	for _, invalid := range []string{
+		`{"rules": [`,
+		`{"rules": [{"id": "blocked", "type": "blocklist", "action": "allow"}]}`,
+		`{"rules": [{"id": "daily", "type": "amount_limit", "action": "deny", "period": "-1h", "currency": "RUB", "max_amount": 1}]}`,
+		`{"rules": [{"id": "cap", "type": "method_cap", "action": "deny", "payment_method": "CHECK", "currency": "RUB", "max_amount": 1}]}`,
+		`{"rules": [{"id": "a", "type": "velocity", "action": "deny", "period": "1m", "max_count": 1},
+			{"id": "a", "type": "velocity", "action": "deny", "period": "1m", "max_count": 2}]}`,
+		`{"rules": [], "unknown": true}`,
+	} {
+		reloadTestRules(t, engine, invalid)
+		if decision := evaluateTestRules(t, engine, s); decision != paymentv1.RiskDecision_RISK_DECISION_DENY {
+			t.Errorf("after invalid file %s decision = %s, want previous rules to deny", invalid, decision)
+		}
+	}
+
+	reloadTestRules(t, engine, `{"rules": []}`)
+	if decision := evaluateTestRules(t, engine, s); decision != paymentv1.RiskDecision_RISK_DECISION_ALLOW {
+		t.Errorf("after fixed file decision = %s, want ALLOW", decision)
+	}
+}
+
+// newTestRules загружает правила из временного файла с содержимым rules
+func newTestRules(t *testing.T, rules string) *RulesEngine {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewRulesEngine(path)
+	if err != nil {
+		t.Fatalf("NewRulesEngine: %v", err)
+	}
+	return engine
+}
+
+// reloadTestRules перезаписывает файл правил и ждет, пока watch заметит изменение
+func reloadTestRules(t *testing.T, engine *RulesEngine, rules string) {
+	t.Helper()
+
+	if err := os.WriteFile(engine.path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Время изменения сдвигается явно: две записи подряд могут получить одинаковое время
+	modTime := engine.modTime.Add(time.Second)
+	if err := os.Chtimes(engine.path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		engine.watch(ctx, 5*time.Millisecond)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+
+	if !engine.modTime.Equal(modTime) {
+		t.Fatalf("watch did not check the changed rules file")
+	}
+}
+
+// evaluateTestRules проверяет правилами оплату testRulesUser
+func evaluateTestRules(t *testing.T, engine *RulesEngine, s *paymentService) paymentv1.RiskDecision {
+	t.Helper()
+
+	risk, err := engine.Evaluate(context.Background(), s.store, &paymentv1.PayOrderRequest{
+		OrderUuid:     uuid.NewString(),
+		UserUuid:      testRulesUser,
+		PaymentMethod: paymentv1.PaymentMethod_PAYMENT_METHOD_CARD,
+		Amount:        rubles(1),
+		Currency:      testCurrency,
+	})
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	return risk.GetDecision()
+}
+
+// payCard проводит оплату testRulesUser картой на amount рублей
+func payCard(t *testing.T, s *paymentService, amount int64) {
+	t.Helper()
+
+	_, err := s.PayOrder(context.Background(), &paymentv1.PayOrderRequest{
+		OrderUuid:     uuid.NewString(),
+		UserUuid:      testRulesUser,
+		PaymentMethod: paymentv1.PaymentMethod_PAYMENT_METHOD_CARD,
+		Amount:        rubles(amount),
+		Currency:      testCurrency,
+	})
+	if err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+}
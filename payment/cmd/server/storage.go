@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+var (
+	// ErrTransactionNotFound возвращается хранилищем, если транзакция с указанным UUID отсутствует
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrOrderAlreadyPaid возвращается CreateTransaction, если по заказу уже есть действующая транзакция
+	ErrOrderAlreadyPaid = errors.New("transaction for order already exists")
+)
+
+// TransactionStore описывает хранилище транзакций.
+// Реализации обязаны быть потокобезопасными, а UpdateTransaction — атомарным:
+// updateFunc получает актуальную копию транзакции, и если она вернула ошибку, изменения не сохраняются.
+// Транзакция заблокирована на время updateFunc, поэтому провайдеры из нее не вызываются (см. runOperation).
+// На один order_uuid приходится не больше одной действующей (не аннулированной и не отклоненной) транзакции:
+// CreateTransaction атомарно проверяет это и возвращает ErrOrderAlreadyPaid,
+// а GetTransactionByOrder возвращает именно действующую транзакцию.
+// ListExpiredAuthorizations пропускает транзакции с незавершенной операцией у провайдера.
+// Вместе с транзакцией CreateTransaction и UpdateTransaction атомарно сохраняют проводки главной книги,
+// которыми записывается ее изменение (см. ledgerEntries), а UpdateTransaction — и добавленные возвраты.
+type TransactionStore interface {
+	GetTransaction(ctx context.Context, uuid string) (*paymentv1.Transaction, error)
+	GetTransactionByOrder(ctx context.Context, orderUUID string) (*paymentv1.Transaction, error)
+	// GetTransactionByReference ищет транзакцию по идентификатору операции у провайдера
+	GetTransactionByReference(ctx context.Context, provider, reference string) (*paymentv1.Transaction, error)
+	ListTransactions(ctx context.Context, filter TransactionFilter) (*TransactionPage, error)
+	CreateTransaction(ctx context.Context, transaction *paymentv1.Transaction) error
+	UpdateTransaction(ctx context.Context, uuid string, updateFunc func(*paymentv1.Transaction) error) error
+	// ListExpiredAuthorizations возвращает UUID до limit авторизаций (в том числе ожидающих подтверждения
+	// провайдера), срок которых истек к моменту before
+	ListExpiredAuthorizations(ctx context.Context, before time.Time, limit int) ([]string, error)
+	// ListStalePendingOperations возвращает UUID до limit транзакций с операцией у провайдера,
+	// начатой не позже before (см. recoverOperation)
+	ListStalePendingOperations(ctx context.Context, before time.Time, limit int) ([]string, error)
+	// GetAccountBalance возвращает баланс счета в валюте: сумму его сторон проводок в миллиардных долях
+	GetAccountBalance(ctx context.Context, account, currency string) (int64, error)
+	ListLedgerEntries(ctx context.Context, filter LedgerFilter) (*LedgerPage, error)
+	// LedgerViolations возвращает несбалансированные проводки и валюты с ненулевой суммой всех проводок
+	LedgerViolations(ctx context.Context) ([]LedgerViolation, error)
+	// UserActivity считает транзакции пользователя, созданные начиная с since (см. UserActivity)
+	UserActivity(ctx context.Context, userUUID, currency string, since time.Time) (UserActivity, error)
+	Close()
+}
+
+// UserActivity — недавние оплаты пользователя для правил антифрода.
+// Count — число всех транзакций, включая отклоненные; Amount — сумма действующих транзакций в валюте
+// в миллиардных долях. С пустой валютой Count считается по всем валютам, а Amount равен нулю.
+type UserActivity struct {
+	Count  int
+	Amount int64
+}
+
+// inactive сообщает, что транзакция больше не занимает заказ: его можно оплатить заново
+func inactive(transaction *paymentv1.Transaction) bool {
+	switch transaction.GetStatus() {
+	case paymentv1.TransactionStatus_TRANSACTION_STATUS_VOIDED,
+		paymentv1.TransactionStatus_TRANSACTION_STATUS_FAILED:
+		return true
+	default:
+		return false
+	}
+}
+
+// expirable сообщает, что авторизацию нужно аннулировать по истечении срока.
+// Транзакция с незавершенной операцией у провайдера пропускается до ее завершения
+// или восстановления (см. recoverOperation).
+func expirable(transaction *paymentv1.Transaction) bool {
+	if transaction.GetPendingOperation() != nil {
+		return false
+	}
+	switch transaction.GetStatus() {
+	case paymentv1.TransactionStatus_TRANSACTION_STATUS_AUTHORIZED,
+		paymentv1.TransactionStatus_TRANSACTION_STATUS_PENDING:
+		return transaction.GetExpiresAt() != nil
+	default:
+		return false
+	}
+}
+
+// InMemoryTransactionStore хранит транзакции в памяти процесса; данные теряются при перезапуске
+type InMemoryTransactionStore struct {
+	mu           sync.RWMutex
+	transactions map[string]*paymentv1.Transaction // ключ — transaction_uuid
+	byOrder      map[string]string                 // order_uuid -> transaction_uuid действующей транзакции
+	entries      []*paymentv1.LedgerEntry          // в порядке записи
+}
+
+func NewInMemoryTransactionStore() *InMemoryTransactionStore {
+	return &InMemoryTransactionStore{
+		transactions: make(map[string]*paymentv1.Transaction),
+		byOrder:      make(map[string]string),
+	}
+}
+
+func (s *InMemoryTransactionStore) GetTransaction(_ context.Context, uuid string) (*paymentv1.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transaction, ok := s.transactions[uuid]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	return proto.Clone(transaction).(*paymentv1.Transaction), nil
+}
+
+func (s *InMemoryTransactionStore) GetTransactionByOrder(_ context.Context, orderUUID string) (*paymentv1.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	uuid, ok := s.byOrder[orderUUID]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	return proto.Clone(s.transactions[uuid]).(*paymentv1.Transaction), nil
+}
+
+func (s *InMemoryTransactionStore) GetTransactionByReference(_ context.Context, provider, reference string) (*paymentv1.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, transaction := range s.transactions {
+		if transaction.GetProvider() == provider && transaction.GetProviderReference() == reference {
+			return proto.Clone(transaction).(*paymentv1.Transaction), nil
+		}
+	}
+	return nil, ErrTransactionNotFound
+}
+
+func (s *InMemoryTransactionStore) ListTransactions(_ context.Context, filter TransactionFilter) (*TransactionPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*paymentv1.Transaction
+	for _, transaction := range s.transactions {
+		if filter.matches(transaction) && filter.afterCursor(transaction) {
+			matched = append(matched, transaction)
+		}
+	}
+	slices.SortFunc(matched, compareTransactions)
+
+	page := &TransactionPage{}
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+		page.NextCursor = cursorFor(matched[len(matched)-1])
+	}
+	page.Transactions = make([]*paymentv1.Transaction, 0, len(matched))
+	for _, transaction := range matched {
+		page.Transactions = append(page.Transactions, proto.Clone(transaction).(*paymentv1.Transaction))
+	}
+	return page, nil
+}
+
+func (s *InMemoryTransactionStore) CreateTransaction(_ context.Context, transaction *paymentv1.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byOrder[transaction.GetOrderUuid()]; ok {
+		return ErrOrderAlreadyPaid
+	}
+	s.transactions[transaction.GetTransactionUuid()] = proto.Clone(transaction).(*paymentv1.Transaction)
+	if !inactive(transaction) {
+		s.byOrder[transaction.GetOrderUuid()] = transaction.GetTransactionUuid()
+	}
+	s.entries = append(s.entries, ledgerEntries(nil, transaction)...)
+	return nil
+}
+
+func (s *InMemoryTransactionStore) UpdateTransaction(_ context.Context, uuid string, updateFunc func(*paymentv1.Transaction) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction, ok := s.transactions[uuid]
+	if !ok {
+		return ErrTransactionNotFound
+	}
+
+	updated := proto.Clone(transaction).(*paymentv1.Transaction)
+	if err := updateFunc(updated); err != nil {
+		return err
+	}
+	s.transactions[uuid] = updated
+	s.entries = append(s.entries, ledgerEntries(transaction, updated)...)
+	if inactive(updated) && s.byOrder[updated.GetOrderUuid()] == uuid {
+		delete(s.byOrder, updated.GetOrderUuid())
+	}
+	return nil
+}
+
+func (s *InMemoryTransactionStore) ListExpiredAuthorizations(_ context.Context, before time.Time, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var uuids []string
+	for uuid, transaction := range s.transactions {
+		if len(uuids) == limit {
+			break
+		}
+		if expirable(transaction) && !transaction.GetExpiresAt().AsTime().After(before) {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids, nil
+}
+
+func (s *InMemoryTransactionStore) ListStalePendingOperations(_ context.Context, before time.Time, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var uuids []string
+	for uuid, transaction := range s.transactions {
+		if len(uuids) == limit {
+			break
+		}
+		if pending := transaction.GetPendingOperation(); pending != nil && !pending.GetStartedAt().AsTime().After(before) {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids, nil
+}
+
+func (s *InMemoryTransactionStore) GetAccountBalance(_ context.Context, account, currency string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var balance int64
+	for _, entry := range s.entries {
+		if entry.GetCurrency() != currency {
+			continue
+		}
+		for _, posting := range entry.GetPostings() {
+			if posting.GetAccount() == account {
+				balance += toNanos(posting.GetAmount())
+			}
+		}
+	}
+	return balance, nil
+}
+
+func (s *InMemoryTransactionStore) ListLedgerEntries(_ context.Context, filter LedgerFilter) (*LedgerPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*paymentv1.LedgerEntry
+	for _, entry := range s.entries {
+		if filter.matches(entry) && filter.afterCursor(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	slices.SortFunc(matched, compareEntries)
+
+	page := &LedgerPage{}
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+		page.NextCursor = entryCursorFor(matched[len(matched)-1])
+	}
+	page.Entries = make([]*paymentv1.LedgerEntry, 0, len(matched))
+	for _, entry := range matched {
+		page.Entries = append(page.Entries, proto.Clone(entry).(*paymentv1.LedgerEntry))
+	}
+	return page, nil
+}
+
+func (s *InMemoryTransactionStore) LedgerViolations(_ context.Context) ([]LedgerViolation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var violations []LedgerViolation
+	totals := make(map[string]int64)
+	for _, entry := range s.entries {
+		var sum int64
+		for _, posting := range entry.GetPostings() {
+			sum += toNanos(posting.GetAmount())
+		}
+		if sum != 0 {
+			violations = append(violations, LedgerViolation{EntryUUID: entry.GetEntryUuid(), Currency: entry.GetCurrency(), Sum: sum})
+		}
+		totals[entry.GetCurrency()] += sum
+	}
+	for currency, sum := range totals {
+		if sum != 0 {
+			violations = append(violations, LedgerViolation{Currency: currency, Sum: sum})
+		}
+	}
+	return violations, nil
+}
+
+func (s *InMemoryTransactionStore) UserActivity(_ context.Context, userUUID, currency string, since time.Time) (UserActivity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var activity UserActivity
+	for _, transaction := range s.transactions {
+		if transaction.GetUserUuid() != userUUID || transaction.GetCreatedAt().AsTime().Before(since) {
+			continue
+		}
+		if currency == "" {
+			activity.Count++
+			continue
+		}
+		if transaction.GetCurrency() != currency {
+			continue
+		}
+		activity.Count++
+		if !inactive(transaction) {
+			activity.Amount += toNanos(transaction.GetAmount())
+		}
+	}
+	return activity, nil
+}
+
+func (s *InMemoryTransactionStore) Close() {}
@@ -0,0 +1,715 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationsLockID — ключ advisory lock, под которым применяются миграции,
+// чтобы несколько экземпляров сервиса не накатывали их одновременно
+const migrationsLockID = 7_300_002
+
+// PostgresTransactionStore хранит транзакции в PostgreSQL.
+// Суммы хранятся в NUMERIC(28, 9), чтобы совпадать с точностью Money без потерь.
+type PostgresTransactionStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresTransactionStore подключается к PostgreSQL по DSN и применяет миграции схемы
+func NewPostgresTransactionStore(ctx context.Context, dsn string) (*PostgresTransactionStore, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create postgres pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+
+	if err := applyMigrations(ctx, pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	return &PostgresTransactionStore{pool: pool}, nil
+}
+
+// applyMigrations накатывает ещё не применённые SQL-файлы из migrations/ в лексикографическом порядке.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью в schema_migrations.
+func applyMigrations(ctx context.Context, pool *pgxpool.Pool) error {
+	if _, err := pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    TEXT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	names, err := fs.Glob(migrationsFS, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		script, err := migrationsFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationsLockID); err != nil {
+				return err
+			}
+
+			var applied bool
+			if err := tx.QueryRow(ctx,
+				"SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", name,
+			).Scan(&applied); err != nil {
+				return err
+			}
+			if applied {
+				return nil
+			}
+
+			if _, err := tx.Exec(ctx, string(script)); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", name); err != nil {
+				return err
+			}
+			log.Printf("applied migration %s", name)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// nanosToNumeric и numericToNanos переводят сумму между миллиардными долями и NUMERIC
+func nanosToNumeric(nanos int64) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(nanos), Exp: -9, Valid: true}
+}
+
+func numericToNanos(n pgtype.Numeric) (int64, error) {
+	if !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite {
+		return 0, errors.New("amount is not a finite number")
+	}
+	value := new(big.Int).Set(n.Int)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(n.Exp+9))), nil)
+	if n.Exp+9 >= 0 {
+		value.Mul(value, scale)
+	} else {
+		value.Quo(value, scale)
+	}
+	if !value.IsInt64() {
+		return 0, errors.New("amount overflows int64 nanos")
+	}
+	return value.Int64(), nil
+}
+
+func abs(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+const transactionColumns = `transaction_uuid, order_uuid, user_uuid, payment_method, amount, currency, refunded_amount, status, created_at, updated_at, expires_at, provider, provider_reference, decline_code, risk_decision, risk_rule_ids, ` +
+	`pending_operation_uuid, pending_operation_type, pending_operation_amount, pending_operation_key, pending_operation_started_at, pending_operation_reason`
+
+// activeTransaction — условие частичного уникального индекса по order_uuid (миграция 00005)
+const activeTransaction = `status NOT IN ('TRANSACTION_STATUS_VOIDED', 'TRANSACTION_STATUS_FAILED')`
+
+func scanTransaction(row pgx.Row) (*paymentv1.Transaction, error) {
+	var (
+		transaction            paymentv1.Transaction
+		paymentMethod, status  string
+		riskDecision           string
+		riskRuleIDs            []string
+		amount, refundedAmount pgtype.Numeric
+		createdAt, updatedAt   pgtype.Timestamptz
+		expiresAt              pgtype.Timestamptz
+		pendingUUID            pgtype.Text
+		pendingType            pgtype.Text
+		pendingAmount          pgtype.Numeric
+		pendingKey             pgtype.Text
+		pendingStartedAt       pgtype.Timestamptz
+		pendingReason          pgtype.Text
+	)
+	if err := row.Scan(
+		&transaction.TransactionUuid,
+		&transaction.OrderUuid,
+		&transaction.UserUuid,
+		&paymentMethod,
+		&amount,
+		&transaction.Currency,
+		&refundedAmount,
+		&status,
+		&createdAt,
+		&updatedAt,
+		&expiresAt,
+		&transaction.Provider,
+		&transaction.ProviderReference,
+		&transaction.DeclineCode,
+		&riskDecision,
+		&riskRuleIDs,
+		&pendingUUID,
+		&pendingType,
+		&pendingAmount,
+		&pendingKey,
+		&pendingStartedAt,
+		&pendingReason,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+
+	amountNanos, err := numericToNanos(amount)
+	if err != nil {
+		return nil, err
+	}
+	refundedNanos, err := numericToNanos(refundedAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.PaymentMethod = paymentv1.PaymentMethod(paymentv1.PaymentMethod_value[paymentMethod])
+	transaction.Status = paymentv1.TransactionStatus(paymentv1.TransactionStatus_value[status])
+	transaction.Amount = fromNanos(amountNanos)
+	transaction.RefundedAmount = fromNanos(refundedNanos)
+	transaction.CreatedAt = timestamppb.New(createdAt.Time)
+	transaction.UpdatedAt = timestamppb.New(updatedAt.Time)
+	if expiresAt.Valid {
+		transaction.ExpiresAt = timestamppb.New(expiresAt.Time)
+	}
+	if decision := paymentv1.RiskDecision(paymentv1.RiskDecision_value[riskDecision]); decision != paymentv1.RiskDecision_RISK_DECISION_UNSPECIFIED {
+		transaction.Risk = &paymentv1.RiskAssessment{Decision: decision, RuleIds: riskRuleIDs}
+	}
+	if pendingUUID.Valid {
+		pending := &paymentv1.PendingOperation{
+			OperationUuid:  pendingUUID.String,
+			Type:           paymentv1.OperationType(paymentv1.OperationType_value[pendingType.String]),
+			IdempotencyKey: pendingKey.String,
+			StartedAt:      timestamppb.New(pendingStartedAt.Time),
+			Reason:         pendingReason.String,
+		}
+		if pendingAmount.Valid {
+			nanos, err := numericToNanos(pendingAmount)
+			if err != nil {
+				return nil, err
+			}
+			pending.Amount = fromNanos(nanos)
+		}
+		transaction.PendingOperation = pending
+	}
+	return &transaction, nil
+}
+
+// querier — общая часть pgxpool.Pool и pgx.Tx, через которую дочитываются возвраты
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// loadRefunds дочитывает возвраты транзакций из refunds одним запросом
+func loadRefunds(ctx context.Context, q querier, transactions ...*paymentv1.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	byUUID := make(map[string]*paymentv1.Transaction, len(transactions))
+	uuids := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		byUUID[transaction.GetTransactionUuid()] = transaction
+		uuids = append(uuids, transaction.GetTransactionUuid())
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT refund_uuid, transaction_uuid, amount, reason, idempotency_key, created_at FROM refunds
+		WHERE transaction_uuid = ANY($1)
+		ORDER BY transaction_uuid, created_at, refund_uuid`, uuids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			refund          paymentv1.Refund
+			transactionUUID string
+			amount          pgtype.Numeric
+			idempotencyKey  *string
+			createdAt       time.Time
+		)
+		if err := rows.Scan(&refund.RefundUuid, &transactionUUID, &amount, &refund.Reason, &idempotencyKey, &createdAt); err != nil {
+			return err
+		}
+		nanos, err := numericToNanos(amount)
+		if err != nil {
+			return err
+		}
+		refund.Amount = fromNanos(nanos)
+		if idempotencyKey != nil {
+			refund.IdempotencyKey = *idempotencyKey
+		}
+		refund.CreatedAt = timestamppb.New(createdAt)
+		transaction := byUUID[transactionUUID]
+		transaction.Refunds = append(transaction.Refunds, &refund)
+	}
+	return rows.Err()
+}
+
+// insertRefunds сохраняет возвраты, добавленные к транзакции при изменении before -> after
+func insertRefunds(ctx context.Context, tx pgx.Tx, before, after *paymentv1.Transaction) error {
+	for _, refund := range after.GetRefunds()[len(before.GetRefunds()):] {
+		var idempotencyKey *string
+		if key := refund.GetIdempotencyKey(); key != "" {
+			idempotencyKey = &key
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO refunds (refund_uuid, transaction_uuid, amount, reason, idempotency_key, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			refund.GetRefundUuid(),
+			after.GetTransactionUuid(),
+			nanosToNumeric(toNanos(refund.GetAmount())),
+			refund.GetReason(),
+			idempotencyKey,
+			refund.GetCreatedAt().AsTime(),
+		); err != nil {
+			return fmt.Errorf("failed to insert refund: %w", err)
+		}
+	}
+	return nil
+}
+
+// getTransaction выбирает одну транзакцию запросом по transactionColumns вместе с ее возвратами
+func (s *PostgresTransactionStore) getTransaction(ctx context.Context, query string, args ...any) (*paymentv1.Transaction, error) {
+	transaction, err := scanTransaction(s.pool.QueryRow(ctx, query, args...))
+	if err != nil {
+		return nil, err
+	}
+	if err := loadRefunds(ctx, s.pool, transaction); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+func (s *PostgresTransactionStore) GetTransaction(ctx context.Context, uuid string) (*paymentv1.Transaction, error) {
+	return s.getTransaction(ctx,
+		"SELECT "+transactionColumns+" FROM transactions WHERE transaction_uuid = $1", uuid)
+}
+
+func (s *PostgresTransactionStore) GetTransactionByOrder(ctx context.Context, orderUUID string) (*paymentv1.Transaction, error) {
+	return s.getTransaction(ctx,
+		"SELECT "+transactionColumns+" FROM transactions WHERE order_uuid = $1 AND "+activeTransaction, orderUUID)
+}
+
+func (s *PostgresTransactionStore) GetTransactionByReference(ctx context.Context, provider, reference string) (*paymentv1.Transaction, error) {
+	return s.getTransaction(ctx,
+		"SELECT "+transactionColumns+" FROM transactions WHERE provider = $1 AND provider_reference = $2",
+		provider, reference)
+}
+
+// ListTransactions строит запрос по фильтру и использует ключевую пагинацию
+// по (created_at, transaction_uuid), опираясь на индексы из миграции 00001
+func (s *PostgresTransactionStore) ListTransactions(ctx context.Context, filter TransactionFilter) (*TransactionPage, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.UserUUID != "" {
+		conditions = append(conditions, "user_uuid = "+arg(filter.UserUUID))
+	}
+	if filter.OrderUUID != "" {
+		conditions = append(conditions, "order_uuid = "+arg(filter.OrderUUID))
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.CreatedTo))
+	}
+	if filter.RiskDecision != paymentv1.RiskDecision_RISK_DECISION_UNSPECIFIED {
+		conditions = append(conditions, "risk_decision = "+arg(filter.RiskDecision.String()))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, transaction_uuid) < (%s, %s)",
+			arg(filter.After.CreatedAt), arg(filter.After.UUID)))
+	}
+
+	query := "SELECT " + transactionColumns + " FROM transactions"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, transaction_uuid DESC LIMIT " + arg(filter.Limit+1)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*paymentv1.Transaction, error) {
+		return scanTransaction(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := loadRefunds(ctx, s.pool, transactions...); err != nil {
+		return nil, err
+	}
+
+	page := &TransactionPage{}
+	if len(transactions) > filter.Limit {
+		transactions = transactions[:filter.Limit]
+		page.NextCursor = cursorFor(transactions[len(transactions)-1])
+	}
+	page.Transactions = transactions
+	return page, nil
+}
+
+// CreateTransaction опирается на уникальный индекс по order_uuid действующих транзакций,
+// поэтому из конкурентных оплат одного заказа сохраняется только одна
+func (s *PostgresTransactionStore) CreateTransaction(ctx context.Context, transaction *paymentv1.Transaction) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return createTransaction(ctx, tx, transaction)
+	})
+}
+
+func createTransaction(ctx context.Context, tx pgx.Tx, transaction *paymentv1.Transaction) error {
+	args := []any{
+		transaction.GetTransactionUuid(),
+		transaction.GetOrderUuid(),
+		transaction.GetUserUuid(),
+		transaction.GetPaymentMethod().String(),
+		nanosToNumeric(toNanos(transaction.GetAmount())),
+		transaction.GetCurrency(),
+		nanosToNumeric(toNanos(transaction.GetRefundedAmount())),
+		transaction.GetStatus().String(),
+		transaction.GetCreatedAt().AsTime(),
+		transaction.GetUpdatedAt().AsTime(),
+		optionalTime(transaction.GetExpiresAt()),
+		transaction.GetProvider(),
+		transaction.GetProviderReference(),
+		transaction.GetDeclineCode(),
+		transaction.GetRisk().GetDecision().String(),
+		riskRuleIDs(transaction.GetRisk()),
+	}
+	args = append(args, pendingOperationArgs(transaction.GetPendingOperation())...)
+	tag, err := tx.Exec(ctx,
+		"INSERT INTO transactions ("+transactionColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,"+
+			" $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)"+
+			" ON CONFLICT (order_uuid) WHERE "+activeTransaction+" DO NOTHING",
+		args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrderAlreadyPaid
+	}
+	return insertLedgerEntries(ctx, tx, ledgerEntries(nil, transaction))
+}
+
+// UpdateTransaction блокирует строку транзакции (SELECT ... FOR UPDATE) на время применения updateFunc,
+// поэтому конкурентные возвраты по одной транзакции выполняются последовательно
+func (s *PostgresTransactionStore) UpdateTransaction(ctx context.Context, uuid string, updateFunc func(*paymentv1.Transaction) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		transaction, err := scanTransaction(tx.QueryRow(ctx,
+			"SELECT "+transactionColumns+" FROM transactions WHERE transaction_uuid = $1 FOR UPDATE", uuid))
+		if err != nil {
+			return err
+		}
+		if err := loadRefunds(ctx, tx, transaction); err != nil {
+			return err
+		}
+
+		before := proto.Clone(transaction).(*paymentv1.Transaction)
+		if err := updateFunc(transaction); err != nil {
+			return err
+		}
+
+		args := []any{
+			uuid,
+			nanosToNumeric(toNanos(transaction.GetRefundedAmount())),
+			transaction.GetStatus().String(),
+			transaction.GetUpdatedAt().AsTime(),
+			transaction.GetDeclineCode(),
+		}
+		args = append(args, pendingOperationArgs(transaction.GetPendingOperation())...)
+		_, err = tx.Exec(ctx, `
+			UPDATE transactions
+			SET refunded_amount = $2, status = $3, updated_at = $4, decline_code = $5,
+				pending_operation_uuid = $6, pending_operation_type = $7, pending_operation_amount = $8,
+				pending_operation_key = $9, pending_operation_started_at = $10, pending_operation_reason = $11
+			WHERE transaction_uuid = $1`,
+			args...)
+		if err != nil {
+			return err
+		}
+		if err := insertRefunds(ctx, tx, before, transaction); err != nil {
+			return err
+		}
+		return insertLedgerEntries(ctx, tx, ledgerEntries(before, transaction))
+	})
+}
+
+func (s *PostgresTransactionStore) ListExpiredAuthorizations(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT transaction_uuid FROM transactions
+		WHERE status IN ('TRANSACTION_STATUS_AUTHORIZED', 'TRANSACTION_STATUS_PENDING') AND expires_at <= $1
+			AND pending_operation_uuid IS NULL
+		ORDER BY expires_at
+		LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// ListStalePendingOperations использует частичный индекс transactions_pending_operation_idx (миграция 00010)
+func (s *PostgresTransactionStore) ListStalePendingOperations(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT transaction_uuid FROM transactions
+		WHERE pending_operation_uuid IS NOT NULL AND pending_operation_started_at <= $1
+		ORDER BY pending_operation_started_at
+		LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// insertLedgerEntries сохраняет проводки в транзакции БД, в которой изменена транзакция оплаты
+func insertLedgerEntries(ctx context.Context, tx pgx.Tx, entries []*paymentv1.LedgerEntry) error {
+	for _, entry := range entries {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ledger_entries (entry_uuid, transaction_uuid, type, currency, created_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+			entry.GetEntryUuid(),
+			entry.GetTransactionUuid(),
+			entry.GetType().String(),
+			entry.GetCurrency(),
+			entry.GetCreatedAt().AsTime(),
+		); err != nil {
+			return fmt.Errorf("failed to insert ledger entry: %w", err)
+		}
+		for _, posting := range entry.GetPostings() {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO ledger_postings (entry_uuid, account, currency, amount)
+				VALUES ($1, $2, $3, $4)`,
+				entry.GetEntryUuid(),
+				posting.GetAccount(),
+				entry.GetCurrency(),
+				nanosToNumeric(toNanos(posting.GetAmount())),
+			); err != nil {
+				return fmt.Errorf("failed to insert ledger posting: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *PostgresTransactionStore) GetAccountBalance(ctx context.Context, account, currency string) (int64, error) {
+	var balance pgtype.Numeric
+	if err := s.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM ledger_postings
+		WHERE account = $1 AND currency = $2`, account, currency,
+	).Scan(&balance); err != nil {
+		return 0, err
+	}
+	return numericToNanos(balance)
+}
+
+// ListLedgerEntries выбирает страницу проводок с ключевой пагинацией по (created_at, entry_uuid)
+// и затем загружает их стороны одним запросом
+func (s *PostgresTransactionStore) ListLedgerEntries(ctx context.Context, filter LedgerFilter) (*LedgerPage, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Account != "" {
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM ledger_postings p WHERE p.entry_uuid = e.entry_uuid AND p.account = "+arg(filter.Account)+")")
+	}
+	if filter.TransactionUUID != "" {
+		conditions = append(conditions, "e.transaction_uuid = "+arg(filter.TransactionUUID))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(e.created_at, e.entry_uuid) < (%s, %s)",
+			arg(filter.After.CreatedAt), arg(filter.After.UUID)))
+	}
+
+	query := "SELECT e.entry_uuid, e.transaction_uuid, e.type, e.currency, e.created_at FROM ledger_entries e"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY e.created_at DESC, e.entry_uuid DESC LIMIT " + arg(filter.Limit+1)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*paymentv1.LedgerEntry, error) {
+		var (
+			entry     paymentv1.LedgerEntry
+			entryType string
+			createdAt time.Time
+		)
+		if err := row.Scan(&entry.EntryUuid, &entry.TransactionUuid, &entryType, &entry.Currency, &createdAt); err != nil {
+			return nil, err
+		}
+		entry.Type = paymentv1.LedgerEntryType(paymentv1.LedgerEntryType_value[entryType])
+		entry.CreatedAt = timestamppb.New(createdAt)
+		return &entry, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &LedgerPage{}
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+		page.NextCursor = entryCursorFor(entries[len(entries)-1])
+	}
+	page.Entries = entries
+	if len(entries) == 0 {
+		return page, nil
+	}
+
+	byUUID := make(map[string]*paymentv1.LedgerEntry, len(entries))
+	uuids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		byUUID[entry.GetEntryUuid()] = entry
+		uuids = append(uuids, entry.GetEntryUuid())
+	}
+	rows, err = s.pool.Query(ctx, `
+		SELECT entry_uuid, account, amount FROM ledger_postings
+		WHERE entry_uuid = ANY($1)
+		ORDER BY entry_uuid, account`, uuids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			entryUUID, account string
+			amount             pgtype.Numeric
+		)
+		if err := rows.Scan(&entryUUID, &account, &amount); err != nil {
+			return nil, err
+		}
+		nanos, err := numericToNanos(amount)
+		if err != nil {
+			return nil, err
+		}
+		entry := byUUID[entryUUID]
+		entry.Postings = append(entry.Postings, &paymentv1.Posting{Account: account, Amount: fromNanos(nanos)})
+	}
+	return page, rows.Err()
+}
+
+func (s *PostgresTransactionStore) LedgerViolations(ctx context.Context) ([]LedgerViolation, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT entry_uuid, currency, SUM(amount) FROM ledger_postings
+		GROUP BY entry_uuid, currency HAVING SUM(amount) <> 0
+		UNION ALL
+		SELECT '', currency, SUM(amount) FROM ledger_postings
+		GROUP BY currency HAVING SUM(amount) <> 0`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (LedgerViolation, error) {
+		var (
+			violation LedgerViolation
+			sum       pgtype.Numeric
+		)
+		if err := row.Scan(&violation.EntryUUID, &violation.Currency, &sum); err != nil {
+			return violation, err
+		}
+		nanos, err := numericToNanos(sum)
+		violation.Sum = nanos
+		return violation, err
+	})
+}
+
+func (s *PostgresTransactionStore) UserActivity(ctx context.Context, userUUID, currency string, since time.Time) (UserActivity, error) {
+	var (
+		activity UserActivity
+		amount   pgtype.Numeric
+	)
+	err := s.pool.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(amount) FILTER (WHERE $2 <> '' AND `+activeTransaction+`), 0)
+		FROM transactions
+		WHERE user_uuid = $1 AND ($2 = '' OR currency = $2) AND created_at >= $3`,
+		userUUID, currency, since,
+	).Scan(&activity.Count, &amount)
+	if err != nil {
+		return activity, err
+	}
+	activity.Amount, err = numericToNanos(amount)
+	return activity, err
+}
+
+// riskRuleIDs возвращает сработавшие правила для колонки TEXT[] NOT NULL
+func riskRuleIDs(risk *paymentv1.RiskAssessment) []string {
+	if risk.GetRuleIds() == nil {
+		return []string{}
+	}
+	return risk.GetRuleIds()
+}
+
+// pendingOperationArgs возвращает значения колонок pending_operation_* (миграции 00010 и 00011);
+// без незавершенной операции все они NULL
+func pendingOperationArgs(op *paymentv1.PendingOperation) []any {
+	if op == nil {
+		return []any{nil, nil, nil, nil, nil, nil}
+	}
+	var amount pgtype.Numeric
+	if op.GetAmount() != nil {
+		amount = nanosToNumeric(toNanos(op.GetAmount()))
+	}
+	return []any{op.GetOperationUuid(), op.GetType().String(), amount, op.GetIdempotencyKey(), op.GetStartedAt().AsTime(), op.GetReason()}
+}
+
+// optionalTime переводит необязательную отметку времени в NULL, если она не задана
+func optionalTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func (s *PostgresTransactionStore) Close() {
+	s.pool.Close()
+}
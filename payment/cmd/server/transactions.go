@@ -0,0 +1,199 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// TransactionFilter описывает выборку транзакций для ListTransactions.
+// Пустые поля не ограничивают выборку; CreatedFrom включается в интервал, CreatedTo — нет.
+// Транзакции всегда упорядочены от новых к старым.
+type TransactionFilter struct {
+	UserUUID    string
+	OrderUUID   string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// RiskDecision, если задан, оставляет только транзакции с этим решением правил антифрода
+	RiskDecision paymentv1.RiskDecision
+
+	Limit int
+	After *PageCursor
+}
+
+// TransactionPage — страница результата ListTransactions
+type TransactionPage struct {
+	Transactions []*paymentv1.Transaction
+	NextCursor   *PageCursor
+}
+
+// PageCursor указывает на последнюю запись (транзакцию или проводку) предыдущей страницы.
+// Пагинация ключевая: по (created_at, UUID записи).
+type PageCursor struct {
+	CreatedAt time.Time `json:"c"`
+	UUID      string    `json:"u"`
+}
+
+func cursorFor(transaction *paymentv1.Transaction) *PageCursor {
+	return &PageCursor{
+		CreatedAt: transaction.GetCreatedAt().AsTime(),
+		UUID:      transaction.GetTransactionUuid(),
+	}
+}
+
+func (c *PageCursor) Encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// pageToken кодирует курсор следующей страницы; ошибка возвращается клиенту как Internal
+func pageToken(cursor *PageCursor) (string, error) {
+	token, err := cursor.Encode()
+	if err != nil {
+		log.Printf("failed to encode page token: %v", err)
+		return "", status.Error(codes.Internal, "internal error")
+	}
+	return token, nil
+}
+
+func decodePageCursor(s string) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid page_token")
+	}
+	var c PageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.UUID == "" {
+		return nil, errors.New("invalid page_token")
+	}
+	return &c, nil
+}
+
+// compareTransactions задает порядок выдачи: от новых к старым, при равном времени — по убыванию UUID
+func compareTransactions(a, b *paymentv1.Transaction) int {
+	if result := b.GetCreatedAt().AsTime().Compare(a.GetCreatedAt().AsTime()); result != 0 {
+		return result
+	}
+	return cmp.Compare(b.GetTransactionUuid(), a.GetTransactionUuid())
+}
+
+// afterCursor сообщает, идет ли транзакция строго после курсора
+func (f TransactionFilter) afterCursor(transaction *paymentv1.Transaction) bool {
+	if f.After == nil {
+		return true
+	}
+	createdAt := transaction.GetCreatedAt().AsTime()
+	if !createdAt.Equal(f.After.CreatedAt) {
+		return createdAt.Before(f.After.CreatedAt)
+	}
+	return transaction.GetTransactionUuid() < f.After.UUID
+}
+
+func (f TransactionFilter) matches(transaction *paymentv1.Transaction) bool {
+	if f.UserUUID != "" && transaction.GetUserUuid() != f.UserUUID {
+		return false
+	}
+	if f.OrderUUID != "" && transaction.GetOrderUuid() != f.OrderUUID {
+		return false
+	}
+	if f.RiskDecision != paymentv1.RiskDecision_RISK_DECISION_UNSPECIFIED && transaction.GetRisk().GetDecision() != f.RiskDecision {
+		return false
+	}
+	createdAt := transaction.GetCreatedAt().AsTime()
+	if f.CreatedFrom != nil && createdAt.Before(*f.CreatedFrom) {
+		return false
+	}
+	if f.CreatedTo != nil && !createdAt.Before(*f.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+// transactionFilterFromRequest разбирает и проверяет параметры ListTransactions
+func transactionFilterFromRequest(req *paymentv1.ListTransactionsRequest) (TransactionFilter, error) {
+	filter := TransactionFilter{
+		UserUUID:  req.GetFilter().GetUserUuid(),
+		OrderUUID: req.GetFilter().GetOrderUuid(),
+		Limit:     defaultPageSize,
+	}
+
+	filter.RiskDecision = req.GetFilter().GetRiskDecision()
+	if _, ok := paymentv1.RiskDecision_name[int32(filter.RiskDecision)]; !ok {
+		return filter, fmt.Errorf("unknown risk_decision %d", filter.RiskDecision)
+	}
+
+	if createdFrom := req.GetFilter().GetCreatedFrom(); createdFrom != nil {
+		t := createdFrom.AsTime()
+		filter.CreatedFrom = &t
+	}
+	if createdTo := req.GetFilter().GetCreatedTo(); createdTo != nil {
+		t := createdTo.AsTime()
+		filter.CreatedTo = &t
+	}
+
+	switch size := req.GetPageSize(); {
+	case size == 0:
+	case size < 0 || size > maxPageSize:
+		return filter, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+	default:
+		filter.Limit = int(size)
+	}
+
+	if token := req.GetPageToken(); token != "" {
+		cursor, err := decodePageCursor(token)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = cursor
+	}
+
+	return filter, nil
+}
+
+func (s *paymentService) GetTransaction(ctx context.Context, req *paymentv1.GetTransactionRequest) (*paymentv1.GetTransactionResponse, error) {
+	transaction, err := s.store.GetTransaction(ctx, req.GetTransactionUuid())
+	if errors.Is(err, ErrTransactionNotFound) {
+		return nil, status.Errorf(codes.NotFound, "transaction %s not found", req.GetTransactionUuid())
+	}
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return &paymentv1.GetTransactionResponse{Transaction: transaction}, nil
+}
+
+func (s *paymentService) ListTransactions(ctx context.Context, req *paymentv1.ListTransactionsRequest) (*paymentv1.ListTransactionsResponse, error) {
+	filter, err := transactionFilterFromRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	page, err := s.store.ListTransactions(ctx, filter)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	resp := &paymentv1.ListTransactionsResponse{Transactions: page.Transactions}
+	if page.NextCursor != nil {
+		if resp.NextPageToken, err = pageToken(page.NextCursor); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// maxAmountUnits — верхняя граница суммы одной операции. Она же гарантирует,
+// что сумма в миллиардных долях (toNanos) помещается в int64.
+const maxAmountUnits = 1_000_000_000
+
+// maxIdempotencyKeyLength — наибольшая длина ключа идемпотентности возврата
+const maxIdempotencyKeyLength = 255
+
+// fieldRule — правило проверки одного поля запроса.
+// check возвращает описание нарушения или nil, если поле корректно.
+type fieldRule[T any] struct {
+	field string
+	check func(req T) error
+}
+
+// paymentRequest — общие поля PayOrderRequest и AuthorizePaymentRequest
+type paymentRequest interface {
+	GetOrderUuid() string
+	GetUserUuid() string
+	GetPaymentMethod() paymentv1.PaymentMethod
+	GetAmount() *paymentv1.Money
+	GetCurrency() string
+}
+
+// paymentRules описывают допустимый запрос на оплату или авторизацию
+var paymentRules = []fieldRule[paymentRequest]{
+	{"order_uuid", func(req paymentRequest) error { return checkUUID(req.GetOrderUuid()) }},
+	{"user_uuid", func(req paymentRequest) error { return checkUUID(req.GetUserUuid()) }},
+	{"payment_method", func(req paymentRequest) error { return checkPaymentMethod(req.GetPaymentMethod()) }},
+	{"amount", func(req paymentRequest) error { return checkAmount(req.GetAmount()) }},
+	{"currency", func(req paymentRequest) error { return checkCurrency(req.GetCurrency()) }},
+}
+
+// transactionRequest — запрос, адресованный существующей транзакции
+type transactionRequest interface {
+	GetTransactionUuid() string
+}
+
+// transactionRules описывают допустимые CapturePaymentRequest и VoidAuthorizationRequest
+var transactionRules = []fieldRule[transactionRequest]{
+	{"transaction_uuid", func(req transactionRequest) error { return checkUUID(req.GetTransactionUuid()) }},
+}
+
+// refundPaymentRules описывают допустимый RefundPaymentRequest
+var refundPaymentRules = []fieldRule[*paymentv1.RefundPaymentRequest]{
+	{"transaction_uuid", func(req *paymentv1.RefundPaymentRequest) error { return checkUUID(req.GetTransactionUuid()) }},
+	{"amount", func(req *paymentv1.RefundPaymentRequest) error { return checkAmount(req.GetAmount()) }},
+	{"idempotency_key", func(req *paymentv1.RefundPaymentRequest) error { return checkIdempotencyKey(req.GetIdempotencyKey()) }},
+}
+
+// validate проверяет запрос по всем правилам и возвращает InvalidArgument
+// с деталями google.rpc.BadRequest, перечисляющими все нарушенные поля
+func validate[T any](req T, rules []fieldRule[T]) error {
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, rule := range rules {
+		if err := rule.check(req); err != nil {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       rule.field,
+				Description: err.Error(),
+			})
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	st := status.Newf(codes.InvalidArgument, "invalid %s: %s", violations[0].GetField(), violations[0].GetDescription())
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		// Детали не сериализовались: отдаем статус без них
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func checkUUID(s string) error {
+	if s == "" {
+		return errors.New("must not be empty")
+	}
+	if err := uuid.Validate(s); err != nil {
+		return errors.New("must be a valid UUID")
+	}
+	return nil
+}
+
+func checkPaymentMethod(method paymentv1.PaymentMethod) error {
+	if _, ok := paymentv1.PaymentMethod_name[int32(method)]; !ok || method == paymentv1.PaymentMethod_PAYMENT_METHOD_UNSPECIFIED {
+		return errors.New("must be a known payment method")
+	}
+	return nil
+}
+
+// checkAmount проверяет, что сумма корректна, больше нуля и не превышает maxAmountUnits
+func checkAmount(m *paymentv1.Money) error {
+	if m == nil {
+		return errors.New("is required")
+	}
+	if m.GetNanos() <= -nanosPerUnit || m.GetNanos() >= nanosPerUnit {
+		return errors.New("nanos must be between -999999999 and 999999999")
+	}
+	if (m.GetUnits() > 0 && m.GetNanos() < 0) || (m.GetUnits() < 0 && m.GetNanos() > 0) {
+		return errors.New("units and nanos must have the same sign")
+	}
+	if m.GetUnits() < 0 || (m.GetUnits() == 0 && m.GetNanos() <= 0) {
+		return errors.New("must be positive")
+	}
+	if m.GetUnits() > maxAmountUnits || (m.GetUnits() == maxAmountUnits && m.GetNanos() > 0) {
+		return fmt.Errorf("must not exceed %d", maxAmountUnits)
+	}
+	return nil
+}
+
+func checkCurrency(currency string) error {
+	if !currencyPattern.MatchString(currency) {
+		return errors.New("must be an ISO 4217 code")
+	}
+	return nil
+}
+
+// checkIdempotencyKey проверяет необязательный ключ идемпотентности
+func checkIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("must not be longer than %d bytes", maxIdempotencyKeyLength)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// ErrWalletOperationNotFound возвращается WalletStore, если операции с указанным reference нет
+var ErrWalletOperationNotFound = errors.New("wallet operation not found")
+
+// InsufficientFundsError — на кошельке недостаточно доступных средств для операции
+type InsufficientFundsError struct {
+	Available int64 // доступный остаток в миллиардных долях
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient wallet funds: %s available", formatNanos(e.Available))
+}
+
+// WalletOperation — оплата с кошелька. Неподтвержденная (Captured == false) операция
+// только блокирует сумму, списание выполняет Capture.
+type WalletOperation struct {
+	Reference string
+	UserUUID  string
+	Currency  string
+	Amount    int64
+	Captured  bool
+}
+
+// Статусы операций кошелька
+const (
+	walletOperationHeld     = "HELD"
+	walletOperationCaptured = "CAPTURED"
+	walletOperationVoided   = "VOIDED"
+)
+
+// WalletStore хранит кошельки инвесторов и операции по ним.
+// Все методы атомарны: проверка остатка и изменение кошелька выполняются как одно действие,
+// поэтому параллельные оплаты не уведут баланс в минус. Повтор Capture и Void уже выполненной
+// операции ничего не меняет, как и повтор Refund с тем же ключом.
+type WalletStore interface {
+	// GetWallet возвращает кошелек; у пользователя без пополнений он пустой
+	GetWallet(ctx context.Context, userUUID, currency string) (*paymentv1.Wallet, error)
+	TopUp(ctx context.Context, userUUID, currency string, amount int64) (*paymentv1.Wallet, error)
+	// Hold блокирует или сразу списывает сумму операции; при нехватке средств возвращает *InsufficientFundsError
+	Hold(ctx context.Context, operation WalletOperation) error
+	Capture(ctx context.Context, reference string) error
+	Void(ctx context.Context, reference string) error
+	// Refund возвращает на кошелек часть списанной суммы операции; key — ключ идемпотентности возврата
+	Refund(ctx context.Context, reference string, amount int64, key string) error
+}
+
+// newWallet собирает proto кошелька из сумм в миллиардных долях
+func newWallet(userUUID, currency string, balance, held int64, updatedAt time.Time) *paymentv1.Wallet {
+	wallet := &paymentv1.Wallet{
+		UserUuid:  userUUID,
+		Currency:  currency,
+		Balance:   fromNanos(balance),
+		Held:      fromNanos(held),
+		Available: fromNanos(balance - held),
+	}
+	if !updatedAt.IsZero() {
+		wallet.UpdatedAt = timestamppb.New(updatedAt)
+	}
+	return wallet
+}
+
+type walletKey struct {
+	userUUID string
+	currency string
+}
+
+type walletState struct {
+	balance   int64
+	held      int64
+	updatedAt time.Time
+}
+
+type walletOperationState struct {
+	WalletOperation
+	status   string
+	refunded int64
+	// refundKeys — ключи выполненных возвратов
+	refundKeys map[string]bool
+}
+
+// InMemoryWalletStore хранит кошельки в памяти процесса; данные теряются при перезапуске
+type InMemoryWalletStore struct {
+	mu         sync.Mutex
+	wallets    map[walletKey]*walletState
+	operations map[string]*walletOperationState // ключ — reference
+}
+
+func NewInMemoryWalletStore() *InMemoryWalletStore {
+	return &InMemoryWalletStore{
+		wallets:    make(map[walletKey]*walletState),
+		operations: make(map[string]*walletOperationState),
+	}
+}
+
+// wallet возвращает состояние кошелька, создавая пустое. Вызывается под s.mu.
+func (s *InMemoryWalletStore) wallet(userUUID, currency string) *walletState {
+	key := walletKey{userUUID: userUUID, currency: currency}
+	state, ok := s.wallets[key]
+	if !ok {
+		state = &walletState{}
+		s.wallets[key] = state
+	}
+	return state
+}
+
+func (s *InMemoryWalletStore) GetWallet(_ context.Context, userUUID, currency string) (*paymentv1.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.wallets[walletKey{userUUID: userUUID, currency: currency}]
+	if !ok {
+		return newWallet(userUUID, currency, 0, 0, time.Time{}), nil
+	}
+	return newWallet(userUUID, currency, state.balance, state.held, state.updatedAt), nil
+}
+
+func (s *InMemoryWalletStore) TopUp(_ context.Context, userUUID, currency string, amount int64) (*paymentv1.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.wallet(userUUID, currency)
+	state.balance += amount
+	state.updatedAt = now().AsTime()
+	return newWallet(userUUID, currency, state.balance, state.held, state.updatedAt), nil
+}
+
+func (s *InMemoryWalletStore) Hold(_ context.Context, operation WalletOperation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.wallet(operation.UserUUID, operation.Currency)
+	if available := state.balance - state.held; available < operation.Amount {
+		return &InsufficientFundsError{Available: available}
+	}
+
+	status := walletOperationHeld
+	if operation.Captured {
+		state.balance -= operation.Amount
+		status = walletOperationCaptured
+	} else {
+		state.held += operation.Amount
+	}
+	state.updatedAt = now().AsTime()
+	s.operations[operation.Reference] = &walletOperationState{WalletOperation: operation, status: status}
+	return nil
+}
+
+func (s *InMemoryWalletStore) Capture(_ context.Context, reference string) error {
+	return s.update(reference, func(operation *walletOperationState, state *walletState) error {
+		switch operation.status {
+		case walletOperationCaptured:
+			return nil
+		case walletOperationHeld:
+			state.held -= operation.Amount
+			state.balance -= operation.Amount
+			operation.status = walletOperationCaptured
+			return nil
+		default:
+			return fmt.Errorf("wallet operation %s is %s and cannot be captured", reference, operation.status)
+		}
+	})
+}
+
+func (s *InMemoryWalletStore) Void(_ context.Context, reference string) error {
+	return s.update(reference, func(operation *walletOperationState, state *walletState) error {
+		switch operation.status {
+		case walletOperationVoided:
+			return nil
+		case walletOperationHeld:
+			state.held -= operation.Amount
+			operation.status = walletOperationVoided
+			return nil
+		default:
+			return fmt.Errorf("wallet operation %s is %s and cannot be voided", reference, operation.status)
+		}
+	})
+}
+
+func (s *InMemoryWalletStore) Refund(_ context.Context, reference string, amount int64, key string) error {
+	return s.update(reference, func(operation *walletOperationState, state *walletState) error {
+		if operation.refundKeys[key] {
+			return nil
+		}
+		if operation.status != walletOperationCaptured {
+			return fmt.Errorf("wallet operation %s is %s and cannot be refunded", reference, operation.status)
+		}
+		if operation.refunded+amount > operation.Amount {
+			return fmt.Errorf("refund exceeds wallet operation %s", reference)
+		}
+		operation.refunded += amount
+		state.balance += amount
+		if operation.refundKeys == nil {
+			operation.refundKeys = make(map[string]bool)
+		}
+		operation.refundKeys[key] = true
+		return nil
+	})
+}
+
+// update применяет updateFunc к операции и ее кошельку под блокировкой
+func (s *InMemoryWalletStore) update(reference string, updateFunc func(*walletOperationState, *walletState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operation, ok := s.operations[reference]
+	if !ok {
+		return ErrWalletOperationNotFound
+	}
+	state := s.wallet(operation.UserUUID, operation.Currency)
+	if err := updateFunc(operation, state); err != nil {
+		return err
+	}
+	state.updatedAt = now().AsTime()
+	return nil
+}
+
+// topUpWalletRules описывают допустимый TopUpWalletRequest
+var topUpWalletRules = []fieldRule[*paymentv1.TopUpWalletRequest]{
+	{"user_uuid", func(req *paymentv1.TopUpWalletRequest) error { return checkUUID(req.GetUserUuid()) }},
+	{"amount", func(req *paymentv1.TopUpWalletRequest) error { return checkAmount(req.GetAmount()) }},
+	{"currency", func(req *paymentv1.TopUpWalletRequest) error { return checkCurrency(req.GetCurrency()) }},
+}
+
+// getWalletRules описывают допустимый GetWalletRequest
+var getWalletRules = []fieldRule[*paymentv1.GetWalletRequest]{
+	{"user_uuid", func(req *paymentv1.GetWalletRequest) error { return checkUUID(req.GetUserUuid()) }},
+	{"currency", func(req *paymentv1.GetWalletRequest) error { return checkCurrency(req.GetCurrency()) }},
+}
+
+func (s *paymentService) TopUpWallet(ctx context.Context, req *paymentv1.TopUpWalletRequest) (*paymentv1.TopUpWalletResponse, error) {
+	if err := validate(req, topUpWalletRules); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.wallets.TopUp(ctx, req.GetUserUuid(), req.GetCurrency(), toNanos(req.GetAmount()))
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	log.Printf("Кошелек %s пополнен на %s %s, доступно: %s", req.GetUserUuid(),
+		formatNanos(toNanos(req.GetAmount())), req.GetCurrency(), formatNanos(toNanos(wallet.GetAvailable())))
+	return &paymentv1.TopUpWalletResponse{Wallet: wallet}, nil
+}
+
+func (s *paymentService) GetWallet(ctx context.Context, req *paymentv1.GetWalletRequest) (*paymentv1.GetWalletResponse, error) {
+	if err := validate(req, getWalletRules); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.wallets.GetWallet(ctx, req.GetUserUuid(), req.GetCurrency())
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return &paymentv1.GetWalletResponse{Wallet: wallet}, nil
+}
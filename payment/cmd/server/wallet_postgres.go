@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	paymentv1 "github.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1"
+)
+
+// PostgresWalletStore хранит кошельки в той же базе, что и транзакции (миграция 00007).
+// Строка кошелька блокируется (SELECT ... FOR UPDATE) на время проверки остатка и изменения.
+type PostgresWalletStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresWalletStore(pool *pgxpool.Pool) *PostgresWalletStore {
+	return &PostgresWalletStore{pool: pool}
+}
+
+func (s *PostgresWalletStore) GetWallet(ctx context.Context, userUUID, currency string) (*paymentv1.Wallet, error) {
+	balance, held, updatedAt, err := selectWallet(s.pool.QueryRow(ctx, `
+		SELECT balance, held, updated_at FROM wallets WHERE user_uuid = $1 AND currency = $2`,
+		userUUID, currency))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return newWallet(userUUID, currency, 0, 0, time.Time{}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newWallet(userUUID, currency, balance, held, updatedAt), nil
+}
+
+func (s *PostgresWalletStore) TopUp(ctx context.Context, userUUID, currency string, amount int64) (*paymentv1.Wallet, error) {
+	balance, held, updatedAt, err := selectWallet(s.pool.QueryRow(ctx, `
+		INSERT INTO wallets (user_uuid, currency, balance, held, updated_at) VALUES ($1, $2, $3, 0, $4)
+		ON CONFLICT (user_uuid, currency) DO UPDATE
+		SET balance = wallets.balance + EXCLUDED.balance, updated_at = EXCLUDED.updated_at
+		RETURNING balance, held, updated_at`,
+		userUUID, currency, nanosToNumeric(amount), now().AsTime()))
+	if err != nil {
+		return nil, err
+	}
+	return newWallet(userUUID, currency, balance, held, updatedAt), nil
+}
+
+func (s *PostgresWalletStore) Hold(ctx context.Context, operation WalletOperation) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		balance, held, _, err := selectWallet(tx.QueryRow(ctx, `
+			SELECT balance, held, updated_at FROM wallets WHERE user_uuid = $1 AND currency = $2 FOR UPDATE`,
+			operation.UserUUID, operation.Currency))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &InsufficientFundsError{}
+		}
+		if err != nil {
+			return err
+		}
+		if available := balance - held; available < operation.Amount {
+			return &InsufficientFundsError{Available: available}
+		}
+
+		status := walletOperationHeld
+		if operation.Captured {
+			balance -= operation.Amount
+			status = walletOperationCaptured
+		} else {
+			held += operation.Amount
+		}
+		if err := updateWallet(ctx, tx, operation.UserUUID, operation.Currency, balance, held); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO wallet_operations (reference, user_uuid, currency, amount, status, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			operation.Reference, operation.UserUUID, operation.Currency,
+			nanosToNumeric(operation.Amount), status, now().AsTime())
+		return err
+	})
+}
+
+func (s *PostgresWalletStore) Capture(ctx context.Context, reference string) error {
+	return s.update(ctx, reference, func(_ pgx.Tx, operation *walletOperationState, balance, held *int64) error {
+		switch operation.status {
+		case walletOperationCaptured:
+			return nil
+		case walletOperationHeld:
+			*held -= operation.Amount
+			*balance -= operation.Amount
+			operation.status = walletOperationCaptured
+			return nil
+		default:
+			return fmt.Errorf("wallet operation %s is %s and cannot be captured", reference, operation.status)
+		}
+	})
+}
+
+func (s *PostgresWalletStore) Void(ctx context.Context, reference string) error {
+	return s.update(ctx, reference, func(_ pgx.Tx, operation *walletOperationState, _, held *int64) error {
+		switch operation.status {
+		case walletOperationVoided:
+			return nil
+		case walletOperationHeld:
+			*held -= operation.Amount
+			operation.status = walletOperationVoided
+			return nil
+		default:
+			return fmt.Errorf("wallet operation %s is %s and cannot be voided", reference, operation.status)
+		}
+	})
+}
+
+func (s *PostgresWalletStore) Refund(ctx context.Context, reference string, amount int64, key string) error {
+	return s.update(ctx, reference, func(tx pgx.Tx, operation *walletOperationState, balance, _ *int64) error {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO wallet_refunds (reference, idempotency_key, amount, created_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (reference, idempotency_key) DO NOTHING`,
+			reference, key, nanosToNumeric(amount), now().AsTime())
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			// Возврат с этим ключом уже выполнен
+			return nil
+		}
+		if operation.status != walletOperationCaptured {
+			return fmt.Errorf("wallet operation %s is %s and cannot be refunded", reference, operation.status)
+		}
+		if operation.refunded+amount > operation.Amount {
+			return fmt.Errorf("refund exceeds wallet operation %s", reference)
+		}
+		operation.refunded += amount
+		*balance += amount
+		return nil
+	})
+}
+
+// update блокирует операцию и ее кошелек, применяет updateFunc и сохраняет оба.
+// updateFunc выполняется в той же транзакции БД tx.
+func (s *PostgresWalletStore) update(ctx context.Context, reference string, updateFunc func(tx pgx.Tx, operation *walletOperationState, balance, held *int64) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		var (
+			operation        walletOperationState
+			amount, refunded pgtype.Numeric
+		)
+		err := tx.QueryRow(ctx, `
+			SELECT user_uuid, currency, amount, refunded_amount, status
+			FROM wallet_operations WHERE reference = $1 FOR UPDATE`, reference,
+		).Scan(&operation.UserUUID, &operation.Currency, &amount, &refunded, &operation.status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWalletOperationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		operation.Reference = reference
+		if operation.Amount, err = numericToNanos(amount); err != nil {
+			return err
+		}
+		if operation.refunded, err = numericToNanos(refunded); err != nil {
+			return err
+		}
+
+		balance, held, _, err := selectWallet(tx.QueryRow(ctx, `
+			SELECT balance, held, updated_at FROM wallets WHERE user_uuid = $1 AND currency = $2 FOR UPDATE`,
+			operation.UserUUID, operation.Currency))
+		if err != nil {
+			return err
+		}
+
+		if err := updateFunc(tx, &operation, &balance, &held); err != nil {
+			return err
+		}
+		if err := updateWallet(ctx, tx, operation.UserUUID, operation.Currency, balance, held); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE wallet_operations SET status = $2, refunded_amount = $3 WHERE reference = $1`,
+			reference, operation.status, nanosToNumeric(operation.refunded))
+		return err
+	})
+}
+
+func selectWallet(row pgx.Row) (balance, held int64, updatedAt time.Time, err error) {
+	var balanceNumeric, heldNumeric pgtype.Numeric
+	if err = row.Scan(&balanceNumeric, &heldNumeric, &updatedAt); err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	if balance, err = numericToNanos(balanceNumeric); err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	if held, err = numericToNanos(heldNumeric); err != nil {
+		return 0, 0, time.Time{}, err
+	}
+	return balance, held, updatedAt, nil
+}
+
+func updateWallet(ctx context.Context, tx pgx.Tx, userUUID, currency string, balance, held int64) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE wallets SET balance = $3, held = $4, updated_at = $5
+		WHERE user_uuid = $1 AND currency = $2`,
+		userUUID, currency, nanosToNumeric(balance), nanosToNumeric(held), now().AsTime())
+	return err
+}
@@ -0,0 +1,50 @@
+// Code generated by ogen, DO NOT EDIT.
+package orderv1
+
+type CreateWebhookSubscriptionRes interface {
+	createWebhookSubscriptionRes()
+}
+
+type DeleteWebhookSubscriptionRes interface {
+	deleteWebhookSubscriptionRes()
+}
+
+type GetOrdersRes interface {
+	getOrdersRes()
+}
+
+type ListOrdersRes interface {
+	listOrdersRes()
+}
+
+type ListWebhookDeliveriesRes interface {
+	listWebhookDeliveriesRes()
+}
+
+type ListWebhookSubscriptionsRes interface {
+	listWebhookSubscriptionsRes()
+}
+
+type PostOrdersCancelRes interface {
+	postOrdersCancelRes()
+}
+
+type PostOrdersFulfilRes interface {
+	postOrdersFulfilRes()
+}
+
+type PostOrdersPayRes interface {
+	postOrdersPayRes()
+}
+
+type PostOrdersRefundRes interface {
+	postOrdersRefundRes()
+}
+
+type PostOrdersRes interface {
+	postOrdersRes()
+}
+
+type PostPaymentNotificationsRes interface {
+	postPaymentNotificationsRes()
+}
@@ -0,0 +1,21 @@
+// Code generated by ogen, DO NOT EDIT.
+
+package orderv1
+
+// OperationName is the ogen operation name
+type OperationName = string
+
+const (
+	CreateWebhookSubscriptionOperation OperationName = "CreateWebhookSubscription"
+	DeleteWebhookSubscriptionOperation OperationName = "DeleteWebhookSubscription"
+	GetOrdersOperation                 OperationName = "GetOrders"
+	ListOrdersOperation                OperationName = "ListOrders"
+	ListWebhookDeliveriesOperation     OperationName = "ListWebhookDeliveries"
+	ListWebhookSubscriptionsOperation  OperationName = "ListWebhookSubscriptions"
+	PostOrdersOperation                OperationName = "PostOrders"
+	PostOrdersCancelOperation          OperationName = "PostOrdersCancel"
+	PostOrdersFulfilOperation          OperationName = "PostOrdersFulfil"
+	PostOrdersPayOperation             OperationName = "PostOrdersPay"
+	PostOrdersRefundOperation          OperationName = "PostOrdersRefund"
+	PostPaymentNotificationsOperation  OperationName = "PostPaymentNotifications"
+)
@@ -0,0 +1,2086 @@
+// Code generated by ogen, DO NOT EDIT.
+
+package orderv1
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+)
+
+// Ref: #/components/schemas/BadGatewayError
+type BadGatewayError struct {
+	Code    OptInt    `json:"code"`
+	Message OptString `json:"message"`
+}
+
+// GetCode returns the value of Code.
+func (s *BadGatewayError) GetCode() OptInt {
+	return s.Code
+}
+
+// GetMessage returns the value of Message.
+func (s *BadGatewayError) GetMessage() OptString {
+	return s.Message
+}
+
+// SetCode sets the value of Code.
+func (s *BadGatewayError) SetCode(val OptInt) {
+	s.Code = val
+}
+
+// SetMessage sets the value of Message.
+func (s *BadGatewayError) SetMessage(val OptString) {
+	s.Message = val
+}
+
+func (*BadGatewayError) postOrdersCancelRes()         {}
+func (*BadGatewayError) postOrdersFulfilRes()         {}
+func (*BadGatewayError) postOrdersPayRes()            {}
+func (*BadGatewayError) postOrdersRefundRes()         {}
+func (*BadGatewayError) postOrdersRes()               {}
+func (*BadGatewayError) postPaymentNotificationsRes() {}
+
+// Ref: #/components/schemas/BadRequestError
+type BadRequestError struct {
+	Code    OptInt    `json:"code"`
+	Message OptString `json:"message"`
+	// Нарушения по отдельным полям, если запрос отклонен их
+	// проверкой.
+	Violations []BadRequestErrorViolationsItem `json:"violations"`
+}
+
+// GetCode returns the value of Code.
+func (s *BadRequestError) GetCode() OptInt {
+	return s.Code
+}
+
+// GetMessage returns the value of Message.
+func (s *BadRequestError) GetMessage() OptString {
+	return s.Message
+}
+
+// GetViolations returns the value of Violations.
+func (s *BadRequestError) GetViolations() []BadRequestErrorViolationsItem {
+	return s.Violations
+}
+
+// SetCode sets the value of Code.
+func (s *BadRequestError) SetCode(val OptInt) {
+	s.Code = val
+}
+
+// SetMessage sets the value of Message.
+func (s *BadRequestError) SetMessage(val OptString) {
+	s.Message = val
+}
+
+// SetViolations sets the value of Violations.
+func (s *BadRequestError) SetViolations(val []BadRequestErrorViolationsItem) {
+	s.Violations = val
+}
+
+func (*BadRequestError) createWebhookSubscriptionRes() {}
+func (*BadRequestError) listOrdersRes()                {}
+func (*BadRequestError) postOrdersPayRes()             {}
+func (*BadRequestError) postOrdersRefundRes()          {}
+func (*BadRequestError) postOrdersRes()                {}
+func (*BadRequestError) postPaymentNotificationsRes()  {}
+
+type BadRequestErrorViolationsItem struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// GetField returns the value of Field.
+func (s *BadRequestErrorViolationsItem) GetField() string {
+	return s.Field
+}
+
+// GetDescription returns the value of Description.
+func (s *BadRequestErrorViolationsItem) GetDescription() string {
+	return s.Description
+}
+
+// SetField sets the value of Field.
+func (s *BadRequestErrorViolationsItem) SetField(val string) {
+	s.Field = val
+}
+
+// SetDescription sets the value of Description.
+func (s *BadRequestErrorViolationsItem) SetDescription(val string) {
+	s.Description = val
+}
+
+// Ref: #/components/schemas/ConflictError
+type ConflictError struct {
+	Code    OptInt    `json:"code"`
+	Message OptString `json:"message"`
+	// Текущий статус заказа, если конфликт вызван
+	// недопустимым переходом.
+	CurrentStatus OptOrderStatus `json:"current_status"`
+	// Статус, в который запрошен переход.
+	RequestedStatus OptOrderStatus `json:"requested_status"`
+}
+
+// GetCode returns the value of Code.
+func (s *ConflictError) GetCode() OptInt {
+	return s.Code
+}
+
+// GetMessage returns the value of Message.
+func (s *ConflictError) GetMessage() OptString {
+	return s.Message
+}
+
+// GetCurrentStatus returns the value of CurrentStatus.
+func (s *ConflictError) GetCurrentStatus() OptOrderStatus {
+	return s.CurrentStatus
+}
+
+// GetRequestedStatus returns the value of RequestedStatus.
+func (s *ConflictError) GetRequestedStatus() OptOrderStatus {
+	return s.RequestedStatus
+}
+
+// SetCode sets the value of Code.
+func (s *ConflictError) SetCode(val OptInt) {
+	s.Code = val
+}
+
+// SetMessage sets the value of Message.
+func (s *ConflictError) SetMessage(val OptString) {
+	s.Message = val
+}
+
+// SetCurrentStatus sets the value of CurrentStatus.
+func (s *ConflictError) SetCurrentStatus(val OptOrderStatus) {
+	s.CurrentStatus = val
+}
+
+// SetRequestedStatus sets the value of RequestedStatus.
+func (s *ConflictError) SetRequestedStatus(val OptOrderStatus) {
+	s.RequestedStatus = val
+}
+
+func (*ConflictError) postOrdersCancelRes() {}
+func (*ConflictError) postOrdersFulfilRes() {}
+func (*ConflictError) postOrdersPayRes()    {}
+func (*ConflictError) postOrdersRefundRes() {}
+
+// Ref: #/components/schemas/CreateOrderRequest
+type CreateOrderRequest struct {
+	// UUID пользователя.
+	UserUUID uuid.UUID `json:"user_uuid"`
+	// Позиции заказа с количеством.
+	Items []OrderItemRequest `json:"items"`
+	// Список UUID деталей. Каждый элемент считается позицией
+	// с количеством 1, повторы одного UUID суммируются.
+	// Используйте items.
+	//
+	// Deprecated: schema marks this property as deprecated.
+	PartUuids []uuid.UUID `json:"part_uuids"`
+}
+
+// GetUserUUID returns the value of UserUUID.
+func (s *CreateOrderRequest) GetUserUUID() uuid.UUID {
+	return s.UserUUID
+}
+
+// GetItems returns the value of Items.
+func (s *CreateOrderRequest) GetItems() []OrderItemRequest {
+	return s.Items
+}
+
+// GetPartUuids returns the value of PartUuids.
+func (s *CreateOrderRequest) GetPartUuids() []uuid.UUID {
+	return s.PartUuids
+}
+
+// SetUserUUID sets the value of UserUUID.
+func (s *CreateOrderRequest) SetUserUUID(val uuid.UUID) {
+	s.UserUUID = val
+}
+
+// SetItems sets the value of Items.
+func (s *CreateOrderRequest) SetItems(val []OrderItemRequest) {
+	s.Items = val
+}
+
+// SetPartUuids sets the value of PartUuids.
+func (s *CreateOrderRequest) SetPartUuids(val []uuid.UUID) {
+	s.PartUuids = val
+}
+
+// Ref: #/components/schemas/CreateOrderResponse
+type CreateOrderResponse struct {
+	// UUID созданного заказа.
+	OrderUUID uuid.UUID `json:"order_uuid"`
+	// Общая стоимость заказа.
+	TotalPrice float64 `json:"total_price"`
+}
+
+// GetOrderUUID returns the value of OrderUUID.
+func (s *CreateOrderResponse) GetOrderUUID() uuid.UUID {
+	return s.OrderUUID
+}
+
+// GetTotalPrice returns the value of TotalPrice.
+func (s *CreateOrderResponse) GetTotalPrice() float64 {
+	return s.TotalPrice
+}
+
+// SetOrderUUID sets the value of OrderUUID.
+func (s *CreateOrderResponse) SetOrderUUID(val uuid.UUID) {
+	s.OrderUUID = val
+}
+
+// SetTotalPrice sets the value of TotalPrice.
+func (s *CreateOrderResponse) SetTotalPrice(val float64) {
+	s.TotalPrice = val
+}
+
+func (*CreateOrderResponse) postOrdersRes() {}
+
+// Ref: #/components/schemas/CreateWebhookSubscriptionRequest
+type CreateWebhookSubscriptionRequest struct {
+	// Адрес http(s), на который отправляются события.
+	URL url.URL `json:"url"`
+	// Типы событий, на которые оформлена подписка.
+	Events []WebhookEventType `json:"events"`
+	// Секрет подписи; если не указан, генерируется и
+	// возвращается в ответе.
+	Secret OptString `json:"secret"`
+}
+
+// GetURL returns the value of URL.
+func (s *CreateWebhookSubscriptionRequest) GetURL() url.URL {
+	return s.URL
+}
+
+// GetEvents returns the value of Events.
+func (s *CreateWebhookSubscriptionRequest) GetEvents() []WebhookEventType {
+	return s.Events
+}
+
+// GetSecret returns the value of Secret.
+func (s *CreateWebhookSubscriptionRequest) GetSecret() OptString {
+	return s.Secret
+}
+
+// SetURL sets the value of URL.
+func (s *CreateWebhookSubscriptionRequest) SetURL(val url.URL) {
+	s.URL = val
+}
+
+// SetEvents sets the value of Events.
+func (s *CreateWebhookSubscriptionRequest) SetEvents(val []WebhookEventType) {
+	s.Events = val
+}
+
+// SetSecret sets the value of Secret.
+func (s *CreateWebhookSubscriptionRequest) SetSecret(val OptString) {
+	s.Secret = val
+}
+
+// DeleteWebhookSubscriptionNoContent is response for DeleteWebhookSubscription operation.
+type DeleteWebhookSubscriptionNoContent struct{}
+
+func (*DeleteWebhookSubscriptionNoContent) deleteWebhookSubscriptionRes() {}
+
+// Ref: #/components/schemas/InsufficientStockError
+type InsufficientStockError struct {
+	Code    OptInt    `json:"code"`
+	Message OptString `json:"message"`
+	// Нехватка остатка по каждой детали заказа.
+	Shortages []InsufficientStockErrorShortagesItem `json:"shortages"`
+}
+
+// GetCode returns the value of Code.
+func (s *InsufficientStockError) GetCode() OptInt {
+	return s.Code
+}
+
+// GetMessage returns the value of Message.
+func (s *InsufficientStockError) GetMessage() OptString {
+	return s.Message
+}
+
+// GetShortages returns the value of Shortages.
+func (s *InsufficientStockError) GetShortages() []InsufficientStockErrorShortagesItem {
+	return s.Shortages
+}
+
+// SetCode sets the value of Code.
+func (s *InsufficientStockError) SetCode(val OptInt) {
+	s.Code = val
+}
+
+// SetMessage sets the value of Message.
+func (s *InsufficientStockError) SetMessage(val OptString) {
+	s.Message = val
+}
+
+// SetShortages sets the value of Shortages.
+func (s *InsufficientStockError) SetShortages(val []InsufficientStockErrorShortagesItem) {
+	s.Shortages = val
+}
+
+func (*InsufficientStockError) postOrdersRes() {}
+
+type InsufficientStockErrorShortagesItem struct {
+	PartUUID uuid.UUID `json:"part_uuid"`
+	// Запрошенное количество.
+	Requested int64 `json:"requested"`
+	// Доступный остаток на складе.
+	Available int64 `json:"available"`
+}
+
+// GetPartUUID returns the value of PartUUID.
+func (s *InsufficientStockErrorShortagesItem) GetPartUUID() uuid.UUID {
+	return s.PartUUID
+}
+
+// GetRequested returns the value of Requested.
+func (s *InsufficientStockErrorShortagesItem) GetRequested() int64 {
+	return s.Requested
+}
+
+// GetAvailable returns the value of Available.
+func (s *InsufficientStockErrorShortagesItem) GetAvailable() int64 {
+	return s.Available
+}
+
+// SetPartUUID sets the value of PartUUID.
+func (s *InsufficientStockErrorShortagesItem) SetPartUUID(val uuid.UUID) {
+	s.PartUUID = val
+}
+
+// SetRequested sets the value of Requested.
+func (s *InsufficientStockErrorShortagesItem) SetRequested(val int64) {
+	s.Requested = val
+}
+
+// SetAvailable sets the value of Available.
+func (s *InsufficientStockErrorShortagesItem) SetAvailable(val int64) {
+	s.Available = val
+}
+
+// Ref: #/components/schemas/InternalServerError
+type InternalServerError struct {
+	Code    OptInt    `json:"code"`
+	Message OptString `json:"message"`
+}
+
+// GetCode returns the value of Code.
+func (s *InternalServerError) GetCode() OptInt {
+	return s.Code
+}
+
+// GetMessage returns the value of Message.
+func (s *InternalServerError) GetMessage() OptString {
+	return s.Message
+}
+
+// SetCode sets the value of Code.
+func (s *InternalServerError) SetCode(val OptInt) {
+	s.Code = val
+}
+
+// SetMessage sets the value of Message.
+func (s *InternalServerError) SetMessage(val OptString) {
+	s.Message = val
+}
+
+func (*InternalServerError) createWebhookSubscriptionRes() {}
+func (*InternalServerError) deleteWebhookSubscriptionRes() {}
+func (*InternalServerError) getOrdersRes()                 {}
+func (*InternalServerError) listOrdersRes()                {}
+func (*InternalServerError) listWebhookDeliveriesRes()     {}
+func (*InternalServerError) listWebhookSubscriptionsRes()  {}
+func (*InternalServerError) postOrdersCancelRes()          {}
+func (*InternalServerError) postOrdersFulfilRes()          {}
+func (*InternalServerError) postOrdersPayRes()             {}
+func (*InternalServerError) postOrdersRefundRes()          {}
+func (*InternalServerError) postOrdersRes()                {}
+func (*InternalServerError) postPaymentNotificationsRes()  {}
+
+// Ref: #/components/schemas/ListOrdersResponse
+type ListOrdersResponse struct {
+	Orders []OrderDTO `json:"orders"`
+	// Курсор следующей страницы; отсутствует на последней
+	// странице.
+	NextCursor OptString `json:"next_cursor"`
+}
+
+// GetOrders returns the value of Orders.
+func (s *ListOrdersResponse) GetOrders() []OrderDTO {
+	return s.Orders
+}
+
+// GetNextCursor returns the value of NextCursor.
+func (s *ListOrdersResponse) GetNextCursor() OptString {
+	return s.NextCursor
+}
+
+// SetOrders sets the value of Orders.
+func (s *ListOrdersResponse) SetOrders(val []OrderDTO) {
+	s.Orders = val
+}
+
+// SetNextCursor sets the value of NextCursor.
+func (s *ListOrdersResponse) SetNextCursor(val OptString) {
+	s.NextCursor = val
+}
+
+func (*ListOrdersResponse) listOrdersRes() {}
+
+// Ref: #/components/schemas/ListWebhookDeliveriesResponse
+type ListWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// GetDeliveries returns the value of Deliveries.
+func (s *ListWebhookDeliveriesResponse) GetDeliveries() []WebhookDelivery {
+	return s.Deliveries
+}
+
+// SetDeliveries sets the value of Deliveries.
+func (s *ListWebhookDeliveriesResponse) SetDeliveries(val []WebhookDelivery) {
+	s.Deliveries = val
+}
+
+func (*ListWebhookDeliveriesResponse) listWebhookDeliveriesRes() {}
+
+// Ref: #/components/schemas/ListWebhookSubscriptionsResponse
+type ListWebhookSubscriptionsResponse struct {
+	Subscriptions []WebhookSubscription `json:"subscriptions"`
+}
+
+// GetSubscriptions returns the value of Subscriptions.
+func (s *ListWebhookSubscriptionsResponse) GetSubscriptions() []WebhookSubscription {
+	return s.Subscriptions
+}
+
+// SetSubscriptions sets the value of Subscriptions.
+func (s *ListWebhookSubscriptionsResponse) SetSubscriptions(val []WebhookSubscription) {
+	s.Subscriptions = val
+}
+
+func (*ListWebhookSubscriptionsResponse) listWebhookSubscriptionsRes() {}
+
+// Ref: #/components/schemas/NotFoundError
+type NotFoundError struct {
+	Code    OptInt    `json:"code"`
+	Message OptString `json:"message"`
+}
+
+// GetCode returns the value of Code.
+func (s *NotFoundError) GetCode() OptInt {
+	return s.Code
+}
+
+// GetMessage returns the value of Message.
+func (s *NotFoundError) GetMessage() OptString {
+	return s.Message
+}
+
+// SetCode sets the value of Code.
+func (s *NotFoundError) SetCode(val OptInt) {
+	s.Code = val
+}
+
+// SetMessage sets the value of Message.
+func (s *NotFoundError) SetMessage(val OptString) {
+	s.Message = val
+}
+
+func (*NotFoundError) deleteWebhookSubscriptionRes() {}
+func (*NotFoundError) getOrdersRes()                 {}
+func (*NotFoundError) listWebhookDeliveriesRes()     {}
+func (*NotFoundError) postOrdersCancelRes()          {}
+func (*NotFoundError) postOrdersFulfilRes()          {}
+func (*NotFoundError) postOrdersPayRes()             {}
+func (*NotFoundError) postOrdersRefundRes()          {}
+func (*NotFoundError) postPaymentNotificationsRes()  {}
+
+// NewOptDateTime returns new OptDateTime with value set to v.
+func NewOptDateTime(v time.Time) OptDateTime {
+	return OptDateTime{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptDateTime is optional time.Time.
+type OptDateTime struct {
+	Value time.Time
+	Set   bool
+}
+
+// IsSet returns true if OptDateTime was set.
+func (o OptDateTime) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptDateTime) Reset() {
+	var v time.Time
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptDateTime) SetTo(v time.Time) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptDateTime) Get() (v time.Time, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptDateTime) Or(d time.Time) time.Time {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptFloat64 returns new OptFloat64 with value set to v.
+func NewOptFloat64(v float64) OptFloat64 {
+	return OptFloat64{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptFloat64 is optional float64.
+type OptFloat64 struct {
+	Value float64
+	Set   bool
+}
+
+// IsSet returns true if OptFloat64 was set.
+func (o OptFloat64) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptFloat64) Reset() {
+	var v float64
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptFloat64) SetTo(v float64) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptFloat64) Get() (v float64, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptFloat64) Or(d float64) float64 {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptInt returns new OptInt with value set to v.
+func NewOptInt(v int) OptInt {
+	return OptInt{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptInt is optional int.
+type OptInt struct {
+	Value int
+	Set   bool
+}
+
+// IsSet returns true if OptInt was set.
+func (o OptInt) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptInt) Reset() {
+	var v int
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptInt) SetTo(v int) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptInt) Get() (v int, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptInt) Or(d int) int {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptNilString returns new OptNilString with value set to v.
+func NewOptNilString(v string) OptNilString {
+	return OptNilString{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptNilString is optional nullable string.
+type OptNilString struct {
+	Value string
+	Set   bool
+	Null  bool
+}
+
+// IsSet returns true if OptNilString was set.
+func (o OptNilString) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptNilString) Reset() {
+	var v string
+	o.Value = v
+	o.Set = false
+	o.Null = false
+}
+
+// SetTo sets value to v.
+func (o *OptNilString) SetTo(v string) {
+	o.Set = true
+	o.Null = false
+	o.Value = v
+}
+
+// IsNull returns true if value is Null.
+func (o OptNilString) IsNull() bool { return o.Null }
+
+// SetToNull sets value to null.
+func (o *OptNilString) SetToNull() {
+	o.Set = true
+	o.Null = true
+	var v string
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptNilString) Get() (v string, ok bool) {
+	if o.Null {
+		return v, false
+	}
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptNilString) Or(d string) string {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptNilUUID returns new OptNilUUID with value set to v.
+func NewOptNilUUID(v uuid.UUID) OptNilUUID {
+	return OptNilUUID{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptNilUUID is optional nullable uuid.UUID.
+type OptNilUUID struct {
+	Value uuid.UUID
+	Set   bool
+	Null  bool
+}
+
+// IsSet returns true if OptNilUUID was set.
+func (o OptNilUUID) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptNilUUID) Reset() {
+	var v uuid.UUID
+	o.Value = v
+	o.Set = false
+	o.Null = false
+}
+
+// SetTo sets value to v.
+func (o *OptNilUUID) SetTo(v uuid.UUID) {
+	o.Set = true
+	o.Null = false
+	o.Value = v
+}
+
+// IsNull returns true if value is Null.
+func (o OptNilUUID) IsNull() bool { return o.Null }
+
+// SetToNull sets value to null.
+func (o *OptNilUUID) SetToNull() {
+	o.Set = true
+	o.Null = true
+	var v uuid.UUID
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptNilUUID) Get() (v uuid.UUID, ok bool) {
+	if o.Null {
+		return v, false
+	}
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptNilUUID) Or(d uuid.UUID) uuid.UUID {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptOrderStatus returns new OptOrderStatus with value set to v.
+func NewOptOrderStatus(v OrderStatus) OptOrderStatus {
+	return OptOrderStatus{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptOrderStatus is optional OrderStatus.
+type OptOrderStatus struct {
+	Value OrderStatus
+	Set   bool
+}
+
+// IsSet returns true if OptOrderStatus was set.
+func (o OptOrderStatus) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptOrderStatus) Reset() {
+	var v OrderStatus
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptOrderStatus) SetTo(v OrderStatus) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptOrderStatus) Get() (v OrderStatus, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptOrderStatus) Or(d OrderStatus) OrderStatus {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptRefundOrderRequest returns new OptRefundOrderRequest with value set to v.
+func NewOptRefundOrderRequest(v RefundOrderRequest) OptRefundOrderRequest {
+	return OptRefundOrderRequest{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptRefundOrderRequest is optional RefundOrderRequest.
+type OptRefundOrderRequest struct {
+	Value RefundOrderRequest
+	Set   bool
+}
+
+// IsSet returns true if OptRefundOrderRequest was set.
+func (o OptRefundOrderRequest) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptRefundOrderRequest) Reset() {
+	var v RefundOrderRequest
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptRefundOrderRequest) SetTo(v RefundOrderRequest) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptRefundOrderRequest) Get() (v RefundOrderRequest, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptRefundOrderRequest) Or(d RefundOrderRequest) RefundOrderRequest {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptSortByQuery returns new OptSortByQuery with value set to v.
+func NewOptSortByQuery(v SortByQuery) OptSortByQuery {
+	return OptSortByQuery{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptSortByQuery is optional SortByQuery.
+type OptSortByQuery struct {
+	Value SortByQuery
+	Set   bool
+}
+
+// IsSet returns true if OptSortByQuery was set.
+func (o OptSortByQuery) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptSortByQuery) Reset() {
+	var v SortByQuery
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptSortByQuery) SetTo(v SortByQuery) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptSortByQuery) Get() (v SortByQuery, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptSortByQuery) Or(d SortByQuery) SortByQuery {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptSortOrderQuery returns new OptSortOrderQuery with value set to v.
+func NewOptSortOrderQuery(v SortOrderQuery) OptSortOrderQuery {
+	return OptSortOrderQuery{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptSortOrderQuery is optional SortOrderQuery.
+type OptSortOrderQuery struct {
+	Value SortOrderQuery
+	Set   bool
+}
+
+// IsSet returns true if OptSortOrderQuery was set.
+func (o OptSortOrderQuery) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptSortOrderQuery) Reset() {
+	var v SortOrderQuery
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptSortOrderQuery) SetTo(v SortOrderQuery) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptSortOrderQuery) Get() (v SortOrderQuery, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptSortOrderQuery) Or(d SortOrderQuery) SortOrderQuery {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptString returns new OptString with value set to v.
+func NewOptString(v string) OptString {
+	return OptString{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptString is optional string.
+type OptString struct {
+	Value string
+	Set   bool
+}
+
+// IsSet returns true if OptString was set.
+func (o OptString) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptString) Reset() {
+	var v string
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptString) SetTo(v string) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptString) Get() (v string, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptString) Or(d string) string {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptUUID returns new OptUUID with value set to v.
+func NewOptUUID(v uuid.UUID) OptUUID {
+	return OptUUID{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptUUID is optional uuid.UUID.
+type OptUUID struct {
+	Value uuid.UUID
+	Set   bool
+}
+
+// IsSet returns true if OptUUID was set.
+func (o OptUUID) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptUUID) Reset() {
+	var v uuid.UUID
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptUUID) SetTo(v uuid.UUID) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptUUID) Get() (v uuid.UUID, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptUUID) Or(d uuid.UUID) uuid.UUID {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptWebhookDeliveryStatus returns new OptWebhookDeliveryStatus with value set to v.
+func NewOptWebhookDeliveryStatus(v WebhookDeliveryStatus) OptWebhookDeliveryStatus {
+	return OptWebhookDeliveryStatus{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptWebhookDeliveryStatus is optional WebhookDeliveryStatus.
+type OptWebhookDeliveryStatus struct {
+	Value WebhookDeliveryStatus
+	Set   bool
+}
+
+// IsSet returns true if OptWebhookDeliveryStatus was set.
+func (o OptWebhookDeliveryStatus) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptWebhookDeliveryStatus) Reset() {
+	var v WebhookDeliveryStatus
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptWebhookDeliveryStatus) SetTo(v WebhookDeliveryStatus) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptWebhookDeliveryStatus) Get() (v WebhookDeliveryStatus, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptWebhookDeliveryStatus) Or(d WebhookDeliveryStatus) WebhookDeliveryStatus {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// Ref: #/components/schemas/OrderDTO
+type OrderDTO struct {
+	OrderUUID OptUUID     `json:"order_uuid"`
+	UserUUID  OptUUID     `json:"user_uuid"`
+	PartUuids []uuid.UUID `json:"part_uuids"`
+	Items     []OrderItem `json:"items"`
+	// Сумма quantity × unit_price по всем позициям.
+	TotalPrice OptFloat64 `json:"total_price"`
+	// Сумма, уже возвращенная по транзакции заказа.
+	RefundedAmount  OptFloat64     `json:"refunded_amount"`
+	TransactionUUID OptNilUUID     `json:"transaction_uuid"`
+	PaymentMethod   OptNilString   `json:"payment_method"`
+	Status          OptOrderStatus `json:"status"`
+	CreatedAt       OptDateTime    `json:"created_at"`
+	UpdatedAt       OptDateTime    `json:"updated_at"`
+}
+
+// GetOrderUUID returns the value of OrderUUID.
+func (s *OrderDTO) GetOrderUUID() OptUUID {
+	return s.OrderUUID
+}
+
+// GetUserUUID returns the value of UserUUID.
+func (s *OrderDTO) GetUserUUID() OptUUID {
+	return s.UserUUID
+}
+
+// GetPartUuids returns the value of PartUuids.
+func (s *OrderDTO) GetPartUuids() []uuid.UUID {
+	return s.PartUuids
+}
+
+// GetItems returns the value of Items.
+func (s *OrderDTO) GetItems() []OrderItem {
+	return s.Items
+}
+
+// GetTotalPrice returns the value of TotalPrice.
+func (s *OrderDTO) GetTotalPrice() OptFloat64 {
+	return s.TotalPrice
+}
+
+// GetRefundedAmount returns the value of RefundedAmount.
+func (s *OrderDTO) GetRefundedAmount() OptFloat64 {
+	return s.RefundedAmount
+}
+
+// GetTransactionUUID returns the value of TransactionUUID.
+func (s *OrderDTO) GetTransactionUUID() OptNilUUID {
+	return s.TransactionUUID
+}
+
+// GetPaymentMethod returns the value of PaymentMethod.
+func (s *OrderDTO) GetPaymentMethod() OptNilString {
+	return s.PaymentMethod
+}
+
+// GetStatus returns the value of Status.
+func (s *OrderDTO) GetStatus() OptOrderStatus {
+	return s.Status
+}
+
+// GetCreatedAt returns the value of CreatedAt.
+func (s *OrderDTO) GetCreatedAt() OptDateTime {
+	return s.CreatedAt
+}
+
+// GetUpdatedAt returns the value of UpdatedAt.
+func (s *OrderDTO) GetUpdatedAt() OptDateTime {
+	return s.UpdatedAt
+}
+
+// SetOrderUUID sets the value of OrderUUID.
+func (s *OrderDTO) SetOrderUUID(val OptUUID) {
+	s.OrderUUID = val
+}
+
+// SetUserUUID sets the value of UserUUID.
+func (s *OrderDTO) SetUserUUID(val OptUUID) {
+	s.UserUUID = val
+}
+
+// SetPartUuids sets the value of PartUuids.
+func (s *OrderDTO) SetPartUuids(val []uuid.UUID) {
+	s.PartUuids = val
+}
+
+// SetItems sets the value of Items.
+func (s *OrderDTO) SetItems(val []OrderItem) {
+	s.Items = val
+}
+
+// SetTotalPrice sets the value of TotalPrice.
+func (s *OrderDTO) SetTotalPrice(val OptFloat64) {
+	s.TotalPrice = val
+}
+
+// SetRefundedAmount sets the value of RefundedAmount.
+func (s *OrderDTO) SetRefundedAmount(val OptFloat64) {
+	s.RefundedAmount = val
+}
+
+// SetTransactionUUID sets the value of TransactionUUID.
+func (s *OrderDTO) SetTransactionUUID(val OptNilUUID) {
+	s.TransactionUUID = val
+}
+
+// SetPaymentMethod sets the value of PaymentMethod.
+func (s *OrderDTO) SetPaymentMethod(val OptNilString) {
+	s.PaymentMethod = val
+}
+
+// SetStatus sets the value of Status.
+func (s *OrderDTO) SetStatus(val OptOrderStatus) {
+	s.Status = val
+}
+
+// SetCreatedAt sets the value of CreatedAt.
+func (s *OrderDTO) SetCreatedAt(val OptDateTime) {
+	s.CreatedAt = val
+}
+
+// SetUpdatedAt sets the value of UpdatedAt.
+func (s *OrderDTO) SetUpdatedAt(val OptDateTime) {
+	s.UpdatedAt = val
+}
+
+func (*OrderDTO) getOrdersRes() {}
+
+// Ref: #/components/schemas/OrderItem
+type OrderItem struct {
+	// UUID детали.
+	PartUUID uuid.UUID `json:"part_uuid"`
+	// Количество деталей.
+	Quantity int64 `json:"quantity"`
+	// Цена за единицу на момент создания заказа.
+	UnitPrice float64 `json:"unit_price"`
+}
+
+// GetPartUUID returns the value of PartUUID.
+func (s *OrderItem) GetPartUUID() uuid.UUID {
+	return s.PartUUID
+}
+
+// GetQuantity returns the value of Quantity.
+func (s *OrderItem) GetQuantity() int64 {
+	return s.Quantity
+}
+
+// GetUnitPrice returns the value of UnitPrice.
+func (s *OrderItem) GetUnitPrice() float64 {
+	return s.UnitPrice
+}
+
+// SetPartUUID sets the value of PartUUID.
+func (s *OrderItem) SetPartUUID(val uuid.UUID) {
+	s.PartUUID = val
+}
+
+// SetQuantity sets the value of Quantity.
+func (s *OrderItem) SetQuantity(val int64) {
+	s.Quantity = val
+}
+
+// SetUnitPrice sets the value of UnitPrice.
+func (s *OrderItem) SetUnitPrice(val float64) {
+	s.UnitPrice = val
+}
+
+// Ref: #/components/schemas/OrderItemRequest
+type OrderItemRequest struct {
+	// UUID детали.
+	PartUUID uuid.UUID `json:"part_uuid"`
+	// Количество деталей.
+	Quantity int64 `json:"quantity"`
+}
+
+// GetPartUUID returns the value of PartUUID.
+func (s *OrderItemRequest) GetPartUUID() uuid.UUID {
+	return s.PartUUID
+}
+
+// GetQuantity returns the value of Quantity.
+func (s *OrderItemRequest) GetQuantity() int64 {
+	return s.Quantity
+}
+
+// SetPartUUID sets the value of PartUUID.
+func (s *OrderItemRequest) SetPartUUID(val uuid.UUID) {
+	s.PartUUID = val
+}
+
+// SetQuantity sets the value of Quantity.
+func (s *OrderItemRequest) SetQuantity(val int64) {
+	s.Quantity = val
+}
+
+// Статус заказа.
+// Ref: #/components/schemas/OrderStatus
+type OrderStatus string
+
+const (
+	OrderStatusPENDINGPAYMENT    OrderStatus = "PENDING_PAYMENT"
+	OrderStatusPAYMENTPENDING    OrderStatus = "PAYMENT_PENDING"
+	OrderStatusAUTHORIZED        OrderStatus = "AUTHORIZED"
+	OrderStatusPAID              OrderStatus = "PAID"
+	OrderStatusCANCELLED         OrderStatus = "CANCELLED"
+	OrderStatusPARTIALLYREFUNDED OrderStatus = "PARTIALLY_REFUNDED"
+	OrderStatusREFUNDED          OrderStatus = "REFUNDED"
+)
+
+// AllValues returns all OrderStatus values.
+func (OrderStatus) AllValues() []OrderStatus {
+	return []OrderStatus{
+		OrderStatusPENDINGPAYMENT,
+		OrderStatusPAYMENTPENDING,
+		OrderStatusAUTHORIZED,
+		OrderStatusPAID,
+		OrderStatusCANCELLED,
+		OrderStatusPARTIALLYREFUNDED,
+		OrderStatusREFUNDED,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s OrderStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case OrderStatusPENDINGPAYMENT:
+		return []byte(s), nil
+	case OrderStatusPAYMENTPENDING:
+		return []byte(s), nil
+	case OrderStatusAUTHORIZED:
+		return []byte(s), nil
+	case OrderStatusPAID:
+		return []byte(s), nil
+	case OrderStatusCANCELLED:
+		return []byte(s), nil
+	case OrderStatusPARTIALLYREFUNDED:
+		return []byte(s), nil
+	case OrderStatusREFUNDED:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *OrderStatus) UnmarshalText(data []byte) error {
+	switch OrderStatus(data) {
+	case OrderStatusPENDINGPAYMENT:
+		*s = OrderStatusPENDINGPAYMENT
+		return nil
+	case OrderStatusPAYMENTPENDING:
+		*s = OrderStatusPAYMENTPENDING
+		return nil
+	case OrderStatusAUTHORIZED:
+		*s = OrderStatusAUTHORIZED
+		return nil
+	case OrderStatusPAID:
+		*s = OrderStatusPAID
+		return nil
+	case OrderStatusCANCELLED:
+		*s = OrderStatusCANCELLED
+		return nil
+	case OrderStatusPARTIALLYREFUNDED:
+		*s = OrderStatusPARTIALLYREFUNDED
+		return nil
+	case OrderStatusREFUNDED:
+		*s = OrderStatusREFUNDED
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Ref: #/components/schemas/PayOrderRequest
+type PayOrderRequest struct {
+	// Способ оплаты.
+	PaymentMethod PaymentMethod `json:"payment_method"`
+}
+
+// GetPaymentMethod returns the value of PaymentMethod.
+func (s *PayOrderRequest) GetPaymentMethod() PaymentMethod {
+	return s.PaymentMethod
+}
+
+// SetPaymentMethod sets the value of PaymentMethod.
+func (s *PayOrderRequest) SetPaymentMethod(val PaymentMethod) {
+	s.PaymentMethod = val
+}
+
+// Ref: #/components/schemas/PayOrderResponse
+type PayOrderResponse struct {
+	// UUID транзакции.
+	TransactionUUID uuid.UUID `json:"transaction_uuid"`
+	// Статус заказа после оплаты: AUTHORIZED, либо PAYMENT_PENDING, если
+	// способ оплаты асинхронный
+	// (СБП, 3-D Secure) и провайдер еще не подтвердил платеж.
+	Status OptOrderStatus `json:"status"`
+}
+
+// GetTransactionUUID returns the value of TransactionUUID.
+func (s *PayOrderResponse) GetTransactionUUID() uuid.UUID {
+	return s.TransactionUUID
+}
+
+// GetStatus returns the value of Status.
+func (s *PayOrderResponse) GetStatus() OptOrderStatus {
+	return s.Status
+}
+
+// SetTransactionUUID sets the value of TransactionUUID.
+func (s *PayOrderResponse) SetTransactionUUID(val uuid.UUID) {
+	s.TransactionUUID = val
+}
+
+// SetStatus sets the value of Status.
+func (s *PayOrderResponse) SetStatus(val OptOrderStatus) {
+	s.Status = val
+}
+
+func (*PayOrderResponse) postOrdersPayRes() {}
+
+// Способ оплаты.
+// Ref: #/components/schemas/PaymentMethod
+type PaymentMethod string
+
+const (
+	PaymentMethodUNKNOWN       PaymentMethod = "UNKNOWN"
+	PaymentMethodCARD          PaymentMethod = "CARD"
+	PaymentMethodSBP           PaymentMethod = "SBP"
+	PaymentMethodCREDITCARD    PaymentMethod = "CREDIT_CARD"
+	PaymentMethodINVESTORMONEY PaymentMethod = "INVESTOR_MONEY"
+)
+
+// AllValues returns all PaymentMethod values.
+func (PaymentMethod) AllValues() []PaymentMethod {
+	return []PaymentMethod{
+		PaymentMethodUNKNOWN,
+		PaymentMethodCARD,
+		PaymentMethodSBP,
+		PaymentMethodCREDITCARD,
+		PaymentMethodINVESTORMONEY,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s PaymentMethod) MarshalText() ([]byte, error) {
+	switch s {
+	case PaymentMethodUNKNOWN:
+		return []byte(s), nil
+	case PaymentMethodCARD:
+		return []byte(s), nil
+	case PaymentMethodSBP:
+		return []byte(s), nil
+	case PaymentMethodCREDITCARD:
+		return []byte(s), nil
+	case PaymentMethodINVESTORMONEY:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *PaymentMethod) UnmarshalText(data []byte) error {
+	switch PaymentMethod(data) {
+	case PaymentMethodUNKNOWN:
+		*s = PaymentMethodUNKNOWN
+		return nil
+	case PaymentMethodCARD:
+		*s = PaymentMethodCARD
+		return nil
+	case PaymentMethodSBP:
+		*s = PaymentMethodSBP
+		return nil
+	case PaymentMethodCREDITCARD:
+		*s = PaymentMethodCREDITCARD
+		return nil
+	case PaymentMethodINVESTORMONEY:
+		*s = PaymentMethodINVESTORMONEY
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Ref: #/components/schemas/PaymentNotification
+type PaymentNotification struct {
+	// UUID транзакции, платеж по которой завершен.
+	TransactionUUID uuid.UUID `json:"transaction_uuid"`
+	// UUID заказа.
+	OrderUUID OptUUID `json:"order_uuid"`
+}
+
+// GetTransactionUUID returns the value of TransactionUUID.
+func (s *PaymentNotification) GetTransactionUUID() uuid.UUID {
+	return s.TransactionUUID
+}
+
+// GetOrderUUID returns the value of OrderUUID.
+func (s *PaymentNotification) GetOrderUUID() OptUUID {
+	return s.OrderUUID
+}
+
+// SetTransactionUUID sets the value of TransactionUUID.
+func (s *PaymentNotification) SetTransactionUUID(val uuid.UUID) {
+	s.TransactionUUID = val
+}
+
+// SetOrderUUID sets the value of OrderUUID.
+func (s *PaymentNotification) SetOrderUUID(val OptUUID) {
+	s.OrderUUID = val
+}
+
+// Ref: #/components/schemas/PaymentRequiredError
+type PaymentRequiredError struct {
+	Code    OptInt    `json:"code"`
+	Message OptString `json:"message"`
+	// Код отказа платежного провайдера либо RISK_DENIED, если
+	// оплату запретили правила.
+	DeclineCode OptString `json:"decline_code"`
+	// Доступный остаток кошелька инвестора, если оплата
+	// отклонена из-за нехватки средств.
+	AvailableBalance OptFloat64 `json:"available_balance"`
+	// Сумма, которую требовалось оплатить с кошелька.
+	RequiredAmount OptFloat64 `json:"required_amount"`
+	// Идентификаторы правил антифрода и лимитов,
+	// запретивших оплату (decline_code RISK_DENIED).
+	RuleIds []string `json:"rule_ids"`
+}
+
+// GetCode returns the value of Code.
+func (s *PaymentRequiredError) GetCode() OptInt {
+	return s.Code
+}
+
+// GetMessage returns the value of Message.
+func (s *PaymentRequiredError) GetMessage() OptString {
+	return s.Message
+}
+
+// GetDeclineCode returns the value of DeclineCode.
+func (s *PaymentRequiredError) GetDeclineCode() OptString {
+	return s.DeclineCode
+}
+
+// GetAvailableBalance returns the value of AvailableBalance.
+func (s *PaymentRequiredError) GetAvailableBalance() OptFloat64 {
+	return s.AvailableBalance
+}
+
+// GetRequiredAmount returns the value of RequiredAmount.
+func (s *PaymentRequiredError) GetRequiredAmount() OptFloat64 {
+	return s.RequiredAmount
+}
+
+// GetRuleIds returns the value of RuleIds.
+func (s *PaymentRequiredError) GetRuleIds() []string {
+	return s.RuleIds
+}
+
+// SetCode sets the value of Code.
+func (s *PaymentRequiredError) SetCode(val OptInt) {
+	s.Code = val
+}
+
+// SetMessage sets the value of Message.
+func (s *PaymentRequiredError) SetMessage(val OptString) {
+	s.Message = val
+}
+
+// SetDeclineCode sets the value of DeclineCode.
+func (s *PaymentRequiredError) SetDeclineCode(val OptString) {
+	s.DeclineCode = val
+}
+
+// SetAvailableBalance sets the value of AvailableBalance.
+func (s *PaymentRequiredError) SetAvailableBalance(val OptFloat64) {
+	s.AvailableBalance = val
+}
+
+// SetRequiredAmount sets the value of RequiredAmount.
+func (s *PaymentRequiredError) SetRequiredAmount(val OptFloat64) {
+	s.RequiredAmount = val
+}
+
+// SetRuleIds sets the value of RuleIds.
+func (s *PaymentRequiredError) SetRuleIds(val []string) {
+	s.RuleIds = val
+}
+
+func (*PaymentRequiredError) postOrdersPayRes()    {}
+func (*PaymentRequiredError) postOrdersRefundRes() {}
+
+// PostOrdersCancelNoContent is response for PostOrdersCancel operation.
+type PostOrdersCancelNoContent struct{}
+
+func (*PostOrdersCancelNoContent) postOrdersCancelRes() {}
+
+// PostOrdersFulfilNoContent is response for PostOrdersFulfil operation.
+type PostOrdersFulfilNoContent struct{}
+
+func (*PostOrdersFulfilNoContent) postOrdersFulfilRes() {}
+
+// PostPaymentNotificationsNoContent is response for PostPaymentNotifications operation.
+type PostPaymentNotificationsNoContent struct{}
+
+func (*PostPaymentNotificationsNoContent) postPaymentNotificationsRes() {}
+
+// Ref: #/components/schemas/RefundOrderRequest
+type RefundOrderRequest struct {
+	// Сумма возврата; если не указана, возвращается вся
+	// оставшаяся сумма заказа.
+	Amount OptFloat64 `json:"amount"`
+	// Причина возврата.
+	Reason OptString `json:"reason"`
+}
+
+// GetAmount returns the value of Amount.
+func (s *RefundOrderRequest) GetAmount() OptFloat64 {
+	return s.Amount
+}
+
+// GetReason returns the value of Reason.
+func (s *RefundOrderRequest) GetReason() OptString {
+	return s.Reason
+}
+
+// SetAmount sets the value of Amount.
+func (s *RefundOrderRequest) SetAmount(val OptFloat64) {
+	s.Amount = val
+}
+
+// SetReason sets the value of Reason.
+func (s *RefundOrderRequest) SetReason(val OptString) {
+	s.Reason = val
+}
+
+// Ref: #/components/schemas/RefundOrderResponse
+type RefundOrderResponse struct {
+	// UUID возврата в Payment Service.
+	RefundUUID uuid.UUID `json:"refund_uuid"`
+	// Сумма всех возвратов по заказу.
+	RefundedAmount float64     `json:"refunded_amount"`
+	Status         OrderStatus `json:"status"`
+}
+
+// GetRefundUUID returns the value of RefundUUID.
+func (s *RefundOrderResponse) GetRefundUUID() uuid.UUID {
+	return s.RefundUUID
+}
+
+// GetRefundedAmount returns the value of RefundedAmount.
+func (s *RefundOrderResponse) GetRefundedAmount() float64 {
+	return s.RefundedAmount
+}
+
+// GetStatus returns the value of Status.
+func (s *RefundOrderResponse) GetStatus() OrderStatus {
+	return s.Status
+}
+
+// SetRefundUUID sets the value of RefundUUID.
+func (s *RefundOrderResponse) SetRefundUUID(val uuid.UUID) {
+	s.RefundUUID = val
+}
+
+// SetRefundedAmount sets the value of RefundedAmount.
+func (s *RefundOrderResponse) SetRefundedAmount(val float64) {
+	s.RefundedAmount = val
+}
+
+// SetStatus sets the value of Status.
+func (s *RefundOrderResponse) SetStatus(val OrderStatus) {
+	s.Status = val
+}
+
+func (*RefundOrderResponse) postOrdersRefundRes() {}
+
+type SortByQuery string
+
+const (
+	SortByQueryCreatedAt  SortByQuery = "created_at"
+	SortByQueryTotalPrice SortByQuery = "total_price"
+)
+
+// AllValues returns all SortByQuery values.
+func (SortByQuery) AllValues() []SortByQuery {
+	return []SortByQuery{
+		SortByQueryCreatedAt,
+		SortByQueryTotalPrice,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s SortByQuery) MarshalText() ([]byte, error) {
+	switch s {
+	case SortByQueryCreatedAt:
+		return []byte(s), nil
+	case SortByQueryTotalPrice:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *SortByQuery) UnmarshalText(data []byte) error {
+	switch SortByQuery(data) {
+	case SortByQueryCreatedAt:
+		*s = SortByQueryCreatedAt
+		return nil
+	case SortByQueryTotalPrice:
+		*s = SortByQueryTotalPrice
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+type SortOrderQuery string
+
+const (
+	SortOrderQueryAsc  SortOrderQuery = "asc"
+	SortOrderQueryDesc SortOrderQuery = "desc"
+)
+
+// AllValues returns all SortOrderQuery values.
+func (SortOrderQuery) AllValues() []SortOrderQuery {
+	return []SortOrderQuery{
+		SortOrderQueryAsc,
+		SortOrderQueryDesc,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s SortOrderQuery) MarshalText() ([]byte, error) {
+	switch s {
+	case SortOrderQueryAsc:
+		return []byte(s), nil
+	case SortOrderQueryDesc:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *SortOrderQuery) UnmarshalText(data []byte) error {
+	switch SortOrderQuery(data) {
+	case SortOrderQueryAsc:
+		*s = SortOrderQueryAsc
+		return nil
+	case SortOrderQueryDesc:
+		*s = SortOrderQueryDesc
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Ref: #/components/schemas/UnprocessableEntityError
+type UnprocessableEntityError struct {
+	Code    OptInt    `json:"code"`
+	Message OptString `json:"message"`
+}
+
+// GetCode returns the value of Code.
+func (s *UnprocessableEntityError) GetCode() OptInt {
+	return s.Code
+}
+
+// GetMessage returns the value of Message.
+func (s *UnprocessableEntityError) GetMessage() OptString {
+	return s.Message
+}
+
+// SetCode sets the value of Code.
+func (s *UnprocessableEntityError) SetCode(val OptInt) {
+	s.Code = val
+}
+
+// SetMessage sets the value of Message.
+func (s *UnprocessableEntityError) SetMessage(val OptString) {
+	s.Message = val
+}
+
+func (*UnprocessableEntityError) postOrdersPayRes()    {}
+func (*UnprocessableEntityError) postOrdersRefundRes() {}
+func (*UnprocessableEntityError) postOrdersRes()       {}
+
+// Ref: #/components/schemas/WebhookDelivery
+type WebhookDelivery struct {
+	DeliveryUUID uuid.UUID `json:"delivery_uuid"`
+	// UUID события; одно событие доставляется каждой
+	// подписке отдельно.
+	EventID   uuid.UUID             `json:"event_id"`
+	EventType WebhookEventType      `json:"event_type"`
+	OrderUUID uuid.UUID             `json:"order_uuid"`
+	Status    WebhookDeliveryStatus `json:"status"`
+	// Число выполненных попыток.
+	Attempts int `json:"attempts"`
+	// HTTP-статус ответа получателя на последнюю попытку.
+	ResponseStatus OptInt `json:"response_status"`
+	// Причина неудачи последней попытки.
+	LastError     OptString   `json:"last_error"`
+	CreatedAt     time.Time   `json:"created_at"`
+	LastAttemptAt OptDateTime `json:"last_attempt_at"`
+	// Когда будет следующая попытка; только для PENDING.
+	NextAttemptAt OptDateTime `json:"next_attempt_at"`
+}
+
+// GetDeliveryUUID returns the value of DeliveryUUID.
+func (s *WebhookDelivery) GetDeliveryUUID() uuid.UUID {
+	return s.DeliveryUUID
+}
+
+// GetEventID returns the value of EventID.
+func (s *WebhookDelivery) GetEventID() uuid.UUID {
+	return s.EventID
+}
+
+// GetEventType returns the value of EventType.
+func (s *WebhookDelivery) GetEventType() WebhookEventType {
+	return s.EventType
+}
+
+// GetOrderUUID returns the value of OrderUUID.
+func (s *WebhookDelivery) GetOrderUUID() uuid.UUID {
+	return s.OrderUUID
+}
+
+// GetStatus returns the value of Status.
+func (s *WebhookDelivery) GetStatus() WebhookDeliveryStatus {
+	return s.Status
+}
+
+// GetAttempts returns the value of Attempts.
+func (s *WebhookDelivery) GetAttempts() int {
+	return s.Attempts
+}
+
+// GetResponseStatus returns the value of ResponseStatus.
+func (s *WebhookDelivery) GetResponseStatus() OptInt {
+	return s.ResponseStatus
+}
+
+// GetLastError returns the value of LastError.
+func (s *WebhookDelivery) GetLastError() OptString {
+	return s.LastError
+}
+
+// GetCreatedAt returns the value of CreatedAt.
+func (s *WebhookDelivery) GetCreatedAt() time.Time {
+	return s.CreatedAt
+}
+
+// GetLastAttemptAt returns the value of LastAttemptAt.
+func (s *WebhookDelivery) GetLastAttemptAt() OptDateTime {
+	return s.LastAttemptAt
+}
+
+// GetNextAttemptAt returns the value of NextAttemptAt.
+func (s *WebhookDelivery) GetNextAttemptAt() OptDateTime {
+	return s.NextAttemptAt
+}
+
+// SetDeliveryUUID sets the value of DeliveryUUID.
+func (s *WebhookDelivery) SetDeliveryUUID(val uuid.UUID) {
+	s.DeliveryUUID = val
+}
+
+// SetEventID sets the value of EventID.
+func (s *WebhookDelivery) SetEventID(val uuid.UUID) {
+	s.EventID = val
+}
+
+// SetEventType sets the value of EventType.
+func (s *WebhookDelivery) SetEventType(val WebhookEventType) {
+	s.EventType = val
+}
+
+// SetOrderUUID sets the value of OrderUUID.
+func (s *WebhookDelivery) SetOrderUUID(val uuid.UUID) {
+	s.OrderUUID = val
+}
+
+// SetStatus sets the value of Status.
+func (s *WebhookDelivery) SetStatus(val WebhookDeliveryStatus) {
+	s.Status = val
+}
+
+// SetAttempts sets the value of Attempts.
+func (s *WebhookDelivery) SetAttempts(val int) {
+	s.Attempts = val
+}
+
+// SetResponseStatus sets the value of ResponseStatus.
+func (s *WebhookDelivery) SetResponseStatus(val OptInt) {
+	s.ResponseStatus = val
+}
+
+// SetLastError sets the value of LastError.
+func (s *WebhookDelivery) SetLastError(val OptString) {
+	s.LastError = val
+}
+
+// SetCreatedAt sets the value of CreatedAt.
+func (s *WebhookDelivery) SetCreatedAt(val time.Time) {
+	s.CreatedAt = val
+}
+
+// SetLastAttemptAt sets the value of LastAttemptAt.
+func (s *WebhookDelivery) SetLastAttemptAt(val OptDateTime) {
+	s.LastAttemptAt = val
+}
+
+// SetNextAttemptAt sets the value of NextAttemptAt.
+func (s *WebhookDelivery) SetNextAttemptAt(val OptDateTime) {
+	s.NextAttemptAt = val
+}
+
+// Статус доставки события: PENDING — ожидает отправки или
+// повтора, DELIVERED — получатель ответил 2xx,
+// FAILED — все попытки исчерпаны.
+// Ref: #/components/schemas/WebhookDeliveryStatus
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPENDING   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusDELIVERED WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryStatusFAILED    WebhookDeliveryStatus = "FAILED"
+)
+
+// AllValues returns all WebhookDeliveryStatus values.
+func (WebhookDeliveryStatus) AllValues() []WebhookDeliveryStatus {
+	return []WebhookDeliveryStatus{
+		WebhookDeliveryStatusPENDING,
+		WebhookDeliveryStatusDELIVERED,
+		WebhookDeliveryStatusFAILED,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s WebhookDeliveryStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case WebhookDeliveryStatusPENDING:
+		return []byte(s), nil
+	case WebhookDeliveryStatusDELIVERED:
+		return []byte(s), nil
+	case WebhookDeliveryStatusFAILED:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *WebhookDeliveryStatus) UnmarshalText(data []byte) error {
+	switch WebhookDeliveryStatus(data) {
+	case WebhookDeliveryStatusPENDING:
+		*s = WebhookDeliveryStatusPENDING
+		return nil
+	case WebhookDeliveryStatusDELIVERED:
+		*s = WebhookDeliveryStatusDELIVERED
+		return nil
+	case WebhookDeliveryStatusFAILED:
+		*s = WebhookDeliveryStatusFAILED
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Тип события заказа: order.created — заказ создан, order.paid —
+// заказ оплачен (сумма заблокирована,
+// статус AUTHORIZED, в том числе после подтверждения
+// асинхронного платежа), order.cancelled — заказ
+// отменен (неоплаченный — статус CANCELLED, оплаченный —
+// полный возврат, статус REFUNDED).
+// Ref: #/components/schemas/WebhookEventType
+type WebhookEventType string
+
+const (
+	WebhookEventTypeOrderCreated   WebhookEventType = "order.created"
+	WebhookEventTypeOrderPaid      WebhookEventType = "order.paid"
+	WebhookEventTypeOrderCancelled WebhookEventType = "order.cancelled"
+)
+
+// AllValues returns all WebhookEventType values.
+func (WebhookEventType) AllValues() []WebhookEventType {
+	return []WebhookEventType{
+		WebhookEventTypeOrderCreated,
+		WebhookEventTypeOrderPaid,
+		WebhookEventTypeOrderCancelled,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s WebhookEventType) MarshalText() ([]byte, error) {
+	switch s {
+	case WebhookEventTypeOrderCreated:
+		return []byte(s), nil
+	case WebhookEventTypeOrderPaid:
+		return []byte(s), nil
+	case WebhookEventTypeOrderCancelled:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *WebhookEventType) UnmarshalText(data []byte) error {
+	switch WebhookEventType(data) {
+	case WebhookEventTypeOrderCreated:
+		*s = WebhookEventTypeOrderCreated
+		return nil
+	case WebhookEventTypeOrderPaid:
+		*s = WebhookEventTypeOrderPaid
+		return nil
+	case WebhookEventTypeOrderCancelled:
+		*s = WebhookEventTypeOrderCancelled
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
+// Ref: #/components/schemas/WebhookSubscription
+type WebhookSubscription struct {
+	SubscriptionUUID uuid.UUID          `json:"subscription_uuid"`
+	URL              url.URL            `json:"url"`
+	Events           []WebhookEventType `json:"events"`
+	// Секрет подписи; возвращается только при создании
+	// подписки.
+	Secret    OptString `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetSubscriptionUUID returns the value of SubscriptionUUID.
+func (s *WebhookSubscription) GetSubscriptionUUID() uuid.UUID {
+	return s.SubscriptionUUID
+}
+
+// GetURL returns the value of URL.
+func (s *WebhookSubscription) GetURL() url.URL {
+	return s.URL
+}
+
+// GetEvents returns the value of Events.
+func (s *WebhookSubscription) GetEvents() []WebhookEventType {
+	return s.Events
+}
+
+// GetSecret returns the value of Secret.
+func (s *WebhookSubscription) GetSecret() OptString {
+	return s.Secret
+}
+
+// GetCreatedAt returns the value of CreatedAt.
+func (s *WebhookSubscription) GetCreatedAt() time.Time {
+	return s.CreatedAt
+}
+
+// SetSubscriptionUUID sets the value of SubscriptionUUID.
+func (s *WebhookSubscription) SetSubscriptionUUID(val uuid.UUID) {
+	s.SubscriptionUUID = val
+}
+
+// SetURL sets the value of URL.
+func (s *WebhookSubscription) SetURL(val url.URL) {
+	s.URL = val
+}
+
+// SetEvents sets the value of Events.
+func (s *WebhookSubscription) SetEvents(val []WebhookEventType) {
+	s.Events = val
+}
+
+// SetSecret sets the value of Secret.
+func (s *WebhookSubscription) SetSecret(val OptString) {
+	s.Secret = val
+}
+
+// SetCreatedAt sets the value of CreatedAt.
+func (s *WebhookSubscription) SetCreatedAt(val time.Time) {
+	s.CreatedAt = val
+}
+
+func (*WebhookSubscription) createWebhookSubscriptionRes() {}
@@ -0,0 +1,142 @@
+// Code generated by ogen, DO NOT EDIT.
+
+package orderv1
+
+import (
+	"context"
+)
+
+// Handler handles operations described by OpenAPI v3 specification.
+type Handler interface {
+	// CreateWebhookSubscription implements createWebhookSubscription operation.
+	//
+	// Регистрирует адрес, на который Order Service отправляет
+	// события выбранных типов.
+	// Каждое событие подписывается HMAC-SHA256 секретом
+	// подписки (см. схему WebhookEvent)
+	// и повторяется с экспоненциальной задержкой, пока
+	// получатель не ответит 2xx.
+	//
+	// POST /api/v1/webhooks/subscriptions
+	CreateWebhookSubscription(ctx context.Context, req *CreateWebhookSubscriptionRequest) (CreateWebhookSubscriptionRes, error)
+	// DeleteWebhookSubscription implements deleteWebhookSubscription operation.
+	//
+	// Удаляет подписку вместе с журналом доставок;
+	// неотправленные события больше не доставляются.
+	//
+	// DELETE /api/v1/webhooks/subscriptions/{subscription_uuid}
+	DeleteWebhookSubscription(ctx context.Context, params DeleteWebhookSubscriptionParams) (DeleteWebhookSubscriptionRes, error)
+	// GetOrders implements getOrders operation.
+	//
+	// Возвращает информацию о заказе по UUID.
+	//
+	// GET /api/v1/orders/{order_uuid}
+	GetOrders(ctx context.Context, params GetOrdersParams) (GetOrdersRes, error)
+	// ListOrders implements listOrders operation.
+	//
+	// Возвращает заказы по фильтрам с курсорной пагинацией
+	// и сортировкой.
+	//
+	// GET /api/v1/orders
+	ListOrders(ctx context.Context, params ListOrdersParams) (ListOrdersRes, error)
+	// ListWebhookDeliveries implements listWebhookDeliveries operation.
+	//
+	// Возвращает доставки событий подписки от новых к
+	// старым.
+	//
+	// GET /api/v1/webhooks/subscriptions/{subscription_uuid}/deliveries
+	ListWebhookDeliveries(ctx context.Context, params ListWebhookDeliveriesParams) (ListWebhookDeliveriesRes, error)
+	// ListWebhookSubscriptions implements listWebhookSubscriptions operation.
+	//
+	// Возвращает все подписки без секретов подписи.
+	//
+	// GET /api/v1/webhooks/subscriptions
+	ListWebhookSubscriptions(ctx context.Context) (ListWebhookSubscriptionsRes, error)
+	// PostOrders implements postOrders operation.
+	//
+	// Создает новый заказ на основе выбранных
+	// пользователем деталей.
+	//
+	// POST /api/v1/orders
+	PostOrders(ctx context.Context, req *CreateOrderRequest, params PostOrdersParams) (PostOrdersRes, error)
+	// PostOrdersCancel implements postOrdersCancel operation.
+	//
+	// Отменяет ранее созданный заказ. Неоплаченный заказ
+	// переходит в CANCELLED, у авторизованного
+	// (AUTHORIZED) перед этим снимается блокировка суммы, а по
+	// оплаченному оформляется возврат
+	// оставшейся суммы и заказ переходит в REFUNDED.
+	// В обоих случаях зарезервированные детали
+	// возвращаются на склад.
+	//
+	// POST /api/v1/orders/{order_uuid}/cancel
+	PostOrdersCancel(ctx context.Context, params PostOrdersCancelParams) (PostOrdersCancelRes, error)
+	// PostOrdersFulfil implements postOrdersFulfil operation.
+	//
+	// Завершает сборку заказа: списывает авторизованную
+	// сумму, переводит заказ из AUTHORIZED в PAID
+	// и окончательно списывает зарезервированные детали
+	// со склада.
+	// Если срок авторизации истек, заказ возвращается в
+	// PENDING_PAYMENT и его нужно оплатить заново.
+	//
+	// POST /api/v1/orders/{order_uuid}/fulfil
+	PostOrdersFulfil(ctx context.Context, params PostOrdersFulfilParams) (PostOrdersFulfilRes, error)
+	// PostOrdersPay implements postOrdersPay operation.
+	//
+	// Блокирует сумму ранее созданного заказа (авторизация
+	// платежа) и переводит его в AUTHORIZED.
+	// Деньги списываются при выдаче заказа (POST
+	// /api/v1/orders/{order_uuid}/fulfil);
+	// несписанная авторизация аннулируется Payment Service по
+	// истечении срока.
+	// Асинхронные способы оплаты (СБП, карта с 3-D Secure)
+	// переводят заказ в PAYMENT_PENDING:
+	// итог платежа Payment Service сообщает через POST
+	// /api/v1/payment-notifications.
+	//
+	// POST /api/v1/orders/{order_uuid}/pay
+	PostOrdersPay(ctx context.Context, req *PayOrderRequest, params PostOrdersPayParams) (PostOrdersPayRes, error)
+	// PostOrdersRefund implements postOrdersRefund operation.
+	//
+	// Возвращает всю оставшуюся сумму оплаченного заказа
+	// или ее часть.
+	// После частичного возврата заказ переходит в
+	// PARTIALLY_REFUNDED, после полного — в REFUNDED
+	// с возвратом деталей на склад.
+	//
+	// POST /api/v1/orders/{order_uuid}/refund
+	PostOrdersRefund(ctx context.Context, req OptRefundOrderRequest, params PostOrdersRefundParams) (PostOrdersRefundRes, error)
+	// PostPaymentNotifications implements postPaymentNotifications operation.
+	//
+	// Вызывается Payment Service, когда провайдер подтвердил или
+	// отклонил асинхронный платеж.
+	// Order Service перечитывает транзакцию через GetTransaction и
+	// переводит заказ из PAYMENT_PENDING
+	// в AUTHORIZED (PAID — после выдачи), а при отказе или
+	// аннулировании — обратно в PENDING_PAYMENT.
+	// Повторное уведомление и уведомление по заказу в
+	// другом статусе ничего не меняют.
+	//
+	// POST /api/v1/payment-notifications
+	PostPaymentNotifications(ctx context.Context, req *PaymentNotification) (PostPaymentNotificationsRes, error)
+}
+
+// Server implements http server based on OpenAPI v3 specification and
+// calls Handler to handle requests.
+type Server struct {
+	h Handler
+	baseServer
+}
+
+// NewServer creates new Server.
+func NewServer(h Handler, opts ...ServerOption) (*Server, error) {
+	s, err := newServerConfig(opts...).baseServer()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		h:          h,
+		baseServer: s,
+	}, nil
+}
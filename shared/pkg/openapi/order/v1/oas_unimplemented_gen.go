@@ -0,0 +1,162 @@
+// Code generated by ogen, DO NOT EDIT.
+
+package orderv1
+
+import (
+	"context"
+
+	ht "github.com/ogen-go/ogen/http"
+)
+
+// UnimplementedHandler is no-op Handler which returns http.ErrNotImplemented.
+type UnimplementedHandler struct{}
+
+var _ Handler = UnimplementedHandler{}
+
+// CreateWebhookSubscription implements createWebhookSubscription operation.
+//
+// Регистрирует адрес, на который Order Service отправляет
+// события выбранных типов.
+// Каждое событие подписывается HMAC-SHA256 секретом
+// подписки (см. схему WebhookEvent)
+// и повторяется с экспоненциальной задержкой, пока
+// получатель не ответит 2xx.
+//
+// POST /api/v1/webhooks/subscriptions
+func (UnimplementedHandler) CreateWebhookSubscription(ctx context.Context, req *CreateWebhookSubscriptionRequest) (r CreateWebhookSubscriptionRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// DeleteWebhookSubscription implements deleteWebhookSubscription operation.
+//
+// Удаляет подписку вместе с журналом доставок;
+// неотправленные события больше не доставляются.
+//
+// DELETE /api/v1/webhooks/subscriptions/{subscription_uuid}
+func (UnimplementedHandler) DeleteWebhookSubscription(ctx context.Context, params DeleteWebhookSubscriptionParams) (r DeleteWebhookSubscriptionRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// GetOrders implements getOrders operation.
+//
+// Возвращает информацию о заказе по UUID.
+//
+// GET /api/v1/orders/{order_uuid}
+func (UnimplementedHandler) GetOrders(ctx context.Context, params GetOrdersParams) (r GetOrdersRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListOrders implements listOrders operation.
+//
+// Возвращает заказы по фильтрам с курсорной пагинацией
+// и сортировкой.
+//
+// GET /api/v1/orders
+func (UnimplementedHandler) ListOrders(ctx context.Context, params ListOrdersParams) (r ListOrdersRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListWebhookDeliveries implements listWebhookDeliveries operation.
+//
+// Возвращает доставки событий подписки от новых к
+// старым.
+//
+// GET /api/v1/webhooks/subscriptions/{subscription_uuid}/deliveries
+func (UnimplementedHandler) ListWebhookDeliveries(ctx context.Context, params ListWebhookDeliveriesParams) (r ListWebhookDeliveriesRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ListWebhookSubscriptions implements listWebhookSubscriptions operation.
+//
+// Возвращает все подписки без секретов подписи.
+//
+// GET /api/v1/webhooks/subscriptions
+func (UnimplementedHandler) ListWebhookSubscriptions(ctx context.Context) (r ListWebhookSubscriptionsRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// PostOrders implements postOrders operation.
+//
+// Создает новый заказ на основе выбранных
+// пользователем деталей.
+//
+// POST /api/v1/orders
+func (UnimplementedHandler) PostOrders(ctx context.Context, req *CreateOrderRequest, params PostOrdersParams) (r PostOrdersRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// PostOrdersCancel implements postOrdersCancel operation.
+//
+// Отменяет ранее созданный заказ. Неоплаченный заказ
+// переходит в CANCELLED, у авторизованного
+// (AUTHORIZED) перед этим снимается блокировка суммы, а по
+// оплаченному оформляется возврат
+// оставшейся суммы и заказ переходит в REFUNDED.
+// В обоих случаях зарезервированные детали
+// возвращаются на склад.
+//
+// POST /api/v1/orders/{order_uuid}/cancel
+func (UnimplementedHandler) PostOrdersCancel(ctx context.Context, params PostOrdersCancelParams) (r PostOrdersCancelRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// PostOrdersFulfil implements postOrdersFulfil operation.
+//
+// Завершает сборку заказа: списывает авторизованную
+// сумму, переводит заказ из AUTHORIZED в PAID
+// и окончательно списывает зарезервированные детали
+// со склада.
+// Если срок авторизации истек, заказ возвращается в
+// PENDING_PAYMENT и его нужно оплатить заново.
+//
+// POST /api/v1/orders/{order_uuid}/fulfil
+func (UnimplementedHandler) PostOrdersFulfil(ctx context.Context, params PostOrdersFulfilParams) (r PostOrdersFulfilRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// PostOrdersPay implements postOrdersPay operation.
+//
+// Блокирует сумму ранее созданного заказа (авторизация
+// платежа) и переводит его в AUTHORIZED.
+// Деньги списываются при выдаче заказа (POST
+// /api/v1/orders/{order_uuid}/fulfil);
+// несписанная авторизация аннулируется Payment Service по
+// истечении срока.
+// Асинхронные способы оплаты (СБП, карта с 3-D Secure)
+// переводят заказ в PAYMENT_PENDING:
+// итог платежа Payment Service сообщает через POST
+// /api/v1/payment-notifications.
+//
+// POST /api/v1/orders/{order_uuid}/pay
+func (UnimplementedHandler) PostOrdersPay(ctx context.Context, req *PayOrderRequest, params PostOrdersPayParams) (r PostOrdersPayRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// PostOrdersRefund implements postOrdersRefund operation.
+//
+// Возвращает всю оставшуюся сумму оплаченного заказа
+// или ее часть.
+// После частичного возврата заказ переходит в
+// PARTIALLY_REFUNDED, после полного — в REFUNDED
+// с возвратом деталей на склад.
+//
+// POST /api/v1/orders/{order_uuid}/refund
+func (UnimplementedHandler) PostOrdersRefund(ctx context.Context, req OptRefundOrderRequest, params PostOrdersRefundParams) (r PostOrdersRefundRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// PostPaymentNotifications implements postPaymentNotifications operation.
+//
+// Вызывается Payment Service, когда провайдер подтвердил или
+// отклонил асинхронный платеж.
+// Order Service перечитывает транзакцию через GetTransaction и
+// переводит заказ из PAYMENT_PENDING
+// в AUTHORIZED (PAID — после выдачи), а при отказе или
+// аннулировании — обратно в PENDING_PAYMENT.
+// Повторное уведомление и уведомление по заказу в
+// другом статусе ничего не меняют.
+//
+// POST /api/v1/payment-notifications
+func (UnimplementedHandler) PostPaymentNotifications(ctx context.Context, req *PaymentNotification) (r PostPaymentNotificationsRes, _ error) {
+	return r, ht.ErrNotImplemented
+}
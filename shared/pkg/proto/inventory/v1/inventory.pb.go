@@ -9,6 +9,7 @@ package inventoryv1
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -78,6 +79,59 @@ func (Category) EnumDescriptor() ([]byte, []int) {
 	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{0}
 }
 
+// ReservationStatus представляет статус резерва деталей под заказ
+type ReservationStatus int32
+
+const (
+	ReservationStatus_RESERVATION_STATUS_UNSPECIFIED ReservationStatus = 0
+	ReservationStatus_RESERVATION_STATUS_RESERVED    ReservationStatus = 1
+	ReservationStatus_RESERVATION_STATUS_COMMITTED   ReservationStatus = 2
+	ReservationStatus_RESERVATION_STATUS_RELEASED    ReservationStatus = 3
+)
+
+// Enum value maps for ReservationStatus.
+var (
+	ReservationStatus_name = map[int32]string{
+		0: "RESERVATION_STATUS_UNSPECIFIED",
+		1: "RESERVATION_STATUS_RESERVED",
+		2: "RESERVATION_STATUS_COMMITTED",
+		3: "RESERVATION_STATUS_RELEASED",
+	}
+	ReservationStatus_value = map[string]int32{
+		"RESERVATION_STATUS_UNSPECIFIED": 0,
+		"RESERVATION_STATUS_RESERVED":    1,
+		"RESERVATION_STATUS_COMMITTED":   2,
+		"RESERVATION_STATUS_RELEASED":    3,
+	}
+)
+
+func (x ReservationStatus) Enum() *ReservationStatus {
+	p := new(ReservationStatus)
+	*p = x
+	return p
+}
+
+func (x ReservationStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ReservationStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_inventory_v1_inventory_proto_enumTypes[1].Descriptor()
+}
+
+func (ReservationStatus) Type() protoreflect.EnumType {
+	return &file_inventory_v1_inventory_proto_enumTypes[1]
+}
+
+func (x ReservationStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ReservationStatus.Descriptor instead.
+func (ReservationStatus) EnumDescriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{1}
+}
+
 // Dimensions представляет размеры детали
 type Dimensions struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -456,32 +510,29 @@ func (x *Part) GetUpdatedAt() *timestamppb.Timestamp {
 	return nil
 }
 
-// PartsFilter представляет фильтр для поиска деталей
-type PartsFilter struct {
-	state                 protoimpl.MessageState `protogen:"open.v1"`
-	Uuids                 []string               `protobuf:"bytes,1,rep,name=uuids,proto3" json:"uuids,omitempty"`
-	Names                 []string               `protobuf:"bytes,2,rep,name=names,proto3" json:"names,omitempty"`
-	Categories            []Category             `protobuf:"varint,3,rep,packed,name=categories,proto3,enum=inventory.v1.Category" json:"categories,omitempty"`
-	ManufacturerCountries []string               `protobuf:"bytes,4,rep,name=manufacturer_countries,json=manufacturerCountries,proto3" json:"manufacturer_countries,omitempty"`
-	Tags                  []string               `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
-	unknownFields         protoimpl.UnknownFields
-	sizeCache             protoimpl.SizeCache
+// ReservationItem представляет количество одной детали в резерве
+type ReservationItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PartUuid      string                 `protobuf:"bytes,1,opt,name=part_uuid,json=partUuid,proto3" json:"part_uuid,omitempty"`
+	Quantity      int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PartsFilter) Reset() {
-	*x = PartsFilter{}
+func (x *ReservationItem) Reset() {
+	*x = ReservationItem{}
 	mi := &file_inventory_v1_inventory_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PartsFilter) String() string {
+func (x *ReservationItem) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PartsFilter) ProtoMessage() {}
+func (*ReservationItem) ProtoMessage() {}
 
-func (x *PartsFilter) ProtoReflect() protoreflect.Message {
+func (x *ReservationItem) ProtoReflect() protoreflect.Message {
 	mi := &file_inventory_v1_inventory_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -493,69 +544,127 @@ func (x *PartsFilter) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PartsFilter.ProtoReflect.Descriptor instead.
-func (*PartsFilter) Descriptor() ([]byte, []int) {
+// Deprecated: Use ReservationItem.ProtoReflect.Descriptor instead.
+func (*ReservationItem) Descriptor() ([]byte, []int) {
 	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *PartsFilter) GetUuids() []string {
+func (x *ReservationItem) GetPartUuid() string {
 	if x != nil {
-		return x.Uuids
+		return x.PartUuid
 	}
-	return nil
+	return ""
 }
 
-func (x *PartsFilter) GetNames() []string {
+func (x *ReservationItem) GetQuantity() int64 {
 	if x != nil {
-		return x.Names
+		return x.Quantity
 	}
-	return nil
+	return 0
 }
 
-func (x *PartsFilter) GetCategories() []Category {
+// Reservation представляет резерв деталей под заказ
+type Reservation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderUuid     string                 `protobuf:"bytes,1,opt,name=order_uuid,json=orderUuid,proto3" json:"order_uuid,omitempty"`
+	Items         []*ReservationItem     `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	Status        ReservationStatus      `protobuf:"varint,3,opt,name=status,proto3,enum=inventory.v1.ReservationStatus" json:"status,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Reservation) Reset() {
+	*x = Reservation{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Reservation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reservation) ProtoMessage() {}
+
+func (x *Reservation) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[5]
 	if x != nil {
-		return x.Categories
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reservation.ProtoReflect.Descriptor instead.
+func (*Reservation) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Reservation) GetOrderUuid() string {
+	if x != nil {
+		return x.OrderUuid
+	}
+	return ""
+}
+
+func (x *Reservation) GetItems() []*ReservationItem {
+	if x != nil {
+		return x.Items
 	}
 	return nil
 }
 
-func (x *PartsFilter) GetManufacturerCountries() []string {
+func (x *Reservation) GetStatus() ReservationStatus {
 	if x != nil {
-		return x.ManufacturerCountries
+		return x.Status
+	}
+	return ReservationStatus_RESERVATION_STATUS_UNSPECIFIED
+}
+
+func (x *Reservation) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
 	}
 	return nil
 }
 
-func (x *PartsFilter) GetTags() []string {
+func (x *Reservation) GetUpdatedAt() *timestamppb.Timestamp {
 	if x != nil {
-		return x.Tags
+		return x.UpdatedAt
 	}
 	return nil
 }
 
-// GetPartRequest запрос на получение детали
-type GetPartRequest struct {
+// StockShortage описывает нехватку одной детали на складе
+type StockShortage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	PartUuid      string                 `protobuf:"bytes,1,opt,name=part_uuid,json=partUuid,proto3" json:"part_uuid,omitempty"`
+	Requested     int64                  `protobuf:"varint,2,opt,name=requested,proto3" json:"requested,omitempty"`
+	Available     int64                  `protobuf:"varint,3,opt,name=available,proto3" json:"available,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetPartRequest) Reset() {
-	*x = GetPartRequest{}
-	mi := &file_inventory_v1_inventory_proto_msgTypes[5]
+func (x *StockShortage) Reset() {
+	*x = StockShortage{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetPartRequest) String() string {
+func (x *StockShortage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetPartRequest) ProtoMessage() {}
+func (*StockShortage) ProtoMessage() {}
 
-func (x *GetPartRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_inventory_v1_inventory_proto_msgTypes[5]
+func (x *StockShortage) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -566,41 +675,55 @@ func (x *GetPartRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetPartRequest.ProtoReflect.Descriptor instead.
-func (*GetPartRequest) Descriptor() ([]byte, []int) {
-	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use StockShortage.ProtoReflect.Descriptor instead.
+func (*StockShortage) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *GetPartRequest) GetUuid() string {
+func (x *StockShortage) GetPartUuid() string {
 	if x != nil {
-		return x.Uuid
+		return x.PartUuid
 	}
 	return ""
 }
 
-// GetPartResponse ответ с информацией о детали
-type GetPartResponse struct {
+func (x *StockShortage) GetRequested() int64 {
+	if x != nil {
+		return x.Requested
+	}
+	return 0
+}
+
+func (x *StockShortage) GetAvailable() int64 {
+	if x != nil {
+		return x.Available
+	}
+	return 0
+}
+
+// InsufficientStock передается в деталях ошибки FAILED_PRECONDITION метода ReserveParts
+type InsufficientStock struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Part          *Part                  `protobuf:"bytes,1,opt,name=part,proto3" json:"part,omitempty"`
+	Shortages     []*StockShortage       `protobuf:"bytes,1,rep,name=shortages,proto3" json:"shortages,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetPartResponse) Reset() {
-	*x = GetPartResponse{}
-	mi := &file_inventory_v1_inventory_proto_msgTypes[6]
+func (x *InsufficientStock) Reset() {
+	*x = InsufficientStock{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetPartResponse) String() string {
+func (x *InsufficientStock) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetPartResponse) ProtoMessage() {}
+func (*InsufficientStock) ProtoMessage() {}
 
-func (x *GetPartResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_inventory_v1_inventory_proto_msgTypes[6]
+func (x *InsufficientStock) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -611,41 +734,43 @@ func (x *GetPartResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetPartResponse.ProtoReflect.Descriptor instead.
-func (*GetPartResponse) Descriptor() ([]byte, []int) {
-	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use InsufficientStock.ProtoReflect.Descriptor instead.
+func (*InsufficientStock) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *GetPartResponse) GetPart() *Part {
+func (x *InsufficientStock) GetShortages() []*StockShortage {
 	if x != nil {
-		return x.Part
+		return x.Shortages
 	}
 	return nil
 }
 
-// ListPartsRequest запрос на получение списка деталей
-type ListPartsRequest struct {
+// DoubleRange диапазон значений, обе границы включительно; незаданная граница не ограничивает выборку,
+// заданная — ограничивает и при нулевом значении ({max: 0} оставляет только нулевые значения)
+type DoubleRange struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filter        *PartsFilter           `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	Min           *float64               `protobuf:"fixed64,1,opt,name=min,proto3,oneof" json:"min,omitempty"`
+	Max           *float64               `protobuf:"fixed64,2,opt,name=max,proto3,oneof" json:"max,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListPartsRequest) Reset() {
-	*x = ListPartsRequest{}
-	mi := &file_inventory_v1_inventory_proto_msgTypes[7]
+func (x *DoubleRange) Reset() {
+	*x = DoubleRange{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListPartsRequest) String() string {
+func (x *DoubleRange) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListPartsRequest) ProtoMessage() {}
+func (*DoubleRange) ProtoMessage() {}
 
-func (x *ListPartsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_inventory_v1_inventory_proto_msgTypes[7]
+func (x *DoubleRange) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -656,41 +781,139 @@ func (x *ListPartsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListPartsRequest.ProtoReflect.Descriptor instead.
-func (*ListPartsRequest) Descriptor() ([]byte, []int) {
-	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use DoubleRange.ProtoReflect.Descriptor instead.
+func (*DoubleRange) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *ListPartsRequest) GetFilter() *PartsFilter {
+func (x *DoubleRange) GetMin() float64 {
+	if x != nil && x.Min != nil {
+		return *x.Min
+	}
+	return 0
+}
+
+func (x *DoubleRange) GetMax() float64 {
+	if x != nil && x.Max != nil {
+		return *x.Max
+	}
+	return 0
+}
+
+// DimensionsRange диапазоны габаритов детали в единицах Dimensions
+type DimensionsRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Length        *DoubleRange           `protobuf:"bytes,1,opt,name=length,proto3" json:"length,omitempty"`
+	Width         *DoubleRange           `protobuf:"bytes,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height        *DoubleRange           `protobuf:"bytes,3,opt,name=height,proto3" json:"height,omitempty"`
+	Weight        *DoubleRange           `protobuf:"bytes,4,opt,name=weight,proto3" json:"weight,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DimensionsRange) Reset() {
+	*x = DimensionsRange{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DimensionsRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DimensionsRange) ProtoMessage() {}
+
+func (x *DimensionsRange) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[9]
 	if x != nil {
-		return x.Filter
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DimensionsRange.ProtoReflect.Descriptor instead.
+func (*DimensionsRange) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DimensionsRange) GetLength() *DoubleRange {
+	if x != nil {
+		return x.Length
 	}
 	return nil
 }
 
-// ListPartsResponse ответ со списком деталей
-type ListPartsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Parts         []*Part                `protobuf:"bytes,1,rep,name=parts,proto3" json:"parts,omitempty"`
+func (x *DimensionsRange) GetWidth() *DoubleRange {
+	if x != nil {
+		return x.Width
+	}
+	return nil
+}
+
+func (x *DimensionsRange) GetHeight() *DoubleRange {
+	if x != nil {
+		return x.Height
+	}
+	return nil
+}
+
+func (x *DimensionsRange) GetWeight() *DoubleRange {
+	if x != nil {
+		return x.Weight
+	}
+	return nil
+}
+
+// PartsFilter представляет фильтр для поиска деталей. Значения внутри списка объединяются по ИЛИ,
+// поля фильтра — по И; пустые поля не ограничивают выборку.
+// Например, двигатели до 50 000 весом до 100 кг в наличии:
+// {categories: [CATEGORY_ENGINE], price: {max: 50000}, dimensions: {weight: {max: 100}}, min_stock_quantity: 1}
+type PartsFilter struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Uuids                 []string               `protobuf:"bytes,1,rep,name=uuids,proto3" json:"uuids,omitempty"`
+	Names                 []string               `protobuf:"bytes,2,rep,name=names,proto3" json:"names,omitempty"`
+	Categories            []Category             `protobuf:"varint,3,rep,packed,name=categories,proto3,enum=inventory.v1.Category" json:"categories,omitempty"`
+	ManufacturerCountries []string               `protobuf:"bytes,4,rep,name=manufacturer_countries,json=manufacturerCountries,proto3" json:"manufacturer_countries,omitempty"`
+	Tags                  []string               `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	// price диапазон цены
+	Price *DoubleRange `protobuf:"bytes,6,opt,name=price,proto3" json:"price,omitempty"`
+	// min_stock_quantity минимальный остаток, включительно; 1 оставляет только детали в наличии
+	MinStockQuantity *int64 `protobuf:"varint,7,opt,name=min_stock_quantity,json=minStockQuantity,proto3,oneof" json:"min_stock_quantity,omitempty"`
+	// dimensions диапазоны габаритов
+	Dimensions        *DimensionsRange `protobuf:"bytes,8,opt,name=dimensions,proto3" json:"dimensions,omitempty"`
+	ManufacturerNames []string         `protobuf:"bytes,9,rep,name=manufacturer_names,json=manufacturerNames,proto3" json:"manufacturer_names,omitempty"`
+	// created_from начало интервала создания, включительно
+	CreatedFrom *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"`
+	// created_to конец интервала создания, не включительно
+	CreatedTo *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`
+	// updated_from начало интервала последнего изменения, включительно
+	UpdatedFrom *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=updated_from,json=updatedFrom,proto3" json:"updated_from,omitempty"`
+	// updated_to конец интервала последнего изменения, не включительно
+	UpdatedTo     *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=updated_to,json=updatedTo,proto3" json:"updated_to,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListPartsResponse) Reset() {
-	*x = ListPartsResponse{}
-	mi := &file_inventory_v1_inventory_proto_msgTypes[8]
+func (x *PartsFilter) Reset() {
+	*x = PartsFilter{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListPartsResponse) String() string {
+func (x *PartsFilter) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListPartsResponse) ProtoMessage() {}
+func (*PartsFilter) ProtoMessage() {}
 
-func (x *ListPartsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_inventory_v1_inventory_proto_msgTypes[8]
+func (x *PartsFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -701,29 +924,892 @@ func (x *ListPartsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListPartsResponse.ProtoReflect.Descriptor instead.
-func (*ListPartsResponse) Descriptor() ([]byte, []int) {
-	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use PartsFilter.ProtoReflect.Descriptor instead.
+func (*PartsFilter) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *ListPartsResponse) GetParts() []*Part {
+func (x *PartsFilter) GetUuids() []string {
 	if x != nil {
-		return x.Parts
+		return x.Uuids
 	}
 	return nil
 }
 
-var File_inventory_v1_inventory_proto protoreflect.FileDescriptor
+func (x *PartsFilter) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
 
-const file_inventory_v1_inventory_proto_rawDesc = "" +
-	"\n" +
-	"\x1cinventory/v1/inventory.proto\x12\finventory.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"j\n" +
-	"\n" +
-	"Dimensions\x12\x16\n" +
-	"\x06length\x18\x01 \x01(\x01R\x06length\x12\x14\n" +
-	"\x05width\x18\x02 \x01(\x01R\x05width\x12\x16\n" +
-	"\x06height\x18\x03 \x01(\x01R\x06height\x12\x16\n" +
-	"\x06weight\x18\x04 \x01(\x01R\x06weight\"V\n" +
+func (x *PartsFilter) GetCategories() []Category {
+	if x != nil {
+		return x.Categories
+	}
+	return nil
+}
+
+func (x *PartsFilter) GetManufacturerCountries() []string {
+	if x != nil {
+		return x.ManufacturerCountries
+	}
+	return nil
+}
+
+func (x *PartsFilter) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *PartsFilter) GetPrice() *DoubleRange {
+	if x != nil {
+		return x.Price
+	}
+	return nil
+}
+
+func (x *PartsFilter) GetMinStockQuantity() int64 {
+	if x != nil && x.MinStockQuantity != nil {
+		return *x.MinStockQuantity
+	}
+	return 0
+}
+
+func (x *PartsFilter) GetDimensions() *DimensionsRange {
+	if x != nil {
+		return x.Dimensions
+	}
+	return nil
+}
+
+func (x *PartsFilter) GetManufacturerNames() []string {
+	if x != nil {
+		return x.ManufacturerNames
+	}
+	return nil
+}
+
+func (x *PartsFilter) GetCreatedFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedFrom
+	}
+	return nil
+}
+
+func (x *PartsFilter) GetCreatedTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return nil
+}
+
+func (x *PartsFilter) GetUpdatedFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedFrom
+	}
+	return nil
+}
+
+func (x *PartsFilter) GetUpdatedTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedTo
+	}
+	return nil
+}
+
+// GetPartRequest запрос на получение детали
+type GetPartRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPartRequest) Reset() {
+	*x = GetPartRequest{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPartRequest) ProtoMessage() {}
+
+func (x *GetPartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPartRequest.ProtoReflect.Descriptor instead.
+func (*GetPartRequest) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetPartRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+// GetPartResponse ответ с информацией о детали
+type GetPartResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Part          *Part                  `protobuf:"bytes,1,opt,name=part,proto3" json:"part,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPartResponse) Reset() {
+	*x = GetPartResponse{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPartResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPartResponse) ProtoMessage() {}
+
+func (x *GetPartResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPartResponse.ProtoReflect.Descriptor instead.
+func (*GetPartResponse) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetPartResponse) GetPart() *Part {
+	if x != nil {
+		return x.Part
+	}
+	return nil
+}
+
+// ListPartsRequest запрос на получение списка деталей
+type ListPartsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *PartsFilter           `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// page_size размер страницы, от 1 до 100. 0 без page_token возвращает все подходящие детали одним
+	// ответом, как до появления страниц; 0 с page_token означает 20
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// page_token значение next_page_token из предыдущего ответа; filter и order_by должны совпадать
+	// с запросом, вернувшим токен
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// order_by поле сортировки и необязательное направление asc или desc: price, name, stock_quantity
+	// или created_at, например "price desc". Пустое значение упорядочивает по UUID; детали с равным
+	// значением поля упорядочены по UUID в том же направлении.
+	OrderBy       string `protobuf:"bytes,4,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPartsRequest) Reset() {
+	*x = ListPartsRequest{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPartsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPartsRequest) ProtoMessage() {}
+
+func (x *ListPartsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPartsRequest.ProtoReflect.Descriptor instead.
+func (*ListPartsRequest) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListPartsRequest) GetFilter() *PartsFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListPartsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListPartsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListPartsRequest) GetOrderBy() string {
+	if x != nil {
+		return x.OrderBy
+	}
+	return ""
+}
+
+// ListPartsResponse страница деталей
+type ListPartsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Parts []*Part                `protobuf:"bytes,1,rep,name=parts,proto3" json:"parts,omitempty"`
+	// next_page_token пуст на последней странице
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	// total_size число деталей, подходящих под фильтр, на момент запроса
+	TotalSize     int32 `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPartsResponse) Reset() {
+	*x = ListPartsResponse{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPartsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPartsResponse) ProtoMessage() {}
+
+func (x *ListPartsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPartsResponse.ProtoReflect.Descriptor instead.
+func (*ListPartsResponse) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListPartsResponse) GetParts() []*Part {
+	if x != nil {
+		return x.Parts
+	}
+	return nil
+}
+
+func (x *ListPartsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *ListPartsResponse) GetTotalSize() int32 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+// ReservePartsRequest запрос на резервирование деталей под заказ
+type ReservePartsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderUuid     string                 `protobuf:"bytes,1,opt,name=order_uuid,json=orderUuid,proto3" json:"order_uuid,omitempty"`
+	Items         []*ReservationItem     `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReservePartsRequest) Reset() {
+	*x = ReservePartsRequest{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReservePartsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReservePartsRequest) ProtoMessage() {}
+
+func (x *ReservePartsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReservePartsRequest.ProtoReflect.Descriptor instead.
+func (*ReservePartsRequest) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ReservePartsRequest) GetOrderUuid() string {
+	if x != nil {
+		return x.OrderUuid
+	}
+	return ""
+}
+
+func (x *ReservePartsRequest) GetItems() []*ReservationItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// ReservePartsResponse ответ с созданным резервом
+type ReservePartsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reservation   *Reservation           `protobuf:"bytes,1,opt,name=reservation,proto3" json:"reservation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReservePartsResponse) Reset() {
+	*x = ReservePartsResponse{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReservePartsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReservePartsResponse) ProtoMessage() {}
+
+func (x *ReservePartsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReservePartsResponse.ProtoReflect.Descriptor instead.
+func (*ReservePartsResponse) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ReservePartsResponse) GetReservation() *Reservation {
+	if x != nil {
+		return x.Reservation
+	}
+	return nil
+}
+
+// ReleaseReservationRequest запрос на отмену резерва
+type ReleaseReservationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderUuid     string                 `protobuf:"bytes,1,opt,name=order_uuid,json=orderUuid,proto3" json:"order_uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseReservationRequest) Reset() {
+	*x = ReleaseReservationRequest{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseReservationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseReservationRequest) ProtoMessage() {}
+
+func (x *ReleaseReservationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseReservationRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseReservationRequest) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ReleaseReservationRequest) GetOrderUuid() string {
+	if x != nil {
+		return x.OrderUuid
+	}
+	return ""
+}
+
+// ReleaseReservationResponse ответ с отмененным резервом
+type ReleaseReservationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reservation   *Reservation           `protobuf:"bytes,1,opt,name=reservation,proto3" json:"reservation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseReservationResponse) Reset() {
+	*x = ReleaseReservationResponse{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseReservationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseReservationResponse) ProtoMessage() {}
+
+func (x *ReleaseReservationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseReservationResponse.ProtoReflect.Descriptor instead.
+func (*ReleaseReservationResponse) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ReleaseReservationResponse) GetReservation() *Reservation {
+	if x != nil {
+		return x.Reservation
+	}
+	return nil
+}
+
+// CommitReservationRequest запрос на подтверждение резерва
+type CommitReservationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderUuid     string                 `protobuf:"bytes,1,opt,name=order_uuid,json=orderUuid,proto3" json:"order_uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommitReservationRequest) Reset() {
+	*x = CommitReservationRequest{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommitReservationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitReservationRequest) ProtoMessage() {}
+
+func (x *CommitReservationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitReservationRequest.ProtoReflect.Descriptor instead.
+func (*CommitReservationRequest) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *CommitReservationRequest) GetOrderUuid() string {
+	if x != nil {
+		return x.OrderUuid
+	}
+	return ""
+}
+
+// CommitReservationResponse ответ с подтвержденным резервом
+type CommitReservationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reservation   *Reservation           `protobuf:"bytes,1,opt,name=reservation,proto3" json:"reservation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommitReservationResponse) Reset() {
+	*x = CommitReservationResponse{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommitReservationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitReservationResponse) ProtoMessage() {}
+
+func (x *CommitReservationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitReservationResponse.ProtoReflect.Descriptor instead.
+func (*CommitReservationResponse) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CommitReservationResponse) GetReservation() *Reservation {
+	if x != nil {
+		return x.Reservation
+	}
+	return nil
+}
+
+// CreatePartRequest запрос на добавление детали; uuid, created_at и updated_at игнорируются
+type CreatePartRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Part          *Part                  `protobuf:"bytes,1,opt,name=part,proto3" json:"part,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePartRequest) Reset() {
+	*x = CreatePartRequest{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePartRequest) ProtoMessage() {}
+
+func (x *CreatePartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePartRequest.ProtoReflect.Descriptor instead.
+func (*CreatePartRequest) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CreatePartRequest) GetPart() *Part {
+	if x != nil {
+		return x.Part
+	}
+	return nil
+}
+
+// CreatePartResponse ответ с созданной деталью
+type CreatePartResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Part          *Part                  `protobuf:"bytes,1,opt,name=part,proto3" json:"part,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePartResponse) Reset() {
+	*x = CreatePartResponse{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePartResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePartResponse) ProtoMessage() {}
+
+func (x *CreatePartResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePartResponse.ProtoReflect.Descriptor instead.
+func (*CreatePartResponse) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CreatePartResponse) GetPart() *Part {
+	if x != nil {
+		return x.Part
+	}
+	return nil
+}
+
+// UpdatePartRequest запрос на изменение детали part.uuid.
+// Допустимые пути update_mask: name, description, price, stock_quantity, category,
+// dimensions, dimensions.<поле>, manufacturer, manufacturer.<поле>, tags, metadata.
+type UpdatePartRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Part          *Part                  `protobuf:"bytes,1,opt,name=part,proto3" json:"part,omitempty"`
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,2,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePartRequest) Reset() {
+	*x = UpdatePartRequest{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePartRequest) ProtoMessage() {}
+
+func (x *UpdatePartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePartRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePartRequest) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *UpdatePartRequest) GetPart() *Part {
+	if x != nil {
+		return x.Part
+	}
+	return nil
+}
+
+func (x *UpdatePartRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+// UpdatePartResponse ответ с измененной деталью
+type UpdatePartResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Part          *Part                  `protobuf:"bytes,1,opt,name=part,proto3" json:"part,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePartResponse) Reset() {
+	*x = UpdatePartResponse{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePartResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePartResponse) ProtoMessage() {}
+
+func (x *UpdatePartResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePartResponse.ProtoReflect.Descriptor instead.
+func (*UpdatePartResponse) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *UpdatePartResponse) GetPart() *Part {
+	if x != nil {
+		return x.Part
+	}
+	return nil
+}
+
+// DeletePartRequest запрос на удаление детали
+type DeletePartRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uuid          string                 `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePartRequest) Reset() {
+	*x = DeletePartRequest{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePartRequest) ProtoMessage() {}
+
+func (x *DeletePartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePartRequest.ProtoReflect.Descriptor instead.
+func (*DeletePartRequest) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *DeletePartRequest) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+// DeletePartResponse ответ на удаление детали
+type DeletePartResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePartResponse) Reset() {
+	*x = DeletePartResponse{}
+	mi := &file_inventory_v1_inventory_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePartResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePartResponse) ProtoMessage() {}
+
+func (x *DeletePartResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_v1_inventory_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePartResponse.ProtoReflect.Descriptor instead.
+func (*DeletePartResponse) Descriptor() ([]byte, []int) {
+	return file_inventory_v1_inventory_proto_rawDescGZIP(), []int{26}
+}
+
+var File_inventory_v1_inventory_proto protoreflect.FileDescriptor
+
+const file_inventory_v1_inventory_proto_rawDesc = "" +
+	"\n" +
+	"\x1cinventory/v1/inventory.proto\x12\finventory.v1\x1a google/protobuf/field_mask.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"j\n" +
+	"\n" +
+	"Dimensions\x12\x16\n" +
+	"\x06length\x18\x01 \x01(\x01R\x06length\x12\x14\n" +
+	"\x05width\x18\x02 \x01(\x01R\x05width\x12\x16\n" +
+	"\x06height\x18\x03 \x01(\x01R\x06height\x12\x16\n" +
+	"\x06weight\x18\x04 \x01(\x01R\x06weight\"V\n" +
 	"\fManufacturer\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
 	"\acountry\x18\x02 \x01(\tR\acountry\x12\x18\n" +
@@ -756,7 +1842,35 @@ const file_inventory_v1_inventory_proto_rawDesc = "" +
 	"updated_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x1aP\n" +
 	"\rMetadataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12)\n" +
-	"\x05value\x18\x02 \x01(\v2\x13.inventory.v1.ValueR\x05value:\x028\x01\"\xbc\x01\n" +
+	"\x05value\x18\x02 \x01(\v2\x13.inventory.v1.ValueR\x05value:\x028\x01\"J\n" +
+	"\x0fReservationItem\x12\x1b\n" +
+	"\tpart_uuid\x18\x01 \x01(\tR\bpartUuid\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x03R\bquantity\"\x90\x02\n" +
+	"\vReservation\x12\x1d\n" +
+	"\n" +
+	"order_uuid\x18\x01 \x01(\tR\torderUuid\x123\n" +
+	"\x05items\x18\x02 \x03(\v2\x1d.inventory.v1.ReservationItemR\x05items\x127\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x1f.inventory.v1.ReservationStatusR\x06status\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"h\n" +
+	"\rStockShortage\x12\x1b\n" +
+	"\tpart_uuid\x18\x01 \x01(\tR\bpartUuid\x12\x1c\n" +
+	"\trequested\x18\x02 \x01(\x03R\trequested\x12\x1c\n" +
+	"\tavailable\x18\x03 \x01(\x03R\tavailable\"N\n" +
+	"\x11InsufficientStock\x129\n" +
+	"\tshortages\x18\x01 \x03(\v2\x1b.inventory.v1.StockShortageR\tshortages\"K\n" +
+	"\vDoubleRange\x12\x15\n" +
+	"\x03min\x18\x01 \x01(\x01H\x00R\x03min\x88\x01\x01\x12\x15\n" +
+	"\x03max\x18\x02 \x01(\x01H\x01R\x03max\x88\x01\x01B\x06\n" +
+	"\x04_minB\x06\n" +
+	"\x04_max\"\xdb\x01\n" +
+	"\x0fDimensionsRange\x121\n" +
+	"\x06length\x18\x01 \x01(\v2\x19.inventory.v1.DoubleRangeR\x06length\x12/\n" +
+	"\x05width\x18\x02 \x01(\v2\x19.inventory.v1.DoubleRangeR\x05width\x121\n" +
+	"\x06height\x18\x03 \x01(\v2\x19.inventory.v1.DoubleRangeR\x06height\x121\n" +
+	"\x06weight\x18\x04 \x01(\v2\x19.inventory.v1.DoubleRangeR\x06weight\"\x99\x05\n" +
 	"\vPartsFilter\x12\x14\n" +
 	"\x05uuids\x18\x01 \x03(\tR\x05uuids\x12\x14\n" +
 	"\x05names\x18\x02 \x03(\tR\x05names\x126\n" +
@@ -764,24 +1878,88 @@ const file_inventory_v1_inventory_proto_rawDesc = "" +
 	"categories\x18\x03 \x03(\x0e2\x16.inventory.v1.CategoryR\n" +
 	"categories\x125\n" +
 	"\x16manufacturer_countries\x18\x04 \x03(\tR\x15manufacturerCountries\x12\x12\n" +
-	"\x04tags\x18\x05 \x03(\tR\x04tags\"$\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x12/\n" +
+	"\x05price\x18\x06 \x01(\v2\x19.inventory.v1.DoubleRangeR\x05price\x121\n" +
+	"\x12min_stock_quantity\x18\a \x01(\x03H\x00R\x10minStockQuantity\x88\x01\x01\x12=\n" +
+	"\n" +
+	"dimensions\x18\b \x01(\v2\x1d.inventory.v1.DimensionsRangeR\n" +
+	"dimensions\x12-\n" +
+	"\x12manufacturer_names\x18\t \x03(\tR\x11manufacturerNames\x12=\n" +
+	"\fcreated_from\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\vcreatedFrom\x129\n" +
+	"\n" +
+	"created_to\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedTo\x12=\n" +
+	"\fupdated_from\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\vupdatedFrom\x129\n" +
+	"\n" +
+	"updated_to\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedToB\x15\n" +
+	"\x13_min_stock_quantity\"$\n" +
 	"\x0eGetPartRequest\x12\x12\n" +
 	"\x04uuid\x18\x01 \x01(\tR\x04uuid\"9\n" +
 	"\x0fGetPartResponse\x12&\n" +
-	"\x04part\x18\x01 \x01(\v2\x12.inventory.v1.PartR\x04part\"E\n" +
+	"\x04part\x18\x01 \x01(\v2\x12.inventory.v1.PartR\x04part\"\x9c\x01\n" +
 	"\x10ListPartsRequest\x121\n" +
-	"\x06filter\x18\x01 \x01(\v2\x19.inventory.v1.PartsFilterR\x06filter\"=\n" +
+	"\x06filter\x18\x01 \x01(\v2\x19.inventory.v1.PartsFilterR\x06filter\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\x12\x19\n" +
+	"\border_by\x18\x04 \x01(\tR\aorderBy\"\x84\x01\n" +
 	"\x11ListPartsResponse\x12(\n" +
-	"\x05parts\x18\x01 \x03(\v2\x12.inventory.v1.PartR\x05parts*v\n" +
+	"\x05parts\x18\x01 \x03(\v2\x12.inventory.v1.PartR\x05parts\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x03 \x01(\x05R\ttotalSize\"i\n" +
+	"\x13ReservePartsRequest\x12\x1d\n" +
+	"\n" +
+	"order_uuid\x18\x01 \x01(\tR\torderUuid\x123\n" +
+	"\x05items\x18\x02 \x03(\v2\x1d.inventory.v1.ReservationItemR\x05items\"S\n" +
+	"\x14ReservePartsResponse\x12;\n" +
+	"\vreservation\x18\x01 \x01(\v2\x19.inventory.v1.ReservationR\vreservation\":\n" +
+	"\x19ReleaseReservationRequest\x12\x1d\n" +
+	"\n" +
+	"order_uuid\x18\x01 \x01(\tR\torderUuid\"Y\n" +
+	"\x1aReleaseReservationResponse\x12;\n" +
+	"\vreservation\x18\x01 \x01(\v2\x19.inventory.v1.ReservationR\vreservation\"9\n" +
+	"\x18CommitReservationRequest\x12\x1d\n" +
+	"\n" +
+	"order_uuid\x18\x01 \x01(\tR\torderUuid\"X\n" +
+	"\x19CommitReservationResponse\x12;\n" +
+	"\vreservation\x18\x01 \x01(\v2\x19.inventory.v1.ReservationR\vreservation\";\n" +
+	"\x11CreatePartRequest\x12&\n" +
+	"\x04part\x18\x01 \x01(\v2\x12.inventory.v1.PartR\x04part\"<\n" +
+	"\x12CreatePartResponse\x12&\n" +
+	"\x04part\x18\x01 \x01(\v2\x12.inventory.v1.PartR\x04part\"x\n" +
+	"\x11UpdatePartRequest\x12&\n" +
+	"\x04part\x18\x01 \x01(\v2\x12.inventory.v1.PartR\x04part\x12;\n" +
+	"\vupdate_mask\x18\x02 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\"<\n" +
+	"\x12UpdatePartResponse\x12&\n" +
+	"\x04part\x18\x01 \x01(\v2\x12.inventory.v1.PartR\x04part\"'\n" +
+	"\x11DeletePartRequest\x12\x12\n" +
+	"\x04uuid\x18\x01 \x01(\tR\x04uuid\"\x14\n" +
+	"\x12DeletePartResponse*v\n" +
 	"\bCategory\x12\x18\n" +
 	"\x14CATEGORY_UNSPECIFIED\x10\x00\x12\x13\n" +
 	"\x0fCATEGORY_ENGINE\x10\x01\x12\x11\n" +
 	"\rCATEGORY_FUEL\x10\x02\x12\x15\n" +
 	"\x11CATEGORY_PORTHOLE\x10\x03\x12\x11\n" +
-	"\rCATEGORY_WING\x10\x042\xa8\x01\n" +
+	"\rCATEGORY_WING\x10\x04*\x9b\x01\n" +
+	"\x11ReservationStatus\x12\"\n" +
+	"\x1eRESERVATION_STATUS_UNSPECIFIED\x10\x00\x12\x1f\n" +
+	"\x1bRESERVATION_STATUS_RESERVED\x10\x01\x12 \n" +
+	"\x1cRESERVATION_STATUS_COMMITTED\x10\x02\x12\x1f\n" +
+	"\x1bRESERVATION_STATUS_RELEASED\x10\x032\xc1\x05\n" +
 	"\x10InventoryService\x12F\n" +
 	"\aGetPart\x12\x1c.inventory.v1.GetPartRequest\x1a\x1d.inventory.v1.GetPartResponse\x12L\n" +
-	"\tListParts\x12\x1e.inventory.v1.ListPartsRequest\x1a\x1f.inventory.v1.ListPartsResponseBNZLgithub.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1;inventoryv1b\x06proto3"
+	"\tListParts\x12\x1e.inventory.v1.ListPartsRequest\x1a\x1f.inventory.v1.ListPartsResponse\x12U\n" +
+	"\fReserveParts\x12!.inventory.v1.ReservePartsRequest\x1a\".inventory.v1.ReservePartsResponse\x12g\n" +
+	"\x12ReleaseReservation\x12'.inventory.v1.ReleaseReservationRequest\x1a(.inventory.v1.ReleaseReservationResponse\x12d\n" +
+	"\x11CommitReservation\x12&.inventory.v1.CommitReservationRequest\x1a'.inventory.v1.CommitReservationResponse\x12O\n" +
+	"\n" +
+	"CreatePart\x12\x1f.inventory.v1.CreatePartRequest\x1a .inventory.v1.CreatePartResponse\x12O\n" +
+	"\n" +
+	"UpdatePart\x12\x1f.inventory.v1.UpdatePartRequest\x1a .inventory.v1.UpdatePartResponse\x12O\n" +
+	"\n" +
+	"DeletePart\x12\x1f.inventory.v1.DeletePartRequest\x1a .inventory.v1.DeletePartResponseBNZLgithub.com/evgeniyseleznev/bigproj/shared/pkg/proto/inventory/v1;inventoryv1b\x06proto3"
 
 var (
 	file_inventory_v1_inventory_proto_rawDescOnce sync.Once
@@ -795,43 +1973,99 @@ func file_inventory_v1_inventory_proto_rawDescGZIP() []byte {
 	return file_inventory_v1_inventory_proto_rawDescData
 }
 
-var file_inventory_v1_inventory_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_inventory_v1_inventory_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_inventory_v1_inventory_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_inventory_v1_inventory_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_inventory_v1_inventory_proto_goTypes = []any{
-	(Category)(0),                 // 0: inventory.v1.Category
-	(*Dimensions)(nil),            // 1: inventory.v1.Dimensions
-	(*Manufacturer)(nil),          // 2: inventory.v1.Manufacturer
-	(*Value)(nil),                 // 3: inventory.v1.Value
-	(*Part)(nil),                  // 4: inventory.v1.Part
-	(*PartsFilter)(nil),           // 5: inventory.v1.PartsFilter
-	(*GetPartRequest)(nil),        // 6: inventory.v1.GetPartRequest
-	(*GetPartResponse)(nil),       // 7: inventory.v1.GetPartResponse
-	(*ListPartsRequest)(nil),      // 8: inventory.v1.ListPartsRequest
-	(*ListPartsResponse)(nil),     // 9: inventory.v1.ListPartsResponse
-	nil,                           // 10: inventory.v1.Part.MetadataEntry
-	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+	(Category)(0),                      // 0: inventory.v1.Category
+	(ReservationStatus)(0),             // 1: inventory.v1.ReservationStatus
+	(*Dimensions)(nil),                 // 2: inventory.v1.Dimensions
+	(*Manufacturer)(nil),               // 3: inventory.v1.Manufacturer
+	(*Value)(nil),                      // 4: inventory.v1.Value
+	(*Part)(nil),                       // 5: inventory.v1.Part
+	(*ReservationItem)(nil),            // 6: inventory.v1.ReservationItem
+	(*Reservation)(nil),                // 7: inventory.v1.Reservation
+	(*StockShortage)(nil),              // 8: inventory.v1.StockShortage
+	(*InsufficientStock)(nil),          // 9: inventory.v1.InsufficientStock
+	(*DoubleRange)(nil),                // 10: inventory.v1.DoubleRange
+	(*DimensionsRange)(nil),            // 11: inventory.v1.DimensionsRange
+	(*PartsFilter)(nil),                // 12: inventory.v1.PartsFilter
+	(*GetPartRequest)(nil),             // 13: inventory.v1.GetPartRequest
+	(*GetPartResponse)(nil),            // 14: inventory.v1.GetPartResponse
+	(*ListPartsRequest)(nil),           // 15: inventory.v1.ListPartsRequest
+	(*ListPartsResponse)(nil),          // 16: inventory.v1.ListPartsResponse
+	(*ReservePartsRequest)(nil),        // 17: inventory.v1.ReservePartsRequest
+	(*ReservePartsResponse)(nil),       // 18: inventory.v1.ReservePartsResponse
+	(*ReleaseReservationRequest)(nil),  // 19: inventory.v1.ReleaseReservationRequest
+	(*ReleaseReservationResponse)(nil), // 20: inventory.v1.ReleaseReservationResponse
+	(*CommitReservationRequest)(nil),   // 21: inventory.v1.CommitReservationRequest
+	(*CommitReservationResponse)(nil),  // 22: inventory.v1.CommitReservationResponse
+	(*CreatePartRequest)(nil),          // 23: inventory.v1.CreatePartRequest
+	(*CreatePartResponse)(nil),         // 24: inventory.v1.CreatePartResponse
+	(*UpdatePartRequest)(nil),          // 25: inventory.v1.UpdatePartRequest
+	(*UpdatePartResponse)(nil),         // 26: inventory.v1.UpdatePartResponse
+	(*DeletePartRequest)(nil),          // 27: inventory.v1.DeletePartRequest
+	(*DeletePartResponse)(nil),         // 28: inventory.v1.DeletePartResponse
+	nil,                                // 29: inventory.v1.Part.MetadataEntry
+	(*timestamppb.Timestamp)(nil),      // 30: google.protobuf.Timestamp
+	(*fieldmaskpb.FieldMask)(nil),      // 31: google.protobuf.FieldMask
 }
 var file_inventory_v1_inventory_proto_depIdxs = []int32{
 	0,  // 0: inventory.v1.Part.category:type_name -> inventory.v1.Category
-	1,  // 1: inventory.v1.Part.dimensions:type_name -> inventory.v1.Dimensions
-	2,  // 2: inventory.v1.Part.manufacturer:type_name -> inventory.v1.Manufacturer
-	10, // 3: inventory.v1.Part.metadata:type_name -> inventory.v1.Part.MetadataEntry
-	11, // 4: inventory.v1.Part.created_at:type_name -> google.protobuf.Timestamp
-	11, // 5: inventory.v1.Part.updated_at:type_name -> google.protobuf.Timestamp
-	0,  // 6: inventory.v1.PartsFilter.categories:type_name -> inventory.v1.Category
-	4,  // 7: inventory.v1.GetPartResponse.part:type_name -> inventory.v1.Part
-	5,  // 8: inventory.v1.ListPartsRequest.filter:type_name -> inventory.v1.PartsFilter
-	4,  // 9: inventory.v1.ListPartsResponse.parts:type_name -> inventory.v1.Part
-	3,  // 10: inventory.v1.Part.MetadataEntry.value:type_name -> inventory.v1.Value
-	6,  // 11: inventory.v1.InventoryService.GetPart:input_type -> inventory.v1.GetPartRequest
-	8,  // 12: inventory.v1.InventoryService.ListParts:input_type -> inventory.v1.ListPartsRequest
-	7,  // 13: inventory.v1.InventoryService.GetPart:output_type -> inventory.v1.GetPartResponse
-	9,  // 14: inventory.v1.InventoryService.ListParts:output_type -> inventory.v1.ListPartsResponse
-	13, // [13:15] is the sub-list for method output_type
-	11, // [11:13] is the sub-list for method input_type
-	11, // [11:11] is the sub-list for extension type_name
-	11, // [11:11] is the sub-list for extension extendee
-	0,  // [0:11] is the sub-list for field type_name
+	2,  // 1: inventory.v1.Part.dimensions:type_name -> inventory.v1.Dimensions
+	3,  // 2: inventory.v1.Part.manufacturer:type_name -> inventory.v1.Manufacturer
+	29, // 3: inventory.v1.Part.metadata:type_name -> inventory.v1.Part.MetadataEntry
+	30, // 4: inventory.v1.Part.created_at:type_name -> google.protobuf.Timestamp
+	30, // 5: inventory.v1.Part.updated_at:type_name -> google.protobuf.Timestamp
+	6,  // 6: inventory.v1.Reservation.items:type_name -> inventory.v1.ReservationItem
+	1,  // 7: inventory.v1.Reservation.status:type_name -> inventory.v1.ReservationStatus
+	30, // 8: inventory.v1.Reservation.created_at:type_name -> google.protobuf.Timestamp
+	30, // 9: inventory.v1.Reservation.updated_at:type_name -> google.protobuf.Timestamp
+	8,  // 10: inventory.v1.InsufficientStock.shortages:type_name -> inventory.v1.StockShortage
+	10, // 11: inventory.v1.DimensionsRange.length:type_name -> inventory.v1.DoubleRange
+	10, // 12: inventory.v1.DimensionsRange.width:type_name -> inventory.v1.DoubleRange
+	10, // 13: inventory.v1.DimensionsRange.height:type_name -> inventory.v1.DoubleRange
+	10, // 14: inventory.v1.DimensionsRange.weight:type_name -> inventory.v1.DoubleRange
+	0,  // 15: inventory.v1.PartsFilter.categories:type_name -> inventory.v1.Category
+	10, // 16: inventory.v1.PartsFilter.price:type_name -> inventory.v1.DoubleRange
+	11, // 17: inventory.v1.PartsFilter.dimensions:type_name -> inventory.v1.DimensionsRange
+	30, // 18: inventory.v1.PartsFilter.created_from:type_name -> google.protobuf.Timestamp
+	30, // 19: inventory.v1.PartsFilter.created_to:type_name -> google.protobuf.Timestamp
+	30, // 20: inventory.v1.PartsFilter.updated_from:type_name -> google.protobuf.Timestamp
+	30, // 21: inventory.v1.PartsFilter.updated_to:type_name -> google.protobuf.Timestamp
+	5,  // 22: inventory.v1.GetPartResponse.part:type_name -> inventory.v1.Part
+	12, // 23: inventory.v1.ListPartsRequest.filter:type_name -> inventory.v1.PartsFilter
+	5,  // 24: inventory.v1.ListPartsResponse.parts:type_name -> inventory.v1.Part
+	6,  // 25: inventory.v1.ReservePartsRequest.items:type_name -> inventory.v1.ReservationItem
+	7,  // 26: inventory.v1.ReservePartsResponse.reservation:type_name -> inventory.v1.Reservation
+	7,  // 27: inventory.v1.ReleaseReservationResponse.reservation:type_name -> inventory.v1.Reservation
+	7,  // 28: inventory.v1.CommitReservationResponse.reservation:type_name -> inventory.v1.Reservation
+	5,  // 29: inventory.v1.CreatePartRequest.part:type_name -> inventory.v1.Part
+	5,  // 30: inventory.v1.CreatePartResponse.part:type_name -> inventory.v1.Part
+	5,  // 31: inventory.v1.UpdatePartRequest.part:type_name -> inventory.v1.Part
+	31, // 32: inventory.v1.UpdatePartRequest.update_mask:type_name -> google.protobuf.FieldMask
+	5,  // 33: inventory.v1.UpdatePartResponse.part:type_name -> inventory.v1.Part
+	4,  // 34: inventory.v1.Part.MetadataEntry.value:type_name -> inventory.v1.Value
+	13, // 35: inventory.v1.InventoryService.GetPart:input_type -> inventory.v1.GetPartRequest
+	15, // 36: inventory.v1.InventoryService.ListParts:input_type -> inventory.v1.ListPartsRequest
+	17, // 37: inventory.v1.InventoryService.ReserveParts:input_type -> inventory.v1.ReservePartsRequest
+	19, // 38: inventory.v1.InventoryService.ReleaseReservation:input_type -> inventory.v1.ReleaseReservationRequest
+	21, // 39: inventory.v1.InventoryService.CommitReservation:input_type -> inventory.v1.CommitReservationRequest
+	23, // 40: inventory.v1.InventoryService.CreatePart:input_type -> inventory.v1.CreatePartRequest
+	25, // 41: inventory.v1.InventoryService.UpdatePart:input_type -> inventory.v1.UpdatePartRequest
+	27, // 42: inventory.v1.InventoryService.DeletePart:input_type -> inventory.v1.DeletePartRequest
+	14, // 43: inventory.v1.InventoryService.GetPart:output_type -> inventory.v1.GetPartResponse
+	16, // 44: inventory.v1.InventoryService.ListParts:output_type -> inventory.v1.ListPartsResponse
+	18, // 45: inventory.v1.InventoryService.ReserveParts:output_type -> inventory.v1.ReservePartsResponse
+	20, // 46: inventory.v1.InventoryService.ReleaseReservation:output_type -> inventory.v1.ReleaseReservationResponse
+	22, // 47: inventory.v1.InventoryService.CommitReservation:output_type -> inventory.v1.CommitReservationResponse
+	24, // 48: inventory.v1.InventoryService.CreatePart:output_type -> inventory.v1.CreatePartResponse
+	26, // 49: inventory.v1.InventoryService.UpdatePart:output_type -> inventory.v1.UpdatePartResponse
+	28, // 50: inventory.v1.InventoryService.DeletePart:output_type -> inventory.v1.DeletePartResponse
+	43, // [43:51] is the sub-list for method output_type
+	35, // [35:43] is the sub-list for method input_type
+	35, // [35:35] is the sub-list for extension type_name
+	35, // [35:35] is the sub-list for extension extendee
+	0,  // [0:35] is the sub-list for field type_name
 }
 
 func init() { file_inventory_v1_inventory_proto_init() }
@@ -845,13 +2079,15 @@ func file_inventory_v1_inventory_proto_init() {
 		(*Value_DoubleValue)(nil),
 		(*Value_BoolValue)(nil),
 	}
+	file_inventory_v1_inventory_proto_msgTypes[8].OneofWrappers = []any{}
+	file_inventory_v1_inventory_proto_msgTypes[10].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_inventory_v1_inventory_proto_rawDesc), len(file_inventory_v1_inventory_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   10,
+			NumEnums:      2,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -9,6 +9,7 @@ package paymentv1
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -77,31 +78,1906 @@ func (PaymentMethod) EnumDescriptor() ([]byte, []int) {
 	return file_payment_v1_payment_proto_rawDescGZIP(), []int{0}
 }
 
+// TransactionStatus представляет статус транзакции
+type TransactionStatus int32
+
+const (
+	TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED TransactionStatus = 0
+	// Оплата проведена
+	TransactionStatus_TRANSACTION_STATUS_SUCCEEDED TransactionStatus = 1
+	// Часть суммы возвращена
+	TransactionStatus_TRANSACTION_STATUS_PARTIALLY_REFUNDED TransactionStatus = 2
+	// Вся сумма возвращена
+	TransactionStatus_TRANSACTION_STATUS_REFUNDED TransactionStatus = 3
+	// Сумма заблокирована и ожидает списания (CapturePayment)
+	TransactionStatus_TRANSACTION_STATUS_AUTHORIZED TransactionStatus = 4
+	// Авторизация снята без списания: вручную или по истечении срока
+	TransactionStatus_TRANSACTION_STATUS_VOIDED TransactionStatus = 5
+	// Провайдер принял операцию и завершит ее асинхронно (СБП, 3-D Secure).
+	// После callback провайдера транзакция переходит в AUTHORIZED/SUCCEEDED или FAILED
+	TransactionStatus_TRANSACTION_STATUS_PENDING TransactionStatus = 6
+	// Провайдер отклонил асинхронную операцию либо оплату запретили правила антифрода
+	TransactionStatus_TRANSACTION_STATUS_FAILED TransactionStatus = 7
+)
+
+// Enum value maps for TransactionStatus.
+var (
+	TransactionStatus_name = map[int32]string{
+		0: "TRANSACTION_STATUS_UNSPECIFIED",
+		1: "TRANSACTION_STATUS_SUCCEEDED",
+		2: "TRANSACTION_STATUS_PARTIALLY_REFUNDED",
+		3: "TRANSACTION_STATUS_REFUNDED",
+		4: "TRANSACTION_STATUS_AUTHORIZED",
+		5: "TRANSACTION_STATUS_VOIDED",
+		6: "TRANSACTION_STATUS_PENDING",
+		7: "TRANSACTION_STATUS_FAILED",
+	}
+	TransactionStatus_value = map[string]int32{
+		"TRANSACTION_STATUS_UNSPECIFIED":        0,
+		"TRANSACTION_STATUS_SUCCEEDED":          1,
+		"TRANSACTION_STATUS_PARTIALLY_REFUNDED": 2,
+		"TRANSACTION_STATUS_REFUNDED":           3,
+		"TRANSACTION_STATUS_AUTHORIZED":         4,
+		"TRANSACTION_STATUS_VOIDED":             5,
+		"TRANSACTION_STATUS_PENDING":            6,
+		"TRANSACTION_STATUS_FAILED":             7,
+	}
+)
+
+func (x TransactionStatus) Enum() *TransactionStatus {
+	p := new(TransactionStatus)
+	*p = x
+	return p
+}
+
+func (x TransactionStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TransactionStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_payment_v1_payment_proto_enumTypes[1].Descriptor()
+}
+
+func (TransactionStatus) Type() protoreflect.EnumType {
+	return &file_payment_v1_payment_proto_enumTypes[1]
+}
+
+func (x TransactionStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TransactionStatus.Descriptor instead.
+func (TransactionStatus) EnumDescriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{1}
+}
+
+// RiskDecision решение правил антифрода и лимитов по оплате
+type RiskDecision int32
+
+const (
+	RiskDecision_RISK_DECISION_UNSPECIFIED RiskDecision = 0
+	// Ни одно правило не сработало
+	RiskDecision_RISK_DECISION_ALLOW RiskDecision = 1
+	// Оплата проведена, но требует ручной проверки
+	RiskDecision_RISK_DECISION_REVIEW RiskDecision = 2
+	// Оплата запрещена, к провайдеру не отправлялась
+	RiskDecision_RISK_DECISION_DENY RiskDecision = 3
+)
+
+// Enum value maps for RiskDecision.
+var (
+	RiskDecision_name = map[int32]string{
+		0: "RISK_DECISION_UNSPECIFIED",
+		1: "RISK_DECISION_ALLOW",
+		2: "RISK_DECISION_REVIEW",
+		3: "RISK_DECISION_DENY",
+	}
+	RiskDecision_value = map[string]int32{
+		"RISK_DECISION_UNSPECIFIED": 0,
+		"RISK_DECISION_ALLOW":       1,
+		"RISK_DECISION_REVIEW":      2,
+		"RISK_DECISION_DENY":        3,
+	}
+)
+
+func (x RiskDecision) Enum() *RiskDecision {
+	p := new(RiskDecision)
+	*p = x
+	return p
+}
+
+func (x RiskDecision) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RiskDecision) Descriptor() protoreflect.EnumDescriptor {
+	return file_payment_v1_payment_proto_enumTypes[2].Descriptor()
+}
+
+func (RiskDecision) Type() protoreflect.EnumType {
+	return &file_payment_v1_payment_proto_enumTypes[2]
+}
+
+func (x RiskDecision) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RiskDecision.Descriptor instead.
+func (RiskDecision) EnumDescriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{2}
+}
+
+// OperationType операция у провайдера над существующей транзакцией
+type OperationType int32
+
+const (
+	OperationType_OPERATION_TYPE_UNSPECIFIED OperationType = 0
+	OperationType_OPERATION_TYPE_CAPTURE     OperationType = 1
+	OperationType_OPERATION_TYPE_VOID        OperationType = 2
+	OperationType_OPERATION_TYPE_REFUND      OperationType = 3
+)
+
+// Enum value maps for OperationType.
+var (
+	OperationType_name = map[int32]string{
+		0: "OPERATION_TYPE_UNSPECIFIED",
+		1: "OPERATION_TYPE_CAPTURE",
+		2: "OPERATION_TYPE_VOID",
+		3: "OPERATION_TYPE_REFUND",
+	}
+	OperationType_value = map[string]int32{
+		"OPERATION_TYPE_UNSPECIFIED": 0,
+		"OPERATION_TYPE_CAPTURE":     1,
+		"OPERATION_TYPE_VOID":        2,
+		"OPERATION_TYPE_REFUND":      3,
+	}
+)
+
+func (x OperationType) Enum() *OperationType {
+	p := new(OperationType)
+	*p = x
+	return p
+}
+
+func (x OperationType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OperationType) Descriptor() protoreflect.EnumDescriptor {
+	return file_payment_v1_payment_proto_enumTypes[3].Descriptor()
+}
+
+func (OperationType) Type() protoreflect.EnumType {
+	return &file_payment_v1_payment_proto_enumTypes[3]
+}
+
+func (x OperationType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OperationType.Descriptor instead.
+func (OperationType) EnumDescriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{3}
+}
+
+// LedgerEntryType вид движения денег, записанного проводкой
+type LedgerEntryType int32
+
+const (
+	LedgerEntryType_LEDGER_ENTRY_TYPE_UNSPECIFIED LedgerEntryType = 0
+	// Списание оплаты: при PayOrder или CapturePayment
+	LedgerEntryType_LEDGER_ENTRY_TYPE_PAYMENT LedgerEntryType = 1
+	// Возврат всей суммы или ее части
+	LedgerEntryType_LEDGER_ENTRY_TYPE_REFUND LedgerEntryType = 2
+)
+
+// Enum value maps for LedgerEntryType.
+var (
+	LedgerEntryType_name = map[int32]string{
+		0: "LEDGER_ENTRY_TYPE_UNSPECIFIED",
+		1: "LEDGER_ENTRY_TYPE_PAYMENT",
+		2: "LEDGER_ENTRY_TYPE_REFUND",
+	}
+	LedgerEntryType_value = map[string]int32{
+		"LEDGER_ENTRY_TYPE_UNSPECIFIED": 0,
+		"LEDGER_ENTRY_TYPE_PAYMENT":     1,
+		"LEDGER_ENTRY_TYPE_REFUND":      2,
+	}
+)
+
+func (x LedgerEntryType) Enum() *LedgerEntryType {
+	p := new(LedgerEntryType)
+	*p = x
+	return p
+}
+
+func (x LedgerEntryType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LedgerEntryType) Descriptor() protoreflect.EnumDescriptor {
+	return file_payment_v1_payment_proto_enumTypes[4].Descriptor()
+}
+
+func (LedgerEntryType) Type() protoreflect.EnumType {
+	return &file_payment_v1_payment_proto_enumTypes[4]
+}
+
+func (x LedgerEntryType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LedgerEntryType.Descriptor instead.
+func (LedgerEntryType) EnumDescriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{4}
+}
+
+// RiskAssessment результат проверки оплаты правилами
+type RiskAssessment struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Decision RiskDecision           `protobuf:"varint,1,opt,name=decision,proto3,enum=payment.v1.RiskDecision" json:"decision,omitempty"`
+	// rule_ids идентификаторы сработавших правил из файла конфигурации
+	RuleIds       []string `protobuf:"bytes,2,rep,name=rule_ids,json=ruleIds,proto3" json:"rule_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RiskAssessment) Reset() {
+	*x = RiskAssessment{}
+	mi := &file_payment_v1_payment_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RiskAssessment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RiskAssessment) ProtoMessage() {}
+
+func (x *RiskAssessment) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RiskAssessment.ProtoReflect.Descriptor instead.
+func (*RiskAssessment) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RiskAssessment) GetDecision() RiskDecision {
+	if x != nil {
+		return x.Decision
+	}
+	return RiskDecision_RISK_DECISION_UNSPECIFIED
+}
+
+func (x *RiskAssessment) GetRuleIds() []string {
+	if x != nil {
+		return x.RuleIds
+	}
+	return nil
+}
+
+// Money представляет денежную сумму без указания валюты (по образцу google.type.Money).
+// Сумма равна units + nanos / 10^9; units и nanos должны иметь одинаковый знак.
+type Money struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// units целая часть суммы
+	Units int64 `protobuf:"varint,1,opt,name=units,proto3" json:"units,omitempty"`
+	// nanos дробная часть в миллиардных долях, от -999 999 999 до 999 999 999
+	Nanos         int32 `protobuf:"varint,2,opt,name=nanos,proto3" json:"nanos,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Money) Reset() {
+	*x = Money{}
+	mi := &file_payment_v1_payment_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Money) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Money) ProtoMessage() {}
+
+func (x *Money) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Money.ProtoReflect.Descriptor instead.
+func (*Money) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Money) GetUnits() int64 {
+	if x != nil {
+		return x.Units
+	}
+	return 0
+}
+
+func (x *Money) GetNanos() int32 {
+	if x != nil {
+		return x.Nanos
+	}
+	return 0
+}
+
+// Transaction представляет проведенную оплату
+type Transaction struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TransactionUuid string                 `protobuf:"bytes,1,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
+	OrderUuid       string                 `protobuf:"bytes,2,opt,name=order_uuid,json=orderUuid,proto3" json:"order_uuid,omitempty"`
+	UserUuid        string                 `protobuf:"bytes,3,opt,name=user_uuid,json=userUuid,proto3" json:"user_uuid,omitempty"`
+	PaymentMethod   PaymentMethod          `protobuf:"varint,4,opt,name=payment_method,json=paymentMethod,proto3,enum=payment.v1.PaymentMethod" json:"payment_method,omitempty"`
+	Amount          *Money                 `protobuf:"bytes,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	// currency код валюты ISO 4217
+	Currency string `protobuf:"bytes,6,opt,name=currency,proto3" json:"currency,omitempty"`
+	// refunded_amount сумма всех возвратов по транзакции
+	RefundedAmount *Money                 `protobuf:"bytes,7,opt,name=refunded_amount,json=refundedAmount,proto3" json:"refunded_amount,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Status         TransactionStatus      `protobuf:"varint,9,opt,name=status,proto3,enum=payment.v1.TransactionStatus" json:"status,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// expires_at момент, после которого несписанная авторизация аннулируется; задан только для авторизаций
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// provider имя платежного провайдера, через который проведена транзакция
+	Provider string `protobuf:"bytes,12,opt,name=provider,proto3" json:"provider,omitempty"`
+	// provider_reference идентификатор операции на стороне провайдера
+	ProviderReference string `protobuf:"bytes,13,opt,name=provider_reference,json=providerReference,proto3" json:"provider_reference,omitempty"`
+	// decline_code код отказа провайдера либо risk_denied для транзакции в статусе FAILED
+	DeclineCode string `protobuf:"bytes,14,opt,name=decline_code,json=declineCode,proto3" json:"decline_code,omitempty"`
+	// risk решение правил антифрода, принятое при создании транзакции
+	Risk *RiskAssessment `protobuf:"bytes,15,opt,name=risk,proto3" json:"risk,omitempty"`
+	// refunds выполненные возвраты по транзакции в порядке выполнения
+	Refunds []*Refund `protobuf:"bytes,16,rep,name=refunds,proto3" json:"refunds,omitempty"`
+	// pending_operation операция у провайдера, которая выполняется сейчас. Пока она задана,
+	// другие списания, аннулирования и возвраты по транзакции отклоняются с ABORTED.
+	// Отметку, оставшуюся после сбоя дольше PAYMENT_PENDING_OPERATION_LEASE, снимает фоновая задача,
+	// повторив операцию у провайдера.
+	PendingOperation *PendingOperation `protobuf:"bytes,17,opt,name=pending_operation,json=pendingOperation,proto3" json:"pending_operation,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_payment_v1_payment_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Transaction) GetTransactionUuid() string {
+	if x != nil {
+		return x.TransactionUuid
+	}
+	return ""
+}
+
+func (x *Transaction) GetOrderUuid() string {
+	if x != nil {
+		return x.OrderUuid
+	}
+	return ""
+}
+
+func (x *Transaction) GetUserUuid() string {
+	if x != nil {
+		return x.UserUuid
+	}
+	return ""
+}
+
+func (x *Transaction) GetPaymentMethod() PaymentMethod {
+	if x != nil {
+		return x.PaymentMethod
+	}
+	return PaymentMethod_PAYMENT_METHOD_UNSPECIFIED
+}
+
+func (x *Transaction) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *Transaction) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Transaction) GetRefundedAmount() *Money {
+	if x != nil {
+		return x.RefundedAmount
+	}
+	return nil
+}
+
+func (x *Transaction) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Transaction) GetStatus() TransactionStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED
+}
+
+func (x *Transaction) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Transaction) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Transaction) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *Transaction) GetProviderReference() string {
+	if x != nil {
+		return x.ProviderReference
+	}
+	return ""
+}
+
+func (x *Transaction) GetDeclineCode() string {
+	if x != nil {
+		return x.DeclineCode
+	}
+	return ""
+}
+
+func (x *Transaction) GetRisk() *RiskAssessment {
+	if x != nil {
+		return x.Risk
+	}
+	return nil
+}
+
+func (x *Transaction) GetRefunds() []*Refund {
+	if x != nil {
+		return x.Refunds
+	}
+	return nil
+}
+
+func (x *Transaction) GetPendingOperation() *PendingOperation {
+	if x != nil {
+		return x.PendingOperation
+	}
+	return nil
+}
+
+// PendingOperation начатая, но еще не сохраненная операция у провайдера
+type PendingOperation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OperationUuid string                 `protobuf:"bytes,1,opt,name=operation_uuid,json=operationUuid,proto3" json:"operation_uuid,omitempty"`
+	Type          OperationType          `protobuf:"varint,2,opt,name=type,proto3,enum=payment.v1.OperationType" json:"type,omitempty"`
+	// amount сумма возврата; для списания и аннулирования не задана
+	Amount *Money `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	// idempotency_key ключ идемпотентности возврата
+	IdempotencyKey string                 `protobuf:"bytes,4,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	StartedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	// reason причина возврата
+	Reason        string `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PendingOperation) Reset() {
+	*x = PendingOperation{}
+	mi := &file_payment_v1_payment_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PendingOperation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PendingOperation) ProtoMessage() {}
+
+func (x *PendingOperation) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PendingOperation.ProtoReflect.Descriptor instead.
+func (*PendingOperation) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PendingOperation) GetOperationUuid() string {
+	if x != nil {
+		return x.OperationUuid
+	}
+	return ""
+}
+
+func (x *PendingOperation) GetType() OperationType {
+	if x != nil {
+		return x.Type
+	}
+	return OperationType_OPERATION_TYPE_UNSPECIFIED
+}
+
+func (x *PendingOperation) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *PendingOperation) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *PendingOperation) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *PendingOperation) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Refund возврат части или всей суммы транзакции
+type Refund struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	RefundUuid string                 `protobuf:"bytes,1,opt,name=refund_uuid,json=refundUuid,proto3" json:"refund_uuid,omitempty"`
+	Amount     *Money                 `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Reason     string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	// idempotency_key ключ из RefundPaymentRequest; пустой, если запрос был без ключа
+	IdempotencyKey string                 `protobuf:"bytes,4,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Refund) Reset() {
+	*x = Refund{}
+	mi := &file_payment_v1_payment_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Refund) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Refund) ProtoMessage() {}
+
+func (x *Refund) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Refund.ProtoReflect.Descriptor instead.
+func (*Refund) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Refund) GetRefundUuid() string {
+	if x != nil {
+		return x.RefundUuid
+	}
+	return ""
+}
+
+func (x *Refund) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *Refund) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Refund) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *Refund) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
 // PayOrderRequest запрос на оплату заказа
 type PayOrderRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	OrderUuid     string                 `protobuf:"bytes,1,opt,name=order_uuid,json=orderUuid,proto3" json:"order_uuid,omitempty"`
-	UserUuid      string                 `protobuf:"bytes,2,opt,name=user_uuid,json=userUuid,proto3" json:"user_uuid,omitempty"`
-	PaymentMethod PaymentMethod          `protobuf:"varint,3,opt,name=payment_method,json=paymentMethod,proto3,enum=payment.v1.PaymentMethod" json:"payment_method,omitempty"`
+	OrderUuid     string                 `protobuf:"bytes,1,opt,name=order_uuid,json=orderUuid,proto3" json:"order_uuid,omitempty"`
+	UserUuid      string                 `protobuf:"bytes,2,opt,name=user_uuid,json=userUuid,proto3" json:"user_uuid,omitempty"`
+	PaymentMethod PaymentMethod          `protobuf:"varint,3,opt,name=payment_method,json=paymentMethod,proto3,enum=payment.v1.PaymentMethod" json:"payment_method,omitempty"`
+	// amount сумма к оплате, должна быть больше нуля
+	Amount *Money `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	// currency код валюты ISO 4217, например RUB
+	Currency      string `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PayOrderRequest) Reset() {
+	*x = PayOrderRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PayOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PayOrderRequest) ProtoMessage() {}
+
+func (x *PayOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PayOrderRequest.ProtoReflect.Descriptor instead.
+func (*PayOrderRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PayOrderRequest) GetOrderUuid() string {
+	if x != nil {
+		return x.OrderUuid
+	}
+	return ""
+}
+
+func (x *PayOrderRequest) GetUserUuid() string {
+	if x != nil {
+		return x.UserUuid
+	}
+	return ""
+}
+
+func (x *PayOrderRequest) GetPaymentMethod() PaymentMethod {
+	if x != nil {
+		return x.PaymentMethod
+	}
+	return PaymentMethod_PAYMENT_METHOD_UNSPECIFIED
+}
+
+func (x *PayOrderRequest) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *PayOrderRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+// PayOrderResponse ответ на запрос оплаты
+type PayOrderResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TransactionUuid string                 `protobuf:"bytes,1,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
+	Transaction     *Transaction           `protobuf:"bytes,2,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PayOrderResponse) Reset() {
+	*x = PayOrderResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PayOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PayOrderResponse) ProtoMessage() {}
+
+func (x *PayOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PayOrderResponse.ProtoReflect.Descriptor instead.
+func (*PayOrderResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PayOrderResponse) GetTransactionUuid() string {
+	if x != nil {
+		return x.TransactionUuid
+	}
+	return ""
+}
+
+func (x *PayOrderResponse) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+// AuthorizePaymentRequest запрос на блокировку суммы заказа
+type AuthorizePaymentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderUuid     string                 `protobuf:"bytes,1,opt,name=order_uuid,json=orderUuid,proto3" json:"order_uuid,omitempty"`
+	UserUuid      string                 `protobuf:"bytes,2,opt,name=user_uuid,json=userUuid,proto3" json:"user_uuid,omitempty"`
+	PaymentMethod PaymentMethod          `protobuf:"varint,3,opt,name=payment_method,json=paymentMethod,proto3,enum=payment.v1.PaymentMethod" json:"payment_method,omitempty"`
+	// amount сумма к блокировке, должна быть больше нуля
+	Amount *Money `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	// currency код валюты ISO 4217, например RUB
+	Currency      string `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorizePaymentRequest) Reset() {
+	*x = AuthorizePaymentRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorizePaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorizePaymentRequest) ProtoMessage() {}
+
+func (x *AuthorizePaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorizePaymentRequest.ProtoReflect.Descriptor instead.
+func (*AuthorizePaymentRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AuthorizePaymentRequest) GetOrderUuid() string {
+	if x != nil {
+		return x.OrderUuid
+	}
+	return ""
+}
+
+func (x *AuthorizePaymentRequest) GetUserUuid() string {
+	if x != nil {
+		return x.UserUuid
+	}
+	return ""
+}
+
+func (x *AuthorizePaymentRequest) GetPaymentMethod() PaymentMethod {
+	if x != nil {
+		return x.PaymentMethod
+	}
+	return PaymentMethod_PAYMENT_METHOD_UNSPECIFIED
+}
+
+func (x *AuthorizePaymentRequest) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *AuthorizePaymentRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+// AuthorizePaymentResponse ответ на запрос авторизации
+type AuthorizePaymentResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TransactionUuid string                 `protobuf:"bytes,1,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
+	Transaction     *Transaction           `protobuf:"bytes,2,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AuthorizePaymentResponse) Reset() {
+	*x = AuthorizePaymentResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorizePaymentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorizePaymentResponse) ProtoMessage() {}
+
+func (x *AuthorizePaymentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorizePaymentResponse.ProtoReflect.Descriptor instead.
+func (*AuthorizePaymentResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AuthorizePaymentResponse) GetTransactionUuid() string {
+	if x != nil {
+		return x.TransactionUuid
+	}
+	return ""
+}
+
+func (x *AuthorizePaymentResponse) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+// CapturePaymentRequest запрос на списание авторизованной суммы.
+// Повторное списание уже списанной транзакции возвращает ее без изменений.
+type CapturePaymentRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TransactionUuid string                 `protobuf:"bytes,1,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CapturePaymentRequest) Reset() {
+	*x = CapturePaymentRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapturePaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapturePaymentRequest) ProtoMessage() {}
+
+func (x *CapturePaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapturePaymentRequest.ProtoReflect.Descriptor instead.
+func (*CapturePaymentRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CapturePaymentRequest) GetTransactionUuid() string {
+	if x != nil {
+		return x.TransactionUuid
+	}
+	return ""
+}
+
+// CapturePaymentResponse ответ на запрос списания
+type CapturePaymentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transaction   *Transaction           `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapturePaymentResponse) Reset() {
+	*x = CapturePaymentResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapturePaymentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapturePaymentResponse) ProtoMessage() {}
+
+func (x *CapturePaymentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapturePaymentResponse.ProtoReflect.Descriptor instead.
+func (*CapturePaymentResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CapturePaymentResponse) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+// VoidAuthorizationRequest запрос на снятие блокировки.
+// Повторное снятие уже аннулированной авторизации возвращает ее без изменений.
+type VoidAuthorizationRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TransactionUuid string                 `protobuf:"bytes,1,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
+	Reason          string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *VoidAuthorizationRequest) Reset() {
+	*x = VoidAuthorizationRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoidAuthorizationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoidAuthorizationRequest) ProtoMessage() {}
+
+func (x *VoidAuthorizationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoidAuthorizationRequest.ProtoReflect.Descriptor instead.
+func (*VoidAuthorizationRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *VoidAuthorizationRequest) GetTransactionUuid() string {
+	if x != nil {
+		return x.TransactionUuid
+	}
+	return ""
+}
+
+func (x *VoidAuthorizationRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// VoidAuthorizationResponse ответ на запрос снятия блокировки
+type VoidAuthorizationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transaction   *Transaction           `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoidAuthorizationResponse) Reset() {
+	*x = VoidAuthorizationResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoidAuthorizationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoidAuthorizationResponse) ProtoMessage() {}
+
+func (x *VoidAuthorizationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoidAuthorizationResponse.ProtoReflect.Descriptor instead.
+func (*VoidAuthorizationResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *VoidAuthorizationResponse) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+// RefundPaymentRequest запрос на возврат средств по транзакции.
+// Несколько частичных возвратов по одной транзакции суммируются.
+type RefundPaymentRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TransactionUuid string                 `protobuf:"bytes,1,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
+	Reason          string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	// amount сумма возврата: больше нуля и не больше невозвращенного остатка транзакции
+	Amount *Money `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	// idempotency_key ключ идемпотентности возврата. Повтор запроса с тем же ключом по той же
+	// транзакции возвращает уже выполненный возврат и не обращается к провайдеру повторно
+	IdempotencyKey string `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RefundPaymentRequest) Reset() {
+	*x = RefundPaymentRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefundPaymentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefundPaymentRequest) ProtoMessage() {}
+
+func (x *RefundPaymentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefundPaymentRequest.ProtoReflect.Descriptor instead.
+func (*RefundPaymentRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RefundPaymentRequest) GetTransactionUuid() string {
+	if x != nil {
+		return x.TransactionUuid
+	}
+	return ""
+}
+
+func (x *RefundPaymentRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *RefundPaymentRequest) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *RefundPaymentRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+// RefundPaymentResponse ответ на запрос возврата
+type RefundPaymentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RefundUuid    string                 `protobuf:"bytes,1,opt,name=refund_uuid,json=refundUuid,proto3" json:"refund_uuid,omitempty"`
+	Transaction   *Transaction           `protobuf:"bytes,3,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RefundPaymentResponse) Reset() {
+	*x = RefundPaymentResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefundPaymentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefundPaymentResponse) ProtoMessage() {}
+
+func (x *RefundPaymentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefundPaymentResponse.ProtoReflect.Descriptor instead.
+func (*RefundPaymentResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RefundPaymentResponse) GetRefundUuid() string {
+	if x != nil {
+		return x.RefundUuid
+	}
+	return ""
+}
+
+func (x *RefundPaymentResponse) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+// GetTransactionRequest запрос транзакции по UUID
+type GetTransactionRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TransactionUuid string                 `protobuf:"bytes,1,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetTransactionRequest) Reset() {
+	*x = GetTransactionRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTransactionRequest) ProtoMessage() {}
+
+func (x *GetTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTransactionRequest.ProtoReflect.Descriptor instead.
+func (*GetTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetTransactionRequest) GetTransactionUuid() string {
+	if x != nil {
+		return x.TransactionUuid
+	}
+	return ""
+}
+
+// GetTransactionResponse ответ с транзакцией
+type GetTransactionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transaction   *Transaction           `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTransactionResponse) Reset() {
+	*x = GetTransactionResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTransactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTransactionResponse) ProtoMessage() {}
+
+func (x *GetTransactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTransactionResponse.ProtoReflect.Descriptor instead.
+func (*GetTransactionResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetTransactionResponse) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+// TransactionsFilter фильтр транзакций; пустые поля не ограничивают выборку
+type TransactionsFilter struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	UserUuid  string                 `protobuf:"bytes,1,opt,name=user_uuid,json=userUuid,proto3" json:"user_uuid,omitempty"`
+	OrderUuid string                 `protobuf:"bytes,2,opt,name=order_uuid,json=orderUuid,proto3" json:"order_uuid,omitempty"`
+	// created_from начало интервала создания, включительно
+	CreatedFrom *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"`
+	// created_to конец интервала создания, не включительно
+	CreatedTo *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`
+	// risk_decision только транзакции с этим решением правил, например REVIEW для ручной проверки
+	RiskDecision  RiskDecision `protobuf:"varint,5,opt,name=risk_decision,json=riskDecision,proto3,enum=payment.v1.RiskDecision" json:"risk_decision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionsFilter) Reset() {
+	*x = TransactionsFilter{}
+	mi := &file_payment_v1_payment_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionsFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionsFilter) ProtoMessage() {}
+
+func (x *TransactionsFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionsFilter.ProtoReflect.Descriptor instead.
+func (*TransactionsFilter) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *TransactionsFilter) GetUserUuid() string {
+	if x != nil {
+		return x.UserUuid
+	}
+	return ""
+}
+
+func (x *TransactionsFilter) GetOrderUuid() string {
+	if x != nil {
+		return x.OrderUuid
+	}
+	return ""
+}
+
+func (x *TransactionsFilter) GetCreatedFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedFrom
+	}
+	return nil
+}
+
+func (x *TransactionsFilter) GetCreatedTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return nil
+}
+
+func (x *TransactionsFilter) GetRiskDecision() RiskDecision {
+	if x != nil {
+		return x.RiskDecision
+	}
+	return RiskDecision_RISK_DECISION_UNSPECIFIED
+}
+
+// ListTransactionsRequest запрос списка транзакций
+type ListTransactionsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *TransactionsFilter    `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// page_size размер страницы, от 1 до 100; 0 означает 20
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// page_token значение next_page_token из предыдущего ответа
+	PageToken     string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsRequest) Reset() {
+	*x = ListTransactionsRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsRequest) ProtoMessage() {}
+
+func (x *ListTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*ListTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListTransactionsRequest) GetFilter() *TransactionsFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListTransactionsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListTransactionsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+// ListTransactionsResponse страница транзакций
+type ListTransactionsResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Transactions []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	// next_page_token пуст на последней странице
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsResponse) Reset() {
+	*x = ListTransactionsResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsResponse) ProtoMessage() {}
+
+func (x *ListTransactionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsResponse.ProtoReflect.Descriptor instead.
+func (*ListTransactionsResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListTransactionsResponse) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+func (x *ListTransactionsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// Posting одна сторона проводки
+type Posting struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// account счет: customer:<user_uuid>, merchant, refunds или fees
+	Account string `protobuf:"bytes,1,opt,name=account,proto3" json:"account,omitempty"`
+	// amount положительная сумма — дебет счета, отрицательная — кредит
+	Amount        *Money `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Posting) Reset() {
+	*x = Posting{}
+	mi := &file_payment_v1_payment_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Posting) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Posting) ProtoMessage() {}
+
+func (x *Posting) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Posting.ProtoReflect.Descriptor instead.
+func (*Posting) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Posting) GetAccount() string {
+	if x != nil {
+		return x.Account
+	}
+	return ""
+}
+
+func (x *Posting) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+// LedgerEntry проводка главной книги. Сумма postings всегда равна нулю.
+type LedgerEntry struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	EntryUuid string                 `protobuf:"bytes,1,opt,name=entry_uuid,json=entryUuid,proto3" json:"entry_uuid,omitempty"`
+	// transaction_uuid транзакция, движение денег по которой записано
+	TransactionUuid string          `protobuf:"bytes,2,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
+	Type            LedgerEntryType `protobuf:"varint,3,opt,name=type,proto3,enum=payment.v1.LedgerEntryType" json:"type,omitempty"`
+	// currency код валюты ISO 4217
+	Currency      string                 `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	Postings      []*Posting             `protobuf:"bytes,5,rep,name=postings,proto3" json:"postings,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LedgerEntry) Reset() {
+	*x = LedgerEntry{}
+	mi := &file_payment_v1_payment_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LedgerEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LedgerEntry) ProtoMessage() {}
+
+func (x *LedgerEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LedgerEntry.ProtoReflect.Descriptor instead.
+func (*LedgerEntry) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *LedgerEntry) GetEntryUuid() string {
+	if x != nil {
+		return x.EntryUuid
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetTransactionUuid() string {
+	if x != nil {
+		return x.TransactionUuid
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetType() LedgerEntryType {
+	if x != nil {
+		return x.Type
+	}
+	return LedgerEntryType_LEDGER_ENTRY_TYPE_UNSPECIFIED
+}
+
+func (x *LedgerEntry) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *LedgerEntry) GetPostings() []*Posting {
+	if x != nil {
+		return x.Postings
+	}
+	return nil
+}
+
+func (x *LedgerEntry) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+// GetAccountBalanceRequest запрос баланса счета
+type GetAccountBalanceRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Account string                 `protobuf:"bytes,1,opt,name=account,proto3" json:"account,omitempty"`
+	// currency код валюты ISO 4217
+	Currency      string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAccountBalanceRequest) Reset() {
+	*x = GetAccountBalanceRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAccountBalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAccountBalanceRequest) ProtoMessage() {}
+
+func (x *GetAccountBalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAccountBalanceRequest.ProtoReflect.Descriptor instead.
+func (*GetAccountBalanceRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetAccountBalanceRequest) GetAccount() string {
+	if x != nil {
+		return x.Account
+	}
+	return ""
+}
+
+func (x *GetAccountBalanceRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+// GetAccountBalanceResponse баланс счета: сумма дебета минус сумма кредита
+type GetAccountBalanceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Account       string                 `protobuf:"bytes,1,opt,name=account,proto3" json:"account,omitempty"`
+	Currency      string                 `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	Balance       *Money                 `protobuf:"bytes,3,opt,name=balance,proto3" json:"balance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAccountBalanceResponse) Reset() {
+	*x = GetAccountBalanceResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAccountBalanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAccountBalanceResponse) ProtoMessage() {}
+
+func (x *GetAccountBalanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAccountBalanceResponse.ProtoReflect.Descriptor instead.
+func (*GetAccountBalanceResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetAccountBalanceResponse) GetAccount() string {
+	if x != nil {
+		return x.Account
+	}
+	return ""
+}
+
+func (x *GetAccountBalanceResponse) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *GetAccountBalanceResponse) GetBalance() *Money {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+// LedgerEntriesFilter фильтр проводок; пустые поля не ограничивают выборку
+type LedgerEntriesFilter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// account проводки, затрагивающие счет
+	Account         string `protobuf:"bytes,1,opt,name=account,proto3" json:"account,omitempty"`
+	TransactionUuid string `protobuf:"bytes,2,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *LedgerEntriesFilter) Reset() {
+	*x = LedgerEntriesFilter{}
+	mi := &file_payment_v1_payment_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LedgerEntriesFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LedgerEntriesFilter) ProtoMessage() {}
+
+func (x *LedgerEntriesFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LedgerEntriesFilter.ProtoReflect.Descriptor instead.
+func (*LedgerEntriesFilter) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *LedgerEntriesFilter) GetAccount() string {
+	if x != nil {
+		return x.Account
+	}
+	return ""
+}
+
+func (x *LedgerEntriesFilter) GetTransactionUuid() string {
+	if x != nil {
+		return x.TransactionUuid
+	}
+	return ""
+}
+
+// ListLedgerEntriesRequest запрос списка проводок
+type ListLedgerEntriesRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *LedgerEntriesFilter   `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// page_size размер страницы, от 1 до 100; 0 означает 20
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// page_token значение next_page_token из предыдущего ответа
+	PageToken     string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PayOrderRequest) Reset() {
-	*x = PayOrderRequest{}
-	mi := &file_payment_v1_payment_proto_msgTypes[0]
+func (x *ListLedgerEntriesRequest) Reset() {
+	*x = ListLedgerEntriesRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PayOrderRequest) String() string {
+func (x *ListLedgerEntriesRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PayOrderRequest) ProtoMessage() {}
+func (*ListLedgerEntriesRequest) ProtoMessage() {}
 
-func (x *PayOrderRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_payment_v1_payment_proto_msgTypes[0]
+func (x *ListLedgerEntriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -112,55 +1988,202 @@ func (x *PayOrderRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PayOrderRequest.ProtoReflect.Descriptor instead.
-func (*PayOrderRequest) Descriptor() ([]byte, []int) {
-	return file_payment_v1_payment_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use ListLedgerEntriesRequest.ProtoReflect.Descriptor instead.
+func (*ListLedgerEntriesRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{25}
 }
 
-func (x *PayOrderRequest) GetOrderUuid() string {
+func (x *ListLedgerEntriesRequest) GetFilter() *LedgerEntriesFilter {
 	if x != nil {
-		return x.OrderUuid
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListLedgerEntriesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListLedgerEntriesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
 	}
 	return ""
 }
 
-func (x *PayOrderRequest) GetUserUuid() string {
+// ListLedgerEntriesResponse страница проводок
+type ListLedgerEntriesResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Entries []*LedgerEntry         `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// next_page_token пуст на последней странице
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLedgerEntriesResponse) Reset() {
+	*x = ListLedgerEntriesResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLedgerEntriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLedgerEntriesResponse) ProtoMessage() {}
+
+func (x *ListLedgerEntriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLedgerEntriesResponse.ProtoReflect.Descriptor instead.
+func (*ListLedgerEntriesResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ListLedgerEntriesResponse) GetEntries() []*LedgerEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListLedgerEntriesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// Wallet кошелек инвестора. Оплата PAYMENT_METHOD_INVESTOR_MONEY блокирует сумму (held) при авторизации
+// и списывает ее с balance при списании; оплатить можно не больше available.
+type Wallet struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	UserUuid string                 `protobuf:"bytes,1,opt,name=user_uuid,json=userUuid,proto3" json:"user_uuid,omitempty"`
+	// currency код валюты ISO 4217
+	Currency string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	// balance средства на кошельке, включая заблокированные
+	Balance *Money `protobuf:"bytes,3,opt,name=balance,proto3" json:"balance,omitempty"`
+	// held сумма несписанных авторизаций
+	Held *Money `protobuf:"bytes,4,opt,name=held,proto3" json:"held,omitempty"`
+	// available balance за вычетом held
+	Available     *Money                 `protobuf:"bytes,5,opt,name=available,proto3" json:"available,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Wallet) Reset() {
+	*x = Wallet{}
+	mi := &file_payment_v1_payment_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Wallet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Wallet) ProtoMessage() {}
+
+func (x *Wallet) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Wallet.ProtoReflect.Descriptor instead.
+func (*Wallet) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *Wallet) GetUserUuid() string {
 	if x != nil {
 		return x.UserUuid
 	}
 	return ""
 }
 
-func (x *PayOrderRequest) GetPaymentMethod() PaymentMethod {
+func (x *Wallet) GetCurrency() string {
 	if x != nil {
-		return x.PaymentMethod
+		return x.Currency
 	}
-	return PaymentMethod_PAYMENT_METHOD_UNSPECIFIED
+	return ""
 }
 
-// PayOrderResponse ответ на запрос оплаты
-type PayOrderResponse struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	TransactionUuid string                 `protobuf:"bytes,1,opt,name=transaction_uuid,json=transactionUuid,proto3" json:"transaction_uuid,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+func (x *Wallet) GetBalance() *Money {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
 }
 
-func (x *PayOrderResponse) Reset() {
-	*x = PayOrderResponse{}
-	mi := &file_payment_v1_payment_proto_msgTypes[1]
+func (x *Wallet) GetHeld() *Money {
+	if x != nil {
+		return x.Held
+	}
+	return nil
+}
+
+func (x *Wallet) GetAvailable() *Money {
+	if x != nil {
+		return x.Available
+	}
+	return nil
+}
+
+func (x *Wallet) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// TopUpWalletRequest запрос на пополнение кошелька
+type TopUpWalletRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	UserUuid string                 `protobuf:"bytes,1,opt,name=user_uuid,json=userUuid,proto3" json:"user_uuid,omitempty"`
+	// amount сумма пополнения, больше нуля
+	Amount        *Money `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency      string `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopUpWalletRequest) Reset() {
+	*x = TopUpWalletRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PayOrderResponse) String() string {
+func (x *TopUpWalletRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PayOrderResponse) ProtoMessage() {}
+func (*TopUpWalletRequest) ProtoMessage() {}
 
-func (x *PayOrderResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_payment_v1_payment_proto_msgTypes[1]
+func (x *TopUpWalletRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -171,39 +2194,374 @@ func (x *PayOrderResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PayOrderResponse.ProtoReflect.Descriptor instead.
-func (*PayOrderResponse) Descriptor() ([]byte, []int) {
-	return file_payment_v1_payment_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use TopUpWalletRequest.ProtoReflect.Descriptor instead.
+func (*TopUpWalletRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{28}
 }
 
-func (x *PayOrderResponse) GetTransactionUuid() string {
+func (x *TopUpWalletRequest) GetUserUuid() string {
 	if x != nil {
-		return x.TransactionUuid
+		return x.UserUuid
+	}
+	return ""
+}
+
+func (x *TopUpWalletRequest) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *TopUpWalletRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+// TopUpWalletResponse кошелек после пополнения
+type TopUpWalletResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Wallet        *Wallet                `protobuf:"bytes,1,opt,name=wallet,proto3" json:"wallet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopUpWalletResponse) Reset() {
+	*x = TopUpWalletResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopUpWalletResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopUpWalletResponse) ProtoMessage() {}
+
+func (x *TopUpWalletResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopUpWalletResponse.ProtoReflect.Descriptor instead.
+func (*TopUpWalletResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *TopUpWalletResponse) GetWallet() *Wallet {
+	if x != nil {
+		return x.Wallet
+	}
+	return nil
+}
+
+// GetWalletRequest запрос кошелька
+type GetWalletRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserUuid      string                 `protobuf:"bytes,1,opt,name=user_uuid,json=userUuid,proto3" json:"user_uuid,omitempty"`
+	Currency      string                 `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetWalletRequest) Reset() {
+	*x = GetWalletRequest{}
+	mi := &file_payment_v1_payment_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetWalletRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetWalletRequest) ProtoMessage() {}
+
+func (x *GetWalletRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetWalletRequest.ProtoReflect.Descriptor instead.
+func (*GetWalletRequest) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *GetWalletRequest) GetUserUuid() string {
+	if x != nil {
+		return x.UserUuid
+	}
+	return ""
+}
+
+func (x *GetWalletRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
 	}
 	return ""
 }
 
+// GetWalletResponse кошелек инвестора
+type GetWalletResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Wallet        *Wallet                `protobuf:"bytes,1,opt,name=wallet,proto3" json:"wallet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetWalletResponse) Reset() {
+	*x = GetWalletResponse{}
+	mi := &file_payment_v1_payment_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetWalletResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetWalletResponse) ProtoMessage() {}
+
+func (x *GetWalletResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_payment_v1_payment_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetWalletResponse.ProtoReflect.Descriptor instead.
+func (*GetWalletResponse) Descriptor() ([]byte, []int) {
+	return file_payment_v1_payment_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *GetWalletResponse) GetWallet() *Wallet {
+	if x != nil {
+		return x.Wallet
+	}
+	return nil
+}
+
 var File_payment_v1_payment_proto protoreflect.FileDescriptor
 
 const file_payment_v1_payment_proto_rawDesc = "" +
 	"\n" +
 	"\x18payment/v1/payment.proto\x12\n" +
-	"payment.v1\"\x8f\x01\n" +
+	"payment.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"a\n" +
+	"\x0eRiskAssessment\x124\n" +
+	"\bdecision\x18\x01 \x01(\x0e2\x18.payment.v1.RiskDecisionR\bdecision\x12\x19\n" +
+	"\brule_ids\x18\x02 \x03(\tR\aruleIds\"3\n" +
+	"\x05Money\x12\x14\n" +
+	"\x05units\x18\x01 \x01(\x03R\x05units\x12\x14\n" +
+	"\x05nanos\x18\x02 \x01(\x05R\x05nanos\"\xb8\x06\n" +
+	"\vTransaction\x12)\n" +
+	"\x10transaction_uuid\x18\x01 \x01(\tR\x0ftransactionUuid\x12\x1d\n" +
+	"\n" +
+	"order_uuid\x18\x02 \x01(\tR\torderUuid\x12\x1b\n" +
+	"\tuser_uuid\x18\x03 \x01(\tR\buserUuid\x12@\n" +
+	"\x0epayment_method\x18\x04 \x01(\x0e2\x19.payment.v1.PaymentMethodR\rpaymentMethod\x12)\n" +
+	"\x06amount\x18\x05 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x06 \x01(\tR\bcurrency\x12:\n" +
+	"\x0frefunded_amount\x18\a \x01(\v2\x11.payment.v1.MoneyR\x0erefundedAmount\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x125\n" +
+	"\x06status\x18\t \x01(\x0e2\x1d.payment.v1.TransactionStatusR\x06status\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x1a\n" +
+	"\bprovider\x18\f \x01(\tR\bprovider\x12-\n" +
+	"\x12provider_reference\x18\r \x01(\tR\x11providerReference\x12!\n" +
+	"\fdecline_code\x18\x0e \x01(\tR\vdeclineCode\x12.\n" +
+	"\x04risk\x18\x0f \x01(\v2\x1a.payment.v1.RiskAssessmentR\x04risk\x12,\n" +
+	"\arefunds\x18\x10 \x03(\v2\x12.payment.v1.RefundR\arefunds\x12I\n" +
+	"\x11pending_operation\x18\x11 \x01(\v2\x1c.payment.v1.PendingOperationR\x10pendingOperation\"\x8f\x02\n" +
+	"\x10PendingOperation\x12%\n" +
+	"\x0eoperation_uuid\x18\x01 \x01(\tR\roperationUuid\x12-\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x19.payment.v1.OperationTypeR\x04type\x12)\n" +
+	"\x06amount\x18\x03 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12'\n" +
+	"\x0fidempotency_key\x18\x04 \x01(\tR\x0eidempotencyKey\x129\n" +
+	"\n" +
+	"started_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\"\xd0\x01\n" +
+	"\x06Refund\x12\x1f\n" +
+	"\vrefund_uuid\x18\x01 \x01(\tR\n" +
+	"refundUuid\x12)\n" +
+	"\x06amount\x18\x02 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12'\n" +
+	"\x0fidempotency_key\x18\x04 \x01(\tR\x0eidempotencyKey\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xd6\x01\n" +
 	"\x0fPayOrderRequest\x12\x1d\n" +
 	"\n" +
 	"order_uuid\x18\x01 \x01(\tR\torderUuid\x12\x1b\n" +
 	"\tuser_uuid\x18\x02 \x01(\tR\buserUuid\x12@\n" +
-	"\x0epayment_method\x18\x03 \x01(\x0e2\x19.payment.v1.PaymentMethodR\rpaymentMethod\"=\n" +
+	"\x0epayment_method\x18\x03 \x01(\x0e2\x19.payment.v1.PaymentMethodR\rpaymentMethod\x12)\n" +
+	"\x06amount\x18\x04 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\"x\n" +
 	"\x10PayOrderResponse\x12)\n" +
-	"\x10transaction_uuid\x18\x01 \x01(\tR\x0ftransactionUuid*\xa3\x01\n" +
+	"\x10transaction_uuid\x18\x01 \x01(\tR\x0ftransactionUuid\x129\n" +
+	"\vtransaction\x18\x02 \x01(\v2\x17.payment.v1.TransactionR\vtransaction\"\xde\x01\n" +
+	"\x17AuthorizePaymentRequest\x12\x1d\n" +
+	"\n" +
+	"order_uuid\x18\x01 \x01(\tR\torderUuid\x12\x1b\n" +
+	"\tuser_uuid\x18\x02 \x01(\tR\buserUuid\x12@\n" +
+	"\x0epayment_method\x18\x03 \x01(\x0e2\x19.payment.v1.PaymentMethodR\rpaymentMethod\x12)\n" +
+	"\x06amount\x18\x04 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\"\x80\x01\n" +
+	"\x18AuthorizePaymentResponse\x12)\n" +
+	"\x10transaction_uuid\x18\x01 \x01(\tR\x0ftransactionUuid\x129\n" +
+	"\vtransaction\x18\x02 \x01(\v2\x17.payment.v1.TransactionR\vtransaction\"B\n" +
+	"\x15CapturePaymentRequest\x12)\n" +
+	"\x10transaction_uuid\x18\x01 \x01(\tR\x0ftransactionUuid\"S\n" +
+	"\x16CapturePaymentResponse\x129\n" +
+	"\vtransaction\x18\x01 \x01(\v2\x17.payment.v1.TransactionR\vtransaction\"]\n" +
+	"\x18VoidAuthorizationRequest\x12)\n" +
+	"\x10transaction_uuid\x18\x01 \x01(\tR\x0ftransactionUuid\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"V\n" +
+	"\x19VoidAuthorizationResponse\x129\n" +
+	"\vtransaction\x18\x01 \x01(\v2\x17.payment.v1.TransactionR\vtransaction\"\xb3\x01\n" +
+	"\x14RefundPaymentRequest\x12)\n" +
+	"\x10transaction_uuid\x18\x01 \x01(\tR\x0ftransactionUuid\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12)\n" +
+	"\x06amount\x18\x04 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKeyJ\x04\b\x02\x10\x03\"y\n" +
+	"\x15RefundPaymentResponse\x12\x1f\n" +
+	"\vrefund_uuid\x18\x01 \x01(\tR\n" +
+	"refundUuid\x129\n" +
+	"\vtransaction\x18\x03 \x01(\v2\x17.payment.v1.TransactionR\vtransactionJ\x04\b\x02\x10\x03\"B\n" +
+	"\x15GetTransactionRequest\x12)\n" +
+	"\x10transaction_uuid\x18\x01 \x01(\tR\x0ftransactionUuid\"S\n" +
+	"\x16GetTransactionResponse\x129\n" +
+	"\vtransaction\x18\x01 \x01(\v2\x17.payment.v1.TransactionR\vtransaction\"\x89\x02\n" +
+	"\x12TransactionsFilter\x12\x1b\n" +
+	"\tuser_uuid\x18\x01 \x01(\tR\buserUuid\x12\x1d\n" +
+	"\n" +
+	"order_uuid\x18\x02 \x01(\tR\torderUuid\x12=\n" +
+	"\fcreated_from\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\vcreatedFrom\x129\n" +
+	"\n" +
+	"created_to\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedTo\x12=\n" +
+	"\rrisk_decision\x18\x05 \x01(\x0e2\x18.payment.v1.RiskDecisionR\friskDecision\"\x8d\x01\n" +
+	"\x17ListTransactionsRequest\x126\n" +
+	"\x06filter\x18\x01 \x01(\v2\x1e.payment.v1.TransactionsFilterR\x06filter\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"\x7f\n" +
+	"\x18ListTransactionsResponse\x12;\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x17.payment.v1.TransactionR\ftransactions\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"N\n" +
+	"\aPosting\x12\x18\n" +
+	"\aaccount\x18\x01 \x01(\tR\aaccount\x12)\n" +
+	"\x06amount\x18\x02 \x01(\v2\x11.payment.v1.MoneyR\x06amount\"\x90\x02\n" +
+	"\vLedgerEntry\x12\x1d\n" +
+	"\n" +
+	"entry_uuid\x18\x01 \x01(\tR\tentryUuid\x12)\n" +
+	"\x10transaction_uuid\x18\x02 \x01(\tR\x0ftransactionUuid\x12/\n" +
+	"\x04type\x18\x03 \x01(\x0e2\x1b.payment.v1.LedgerEntryTypeR\x04type\x12\x1a\n" +
+	"\bcurrency\x18\x04 \x01(\tR\bcurrency\x12/\n" +
+	"\bpostings\x18\x05 \x03(\v2\x13.payment.v1.PostingR\bpostings\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"P\n" +
+	"\x18GetAccountBalanceRequest\x12\x18\n" +
+	"\aaccount\x18\x01 \x01(\tR\aaccount\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\"~\n" +
+	"\x19GetAccountBalanceResponse\x12\x18\n" +
+	"\aaccount\x18\x01 \x01(\tR\aaccount\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\x12+\n" +
+	"\abalance\x18\x03 \x01(\v2\x11.payment.v1.MoneyR\abalance\"Z\n" +
+	"\x13LedgerEntriesFilter\x12\x18\n" +
+	"\aaccount\x18\x01 \x01(\tR\aaccount\x12)\n" +
+	"\x10transaction_uuid\x18\x02 \x01(\tR\x0ftransactionUuid\"\x8f\x01\n" +
+	"\x18ListLedgerEntriesRequest\x127\n" +
+	"\x06filter\x18\x01 \x01(\v2\x1f.payment.v1.LedgerEntriesFilterR\x06filter\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"v\n" +
+	"\x19ListLedgerEntriesResponse\x121\n" +
+	"\aentries\x18\x01 \x03(\v2\x17.payment.v1.LedgerEntryR\aentries\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\x81\x02\n" +
+	"\x06Wallet\x12\x1b\n" +
+	"\tuser_uuid\x18\x01 \x01(\tR\buserUuid\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\x12+\n" +
+	"\abalance\x18\x03 \x01(\v2\x11.payment.v1.MoneyR\abalance\x12%\n" +
+	"\x04held\x18\x04 \x01(\v2\x11.payment.v1.MoneyR\x04held\x12/\n" +
+	"\tavailable\x18\x05 \x01(\v2\x11.payment.v1.MoneyR\tavailable\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"x\n" +
+	"\x12TopUpWalletRequest\x12\x1b\n" +
+	"\tuser_uuid\x18\x01 \x01(\tR\buserUuid\x12)\n" +
+	"\x06amount\x18\x02 \x01(\v2\x11.payment.v1.MoneyR\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x03 \x01(\tR\bcurrency\"A\n" +
+	"\x13TopUpWalletResponse\x12*\n" +
+	"\x06wallet\x18\x01 \x01(\v2\x12.payment.v1.WalletR\x06wallet\"K\n" +
+	"\x10GetWalletRequest\x12\x1b\n" +
+	"\tuser_uuid\x18\x01 \x01(\tR\buserUuid\x12\x1a\n" +
+	"\bcurrency\x18\x02 \x01(\tR\bcurrency\"?\n" +
+	"\x11GetWalletResponse\x12*\n" +
+	"\x06wallet\x18\x01 \x01(\v2\x12.payment.v1.WalletR\x06wallet*\xa3\x01\n" +
 	"\rPaymentMethod\x12\x1e\n" +
 	"\x1aPAYMENT_METHOD_UNSPECIFIED\x10\x00\x12\x17\n" +
 	"\x13PAYMENT_METHOD_CARD\x10\x01\x12\x16\n" +
 	"\x12PAYMENT_METHOD_SBP\x10\x02\x12\x1e\n" +
 	"\x1aPAYMENT_METHOD_CREDIT_CARD\x10\x03\x12!\n" +
-	"\x1dPAYMENT_METHOD_INVESTOR_MONEY\x10\x042W\n" +
+	"\x1dPAYMENT_METHOD_INVESTOR_MONEY\x10\x04*\xa6\x02\n" +
+	"\x11TransactionStatus\x12\"\n" +
+	"\x1eTRANSACTION_STATUS_UNSPECIFIED\x10\x00\x12 \n" +
+	"\x1cTRANSACTION_STATUS_SUCCEEDED\x10\x01\x12)\n" +
+	"%TRANSACTION_STATUS_PARTIALLY_REFUNDED\x10\x02\x12\x1f\n" +
+	"\x1bTRANSACTION_STATUS_REFUNDED\x10\x03\x12!\n" +
+	"\x1dTRANSACTION_STATUS_AUTHORIZED\x10\x04\x12\x1d\n" +
+	"\x19TRANSACTION_STATUS_VOIDED\x10\x05\x12\x1e\n" +
+	"\x1aTRANSACTION_STATUS_PENDING\x10\x06\x12\x1d\n" +
+	"\x19TRANSACTION_STATUS_FAILED\x10\a*x\n" +
+	"\fRiskDecision\x12\x1d\n" +
+	"\x19RISK_DECISION_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13RISK_DECISION_ALLOW\x10\x01\x12\x18\n" +
+	"\x14RISK_DECISION_REVIEW\x10\x02\x12\x16\n" +
+	"\x12RISK_DECISION_DENY\x10\x03*\x7f\n" +
+	"\rOperationType\x12\x1e\n" +
+	"\x1aOPERATION_TYPE_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16OPERATION_TYPE_CAPTURE\x10\x01\x12\x17\n" +
+	"\x13OPERATION_TYPE_VOID\x10\x02\x12\x19\n" +
+	"\x15OPERATION_TYPE_REFUND\x10\x03*q\n" +
+	"\x0fLedgerEntryType\x12!\n" +
+	"\x1dLEDGER_ENTRY_TYPE_UNSPECIFIED\x10\x00\x12\x1d\n" +
+	"\x19LEDGER_ENTRY_TYPE_PAYMENT\x10\x01\x12\x1c\n" +
+	"\x18LEDGER_ENTRY_TYPE_REFUND\x10\x022\xdd\a\n" +
 	"\x0ePaymentService\x12E\n" +
-	"\bPayOrder\x12\x1b.payment.v1.PayOrderRequest\x1a\x1c.payment.v1.PayOrderResponseBJZHgithub.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1;paymentv1b\x06proto3"
+	"\bPayOrder\x12\x1b.payment.v1.PayOrderRequest\x1a\x1c.payment.v1.PayOrderResponse\x12]\n" +
+	"\x10AuthorizePayment\x12#.payment.v1.AuthorizePaymentRequest\x1a$.payment.v1.AuthorizePaymentResponse\x12W\n" +
+	"\x0eCapturePayment\x12!.payment.v1.CapturePaymentRequest\x1a\".payment.v1.CapturePaymentResponse\x12`\n" +
+	"\x11VoidAuthorization\x12$.payment.v1.VoidAuthorizationRequest\x1a%.payment.v1.VoidAuthorizationResponse\x12T\n" +
+	"\rRefundPayment\x12 .payment.v1.RefundPaymentRequest\x1a!.payment.v1.RefundPaymentResponse\x12W\n" +
+	"\x0eGetTransaction\x12!.payment.v1.GetTransactionRequest\x1a\".payment.v1.GetTransactionResponse\x12]\n" +
+	"\x10ListTransactions\x12#.payment.v1.ListTransactionsRequest\x1a$.payment.v1.ListTransactionsResponse\x12`\n" +
+	"\x11GetAccountBalance\x12$.payment.v1.GetAccountBalanceRequest\x1a%.payment.v1.GetAccountBalanceResponse\x12`\n" +
+	"\x11ListLedgerEntries\x12$.payment.v1.ListLedgerEntriesRequest\x1a%.payment.v1.ListLedgerEntriesResponse\x12N\n" +
+	"\vTopUpWallet\x12\x1e.payment.v1.TopUpWalletRequest\x1a\x1f.payment.v1.TopUpWalletResponse\x12H\n" +
+	"\tGetWallet\x12\x1c.payment.v1.GetWalletRequest\x1a\x1d.payment.v1.GetWalletResponseBJZHgithub.com/evgeniyseleznev/bigproj/shared/pkg/proto/payment/v1;paymentv1b\x06proto3"
 
 var (
 	file_payment_v1_payment_proto_rawDescOnce sync.Once
@@ -217,22 +2575,122 @@ func file_payment_v1_payment_proto_rawDescGZIP() []byte {
 	return file_payment_v1_payment_proto_rawDescData
 }
 
-var file_payment_v1_payment_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_payment_v1_payment_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_payment_v1_payment_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_payment_v1_payment_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
 var file_payment_v1_payment_proto_goTypes = []any{
-	(PaymentMethod)(0),       // 0: payment.v1.PaymentMethod
-	(*PayOrderRequest)(nil),  // 1: payment.v1.PayOrderRequest
-	(*PayOrderResponse)(nil), // 2: payment.v1.PayOrderResponse
+	(PaymentMethod)(0),                // 0: payment.v1.PaymentMethod
+	(TransactionStatus)(0),            // 1: payment.v1.TransactionStatus
+	(RiskDecision)(0),                 // 2: payment.v1.RiskDecision
+	(OperationType)(0),                // 3: payment.v1.OperationType
+	(LedgerEntryType)(0),              // 4: payment.v1.LedgerEntryType
+	(*RiskAssessment)(nil),            // 5: payment.v1.RiskAssessment
+	(*Money)(nil),                     // 6: payment.v1.Money
+	(*Transaction)(nil),               // 7: payment.v1.Transaction
+	(*PendingOperation)(nil),          // 8: payment.v1.PendingOperation
+	(*Refund)(nil),                    // 9: payment.v1.Refund
+	(*PayOrderRequest)(nil),           // 10: payment.v1.PayOrderRequest
+	(*PayOrderResponse)(nil),          // 11: payment.v1.PayOrderResponse
+	(*AuthorizePaymentRequest)(nil),   // 12: payment.v1.AuthorizePaymentRequest
+	(*AuthorizePaymentResponse)(nil),  // 13: payment.v1.AuthorizePaymentResponse
+	(*CapturePaymentRequest)(nil),     // 14: payment.v1.CapturePaymentRequest
+	(*CapturePaymentResponse)(nil),    // 15: payment.v1.CapturePaymentResponse
+	(*VoidAuthorizationRequest)(nil),  // 16: payment.v1.VoidAuthorizationRequest
+	(*VoidAuthorizationResponse)(nil), // 17: payment.v1.VoidAuthorizationResponse
+	(*RefundPaymentRequest)(nil),      // 18: payment.v1.RefundPaymentRequest
+	(*RefundPaymentResponse)(nil),     // 19: payment.v1.RefundPaymentResponse
+	(*GetTransactionRequest)(nil),     // 20: payment.v1.GetTransactionRequest
+	(*GetTransactionResponse)(nil),    // 21: payment.v1.GetTransactionResponse
+	(*TransactionsFilter)(nil),        // 22: payment.v1.TransactionsFilter
+	(*ListTransactionsRequest)(nil),   // 23: payment.v1.ListTransactionsRequest
+	(*ListTransactionsResponse)(nil),  // 24: payment.v1.ListTransactionsResponse
+	(*Posting)(nil),                   // 25: payment.v1.Posting
+	(*LedgerEntry)(nil),               // 26: payment.v1.LedgerEntry
+	(*GetAccountBalanceRequest)(nil),  // 27: payment.v1.GetAccountBalanceRequest
+	(*GetAccountBalanceResponse)(nil), // 28: payment.v1.GetAccountBalanceResponse
+	(*LedgerEntriesFilter)(nil),       // 29: payment.v1.LedgerEntriesFilter
+	(*ListLedgerEntriesRequest)(nil),  // 30: payment.v1.ListLedgerEntriesRequest
+	(*ListLedgerEntriesResponse)(nil), // 31: payment.v1.ListLedgerEntriesResponse
+	(*Wallet)(nil),                    // 32: payment.v1.Wallet
+	(*TopUpWalletRequest)(nil),        // 33: payment.v1.TopUpWalletRequest
+	(*TopUpWalletResponse)(nil),       // 34: payment.v1.TopUpWalletResponse
+	(*GetWalletRequest)(nil),          // 35: payment.v1.GetWalletRequest
+	(*GetWalletResponse)(nil),         // 36: payment.v1.GetWalletResponse
+	(*timestamppb.Timestamp)(nil),     // 37: google.protobuf.Timestamp
 }
 var file_payment_v1_payment_proto_depIdxs = []int32{
-	0, // 0: payment.v1.PayOrderRequest.payment_method:type_name -> payment.v1.PaymentMethod
-	1, // 1: payment.v1.PaymentService.PayOrder:input_type -> payment.v1.PayOrderRequest
-	2, // 2: payment.v1.PaymentService.PayOrder:output_type -> payment.v1.PayOrderResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	2,  // 0: payment.v1.RiskAssessment.decision:type_name -> payment.v1.RiskDecision
+	0,  // 1: payment.v1.Transaction.payment_method:type_name -> payment.v1.PaymentMethod
+	6,  // 2: payment.v1.Transaction.amount:type_name -> payment.v1.Money
+	6,  // 3: payment.v1.Transaction.refunded_amount:type_name -> payment.v1.Money
+	37, // 4: payment.v1.Transaction.created_at:type_name -> google.protobuf.Timestamp
+	1,  // 5: payment.v1.Transaction.status:type_name -> payment.v1.TransactionStatus
+	37, // 6: payment.v1.Transaction.updated_at:type_name -> google.protobuf.Timestamp
+	37, // 7: payment.v1.Transaction.expires_at:type_name -> google.protobuf.Timestamp
+	5,  // 8: payment.v1.Transaction.risk:type_name -> payment.v1.RiskAssessment
+	9,  // 9: payment.v1.Transaction.refunds:type_name -> payment.v1.Refund
+	8,  // 10: payment.v1.Transaction.pending_operation:type_name -> payment.v1.PendingOperation
+	3,  // 11: payment.v1.PendingOperation.type:type_name -> payment.v1.OperationType
+	6,  // 12: payment.v1.PendingOperation.amount:type_name -> payment.v1.Money
+	37, // 13: payment.v1.PendingOperation.started_at:type_name -> google.protobuf.Timestamp
+	6,  // 14: payment.v1.Refund.amount:type_name -> payment.v1.Money
+	37, // 15: payment.v1.Refund.created_at:type_name -> google.protobuf.Timestamp
+	0,  // 16: payment.v1.PayOrderRequest.payment_method:type_name -> payment.v1.PaymentMethod
+	6,  // 17: payment.v1.PayOrderRequest.amount:type_name -> payment.v1.Money
+	7,  // 18: payment.v1.PayOrderResponse.transaction:type_name -> payment.v1.Transaction
+	0,  // 19: payment.v1.AuthorizePaymentRequest.payment_method:type_name -> payment.v1.PaymentMethod
+	6,  // 20: payment.v1.AuthorizePaymentRequest.amount:type_name -> payment.v1.Money
+	7,  // 21: payment.v1.AuthorizePaymentResponse.transaction:type_name -> payment.v1.Transaction
+	7,  // 22: payment.v1.CapturePaymentResponse.transaction:type_name -> payment.v1.Transaction
+	7,  // 23: payment.v1.VoidAuthorizationResponse.transaction:type_name -> payment.v1.Transaction
+	6,  // 24: payment.v1.RefundPaymentRequest.amount:type_name -> payment.v1.Money
+	7,  // 25: payment.v1.RefundPaymentResponse.transaction:type_name -> payment.v1.Transaction
+	7,  // 26: payment.v1.GetTransactionResponse.transaction:type_name -> payment.v1.Transaction
+	37, // 27: payment.v1.TransactionsFilter.created_from:type_name -> google.protobuf.Timestamp
+	37, // 28: payment.v1.TransactionsFilter.created_to:type_name -> google.protobuf.Timestamp
+	2,  // 29: payment.v1.TransactionsFilter.risk_decision:type_name -> payment.v1.RiskDecision
+	22, // 30: payment.v1.ListTransactionsRequest.filter:type_name -> payment.v1.TransactionsFilter
+	7,  // 31: payment.v1.ListTransactionsResponse.transactions:type_name -> payment.v1.Transaction
+	6,  // 32: payment.v1.Posting.amount:type_name -> payment.v1.Money
+	4,  // 33: payment.v1.LedgerEntry.type:type_name -> payment.v1.LedgerEntryType
+	25, // 34: payment.v1.LedgerEntry.postings:type_name -> payment.v1.Posting
+	37, // 35: payment.v1.LedgerEntry.created_at:type_name -> google.protobuf.Timestamp
+	6,  // 36: payment.v1.GetAccountBalanceResponse.balance:type_name -> payment.v1.Money
+	29, // 37: payment.v1.ListLedgerEntriesRequest.filter:type_name -> payment.v1.LedgerEntriesFilter
+	26, // 38: payment.v1.ListLedgerEntriesResponse.entries:type_name -> payment.v1.LedgerEntry
+	6,  // 39: payment.v1.Wallet.balance:type_name -> payment.v1.Money
+	6,  // 40: payment.v1.Wallet.held:type_name -> payment.v1.Money
+	6,  // 41: payment.v1.Wallet.available:type_name -> payment.v1.Money
+	37, // 42: payment.v1.Wallet.updated_at:type_name -> google.protobuf.Timestamp
+	6,  // 43: payment.v1.TopUpWalletRequest.amount:type_name -> payment.v1.Money
+	32, // 44: payment.v1.TopUpWalletResponse.wallet:type_name -> payment.v1.Wallet
+	32, // 45: payment.v1.GetWalletResponse.wallet:type_name -> payment.v1.Wallet
+	10, // 46: payment.v1.PaymentService.PayOrder:input_type -> payment.v1.PayOrderRequest
+	12, // 47: payment.v1.PaymentService.AuthorizePayment:input_type -> payment.v1.AuthorizePaymentRequest
+	14, // 48: payment.v1.PaymentService.CapturePayment:input_type -> payment.v1.CapturePaymentRequest
+	16, // 49: payment.v1.PaymentService.VoidAuthorization:input_type -> payment.v1.VoidAuthorizationRequest
+	18, // 50: payment.v1.PaymentService.RefundPayment:input_type -> payment.v1.RefundPaymentRequest
+	20, // 51: payment.v1.PaymentService.GetTransaction:input_type -> payment.v1.GetTransactionRequest
+	23, // 52: payment.v1.PaymentService.ListTransactions:input_type -> payment.v1.ListTransactionsRequest
+	27, // 53: payment.v1.PaymentService.GetAccountBalance:input_type -> payment.v1.GetAccountBalanceRequest
+	30, // 54: payment.v1.PaymentService.ListLedgerEntries:input_type -> payment.v1.ListLedgerEntriesRequest
+	33, // 55: payment.v1.PaymentService.TopUpWallet:input_type -> payment.v1.TopUpWalletRequest
+	35, // 56: payment.v1.PaymentService.GetWallet:input_type -> payment.v1.GetWalletRequest
+	11, // 57: payment.v1.PaymentService.PayOrder:output_type -> payment.v1.PayOrderResponse
+	13, // 58: payment.v1.PaymentService.AuthorizePayment:output_type -> payment.v1.AuthorizePaymentResponse
+	15, // 59: payment.v1.PaymentService.CapturePayment:output_type -> payment.v1.CapturePaymentResponse
+	17, // 60: payment.v1.PaymentService.VoidAuthorization:output_type -> payment.v1.VoidAuthorizationResponse
+	19, // 61: payment.v1.PaymentService.RefundPayment:output_type -> payment.v1.RefundPaymentResponse
+	21, // 62: payment.v1.PaymentService.GetTransaction:output_type -> payment.v1.GetTransactionResponse
+	24, // 63: payment.v1.PaymentService.ListTransactions:output_type -> payment.v1.ListTransactionsResponse
+	28, // 64: payment.v1.PaymentService.GetAccountBalance:output_type -> payment.v1.GetAccountBalanceResponse
+	31, // 65: payment.v1.PaymentService.ListLedgerEntries:output_type -> payment.v1.ListLedgerEntriesResponse
+	34, // 66: payment.v1.PaymentService.TopUpWallet:output_type -> payment.v1.TopUpWalletResponse
+	36, // 67: payment.v1.PaymentService.GetWallet:output_type -> payment.v1.GetWalletResponse
+	57, // [57:68] is the sub-list for method output_type
+	46, // [46:57] is the sub-list for method input_type
+	46, // [46:46] is the sub-list for extension type_name
+	46, // [46:46] is the sub-list for extension extendee
+	0,  // [0:46] is the sub-list for field type_name
 }
 
 func init() { file_payment_v1_payment_proto_init() }
@@ -245,8 +2703,8 @@ func file_payment_v1_payment_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_payment_v1_payment_proto_rawDesc), len(file_payment_v1_payment_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   2,
+			NumEnums:      5,
+			NumMessages:   32,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -36,7 +36,7 @@ echo -e "${BLUE}🚀 Запускаем все сервисы...${NC}"
 
 # Запуск Inventory Service (gRPC :50051)
 cd "$BASE_DIR/inventory" || exit 1
-go run cmd/server/main.go > /tmp/inventory.log 2>&1 &
+go run ./cmd/server > /tmp/inventory.log 2>&1 &
 INVENTORY_PID=$!
 echo "$INVENTORY_PID" >> "$PID_FILE"
 echo -e "${GREEN}✅ Inventory Service запущен (PID: $INVENTORY_PID) на порту :50051${NC}"
@@ -47,7 +47,7 @@ sleep 1
 
 # Запуск Payment Service (gRPC :50052)
 cd "$BASE_DIR/payment" || exit 1
-go run cmd/server/main.go > /tmp/payment.log 2>&1 &
+go run ./cmd/server > /tmp/payment.log 2>&1 &
 PAYMENT_PID=$!
 echo "$PAYMENT_PID" >> "$PID_FILE"
 echo -e "${GREEN}✅ Payment Service запущен (PID: $PAYMENT_PID) на порту :50052${NC}"
@@ -57,7 +57,7 @@ sleep 1
 
 # Запуск Order Service (HTTP :8080)
 cd "$BASE_DIR/order" || exit 1
-go run cmd/server/main.go > /tmp/order.log 2>&1 &
+go run ./cmd/server > /tmp/order.log 2>&1 &
 ORDER_PID=$!
 echo "$ORDER_PID" >> "$PID_FILE"
 echo -e "${GREEN}✅ Order Service запущен (PID: $ORDER_PID) на порту :8080${NC}"
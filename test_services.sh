@@ -28,15 +28,15 @@ wait_for_service() {
 
 # Запускаем сервисы в фоне
 echo "🚀 Запуск сервисов..."
-cd inventory && go run cmd/server/main.go > /tmp/inventory.log 2>&1 &
+cd inventory && go run ./cmd/server > /tmp/inventory.log 2>&1 &
 INVENTORY_PID=$!
 cd ..
 
-cd payment && go run cmd/server/main.go > /tmp/payment.log 2>&1 &
+cd payment && go run ./cmd/server > /tmp/payment.log 2>&1 &
 PAYMENT_PID=$!
 cd ..
 
-cd order && go run cmd/server/main.go > /tmp/order.log 2>&1 &
+cd order && go run ./cmd/server > /tmp/order.log 2>&1 &
 ORDER_PID=$!
 cd ..
 
@@ -55,8 +55,8 @@ echo "Inventory service работает (gRPC на :50051)"
 
 echo ""
 echo "📝 Тест 2: Создание заказа"
-USER_UUID="user-test-$(date +%s)"
-PART_UUID="part-uuid-1"
+USER_UUID=$(cat /proc/sys/kernel/random/uuid)
+PART_UUID="8d6e1f3a-2c4b-4a5e-9f70-000000000001"
 
 ORDER_RESPONSE=$(curl -s -X POST "http://localhost:8080/api/v1/orders" \
   -H "Content-Type: application/json" \
@@ -100,8 +100,18 @@ fi
 echo ""
 echo "📊 Тест 5: Проверка статуса после оплаты"
 ORDER_INFO=$(curl -s "http://localhost:8080/api/v1/orders/$ORDER_UUID")
-if echo "$ORDER_INFO" | grep -q "PAID"; then
-    echo "✓ Статус заказа после оплаты: PAID"
+if echo "$ORDER_INFO" | grep -q "AUTHORIZED"; then
+    echo "✓ Статус заказа после оплаты: AUTHORIZED"
+else
+    echo "✗ Статус не обновлен на AUTHORIZED"
+fi
+
+echo ""
+echo "🚀 Тест 6: Выдача заказа"
+FULFIL_STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "http://localhost:8080/api/v1/orders/$ORDER_UUID/fulfil")
+ORDER_INFO=$(curl -s "http://localhost:8080/api/v1/orders/$ORDER_UUID")
+if [ "$FULFIL_STATUS" = "204" ] && echo "$ORDER_INFO" | grep -q "PAID"; then
+    echo "✓ Статус заказа после выдачи: PAID"
 else
     echo "✗ Статус не обновлен на PAID"
 fi